@@ -0,0 +1,262 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// Package audit keeps a persistent log of the permission decisions of a node:
+// rejected transactions, peers and RPC calls as well as the permission and
+// governance changes made on chain. Entries are numbered in the order they
+// are recorded and never removed.
+package audit
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+)
+
+// Kind is the type of a recorded decision.
+type Kind string
+
+const (
+	// TxRejected is recorded for transactions refused for lack of permission
+	// or an invalid transaction certificate.
+	TxRejected Kind = "tx"
+
+	// PeerRejected is recorded for peers refused for their enrollment
+	// certificate or disconnected for a message their role may not send.
+	PeerRejected Kind = "peer"
+
+	// RPCDenied is recorded for RPC callers refused by the access control.
+	// Repeated denials of a caller may be aggregated into one entry.
+	RPCDenied Kind = "rpc"
+
+	// PermissionChanged is recorded for changes of the permission registry
+	// and the deployment allow-list included in the chain.
+	PermissionChanged Kind = "permission"
+
+	// GovernanceChanged is recorded for proposals, votes and decisions of
+	// the governance contract included in the chain.
+	GovernanceChanged Kind = "governance"
+)
+
+// Entry is a recorded decision.
+type Entry struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Kind    Kind      `json:"kind"`
+	Subject string    `json:"subject"` // account, peer or caller the decision concerns
+	Detail  string    `json:"detail"`
+}
+
+// Filter selects entries. Zero values match everything.
+type Filter struct {
+	From  int64  `json:"from"`  // first second, unix time
+	To    int64  `json:"to"`    // last second, unix time
+	Kinds []Kind `json:"kinds"` // kinds to include
+	Limit int    `json:"limit"` // maximum number of entries
+}
+
+func (f *Filter) matches(e *Entry) bool {
+	if len(f.Kinds) == 0 {
+		return true
+	}
+	for _, kind := range f.Kinds {
+		if kind == e.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	countKey    = []byte("audit-count")
+	entryPrefix = []byte("audit-entry-")
+)
+
+// Log is an audit log stored in a database.
+type Log struct {
+	db ethdb.Database
+
+	mu    sync.Mutex
+	count uint64
+	last  time.Time
+}
+
+// NewLog opens the audit log kept in db.
+func NewLog(db ethdb.Database) (*Log, error) {
+	l := &Log{db: db}
+	if enc, err := db.Get(countKey); err == nil && len(enc) == 8 {
+		l.count = binary.BigEndian.Uint64(enc)
+	}
+	if l.count > 0 {
+		last, err := l.entry(l.count - 1)
+		if err != nil {
+			return nil, err
+		}
+		l.last = last.Time
+	}
+	return l, nil
+}
+
+// Record appends an entry to the log. Entries are kept in time order, an
+// entry is never recorded before the previous one.
+func (l *Log) Record(kind Kind, subject, detail string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.last) {
+		now = l.last
+	}
+	entry := &Entry{Seq: l.count, Time: now, Kind: kind, Subject: subject, Detail: detail}
+	enc, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	count := make([]byte, 8)
+	binary.BigEndian.PutUint64(count, l.count+1)
+
+	batch := l.db.NewBatch()
+	batch.Put(entryKey(l.count), enc)
+	batch.Put(countKey, count)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	l.count++
+	l.last = now
+	return nil
+}
+
+// Len returns the number of recorded entries.
+func (l *Log) Len() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.count
+}
+
+// Query returns the entries selected by the filter, oldest first.
+func (l *Log) Query(f Filter) ([]*Entry, error) {
+	var entries []*Entry
+	err := l.each(f, func(e *Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// Export writes the entries selected by the filter to w as JSON, one entry
+// per line, and returns their number.
+func (l *Log) Export(w io.Writer, f Filter) (int, error) {
+	var (
+		enc   = json.NewEncoder(w)
+		count int
+	)
+	err := l.each(f, func(e *Entry) error {
+		count++
+		return enc.Encode(e)
+	})
+	return count, err
+}
+
+func (l *Log) each(f Filter, fn func(*Entry) error) error {
+	count := l.Len()
+
+	// Entries are in time order, find the first one in range
+	var (
+		start uint64
+		err   error
+	)
+	if f.From > 0 {
+		start = uint64(sort.Search(int(count), func(i int) bool {
+			e, lerr := l.entry(uint64(i))
+			if lerr != nil {
+				err = lerr
+				return true
+			}
+			return e.Time.Unix() >= f.From
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	for seq, n := start, 0; seq < count && (f.Limit <= 0 || n < f.Limit); seq++ {
+		e, err := l.entry(seq)
+		if err != nil {
+			return err
+		}
+		if f.To > 0 && e.Time.Unix() > f.To {
+			break
+		}
+		if !f.matches(e) {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+		n++
+	}
+	return nil
+}
+
+func (l *Log) entry(seq uint64) (*Entry, error) {
+	enc, err := l.db.Get(entryKey(seq))
+	if err != nil {
+		return nil, err
+	}
+	entry := new(Entry)
+	if err := json.Unmarshal(enc, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func entryKey(seq uint64) []byte {
+	key := make([]byte, len(entryPrefix)+8)
+	copy(key, entryPrefix)
+	binary.BigEndian.PutUint64(key[len(entryPrefix):], seq)
+	return key
+}
+
+var (
+	defaultLock sync.RWMutex
+	defaultLog  *Log
+)
+
+// SetDefault sets the log written by Record. Nothing is recorded while it is
+// nil.
+func SetDefault(l *Log) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+
+	defaultLog = l
+}
+
+// Default returns the log set by SetDefault.
+func Default() *Log {
+	defaultLock.RLock()
+	defer defaultLock.RUnlock()
+
+	return defaultLog
+}
+
+// Record appends an entry to the default log, if any.
+func Record(kind Kind, subject, detail string) {
+	l := Default()
+	if l == nil {
+		return
+	}
+	if err := l.Record(kind, subject, detail); err != nil {
+		glog.V(logger.Error).Infof("Failed to record %s audit entry for %s: %v", kind, subject, err)
+	}
+}
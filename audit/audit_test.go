@@ -0,0 +1,78 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package audit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestLog(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	l, _ := NewLog(db)
+
+	kinds := []Kind{TxRejected, PeerRejected, RPCDenied, TxRejected}
+	for i, kind := range kinds {
+		if err := l.Record(kind, string('a'+byte(i)), "denied"); err != nil {
+			t.Fatalf("failed to record entry %d: %v", i, err)
+		}
+	}
+
+	// Entries survive reopening the database
+	l, err := NewLog(db)
+	if err != nil {
+		t.Fatalf("failed to reopen log: %v", err)
+	}
+	if l.Len() != uint64(len(kinds)) {
+		t.Fatalf("length mismatch: have %d, want %d", l.Len(), len(kinds))
+	}
+	all, _ := l.Query(Filter{})
+	for i, e := range all {
+		if e.Seq != uint64(i) || e.Kind != kinds[i] || i > 0 && e.Time.Before(all[i-1].Time) {
+			t.Errorf("entry %d mismatch: %+v", i, e)
+		}
+	}
+
+	tests := []struct {
+		filter Filter
+		want   string
+	}{
+		{Filter{Kinds: []Kind{TxRejected}}, "ad"},
+		{Filter{Kinds: []Kind{PeerRejected, RPCDenied}}, "bc"},
+		{Filter{Limit: 2}, "ab"},
+		{Filter{Kinds: []Kind{TxRejected}, Limit: 1}, "a"},
+		{Filter{From: time.Now().Add(time.Hour).Unix()}, ""},
+		{Filter{To: time.Now().Add(-time.Hour).Unix()}, ""},
+		{Filter{From: all[0].Time.Unix(), To: all[3].Time.Unix()}, "abcd"},
+	}
+	for i, tt := range tests {
+		entries, err := l.Query(tt.filter)
+		if err != nil {
+			t.Fatalf("test %d: query failed: %v", i, err)
+		}
+		var have string
+		for _, e := range entries {
+			have += e.Subject
+		}
+		if have != tt.want {
+			t.Errorf("test %d: entries mismatch: have %q, want %q", i, have, tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if n, err := l.Export(&buf, Filter{Kinds: []Kind{TxRejected}}); err != nil || n != 2 {
+		t.Fatalf("export failed: %d entries, %v", n, err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"subject":"d"`) {
+		t.Errorf("export mismatch: %q", buf.String())
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/audit"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/node"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	auditFromFlag = cli.StringFlag{
+		Name:  "from",
+		Usage: "Export entries recorded at or after this time (unix seconds, YYYY-MM-DD or RFC 3339)",
+	}
+	auditToFlag = cli.StringFlag{
+		Name:  "to",
+		Usage: "Export entries recorded at or before this time (unix seconds, YYYY-MM-DD or RFC 3339)",
+	}
+	auditKindsFlag = cli.StringFlag{
+		Name:  "kinds",
+		Usage: "Comma separated kinds to export (tx, peer, rpc, permission, governance)",
+	}
+	auditCommand = cli.Command{
+		Name:  "audit",
+		Usage: "Inspect the permission audit log",
+		Subcommands: []cli.Command{
+			{
+				Action: exportAudit,
+				Name:   "export",
+				Usage:  "export the audit log into file",
+				Description: `
+    geth audit export [--from <time>] [--to <time>] [--kinds <kinds>] <file>
+
+Writes the entries of the permission audit log of the data directory to the
+file as JSON, one entry per line. The node must not be running while the log
+is exported, query a running node with admin.auditLog instead.
+`,
+				Flags: []cli.Flag{
+					auditFromFlag,
+					auditToFlag,
+					auditKindsFlag,
+				},
+			},
+		},
+	}
+)
+
+func exportAudit(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	var (
+		filter audit.Filter
+		err    error
+	)
+	if filter.From, err = parseAuditTime(ctx.String(auditFromFlag.Name), false); err != nil {
+		utils.Fatalf("Invalid --%s: %v", auditFromFlag.Name, err)
+	}
+	if filter.To, err = parseAuditTime(ctx.String(auditToFlag.Name), true); err != nil {
+		utils.Fatalf("Invalid --%s: %v", auditToFlag.Name, err)
+	}
+	if kinds := ctx.String(auditKindsFlag.Name); kinds != "" {
+		for _, kind := range strings.Split(kinds, ",") {
+			filter.Kinds = append(filter.Kinds, audit.Kind(strings.TrimSpace(kind)))
+		}
+	}
+
+	log, db, err := node.OpenAuditLog(utils.MustMakeDataDir(ctx))
+	if err != nil {
+		utils.Fatalf("Could not open audit log: %v", err)
+	}
+	defer db.Close()
+
+	out, err := os.Create(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Could not create %s: %v", ctx.Args().First(), err)
+	}
+	defer out.Close()
+
+	count, err := log.Export(out, filter)
+	if err != nil {
+		utils.Fatalf("Export error: %v", err)
+	}
+	fmt.Printf("Exported %d of %d audit entries\n", count, log.Len())
+	return nil
+}
+
+// parseAuditTime parses a time given as unix seconds, date or RFC 3339 time.
+// A date stands for its first second, or its last if end is set. The empty
+// string is returned as 0, meaning no limit.
+func parseAuditTime(s string, end bool) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return secs, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Unix(), nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return 0, fmt.Errorf("unrecognized time %q", s)
+	}
+	if end {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t.Unix(), nil
+}
@@ -0,0 +1,87 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/gateway"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+	"github.com/ethereum/go-ethereum/node"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	gatewayAttachFlag = cli.StringFlag{
+		Name:  "attach",
+		Value: "ipc:" + node.DefaultIPCEndpoint(),
+		Usage: "API endpoint of the node to serve",
+	}
+	gatewayAddrFlag = cli.StringFlag{
+		Name:  "addr",
+		Value: "127.0.0.1:8600",
+		Usage: "Listening address of the REST server",
+	}
+	gatewayContractsFlag = cli.StringFlag{
+		Name:  "contracts",
+		Usage: `JSON file of contracts to register on startup, e.g. { "token": { "address": "0x...", "abi": [...] } }`,
+	}
+	gatewayCommand = cli.Command{
+		Action: serveGateway,
+		Name:   "gateway",
+		Usage:  "Serve the chain of a node as REST resources",
+		Description: `
+    geth gateway [--attach <endpoint>] [--addr <address>] [--contracts <file>]
+
+Serves blocks, transactions, receipts, accounts and contract calls of the node
+at the endpoint as REST resources in JSON, for applications that don't speak
+JSON-RPC. Contracts are registered at runtime with PUT /contracts/<name>. See
+the gateway package for the resources.
+`,
+		Flags: []cli.Flag{
+			gatewayAttachFlag,
+			gatewayAddrFlag,
+			gatewayContractsFlag,
+		},
+	}
+)
+
+func serveGateway(ctx *cli.Context) error {
+	client, err := utils.NewRemoteRPCClientFromString(ctx.String(gatewayAttachFlag.Name))
+	if err != nil {
+		utils.Fatalf("Unable to attach to geth node: %v", err)
+	}
+	defer client.Close()
+
+	gw := gateway.New(client)
+	if file := ctx.String(gatewayContractsFlag.Name); file != "" {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Could not read contracts: %v", err)
+		}
+		var contracts map[string]gateway.ContractRequest
+		if err := json.Unmarshal(blob, &contracts); err != nil {
+			utils.Fatalf("Invalid contracts file %s: %v", file, err)
+		}
+		for name, contract := range contracts {
+			if _, err := gw.Register(name, contract.Address, contract.ABI); err != nil {
+				utils.Fatalf("Could not register contract %s: %v", name, err)
+			}
+		}
+	}
+	addr := ctx.String(gatewayAddrFlag.Name)
+	glog.V(logger.Info).Infof("REST gateway listening on %s", addr)
+	if err := http.ListenAndServe(addr, gw); err != nil {
+		utils.Fatalf("Gateway error: %v", err)
+	}
+	return nil
+}
@@ -92,6 +92,8 @@ func init() {
 		upgradedbCommand,
 		removedbCommand,
 		dumpCommand,
+		auditCommand,
+		gatewayCommand,
 		monitorCommand,
 		accountCommand,
 		walletCommand,
@@ -155,6 +157,8 @@ participating.
 		utils.BlockchainVersionFlag,
 		utils.OlympicFlag,
 		utils.FastSyncFlag,
+		utils.ActivityIndexFlag,
+		utils.ExportFlag,
 		utils.CacheFlag,
 		utils.LightKDFFlag,
 		utils.JSpathFlag,
@@ -173,6 +177,7 @@ participating.
 		utils.NATFlag,
 		utils.NatspecEnabledFlag,
 		utils.NoDiscoverFlag,
+		utils.AllowAllPeersFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.RPCEnabledFlag,
@@ -184,6 +189,13 @@ participating.
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCACLFlag,
+		utils.RPCPolicyFlag,
+		utils.PropertiesFlag,
+		utils.ConsensusFlag,
+		utils.PBFTReplicasFlag,
+		utils.PBFTFaultsFlag,
+		utils.CAServersFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCApiFlag,
 		utils.IPCPathFlag,
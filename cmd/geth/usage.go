@@ -70,11 +70,23 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.DevModeFlag,
 			utils.IdentityFlag,
 			utils.FastSyncFlag,
+			utils.ActivityIndexFlag,
+			utils.ExportFlag,
 			utils.LightKDFFlag,
 			utils.CacheFlag,
 			utils.BlockchainVersionFlag,
 		},
 	},
+	{
+		Name: "DCHAIN",
+		Flags: []cli.Flag{
+			utils.PropertiesFlag,
+			utils.ConsensusFlag,
+			utils.PBFTReplicasFlag,
+			utils.PBFTFaultsFlag,
+			utils.CAServersFlag,
+		},
+	},
 	{
 		Name: "ACCOUNT",
 		Flags: []cli.Flag{
@@ -94,6 +106,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.RPCACLFlag,
+			utils.RPCPolicyFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCApiFlag,
 			utils.IPCPathFlag,
@@ -112,6 +126,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MaxPendingPeersFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
+			utils.AllowAllPeersFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
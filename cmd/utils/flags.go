@@ -37,10 +37,10 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/exporter"
 	"github.com/ethereum/go-ethereum/logger"
 	"github.com/ethereum/go-ethereum/logger/glog"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -51,8 +51,8 @@ import (
 	"github.com/ethereum/go-ethereum/pow"
 	"github.com/ethereum/go-ethereum/release"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/settings"
 	"github.com/ethereum/go-ethereum/whisper"
-	"github.com/spf13/viper"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -82,10 +82,6 @@ OPTIONS:
 `
 }
 
-const (
-	envPrefix = "BC_CONF"
-)
-
 // NewApp creates an app with sane defaults.
 func NewApp(version, usage string) *cli.App {
 	app := cli.NewApp()
@@ -164,6 +160,14 @@ var (
 		Name:  "fast",
 		Usage: "Enable fast syncing through state downloads",
 	}
+	ActivityIndexFlag = cli.BoolFlag{
+		Name:  "index",
+		Usage: "Index the transactions, internal transfers and logs of every account",
+	}
+	ExportFlag = cli.StringFlag{
+		Name:  "export",
+		Usage: "Push blocks, transactions, receipts and logs to the sinks of this JSON file",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -314,6 +318,39 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	RPCACLFlag = cli.BoolFlag{
+		Name:  "rpcacl",
+		Usage: "Restrict the HTTP-RPC and WS-RPC methods to the ones granted to the role of the caller's certificate",
+	}
+	RPCPolicyFlag = cli.StringFlag{
+		Name:  "rpcpolicy",
+		Usage: "JSON file mapping roles to the RPC methods they may call (implies --rpcacl)",
+		Value: "",
+	}
+	// DChain settings
+	PropertiesFlag = cli.StringFlag{
+		Name:  "properties",
+		Usage: "DChain properties file (default: properties.yaml in ./common/)",
+		Value: "",
+	}
+	ConsensusFlag = cli.StringFlag{
+		Name:  "consensus",
+		Usage: "Consensus algorithm, POW or PBFT (overrides consensus.algorithm)",
+		Value: "",
+	}
+	PBFTReplicasFlag = cli.IntFlag{
+		Name:  "consensus.n",
+		Usage: "Maximum number of PBFT replicas (overrides consensus.N)",
+	}
+	PBFTFaultsFlag = cli.IntFlag{
+		Name:  "consensus.f",
+		Usage: "Number of byzantine PBFT replicas tolerated (overrides consensus.f)",
+	}
+	CAServersFlag = cli.StringFlag{
+		Name:  "caservers",
+		Usage: "Comma separated host:port of the CA instances (overrides caserver.addresses)",
+		Value: "",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement (only in combination with console/attach)",
@@ -361,6 +398,10 @@ var (
 		Name:  "nodiscover",
 		Usage: "Disables the peer discovery mechanism (manual peer addition)",
 	}
+	AllowAllPeersFlag = cli.BoolFlag{
+		Name:  "allowallpeers",
+		Usage: "Accepts every enrolled node, ignoring the whitelist managed by the CA",
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -656,6 +697,30 @@ func MakePasswordList(ctx *cli.Context) []string {
 	return lines
 }
 
+// MakeDChainSettings loads the DChain settings from the properties file, the
+// BC_CONF_* environment variables and the command line flags, in increasing
+// order of precedence.
+func MakeDChainSettings(ctx *cli.Context) *settings.Config {
+	overrides := make(map[string]interface{})
+	if ctx.GlobalIsSet(ConsensusFlag.Name) {
+		overrides["consensus.algorithm"] = ctx.GlobalString(ConsensusFlag.Name)
+	}
+	if ctx.GlobalIsSet(PBFTReplicasFlag.Name) {
+		overrides["consensus.N"] = ctx.GlobalInt(PBFTReplicasFlag.Name)
+	}
+	if ctx.GlobalIsSet(PBFTFaultsFlag.Name) {
+		overrides["consensus.f"] = ctx.GlobalInt(PBFTFaultsFlag.Name)
+	}
+	if ctx.GlobalIsSet(CAServersFlag.Name) {
+		overrides["caserver.addresses"] = strings.Split(ctx.GlobalString(CAServersFlag.Name), ",")
+	}
+	dchain, err := settings.Load(ctx.GlobalString(PropertiesFlag.Name), overrides)
+	if err != nil {
+		Fatalf("Invalid DChain settings: %v", err)
+	}
+	return dchain
+}
+
 // MakeSystemNode sets up a local node, configures the services to launch and
 // assembles the P2P protocol stack.
 func MakeSystemNode(name, version string, relconf release.Config, extra []byte, ctx *cli.Context) *node.Node {
@@ -669,20 +734,12 @@ func MakeSystemNode(name, version string, relconf release.Config, extra []byte,
 	if networks > 1 {
 		Fatalf("The %v flags are mutually exclusive", netFlags)
 	}
-	viper.SetEnvPrefix(envPrefix)
-	viper.AutomaticEnv()
-	replacer := strings.NewReplacer(".", "_")
-	viper.SetEnvKeyReplacer(replacer)
-	viper.SetConfigName("properties")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("./common/")
+	dchain := MakeDChainSettings(ctx)
 
-	err := viper.ReadInConfig()
+	dat, err := ioutil.ReadFile(dchain.File)
 	if err != nil {
-		Fatalf("Fatal error when reading config file: %s", err)
+		Fatalf("Failed to read %s: %v", dchain.File, err)
 	}
-
-	dat, err := ioutil.ReadFile("./common/properties.yaml")
 	sum := md5.Sum(dat)
 	configHash := common.BytesToHash([]byte(sum[:]))
 
@@ -692,6 +749,7 @@ func MakeSystemNode(name, version string, relconf release.Config, extra []byte,
 		PrivateKey:      MakeNodeKey(ctx),
 		Name:            MakeNodeName(name, version, ctx),
 		NoDiscovery:     ctx.GlobalBool(NoDiscoverFlag.Name),
+		AllowAllPeers:   ctx.GlobalBool(AllowAllPeersFlag.Name),
 		BootstrapNodes:  MakeBootstrapNodes(ctx),
 		ListenAddr:      MakeListenAddress(ctx),
 		NAT:             MakeNAT(ctx),
@@ -706,6 +764,9 @@ func MakeSystemNode(name, version string, relconf release.Config, extra []byte,
 		WSPort:          ctx.GlobalInt(WSPortFlag.Name),
 		WSOrigins:       ctx.GlobalString(WSAllowedOriginsFlag.Name),
 		WSModules:       MakeRPCModules(ctx.GlobalString(WSApiFlag.Name)),
+		RPCACL:          ctx.GlobalBool(RPCACLFlag.Name) || ctx.GlobalIsSet(RPCPolicyFlag.Name),
+		RPCPolicy:       ctx.GlobalString(RPCPolicyFlag.Name),
+		DChain:          dchain,
 	}
 	// Configure the Ethereum service
 	accman := MakeAccountManager(ctx)
@@ -720,15 +781,12 @@ func MakeSystemNode(name, version string, relconf release.Config, extra []byte,
 		glog.V(logger.Info).Infoln("You're one of the lucky few that will try out the JIT VM (random). If you get a consensus failure please be so kind to report this incident with the block hash that failed. You can switch to the regular VM by setting --jitvm=false")
 	}
 
-	if err := ca.Init(); err != nil {
-		return nil
-	}
-
 	ethConf := &eth.Config{
 		ChainConfig:             MustMakeChainConfig(ctx),
 		Genesis:                 MakeGenesisBlock(ctx),
 		ConfigHash:		 configHash,
 		FastSync:                ctx.GlobalBool(FastSyncFlag.Name),
+		ActivityIndex:           ctx.GlobalBool(ActivityIndexFlag.Name),
 		BlockChainVersion:       ctx.GlobalInt(BlockchainVersionFlag.Name),
 		DatabaseCache:           ctx.GlobalInt(CacheFlag.Name),
 		DatabaseHandles:         MakeDatabaseHandles(),
@@ -817,6 +875,17 @@ func MakeSystemNode(name, version string, relconf release.Config, extra []byte,
 	}); err != nil {
 		Fatalf("Failed to register the Geth release oracle service: %v", err)
 	}
+	if path := ctx.GlobalString(ExportFlag.Name); path != "" {
+		config, err := exporter.LoadConfig(path)
+		if err != nil {
+			Fatalf("Failed to load the export config: %v", err)
+		}
+		if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+			return exporter.New(ctx, config)
+		}); err != nil {
+			Fatalf("Failed to register the exporter service: %v", err)
+		}
+	}
 	return stack
 }
 
@@ -145,6 +145,9 @@ func (m callmsg) Value() *big.Int {
 func (m callmsg) Data() []byte {
 	return m.data
 }
+func (m callmsg) IsCall() bool {
+	return true
+}
 
 // Call forms a transaction from the given arguments and tries to execute it on
 // a private VM with a copy of the state. Any changes are therefore only temporary
@@ -0,0 +1,410 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The activity index keeps lists of entries per account in the chain
+// database, in block order. The number of entries of a list is stored under
+// the account and every entry under its position, so lists are appended to
+// as blocks join the canonical chain and cut back when they leave it.
+var (
+	activityHeadKey     = []byte("activity-head") // hash of the last indexed block
+	activityCountPrefix = []byte("activity-n-")   // activityCountPrefix + kind + address -> number of entries
+	activityEntryPrefix = []byte("activity-e-")   // activityEntryPrefix + kind + address + position -> entry
+)
+
+// MaxActivityEntries limits the number of entries returned by a query.
+const MaxActivityEntries = 1000
+
+// ActivityKind selects one of the lists kept per account.
+type ActivityKind byte
+
+const (
+	ActivityTransactions ActivityKind = 't' // transactions the account took part in
+	ActivityTransfers    ActivityKind = 'i' // internal transfers the account sent or received
+	ActivityLogs         ActivityKind = 'l' // logs the account emitted as a contract
+)
+
+// ActivityRole is the part an account played in an entry.
+type ActivityRole uint8
+
+const (
+	RoleSender    ActivityRole = iota // sent the transaction or transfer
+	RoleRecipient                     // received the transaction or transfer
+	RoleCreated                       // was created by the transaction
+	RoleInternal                      // sent or received internal transfers of the transaction
+	RoleEmitter                       // emitted the log
+)
+
+func (r ActivityRole) String() string {
+	switch r {
+	case RoleSender:
+		return "sender"
+	case RoleRecipient:
+		return "recipient"
+	case RoleCreated:
+		return "created"
+	case RoleInternal:
+		return "internal"
+	case RoleEmitter:
+		return "emitter"
+	}
+	return fmt.Sprintf("role(%d)", uint8(r))
+}
+
+// ActivityEntry is an entry of the list of an account.
+type ActivityEntry struct {
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxIndex     uint
+	Role        ActivityRole
+	Index       uint // position of the transfer among those of the block, or index of the log in the block
+}
+
+// ActivityQuery selects a page of entries of a list.
+type ActivityQuery struct {
+	FromBlock uint64  // first block of the range
+	ToBlock   *uint64 // last block of the range, up to the head if nil
+	Cursor    *uint64 // position of the first entry, as returned for the next page
+	Limit     int     // maximum number of entries, MaxActivityEntries if not positive
+	Reverse   bool    // newest entries first
+}
+
+// ActivityIndex indexes the transactions, internal transfers and logs of the
+// canonical chain by account.
+type ActivityIndex struct {
+	db   ethdb.Database
+	lock sync.Mutex
+}
+
+// NewActivityIndex returns the activity index kept in db.
+func NewActivityIndex(db ethdb.Database) *ActivityIndex {
+	return &ActivityIndex{db: db}
+}
+
+// Head returns the hash of the last indexed block, the zero hash if nothing
+// has been indexed yet.
+func (idx *ActivityIndex) Head() common.Hash {
+	data, _ := idx.db.Get(activityHeadKey)
+	return common.BytesToHash(data)
+}
+
+// Update brings the index to the canonical chain ending at head. Indexed
+// blocks that are no longer canonical are removed, then the canonical blocks
+// above the last remaining one are added.
+func (idx *ActivityIndex) Update(head *types.Block) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	var (
+		hash = idx.Head()
+		next uint64
+	)
+	for hash != (common.Hash{}) {
+		block := GetBlock(idx.db, hash)
+		if block == nil {
+			return fmt.Errorf("indexed block %x missing", hash)
+		}
+		if block.NumberU64() <= head.NumberU64() && GetCanonicalHash(idx.db, block.NumberU64()) == hash {
+			next = block.NumberU64() + 1
+			break
+		}
+		if err := idx.remove(block); err != nil {
+			return err
+		}
+		hash = block.ParentHash()
+	}
+	if next+1 < head.NumberU64() {
+		glog.V(logger.Info).Infof("indexing account activity of blocks #%d to #%d", next, head.NumberU64())
+	}
+	for number := next; number <= head.NumberU64(); number++ {
+		block := GetBlock(idx.db, GetCanonicalHash(idx.db, number))
+		if block == nil {
+			return fmt.Errorf("canonical block #%d missing", number)
+		}
+		if err := idx.add(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activity is an entry along with the list it belongs to.
+type activity struct {
+	kind  ActivityKind
+	addr  common.Address
+	entry *ActivityEntry
+}
+
+// blockActivity returns the entries of a block, in the order they take in
+// their lists.
+func blockActivity(db ethdb.Database, block *types.Block) []activity {
+	var (
+		hash      = block.Hash()
+		receipts  = GetBlockReceipts(db, hash)
+		transfers = GetBlockTransfers(db, hash)
+		entries   []activity
+	)
+	add := func(kind ActivityKind, addr common.Address, txIndex int, role ActivityRole, index int) {
+		entry := &ActivityEntry{BlockNumber: block.NumberU64(), BlockHash: hash, TxIndex: uint(txIndex), Role: role, Index: uint(index)}
+		entries = append(entries, activity{kind, addr, entry})
+	}
+	for i, tx := range block.Transactions() {
+		// Every account appears at most once per role and transaction
+		seen := make(map[common.Address]bool)
+		from, err := tx.From()
+		if err == nil {
+			add(ActivityTransactions, from, i, RoleSender, 0)
+			seen[from] = true
+		}
+		if to := tx.To(); to != nil {
+			add(ActivityTransactions, *to, i, RoleRecipient, 0)
+			seen[*to] = true
+		} else if err == nil {
+			created := crypto.CreateAddress(from, tx.Nonce())
+			add(ActivityTransactions, created, i, RoleCreated, 0)
+			seen[created] = true
+		}
+		for j, transfer := range transfers {
+			if transfer.TxIndex != uint(i) {
+				continue
+			}
+			add(ActivityTransfers, transfer.From, i, RoleSender, j)
+			add(ActivityTransfers, transfer.To, i, RoleRecipient, j)
+			for _, addr := range []common.Address{transfer.From, transfer.To} {
+				if !seen[addr] {
+					add(ActivityTransactions, addr, i, RoleInternal, 0)
+					seen[addr] = true
+				}
+			}
+		}
+		if i < len(receipts) {
+			for _, log := range receipts[i].Logs {
+				add(ActivityLogs, log.Address, i, RoleEmitter, int(log.Index))
+			}
+		}
+	}
+	return entries
+}
+
+// add appends the entries of block to their lists and makes it the head of
+// the index.
+func (idx *ActivityIndex) add(block *types.Block) error {
+	var (
+		batch  = idx.db.NewBatch()
+		counts = make(map[string]uint64)
+	)
+	for _, a := range blockActivity(idx.db, block) {
+		key := activityCountKey(a.kind, a.addr)
+		n, ok := counts[string(key)]
+		if !ok {
+			n = idx.count(key)
+		}
+		data, err := rlp.EncodeToBytes(a.entry)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(activityEntryKey(a.kind, a.addr, n), data); err != nil {
+			return err
+		}
+		counts[string(key)] = n + 1
+	}
+	for key, n := range counts {
+		if err := batch.Put([]byte(key), encodeActivityCount(n)); err != nil {
+			return err
+		}
+	}
+	if err := batch.Put(activityHeadKey, block.Hash().Bytes()); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// remove cuts the entries of block from the end of their lists and makes its
+// parent the head of the index. The block must be the head.
+func (idx *ActivityIndex) remove(block *types.Block) error {
+	var (
+		batch   = idx.db.NewBatch()
+		counts  = make(map[string]uint64)
+		removed [][]byte
+		entries = blockActivity(idx.db, block)
+	)
+	for i := len(entries) - 1; i >= 0; i-- {
+		a := entries[i]
+		key := activityCountKey(a.kind, a.addr)
+		n, ok := counts[string(key)]
+		if !ok {
+			n = idx.count(key)
+		}
+		if n == 0 {
+			return fmt.Errorf("activity of %x in block #%d missing", a.addr, block.NumberU64())
+		}
+		entryKey := activityEntryKey(a.kind, a.addr, n-1)
+		if entry := idx.entry(entryKey); entry == nil || entry.BlockHash != block.Hash() {
+			return fmt.Errorf("activity of %x in block #%d missing", a.addr, block.NumberU64())
+		}
+		removed = append(removed, entryKey)
+		counts[string(key)] = n - 1
+	}
+	for key, n := range counts {
+		if err := batch.Put([]byte(key), encodeActivityCount(n)); err != nil {
+			return err
+		}
+	}
+	if err := batch.Put(activityHeadKey, block.ParentHash().Bytes()); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// Entries past the end of a list are never read, drop them anyway
+	for _, key := range removed {
+		idx.db.Delete(key)
+	}
+	return nil
+}
+
+// Query returns a page of the list of the given kind of addr, along with the
+// position of the first entry of the next page, nil if it is the last one.
+func (idx *ActivityIndex) Query(kind ActivityKind, addr common.Address, q ActivityQuery) ([]*ActivityEntry, *uint64, error) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	limit := q.Limit
+	if limit <= 0 || limit > MaxActivityEntries {
+		limit = MaxActivityEntries
+	}
+	// Find the positions of the block range, lists being in block order
+	count := idx.count(activityCountKey(kind, addr))
+	search := func(number uint64) uint64 {
+		return uint64(sort.Search(int(count), func(i int) bool {
+			entry := idx.entry(activityEntryKey(kind, addr, uint64(i)))
+			return entry == nil || entry.BlockNumber >= number
+		}))
+	}
+	first, end := search(q.FromBlock), count
+	if q.ToBlock != nil {
+		if *q.ToBlock < q.FromBlock {
+			return nil, nil, nil
+		}
+		end = search(*q.ToBlock + 1)
+	}
+	var (
+		entries []*ActivityEntry
+		pos     uint64
+	)
+	read := func(pos uint64) (*ActivityEntry, error) {
+		entry := idx.entry(activityEntryKey(kind, addr, pos))
+		if entry == nil {
+			return nil, fmt.Errorf("activity entry %d of %x missing", pos, addr)
+		}
+		return entry, nil
+	}
+	if !q.Reverse {
+		if pos = first; q.Cursor != nil && *q.Cursor > pos {
+			pos = *q.Cursor
+		}
+		for ; pos < end && len(entries) < limit; pos++ {
+			entry, err := read(pos)
+			if err != nil {
+				return nil, nil, err
+			}
+			entries = append(entries, entry)
+		}
+		if pos < end {
+			return entries, &pos, nil
+		}
+		return entries, nil, nil
+	}
+	// Walk the list backwards, pos being one past the next entry
+	if pos = end; q.Cursor != nil && *q.Cursor+1 < pos {
+		pos = *q.Cursor + 1
+	}
+	for ; pos > first && len(entries) < limit; pos-- {
+		entry, err := read(pos - 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if pos > first {
+		next := pos - 1
+		return entries, &next, nil
+	}
+	return entries, nil, nil
+}
+
+// count returns the number of entries of the list with the given count key.
+func (idx *ActivityIndex) count(key []byte) uint64 {
+	data, _ := idx.db.Get(key)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// entry returns the entry with the given key, nil if missing.
+func (idx *ActivityIndex) entry(key []byte) *ActivityEntry {
+	data, _ := idx.db.Get(key)
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(ActivityEntry)
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		glog.V(logger.Error).Infof("invalid activity entry RLP %x: %v", key, err)
+		return nil
+	}
+	return entry
+}
+
+func activityCountKey(kind ActivityKind, addr common.Address) []byte {
+	key := append(append([]byte{}, activityCountPrefix...), byte(kind))
+	return append(key, addr[:]...)
+}
+
+func activityEntryKey(kind ActivityKind, addr common.Address, pos uint64) []byte {
+	key := append(append([]byte{}, activityEntryPrefix...), byte(kind))
+	key = append(key, addr[:]...)
+	return append(key, encodeActivityCount(pos)...)
+}
+
+func encodeActivityCount(n uint64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, n)
+	return data
+}
+
+// BlockTransfers returns the internal transfers recorded in statedb while
+// processing the transactions of block, in transaction order. The miner
+// doesn't track transaction indexes while assembling blocks, they are set
+// from the block.
+func BlockTransfers(block *types.Block, statedb *state.StateDB) []*types.InternalTransfer {
+	var transfers []*types.InternalTransfer
+	for i, tx := range block.Transactions() {
+		for _, transfer := range statedb.GetTransfers(tx.Hash()) {
+			transfer.TxIndex = uint(i)
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers
+}
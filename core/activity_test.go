@@ -0,0 +1,177 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+var (
+	activityPayee     = common.HexToAddress("0x0fee")
+	activityForwarder = common.HexToAddress("0xf0")
+	activityReverter  = common.HexToAddress("0xf1")
+)
+
+// forwarderCode passes the value it receives on to activityPayee and logs,
+// reverterCode does the same and fails afterwards.
+const (
+	forwarderCode = "600060006000600034730000000000000000000000000000000000000fee6000f15060006000a000"
+	reverterCode  = "600060006000600034730000000000000000000000000000000000000fee6000f150600056"
+)
+
+// activityRoles returns the entries of a list as block:tx:role strings.
+func activityRoles(t *testing.T, index *ActivityIndex, kind ActivityKind, addr common.Address, q ActivityQuery) ([]string, *uint64) {
+	entries, next, err := index.Query(kind, addr, q)
+	if err != nil {
+		t.Fatalf("failed to query activity of %x: %v", addr, err)
+	}
+	roles := []string{}
+	for _, entry := range entries {
+		roles = append(roles, fmt.Sprintf("%d:%d:%v", entry.BlockNumber, entry.TxIndex, entry.Role))
+	}
+	return roles, next
+}
+
+func TestActivityIndex(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	db, _ := ethdb.NewMemDatabase()
+	genesis, err := WriteGenesisBlock(db, strings.NewReader(fmt.Sprintf(`{
+		"config": {"homesteadBlock": 0},
+		"nonce": "0x0000000000000042", "difficulty": "0x1", "gasLimit": "0x1000000",
+		"alloc": {
+			"%x": {"balance": "1000000000000000000"},
+			"%x": {"balance": "0", "code": "%s"},
+			"%x": {"balance": "0", "code": "%s"}
+		}
+	}`, sender, activityForwarder, forwarderCode, activityReverter, reverterCode)))
+	if err != nil {
+		t.Fatalf("failed to write genesis: %v", err)
+	}
+	config, _ := GetChainConfig(db, genesis.Hash())
+
+	// Block 1 pays the payee through the forwarder and pays another account
+	// directly, block 2 pays through the reverter, whose transfer is undone
+	other := common.HexToAddress("0xbb")
+	send := func(nonce uint64, to common.Address, value int64) *types.Transaction {
+		tx, _ := types.NewTransaction(nonce, to, big.NewInt(value), big.NewInt(100000), big.NewInt(1), nil).SignECDSA(key)
+		return tx
+	}
+	blocks, _ := GenerateChain(config, genesis, db, 2, func(i int, gen *BlockGen) {
+		switch i {
+		case 0:
+			gen.AddTx(send(0, activityForwarder, 1000))
+			gen.AddTx(send(1, other, 1))
+		case 1:
+			gen.AddTx(send(2, activityReverter, 5))
+		}
+	})
+	blockchain, err := NewBlockChain(db, config, FakePow{}, new(event.TypeMux))
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// The index catches up with the chain when enabled
+	index := NewActivityIndex(db)
+	if err := blockchain.SetActivityIndex(index); err != nil {
+		t.Fatalf("failed to set activity index: %v", err)
+	}
+	if index.Head() != blocks[1].Hash() {
+		t.Fatalf("index head mismatch: have %x, want %x", index.Head(), blocks[1].Hash())
+	}
+	for i, tt := range []struct {
+		kind ActivityKind
+		addr common.Address
+		want []string
+	}{
+		{ActivityTransactions, sender, []string{"1:0:sender", "1:1:sender", "2:0:sender"}},
+		{ActivityTransactions, activityForwarder, []string{"1:0:recipient"}},
+		{ActivityTransactions, activityPayee, []string{"1:0:internal"}},
+		{ActivityTransactions, activityReverter, []string{"2:0:recipient"}},
+		{ActivityTransactions, other, []string{"1:1:recipient"}},
+		{ActivityTransfers, activityForwarder, []string{"1:0:sender"}},
+		{ActivityTransfers, activityPayee, []string{"1:0:recipient"}},
+		{ActivityTransfers, activityReverter, []string{}},
+		{ActivityLogs, activityForwarder, []string{"1:0:emitter"}},
+		{ActivityLogs, activityReverter, []string{}},
+	} {
+		if have, _ := activityRoles(t, index, tt.kind, tt.addr, ActivityQuery{}); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: %c list of %x mismatch: have %v, want %v", i, tt.kind, tt.addr, have, tt.want)
+		}
+	}
+	transfers := GetBlockTransfers(db, blocks[0].Hash())
+	if len(transfers) != 1 || transfers[0].From != activityForwarder || transfers[0].To != activityPayee || transfers[0].Value.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("internal transfers mismatch: %+v", transfers)
+	}
+	if transfers := GetBlockTransfers(db, blocks[1].Hash()); len(transfers) != 0 {
+		t.Errorf("reverted transfer recorded: %+v", transfers)
+	}
+
+	// Lists are paged in either direction and cut to block ranges
+	one, two := uint64(1), uint64(2)
+	for i, tt := range []struct {
+		query ActivityQuery
+		want  []string
+		next  *uint64
+	}{
+		{ActivityQuery{Limit: 2}, []string{"1:0:sender", "1:1:sender"}, &two},
+		{ActivityQuery{Limit: 2, Cursor: &two}, []string{"2:0:sender"}, nil},
+		{ActivityQuery{Limit: 2, Reverse: true}, []string{"2:0:sender", "1:1:sender"}, new(uint64)},
+		{ActivityQuery{Limit: 2, Reverse: true, Cursor: new(uint64)}, []string{"1:0:sender"}, nil},
+		{ActivityQuery{FromBlock: 2}, []string{"2:0:sender"}, nil},
+		{ActivityQuery{ToBlock: &one}, []string{"1:0:sender", "1:1:sender"}, nil},
+		{ActivityQuery{FromBlock: 2, ToBlock: &one}, []string{}, nil},
+		{ActivityQuery{ToBlock: &one, Limit: 1, Reverse: true}, []string{"1:1:sender"}, new(uint64)},
+	} {
+		have, next := activityRoles(t, index, ActivityTransactions, sender, tt.query)
+		if !reflect.DeepEqual(have, tt.want) || !reflect.DeepEqual(next, tt.next) {
+			t.Errorf("test %d: page mismatch: have %v next %v, want %v next %v", i, have, next, tt.want, tt.next)
+		}
+	}
+
+	// A longer fork replaces the activity of the blocks it reorganises
+	fork, _ := GenerateChain(config, blocks[0], db, 2, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.HexToAddress("0xc0"))
+		if i == 1 {
+			gen.AddTx(send(2, other, 2))
+		}
+	})
+	if _, err := blockchain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if index.Head() != fork[1].Hash() {
+		t.Fatalf("index head mismatch after reorg: have %x, want %x", index.Head(), fork[1].Hash())
+	}
+	for i, tt := range []struct {
+		kind ActivityKind
+		addr common.Address
+		want []string
+	}{
+		{ActivityTransactions, sender, []string{"1:0:sender", "1:1:sender", "3:0:sender"}},
+		{ActivityTransactions, other, []string{"1:1:recipient", "3:0:recipient"}},
+		{ActivityTransactions, activityReverter, []string{}},
+		{ActivityTransfers, activityPayee, []string{"1:0:recipient"}},
+	} {
+		if have, _ := activityRoles(t, index, tt.kind, tt.addr, ActivityQuery{}); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: %c list of %x after reorg mismatch: have %v, want %v", i, tt.kind, tt.addr, have, tt.want)
+		}
+	}
+}
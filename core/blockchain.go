@@ -108,8 +108,10 @@ type BlockChain struct {
 	wg            sync.WaitGroup // chain processing wait group for shutting down
 
 	pow       pow.PoW
-	processor Processor // block processor interface
-	validator Validator // block and state validator interface
+	processor Processor     // block processor interface
+	validator Validator     // block and state validator interface
+	private   *PrivateState  // private transaction state, nil if not kept
+	activity  *ActivityIndex // account activity index, nil if not kept
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -352,6 +354,52 @@ func (self *BlockChain) Processor() Processor {
 	return self.processor
 }
 
+// SetPrivateState sets the private state updated with each written block and
+// starts executing the private transactions of written blocks in the
+// background.
+func (self *BlockChain) SetPrivateState(private *PrivateState) {
+	self.procmu.Lock()
+	defer self.procmu.Unlock()
+	if self.private != nil {
+		self.private.stop()
+	}
+	self.private = private
+	if private != nil {
+		private.start(self)
+	}
+}
+
+// PrivateState returns the private state, nil if none is kept.
+func (self *BlockChain) PrivateState() *PrivateState {
+	self.procmu.RLock()
+	defer self.procmu.RUnlock()
+	return self.private
+}
+
+// SetActivityIndex sets the account activity index updated with each new head
+// block, bringing it up to the current head first.
+func (self *BlockChain) SetActivityIndex(index *ActivityIndex) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if index != nil {
+		if err := index.Update(self.currentBlock); err != nil {
+			return err
+		}
+	}
+	self.procmu.Lock()
+	defer self.procmu.Unlock()
+	self.activity = index
+	return nil
+}
+
+// ActivityIndex returns the account activity index, nil if none is kept.
+func (self *BlockChain) ActivityIndex() *ActivityIndex {
+	self.procmu.RLock()
+	defer self.procmu.RUnlock()
+	return self.activity
+}
+
 // AuxValidator returns the auxiliary validator (Proof of work atm)
 func (self *BlockChain) AuxValidator() pow.PoW { return self.pow }
 
@@ -574,6 +622,9 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+	if private := bc.PrivateState(); private != nil {
+		private.stop()
+	}
 
 	glog.V(logger.Info).Infoln("Chain manager stopped")
 }
@@ -776,6 +827,11 @@ func (self *BlockChain) WriteBlock(block *types.Block) (status WriteStatus, err
 	if ptd == nil {
 		return NonStatTy, ParentError(block.ParentHash())
 	}
+	// Execute the private transactions the node participates in, payloads
+	// may have to be retrieved from the network
+	if private := self.PrivateState(); private != nil {
+		private.enqueue(block)
+	}
 	// Make sure no inconsistent state is leaked during insertion
 	self.mu.Lock()
 	defer self.mu.Unlock()
@@ -803,6 +859,12 @@ func (self *BlockChain) WriteBlock(block *types.Block) (status WriteStatus, err
 		}
 		self.insert(block) // Insert the block as the new head of the chain
 		status = CanonStatTy
+
+		if index := self.ActivityIndex(); index != nil {
+			if err := index.Update(block); err != nil {
+				glog.V(logger.Error).Infof("activity index of block #%d [%x…]: %v", block.Number(), block.Hash().Bytes()[:4], err)
+			}
+		}
 	} else {
 		status = SideStatTy
 	}
@@ -927,6 +989,9 @@ func (self *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 		if err := WriteBlockReceipts(self.chainDb, block.Hash(), receipts); err != nil {
 			return i, err
 		}
+		if err := WriteBlockTransfers(self.chainDb, block.Hash(), BlockTransfers(block, self.stateCache)); err != nil {
+			return i, err
+		}
 
 		// write the block to the chain and get the status
 		status, err := self.WriteBlock(block)
@@ -17,9 +17,12 @@
 package core
 
 import (
+	"crypto/x509"
 	"errors"
 	"math/big"
+	"time"
 
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -38,7 +41,23 @@ type ChainConfig struct {
 
 	HomesteadGasRepriceBlock *big.Int `json:"homesteadGasRepriceBlock"` // Homestead gas reprice switch block (nil = no fork)
 
+	NISTBlock *big.Int `json:"nistBlock"` // NIST curve precompiled contracts switch block (nil = no fork)
+
+	// FreeGas runs the chain without ether economics: gas is still metered
+	// to bound execution, but senders don't pay for it, no block reward is
+	// paid and the transaction pool ignores gas prices.
+	FreeGas bool `json:"freeGas"`
+
+	// DChain holds the network parameters of the dchain section of the
+	// genesis file (nil = not set).
+	DChain *DChainConfig `json:"dchain,omitempty"`
+
 	VmConfig vm.Config `json:"-"`
+
+	// TCertVerifier checks that a transaction certificate is issued to an
+	// enrolled member at the given time (nil = transactions carrying a tcert
+	// are rejected).
+	TCertVerifier func(cert *x509.Certificate, intermediates []*x509.Certificate, now time.Time) error `json:"-"`
 }
 
 // IsHomestead returns whether num is either equal to the homestead block or greater.
@@ -49,6 +68,34 @@ func (c *ChainConfig) IsHomestead(num *big.Int) bool {
 	return num.Cmp(c.HomesteadBlock) >= 0
 }
 
+// IsNIST returns whether num is either equal to the NIST fork block or greater.
+// It implements vm.NISTRuleSet.
+func (c *ChainConfig) IsNIST(num *big.Int) bool {
+	if c.NISTBlock == nil || num == nil {
+		return false
+	}
+	return num.Cmp(c.NISTBlock) >= 0
+}
+
+// TxCost returns the balance a transaction requires: its value, plus the
+// gas it may use at its gas price unless gas is free.
+func (c *ChainConfig) TxCost(tx *types.Transaction) *big.Int {
+	if c.FreeGas {
+		return new(big.Int).Set(tx.Value())
+	}
+	return tx.Cost()
+}
+
+// CARoot returns the CA root certificate committed to by the dchain section
+// of the genesis file, nil if none is set. It implements vm.CARootRuleSet.
+func (c *ChainConfig) CARoot() *x509.Certificate {
+	if c.DChain == nil {
+		return nil
+	}
+	root, _ := c.DChain.Root()
+	return root
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
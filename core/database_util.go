@@ -47,6 +47,8 @@ var (
 	receiptsPrefix      = []byte("receipts-")
 	blockReceiptsPrefix = []byte("receipts-block-")
 
+	blockTransfersPrefix = []byte("transfers-block-")
+
 	mipmapPre    = []byte("mipmap-log-bloom-")
 	MIPMapLevels = []uint64{1000000, 500000, 100000, 50000, 1000}
 
@@ -195,6 +197,21 @@ func GetBlockReceipts(db ethdb.Database, hash common.Hash) types.Receipts {
 	return receipts
 }
 
+// GetBlockTransfers retrieves the internal transfers made by the transactions
+// of a block given by its hash.
+func GetBlockTransfers(db ethdb.Database, hash common.Hash) []*types.InternalTransfer {
+	data, _ := db.Get(append(blockTransfersPrefix, hash[:]...))
+	if len(data) == 0 {
+		return nil
+	}
+	var transfers []*types.InternalTransfer
+	if err := rlp.DecodeBytes(data, &transfers); err != nil {
+		glog.V(logger.Error).Infof("invalid transfer array RLP for hash %x: %v", hash, err)
+		return nil
+	}
+	return transfers
+}
+
 // GetTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func GetTransaction(db ethdb.Database, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
@@ -354,6 +371,23 @@ func WriteBlockReceipts(db ethdb.Database, hash common.Hash, receipts types.Rece
 	return nil
 }
 
+// WriteBlockTransfers stores the internal transfers made by the transactions of
+// a block. Nothing is stored for blocks without any.
+func WriteBlockTransfers(db ethdb.Database, hash common.Hash, transfers []*types.InternalTransfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+	bytes, err := rlp.EncodeToBytes(transfers)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(append(blockTransfersPrefix, hash.Bytes()...), bytes); err != nil {
+		glog.Fatalf("failed to store block transfers into database: %v", err)
+		return err
+	}
+	return nil
+}
+
 // WriteTransactions stores the transactions associated with a specific block
 // into the given database. Beside writing the transaction, the function also
 // stores a metadata entry along with the transaction, detailing the position
@@ -443,6 +477,7 @@ func DeleteTd(db ethdb.Database, hash common.Hash) {
 // DeleteBlock removes all block data associated with a hash.
 func DeleteBlock(db ethdb.Database, hash common.Hash) {
 	DeleteBlockReceipts(db, hash)
+	DeleteBlockTransfers(db, hash)
 	DeleteHeader(db, hash)
 	DeleteBody(db, hash)
 	DeleteTd(db, hash)
@@ -453,6 +488,11 @@ func DeleteBlockReceipts(db ethdb.Database, hash common.Hash) {
 	db.Delete(append(blockReceiptsPrefix, hash.Bytes()...))
 }
 
+// DeleteBlockTransfers removes the internal transfers of a block.
+func DeleteBlockTransfers(db ethdb.Database, hash common.Hash) {
+	db.Delete(append(blockTransfersPrefix, hash.Bytes()...))
+}
+
 // DeleteTransaction removes all transaction data associated with a hash.
 func DeleteTransaction(db ethdb.Database, hash common.Hash) {
 	db.Delete(hash.Bytes())
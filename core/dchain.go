@@ -0,0 +1,159 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+)
+
+// DChainConfig holds the parameters of a DChain network, set by the dchain
+// section of the genesis file and stored with the chain config. All nodes of
+// the network must run with the same parameters, see Check.
+type DChainConfig struct {
+	Consensus  string   `json:"consensus"`  // consensus algorithm, POW or PBFT
+	N          int      `json:"n"`          // maximum number of PBFT replicas
+	F          int      `json:"f"`          // number of byzantine replicas tolerated
+	CARoot     string   `json:"caRoot"`     // PEM encoded root certificate of the CA
+	Validators []uint32 `json:"validators"` // peer ids of the initial validators
+
+	// Permissions holds the initial permission level names of accounts;
+	// the "default" key sets the level of accounts without an entry.
+	Permissions map[string]string `json:"permissions"`
+}
+
+// Validate checks that the parameters are consistent.
+func (c *DChainConfig) Validate() error {
+	if c.N < 0 || c.F < 0 {
+		return fmt.Errorf("invalid N=%d f=%d", c.N, c.F)
+	}
+	switch c.Consensus {
+	case "POW":
+	case "PBFT":
+		if c.N < 3*c.F+1 {
+			return fmt.Errorf("PBFT needs N >= 3f+1, have N=%d f=%d", c.N, c.F)
+		}
+	default:
+		return fmt.Errorf("unknown consensus algorithm %q", c.Consensus)
+	}
+	if c.Consensus == "PBFT" && len(c.Validators) > c.N {
+		return fmt.Errorf("%d validators exceed N=%d", len(c.Validators), c.N)
+	}
+	seen := make(map[uint32]bool)
+	for _, id := range c.Validators {
+		if seen[id] {
+			return fmt.Errorf("duplicate validator %d", id)
+		}
+		seen[id] = true
+	}
+	if c.CARoot != "" {
+		if _, err := c.Root(); err != nil {
+			return err
+		}
+	}
+	_, err := c.permissions()
+	return err
+}
+
+// Root returns the CA root certificate, nil if none is set.
+func (c *DChainConfig) Root() (*x509.Certificate, error) {
+	if c.CARoot == "" {
+		return nil, nil
+	}
+	block, _ := pem.Decode([]byte(c.CARoot))
+	if block == nil {
+		return nil, fmt.Errorf("CA root is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA root: %v", err)
+	}
+	return cert, nil
+}
+
+// IsValidator reports whether the peer id is one of the initial validators.
+func (c *DChainConfig) IsValidator(id uint32) bool {
+	for _, v := range c.Validators {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplicaIndex returns the PBFT replica index of a validator, its position in
+// the list of initial validators. Peer ids are sparse once intermediate CAs
+// issue certificates, replica indexes always lie below the number of
+// validators.
+func (c *DChainConfig) ReplicaIndex(id uint32) (uint32, bool) {
+	for i, v := range c.Validators {
+		if v == id {
+			return uint32(i), true
+		}
+	}
+	return 0, false
+}
+
+// Check returns an error if the settings a node runs with disagree with the
+// network parameters. A node must run with the CA root of the genesis file,
+// if it has one.
+func (c *DChainConfig) Check(consensus string, n, f int, root *x509.Certificate) error {
+	if consensus != c.Consensus {
+		return fmt.Errorf("consensus algorithm %s, genesis has %s", consensus, c.Consensus)
+	}
+	if c.Consensus == "PBFT" && (n != c.N || f != c.F) {
+		return fmt.Errorf("PBFT N=%d f=%d, genesis has N=%d f=%d", n, f, c.N, c.F)
+	}
+	if c.CARoot != "" {
+		genesisRoot, err := c.Root()
+		if err != nil {
+			return err
+		}
+		if root == nil {
+			return fmt.Errorf("no CA root, genesis has %s", genesisRoot.Subject.CommonName)
+		}
+		if !root.Equal(genesisRoot) {
+			return fmt.Errorf("CA root %s, genesis has %s", root.Subject.CommonName, genesisRoot.Subject.CommonName)
+		}
+	}
+	return nil
+}
+
+// permissions returns the initial permission levels by account, the zero
+// address standing for the default level.
+func (c *DChainConfig) permissions() (map[common.Address]PermissionLevel, error) {
+	levels := make(map[common.Address]PermissionLevel)
+	for account, name := range c.Permissions {
+		level, err := ParsePermissionLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		var addr common.Address
+		if account != "default" {
+			if !common.IsHexAddress(account) {
+				return nil, fmt.Errorf("invalid account %q", account)
+			}
+			addr = common.HexToAddress(account)
+		}
+		levels[addr] = level
+	}
+	return levels, nil
+}
+
+// apply sets the initial permission levels in the genesis state, the config
+// must have been validated.
+func (c *DChainConfig) apply(statedb *state.StateDB) {
+	levels, _ := c.permissions()
+	for addr, level := range levels {
+		setPermission(statedb, addr, level)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func testRoot(t *testing.T, name string) (*x509.Certificate, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(raw)
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}))
+}
+
+func writeDChainGenesis(dchain string) (*ChainConfig, *state.StateDB, error) {
+	db, _ := ethdb.NewMemDatabase()
+	genesis, err := WriteGenesisBlock(db, strings.NewReader(fmt.Sprintf(`{
+		"nonce": "0x0000000000000042", "difficulty": "0x1", "gasLimit": "0x1000000",
+		"alloc": {}, "dchain": %s
+	}`, dchain)))
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := GetChainConfig(db, genesis.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+	statedb, _ := state.New(genesis.Root(), db)
+	return config, statedb, nil
+}
+
+func TestDChainGenesis(t *testing.T) {
+	root, rootPEM := testRoot(t, "root")
+	other, _ := testRoot(t, "other")
+	encodedRoot, _ := json.Marshal(rootPEM)
+	admin := common.HexToAddress("0x0000000000000000000000000000000000000aa1")
+
+	config, statedb, err := writeDChainGenesis(fmt.Sprintf(`{
+		"consensus": "PBFT", "n": 4, "f": 1, "caRoot": %s, "validators": [1, 2, 3, 4],
+		"permissions": {"default": "transact", "%s": "admin"}
+	}`, encodedRoot, admin.Hex()))
+	if err != nil {
+		t.Fatalf("failed to write genesis: %v", err)
+	}
+	dchain := config.DChain
+	if dchain == nil || dchain.Consensus != "PBFT" || dchain.N != 4 || dchain.F != 1 || !dchain.IsValidator(3) || dchain.IsValidator(5) {
+		t.Fatalf("stored dchain section mismatch: %+v", dchain)
+	}
+	if level := GetPermission(statedb, admin); level != PermissionAdmin {
+		t.Errorf("admin level mismatch: have %v, want %v", level, PermissionAdmin)
+	}
+	if level := GetPermission(statedb, common.HexToAddress("0x01")); level != PermissionTransact {
+		t.Errorf("default level mismatch: have %v, want %v", level, PermissionTransact)
+	}
+
+	// Nodes must run with the committed parameters
+	if err := dchain.Check("PBFT", 4, 1, root); err != nil {
+		t.Errorf("matching settings refused: %v", err)
+	}
+	for i, tt := range []struct {
+		consensus string
+		n, f      int
+		root      *x509.Certificate
+	}{
+		{"POW", 4, 1, root},
+		{"PBFT", 7, 2, root},
+		{"PBFT", 4, 0, root},
+		{"PBFT", 4, 1, other},
+		{"PBFT", 4, 1, nil},
+	} {
+		if err := dchain.Check(tt.consensus, tt.n, tt.f, tt.root); err == nil {
+			t.Errorf("test %d: disagreeing settings accepted", i)
+		}
+	}
+
+	// Inconsistent sections are refused
+	for _, invalid := range []string{
+		`{"consensus": "RAFT"}`,
+		`{"consensus": "PBFT", "n": 3, "f": 1}`,
+		`{"consensus": "PBFT", "n": 4, "f": 1, "validators": [1, 2, 3, 4, 5]}`,
+		`{"consensus": "PBFT", "n": 4, "f": 1, "validators": [1, 1]}`,
+		`{"consensus": "POW", "caRoot": "root"}`,
+		`{"consensus": "POW", "permissions": {"default": "superuser"}}`,
+		`{"consensus": "POW", "permissions": {"0x01": "admin"}}`,
+	} {
+		if _, _, err := writeDChainGenesis(invalid); err == nil {
+			t.Errorf("invalid section accepted: %s", invalid)
+		}
+	}
+}
+
+func TestReplicaIndex(t *testing.T) {
+	// Validators enrolled by intermediate CAs have peer ids beyond 1<<20
+	dchain := &DChainConfig{Consensus: "PBFT", N: 4, F: 1, Validators: []uint32{1, 2, 1<<20 + 1, 2<<20 + 1}}
+	for i, id := range dchain.Validators {
+		if index, ok := dchain.ReplicaIndex(id); !ok || index != uint32(i) {
+			t.Errorf("peer id %d: replica index %d (%v), want %d", id, index, ok, i)
+		}
+	}
+	if _, ok := dchain.ReplicaIndex(3); ok {
+		t.Error("replica index of a non-validator")
+	}
+}
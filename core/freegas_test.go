@@ -0,0 +1,101 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+func TestFreeGas(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	genesis, err := WriteGenesisBlock(db, strings.NewReader(`{
+		"config": {"homesteadBlock": 0, "freeGas": true},
+		"nonce": "0x0000000000000042", "difficulty": "0x1", "gasLimit": "0x1000000",
+		"alloc": {}
+	}`))
+	if err != nil {
+		t.Fatalf("failed to write genesis: %v", err)
+	}
+	config, err := GetChainConfig(db, genesis.Hash())
+	if err != nil || !config.FreeGas {
+		t.Fatalf("free gas not selected by genesis: %+v, %v", config, err)
+	}
+
+	// An account without funds sends a transaction and creates a contract
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	coinbase := common.HexToAddress("0xc0")
+	transfer, _ := types.NewTransaction(0, common.HexToAddress("0x01"), new(big.Int), big.NewInt(21000), big.NewInt(50), nil).SignECDSA(key)
+	create, _ := types.NewContractCreation(1, new(big.Int), big.NewInt(100000), big.NewInt(50), common.FromHex("0x602a60005500")).SignECDSA(key)
+	blocks, receipts := GenerateChain(config, genesis, db, 1, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(coinbase)
+		gen.AddTx(transfer)
+		gen.AddTx(create)
+	})
+	blockchain, err := NewBlockChain(db, config, FakePow{}, new(event.TypeMux))
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Gas is metered but not paid, and there is no block reward
+	if used := receipts[0][1].GasUsed; used.Cmp(big.NewInt(21000)) <= 0 {
+		t.Errorf("contract creation gas not metered: %v", used)
+	}
+	statedb, _ := blockchain.State()
+	if statedb.GetNonce(addr) != 2 || statedb.GetBalance(addr).Sign() != 0 {
+		t.Errorf("sender mismatch: nonce %d, balance %v", statedb.GetNonce(addr), statedb.GetBalance(addr))
+	}
+	if balance := statedb.GetBalance(coinbase); balance.Sign() != 0 {
+		t.Errorf("coinbase rewarded on free gas chain: %v", balance)
+	}
+	if value := statedb.GetState(crypto.CreateAddress(addr, 1), common.Hash{}); value != common.BigToHash(big.NewInt(42)) {
+		t.Errorf("contract storage mismatch: %x", value)
+	}
+	// Execution stays bounded by the gas limit
+	loop, _ := types.NewContractCreation(2, new(big.Int), big.NewInt(100000), big.NewInt(0), common.FromHex("0x5b600056")).SignECDSA(key)
+	statedb, _ = blockchain.State()
+	header := &types.Header{Number: big.NewInt(2), GasLimit: big.NewInt(1000000), Difficulty: big.NewInt(1), Time: big.NewInt(0)}
+	receipt, _, _, err := ApplyTransaction(config, nil, new(GasPool).AddGas(header.GasLimit), statedb, header, loop, new(big.Int), blockchain.config.VmConfig)
+	if err != nil || receipt.GasUsed.Cmp(big.NewInt(100000)) != 0 {
+		t.Errorf("endless loop not stopped by gas: %v, %v", receipt, err)
+	}
+}
+
+func TestFreeGasPool(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, db)
+	config := &ChainConfig{HomesteadBlock: new(big.Int), FreeGas: true}
+	pool := NewTxPool(config, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+	pool.resetState()
+	pool.minGasPrice = big.NewInt(1000)
+
+	// Gas price and funds for gas are ignored, value must still be covered
+	key, _ := crypto.GenerateKey()
+	if err := pool.Add(transaction(0, big.NewInt(100000), key)); err != ErrInsufficientFunds {
+		t.Fatalf("value transfer without funds error mismatch: %v", err)
+	}
+	tx, _ := types.NewTransaction(0, common.Address{}, new(big.Int), big.NewInt(100000), big.NewInt(1), nil).SignECDSA(key)
+	if err := pool.Add(tx); err != nil {
+		t.Fatalf("transaction refused on free gas chain: %v", err)
+	}
+	pool.promoteExecutables()
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Fatalf("pending transactions mismatch: have %d, want 1", pending)
+	}
+}
@@ -57,6 +57,11 @@ func WriteGenesisBlock(chainDb ethdb.Database, reader io.Reader) (*types.Block,
 			Storage map[string]string
 			Balance string
 		}
+		Deployers  []string // initial deployment allow-list, enforced if not empty
+		Governance *struct {
+			Threshold uint64 // votes needed to decide a proposal
+		} // deploys the governance contract if set
+		DChain *DChainConfig // network parameters, stored with the chain config
 	}
 
 	if err := json.Unmarshal(contents, &genesis); err != nil {
@@ -73,6 +78,28 @@ func WriteGenesisBlock(chainDb ethdb.Database, reader io.Reader) (*types.Block,
 			statedb.SetState(address, common.HexToHash(key), common.HexToHash(value))
 		}
 	}
+	if len(genesis.Deployers) > 0 {
+		setDeployAllowed(statedb, common.Address{}, true)
+		for _, addr := range genesis.Deployers {
+			setDeployAllowed(statedb, common.HexToAddress(addr), true)
+		}
+	}
+	if genesis.Governance != nil {
+		if genesis.Governance.Threshold == 0 {
+			return nil, fmt.Errorf("governance threshold must be at least 1")
+		}
+		deployGovernance(statedb, genesis.Governance.Threshold)
+	}
+	if genesis.DChain != nil {
+		if err := genesis.DChain.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid dchain section: %v", err)
+		}
+		genesis.DChain.apply(statedb)
+		if genesis.ChainConfig == nil {
+			genesis.ChainConfig = new(ChainConfig)
+		}
+		genesis.ChainConfig.DChain = genesis.DChain
+	}
 	root, stateBatch := statedb.CommitBatch()
 
 	difficulty := common.String2Big(genesis.Difficulty)
@@ -94,6 +121,11 @@ func WriteGenesisBlock(chainDb ethdb.Database, reader io.Reader) (*types.Block,
 		if err != nil {
 			return nil, err
 		}
+		if genesis.ChainConfig != nil && genesis.ChainConfig.NISTBlock != nil {
+			if err := scheduleNISTFork(chainDb, block.Hash(), genesis.ChainConfig.NISTBlock); err != nil {
+				return nil, err
+			}
+		}
 		return block, nil
 	}
 
@@ -122,6 +154,27 @@ func WriteGenesisBlock(chainDb ethdb.Database, reader io.Reader) (*types.Block,
 	return block, nil
 }
 
+// scheduleNISTFork adds the NIST fork block to the chain config of an existing
+// chain, which must not have reached it yet.
+func scheduleNISTFork(chainDb ethdb.Database, genesis common.Hash, fork *big.Int) error {
+	config, err := GetChainConfig(chainDb, genesis)
+	if err != nil {
+		return err
+	}
+	if config.NISTBlock != nil {
+		if config.NISTBlock.Cmp(fork) != 0 {
+			return fmt.Errorf("NIST fork already scheduled at block %v", config.NISTBlock)
+		}
+		return nil
+	}
+	if head := GetHeader(chainDb, GetHeadBlockHash(chainDb)); head != nil && head.Number.Cmp(fork) >= 0 {
+		return fmt.Errorf("NIST fork block %v not after the head block %v", fork, head.Number)
+	}
+	config.NISTBlock = fork
+	glog.V(logger.Info).Infof("Scheduled the NIST fork at block %v", fork)
+	return WriteChainConfig(chainDb, genesis, config)
+}
+
 // GenesisBlockForTesting creates a block in which addr has the given wei balance.
 // The state trie of the block is written to db. the passed db needs to contain a state root
 func GenesisBlockForTesting(db ethdb.Database, addr common.Address, balance *big.Int) *types.Block {
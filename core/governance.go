@@ -0,0 +1,473 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/governance"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// GovernanceAddress is the reserved account of the governance system
+// contract, see package governance for its interface. The contract is
+// deployed by the governance section of the genesis file and executed
+// natively as a precompiled contract, so that transactions and contracts
+// alike may call it. Its storage accesses and logs are charged like the EVM
+// instructions, and its storage follows the layout of the equivalent
+// Solidity contract:
+//
+//	slot 0: vote threshold
+//	slot 1: number of proposals, ids start at 1
+//	slot 2: mapping(uint256 => Proposal)
+//	slot 3: mapping(uint256 => mapping(address => uint8)) votes
+//
+// Approved permission and deployer changes are applied to the registries
+// right away; validator changes are left to the CA, which admin nodes update
+// from the Approved events.
+var GovernanceAddress = common.HexToAddress("0x0000000000000000000000000000000000000102")
+
+// governanceCode is stored at GovernanceAddress so that bindings find code
+// there and marks the contract as deployed. It is never run, the precompiled
+// contract takes its place.
+var governanceCode = []byte{0xfe}
+
+var (
+	ErrGovernanceCall  = &PermissionErr{-32016, "invalid governance call"}
+	ErrProposalDecided = &PermissionErr{-32017, "proposal is not pending"}
+	ErrAlreadyVoted    = &PermissionErr{-32018, "account has already voted on the proposal"}
+)
+
+var (
+	governanceABI   abi.ABI
+	governanceCalls = make(map[string]string) // method id => method name
+
+	governanceProposedTopic common.Hash
+	governanceVotedTopic    common.Hash
+	governanceApprovedTopic common.Hash
+	governanceRejectedTopic common.Hash
+)
+
+func init() {
+	var err error
+	if governanceABI, err = abi.JSON(strings.NewReader(governance.GovernanceABI)); err != nil {
+		panic(err)
+	}
+	for name, method := range governanceABI.Methods {
+		governanceCalls[string(method.Id())] = name
+	}
+	governanceProposedTopic = governanceABI.Events["Proposed"].Id()
+	governanceVotedTopic = governanceABI.Events["Voted"].Id()
+	governanceApprovedTopic = governanceABI.Events["Approved"].Id()
+	governanceRejectedTopic = governanceABI.Events["Rejected"].Id()
+
+	vm.Precompiled[string(GovernanceAddress.Bytes())] = vm.NewSystemContract(func(l int) *big.Int {
+		return params.GovernanceGas
+	}, runGovernance, func(env vm.Environment) bool {
+		return GovernanceDeployed(env.Db())
+	})
+}
+
+// Offsets of the proposal fields from the slot of the proposal.
+const (
+	proposalKind = iota
+	proposalAccount
+	proposalNode
+	proposalValue
+	proposalProposer
+	proposalApprovals
+	proposalRejections
+	proposalState
+)
+
+var (
+	governanceThresholdSlot = common.Hash{}
+	governanceCountSlot     = common.BigToHash(big.NewInt(1))
+	governanceProposalsSlot = common.BigToHash(big.NewInt(2))
+	governanceVotesSlot     = common.BigToHash(big.NewInt(3))
+)
+
+// GovernanceDeployed reports whether the genesis block deployed the
+// governance contract.
+func GovernanceDeployed(db vm.Database) bool {
+	return db.GetCodeSize(GovernanceAddress) > 0
+}
+
+// GovernanceThreshold returns the number of votes that decide a proposal.
+func GovernanceThreshold(db vm.Database) uint64 {
+	if threshold := db.GetState(GovernanceAddress, governanceThresholdSlot).Big(); threshold.BitLen() <= 64 && threshold.Uint64() > 0 {
+		return threshold.Uint64()
+	}
+	return 1
+}
+
+// deployGovernance installs the governance contract with the given threshold.
+func deployGovernance(db vm.Database, threshold uint64) {
+	db.SetCode(GovernanceAddress, governanceCode)
+	db.SetState(GovernanceAddress, governanceThresholdSlot, common.BigToHash(new(big.Int).SetUint64(threshold)))
+}
+
+// GovernanceEvent is a decoded event of the governance contract.
+type GovernanceEvent struct {
+	Name    string // Proposed, Voted, Approved or Rejected
+	Id      uint64
+	Sender  common.Address // proposer or voter
+	Approve bool           // vote of Voted events
+	Kind    uint8
+	Account common.Address
+	Node    string // certificate name for validator changes
+	Value   uint8
+}
+
+// ParseGovernanceEvent decodes an event of the governance contract. It
+// returns nil for any other log.
+func ParseGovernanceEvent(log *vm.Log) *GovernanceEvent {
+	if log.Address != GovernanceAddress || len(log.Topics) < 2 {
+		return nil
+	}
+	ev := &GovernanceEvent{Id: log.Topics[1].Big().Uint64()}
+	proposal := func() {
+		ev.Kind = log.Data[31]
+		ev.Account = common.BytesToAddress(log.Data[32:64])
+		ev.Node = governance.NodeString(common.BytesToHash(log.Data[64:96]))
+		ev.Value = log.Data[127]
+	}
+	switch {
+	case log.Topics[0] == governanceProposedTopic && len(log.Topics) == 3 && len(log.Data) == 4*32:
+		ev.Name, ev.Sender = "Proposed", common.BytesToAddress(log.Topics[2][:])
+		proposal()
+	case log.Topics[0] == governanceVotedTopic && len(log.Topics) == 3 && len(log.Data) == 32:
+		ev.Name, ev.Sender, ev.Approve = "Voted", common.BytesToAddress(log.Topics[2][:]), log.Data[31] == 1
+	case log.Topics[0] == governanceApprovedTopic && len(log.Topics) == 2 && len(log.Data) == 4*32:
+		ev.Name = "Approved"
+		proposal()
+	case log.Topics[0] == governanceRejectedTopic && len(log.Topics) == 2:
+		ev.Name = "Rejected"
+	default:
+		return nil
+	}
+	return ev
+}
+
+// governanceCall is a decoded call of the governance contract.
+type governanceCall struct {
+	method  string
+	id      *big.Int
+	voter   common.Address
+	approve bool
+	kind    uint8
+	account common.Address
+	node    common.Hash
+	value   uint8
+}
+
+// parseGovernanceCall decodes call data of the governance contract. Values
+// must be encoded canonically.
+func parseGovernanceCall(data []byte) (*governanceCall, error) {
+	if len(data) < 4 {
+		return nil, ErrGovernanceCall
+	}
+	name, ok := governanceCalls[string(data[:4])]
+	if !ok || len(data) != 4+32*len(governanceABI.Methods[name].Inputs) {
+		return nil, ErrGovernanceCall
+	}
+	words := make([][]byte, 0, len(data)/32)
+	for i := 4; i < len(data); i += 32 {
+		words = append(words, data[i:i+32])
+	}
+	var (
+		call = &governanceCall{method: name}
+		err  error
+	)
+	switch name {
+	case "getProposal":
+		call.id = new(big.Int).SetBytes(words[0])
+	case "getVote":
+		call.id = new(big.Int).SetBytes(words[0])
+		call.voter, err = wordAddress(words[1])
+	case "propose":
+		if call.kind, err = wordUint8(words[0]); err != nil {
+			return nil, err
+		}
+		if call.account, err = wordAddress(words[1]); err != nil {
+			return nil, err
+		}
+		call.node = common.BytesToHash(words[2])
+		call.value, err = wordUint8(words[3])
+	case "vote":
+		call.id = new(big.Int).SetBytes(words[0])
+		var approve uint8
+		if approve, err = wordUint8(words[1]); err == nil && approve > 1 {
+			err = ErrGovernanceCall
+		}
+		call.approve = approve == 1
+	}
+	if err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+func wordUint8(word []byte) (uint8, error) {
+	for _, b := range word[:31] {
+		if b != 0 {
+			return 0, ErrGovernanceCall
+		}
+	}
+	return word[31], nil
+}
+
+func wordAddress(word []byte) (common.Address, error) {
+	for _, b := range word[:12] {
+		if b != 0 {
+			return common.Address{}, ErrGovernanceCall
+		}
+	}
+	return common.BytesToAddress(word[12:]), nil
+}
+
+// CheckGovernanceCall returns an error if from may not send a transaction
+// with the given data to the governance contract. Proposals and votes need
+// an admin account, calls of the constant methods only valid data.
+func CheckGovernanceCall(db vm.Database, from common.Address, data []byte) error {
+	call, err := parseGovernanceCall(data)
+	if err != nil {
+		return err
+	}
+	return checkGovernanceCall(db, from, call)
+}
+
+func checkGovernanceCall(db vm.Database, from common.Address, call *governanceCall) error {
+	switch call.method {
+	case "propose":
+		if GetPermission(db, from) < PermissionAdmin {
+			return ErrPermissionAdmin
+		}
+		return checkProposal(call.kind, call.node, call.value)
+	case "vote":
+		if GetPermission(db, from) < PermissionAdmin {
+			return ErrPermissionAdmin
+		}
+		if proposalField(db, call.id, proposalState).Big().Uint64() != uint64(governance.StatePending) {
+			return ErrProposalDecided
+		}
+		if governanceVote(db, call.id, from) != governance.VoteNone {
+			return ErrAlreadyVoted
+		}
+	}
+	return nil
+}
+
+// checkProposal validates the arguments of a proposal.
+func checkProposal(kind uint8, node common.Hash, value uint8) error {
+	switch kind {
+	case governance.KindPermission:
+		if PermissionLevel(value) > PermissionAdmin {
+			return ErrGovernanceCall
+		}
+	case governance.KindDeployer:
+		if value > 1 {
+			return ErrGovernanceCall
+		}
+	case governance.KindValidator:
+		if value > 1 || node == (common.Hash{}) {
+			return ErrGovernanceCall
+		}
+	case governance.KindThreshold:
+		if value == 0 {
+			return ErrGovernanceCall
+		}
+	default:
+		return ErrGovernanceCall
+	}
+	return nil
+}
+
+// governanceState meters the storage accesses and logs of a governance call
+// like the equivalent EVM instructions.
+type governanceState struct {
+	vm.Database
+	env      vm.Environment
+	contract *vm.Contract
+	outOfGas bool
+}
+
+func (s *governanceState) useGas(gas *big.Int) {
+	if !s.outOfGas && !s.contract.UseGas(gas) {
+		s.outOfGas = true
+	}
+}
+
+func (s *governanceState) GetState(addr common.Address, key common.Hash) common.Hash {
+	s.useGas(s.env.RuleSet().GasTable(s.env.BlockNumber()).SLoad)
+	return s.Database.GetState(addr, key)
+}
+
+func (s *governanceState) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	switch current := s.Database.GetState(addr, key); {
+	case common.EmptyHash(current) && !common.EmptyHash(value):
+		s.useGas(params.SstoreSetGas)
+	case !common.EmptyHash(current) && common.EmptyHash(value):
+		s.AddRefund(params.SstoreRefundGas)
+		s.useGas(params.SstoreClearGas)
+	default:
+		s.useGas(params.SstoreClearGas)
+	}
+	s.Database.SetState(addr, key, value)
+}
+
+func (s *governanceState) addLog(topics []common.Hash, words ...common.Hash) {
+	var data []byte
+	for _, word := range words {
+		data = append(data, word.Bytes()...)
+	}
+	gas := new(big.Int).Mul(big.NewInt(int64(len(topics))), params.LogTopicGas)
+	gas.Add(gas, new(big.Int).Mul(big.NewInt(int64(len(data))), params.LogDataGas))
+	s.useGas(gas.Add(gas, params.LogGas))
+
+	s.env.AddLog(vm.NewLog(GovernanceAddress, topics, data, s.env.BlockNumber().Uint64()))
+}
+
+// runGovernance executes a call of the governance contract and returns the
+// ABI encoded output.
+func runGovernance(env vm.Environment, contract *vm.Contract, data []byte) ([]byte, error) {
+	// Delegated calls would act on the storage of the caller
+	if contract.Address() != GovernanceAddress || contract.Value().Sign() != 0 {
+		return nil, ErrGovernanceCall
+	}
+	call, err := parseGovernanceCall(data)
+	if err != nil {
+		return nil, err
+	}
+	s := &governanceState{Database: env.Db(), env: env, contract: contract}
+	ret, err := execGovernance(s, contract.Caller(), call)
+	if s.outOfGas {
+		return nil, vm.OutOfGasError
+	}
+	return ret, err
+}
+
+func execGovernance(s *governanceState, from common.Address, call *governanceCall) ([]byte, error) {
+	if err := checkGovernanceCall(s, from, call); err != nil {
+		return nil, err
+	}
+	switch call.method {
+	case "threshold":
+		return common.BigToHash(new(big.Int).SetUint64(GovernanceThreshold(s))).Bytes(), nil
+	case "proposalCount":
+		return s.GetState(GovernanceAddress, governanceCountSlot).Bytes(), nil
+	case "getProposal":
+		var out []byte
+		for field := proposalKind; field <= proposalState; field++ {
+			out = append(out, proposalField(s, call.id, field).Bytes()...)
+		}
+		return out, nil
+	case "getVote":
+		return common.BigToHash(big.NewInt(int64(governanceVote(s, call.id, call.voter)))).Bytes(), nil
+	case "propose":
+		id := new(big.Int).Add(s.GetState(GovernanceAddress, governanceCountSlot).Big(), common.Big1)
+		s.SetState(GovernanceAddress, governanceCountSlot, common.BigToHash(id))
+
+		fields := []common.Hash{
+			proposalKind:     common.BigToHash(big.NewInt(int64(call.kind))),
+			proposalAccount:  common.BytesToHash(call.account[:]),
+			proposalNode:     call.node,
+			proposalValue:    common.BigToHash(big.NewInt(int64(call.value))),
+			proposalProposer: common.BytesToHash(from[:]),
+			proposalState:    common.BigToHash(big.NewInt(int64(governance.StatePending))),
+		}
+		for field, value := range fields {
+			setProposalField(s, id, field, value)
+		}
+		s.addLog([]common.Hash{governanceProposedTopic, common.BigToHash(id), common.BytesToHash(from[:])}, fields[proposalKind:proposalProposer]...)
+
+		// The proposer approves implicitly
+		castGovernanceVote(s, id, from, true)
+		return common.BigToHash(id).Bytes(), nil
+	case "vote":
+		castGovernanceVote(s, call.id, from, call.approve)
+		return nil, nil
+	}
+	return nil, ErrGovernanceCall
+}
+
+// castGovernanceVote records the vote of voter on a pending proposal and
+// decides the proposal if the threshold is reached.
+func castGovernanceVote(s *governanceState, id *big.Int, voter common.Address, approve bool) {
+	vote, field := governance.VoteReject, proposalRejections
+	if approve {
+		vote, field = governance.VoteApprove, proposalApprovals
+	}
+	s.SetState(GovernanceAddress, governanceVoteSlot(id, voter), common.BigToHash(big.NewInt(int64(vote))))
+	count := new(big.Int).Add(proposalField(s, id, field).Big(), common.Big1)
+	setProposalField(s, id, field, common.BigToHash(count))
+
+	approveWord := common.Hash{}
+	if approve {
+		approveWord[common.HashLength-1] = 1
+	}
+	s.addLog([]common.Hash{governanceVotedTopic, common.BigToHash(id), common.BytesToHash(voter[:])}, approveWord)
+
+	if count.Cmp(new(big.Int).SetUint64(GovernanceThreshold(s))) < 0 {
+		return
+	}
+	if !approve {
+		setProposalField(s, id, proposalState, common.BigToHash(big.NewInt(int64(governance.StateRejected))))
+		s.addLog([]common.Hash{governanceRejectedTopic, common.BigToHash(id)})
+		return
+	}
+	setProposalField(s, id, proposalState, common.BigToHash(big.NewInt(int64(governance.StateApproved))))
+
+	var (
+		kind    = uint8(proposalField(s, id, proposalKind).Big().Uint64())
+		account = common.BytesToAddress(proposalField(s, id, proposalAccount).Bytes())
+		value   = uint8(proposalField(s, id, proposalValue).Big().Uint64())
+	)
+	switch kind {
+	case governance.KindPermission:
+		setPermission(s, account, PermissionLevel(value))
+	case governance.KindDeployer:
+		setDeployAllowed(s, account, value == 1)
+	case governance.KindThreshold:
+		s.SetState(GovernanceAddress, governanceThresholdSlot, common.BigToHash(big.NewInt(int64(value))))
+	}
+	s.addLog([]common.Hash{governanceApprovedTopic, common.BigToHash(id)},
+		proposalField(s, id, proposalKind), proposalField(s, id, proposalAccount), proposalField(s, id, proposalNode), proposalField(s, id, proposalValue))
+}
+
+// governanceProposalSlot returns the first slot of the proposal with the
+// given id, keccak256(id . 2) as for a Solidity mapping.
+func governanceProposalSlot(id *big.Int) *big.Int {
+	return new(big.Int).SetBytes(crypto.Keccak256(common.BigToHash(id).Bytes(), governanceProposalsSlot.Bytes()))
+}
+
+func proposalField(db vm.Database, id *big.Int, field int) common.Hash {
+	slot := new(big.Int).Add(governanceProposalSlot(id), big.NewInt(int64(field)))
+	return db.GetState(GovernanceAddress, common.BigToHash(slot))
+}
+
+func setProposalField(db vm.Database, id *big.Int, field int, value common.Hash) {
+	slot := new(big.Int).Add(governanceProposalSlot(id), big.NewInt(int64(field)))
+	db.SetState(GovernanceAddress, common.BigToHash(slot), value)
+}
+
+// governanceVoteSlot returns the slot holding the vote of voter on the
+// proposal with the given id.
+func governanceVoteSlot(id *big.Int, voter common.Address) common.Hash {
+	inner := crypto.Keccak256(common.BigToHash(id).Bytes(), governanceVotesSlot.Bytes())
+	return common.BytesToHash(crypto.Keccak256(common.BytesToHash(voter[:]).Bytes(), inner))
+}
+
+func governanceVote(db vm.Database, id *big.Int, voter common.Address) uint8 {
+	return db.GetState(GovernanceAddress, governanceVoteSlot(id, voter))[common.HashLength-1]
+}
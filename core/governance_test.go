@@ -0,0 +1,229 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/governance"
+)
+
+type testProposal struct {
+	Kind       uint8
+	Account    common.Address
+	Node       [32]byte
+	Value      uint8
+	Proposer   common.Address
+	Approvals  *big.Int
+	Rejections *big.Int
+	State      uint8
+}
+
+func TestGovernance(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	genesis, err := WriteGenesisBlock(db, strings.NewReader(`{
+		"nonce": "0x0000000000000042", "difficulty": "0x1", "gasLimit": "0x1000000",
+		"alloc": {}, "governance": {"threshold": 2}
+	}`))
+	if err != nil {
+		t.Fatalf("failed to write genesis: %v", err)
+	}
+	statedb, _ := state.New(genesis.Root(), db)
+	if !GovernanceDeployed(statedb) || GovernanceThreshold(statedb) != 2 {
+		t.Fatal("genesis governance not deployed")
+	}
+
+	var (
+		keys  [4]*ecdsa.PrivateKey
+		addrs [4]common.Address
+		nonce [4]uint64
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		statedb.AddBalance(addrs[i], big.NewInt(100000000))
+		if i < 3 {
+			setPermission(statedb, addrs[i], PermissionAdmin)
+		}
+	}
+	user := addrs[3]
+
+	header := &types.Header{Number: big.NewInt(1), GasLimit: big.NewInt(1000000), Difficulty: big.NewInt(1), Time: big.NewInt(0)}
+	send := func(from int, to common.Address, gas int64, data []byte) ([]byte, error) {
+		tx, _ := types.NewTransaction(nonce[from], to, big.NewInt(0), big.NewInt(gas), big.NewInt(1), data).SignECDSA(keys[from])
+		gp := new(GasPool).AddGas(header.GasLimit)
+		ret, _, err := ApplyMessage(NewEnv(statedb, testChainConfig(), nil, tx, header, vm.Config{}), tx, gp)
+		if err == nil {
+			nonce[from]++
+		}
+		return ret, err
+	}
+	pack := func(method string, args ...interface{}) []byte {
+		data, err := governanceABI.Pack(method, args...)
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", method, err)
+		}
+		return data
+	}
+	apply := func(from int, method string, args ...interface{}) ([]byte, error) {
+		return send(from, GovernanceAddress, 500000, pack(method, args...))
+	}
+	proposal := func(id int64) (p testProposal) {
+		ret, err := apply(0, "getProposal", big.NewInt(id))
+		if err != nil {
+			t.Fatalf("getProposal failed: %v", err)
+		}
+		if err := governanceABI.Unpack(&p, "getProposal", ret); err != nil {
+			t.Fatalf("failed to unpack proposal: %v", err)
+		}
+		return p
+	}
+
+	// A non-admin account may neither propose nor vote
+	if _, err := apply(3, "propose", governance.KindPermission, user, [32]byte{}, uint8(PermissionTransact)); err != ErrPermissionAdmin {
+		t.Fatalf("proposal by non-admin error mismatch: %v", err)
+	}
+	if _, err := apply(0, "propose", governance.KindPermission, user, [32]byte{}, uint8(PermissionAdmin+1)); err != ErrGovernanceCall {
+		t.Fatalf("invalid proposal error mismatch: %v", err)
+	}
+
+	// The proposer approves implicitly, the second approval decides
+	ret, err := apply(0, "propose", governance.KindPermission, user, [32]byte{}, uint8(PermissionTransact))
+	if err != nil {
+		t.Fatalf("proposal failed: %v", err)
+	}
+	if id := new(big.Int).SetBytes(ret); id.Int64() != 1 {
+		t.Fatalf("proposal id mismatch: have %v, want 1", id)
+	}
+	if p := proposal(1); p.State != governance.StatePending || p.Approvals.Int64() != 1 || p.Proposer != addrs[0] || p.Account != user {
+		t.Fatalf("pending proposal mismatch: %+v", p)
+	}
+	if _, err := apply(0, "vote", big.NewInt(1), true); err != ErrAlreadyVoted {
+		t.Fatalf("second vote error mismatch: %v", err)
+	}
+	if _, err := apply(3, "vote", big.NewInt(1), true); err != ErrPermissionAdmin {
+		t.Fatalf("vote by non-admin error mismatch: %v", err)
+	}
+	statedb.StartRecord(common.Hash{1}, common.Hash{}, 0)
+	if _, err := apply(1, "vote", big.NewInt(1), true); err != nil {
+		t.Fatalf("vote failed: %v", err)
+	}
+	if p := proposal(1); p.State != governance.StateApproved || p.Approvals.Int64() != 2 {
+		t.Fatalf("approved proposal mismatch: %+v", p)
+	}
+	if level := GetPermission(statedb, user); level != PermissionTransact {
+		t.Fatalf("permission level mismatch: have %v, want %v", level, PermissionTransact)
+	}
+	var events []*GovernanceEvent
+	for _, log := range statedb.GetLogs(common.Hash{1}) {
+		events = append(events, ParseGovernanceEvent(log))
+	}
+	if len(events) != 2 || events[0].Name != "Voted" || events[0].Sender != addrs[1] || !events[0].Approve {
+		t.Fatalf("vote event mismatch: %+v", events)
+	}
+	if approval := events[1]; approval.Name != "Approved" || approval.Id != 1 || approval.Kind != governance.KindPermission || approval.Account != user {
+		t.Fatalf("approval event mismatch: %+v", approval)
+	}
+	if _, err := apply(2, "vote", big.NewInt(1), true); err != ErrProposalDecided {
+		t.Fatalf("vote on decided proposal error mismatch: %v", err)
+	}
+
+	// Rejections decide as well
+	if _, err := apply(0, "propose", governance.KindDeployer, user, [32]byte{}, uint8(1)); err != nil {
+		t.Fatalf("proposal failed: %v", err)
+	}
+	apply(1, "vote", big.NewInt(2), false)
+	apply(2, "vote", big.NewInt(2), false)
+	if p := proposal(2); p.State != governance.StateRejected || p.Rejections.Int64() != 2 {
+		t.Fatalf("rejected proposal mismatch: %+v", p)
+	}
+	ret, err = apply(0, "getVote", big.NewInt(2), addrs[1])
+	if err != nil || new(big.Int).SetBytes(ret).Int64() != int64(governance.VoteReject) {
+		t.Fatalf("vote mismatch: %x, %v", ret, err)
+	}
+
+	// Approved deployer and threshold changes apply right away
+	apply(1, "propose", governance.KindDeployer, common.Address{}, [32]byte{}, uint8(1))
+	apply(2, "vote", big.NewInt(3), true)
+	if !DeployAllowListEnabled(statedb) {
+		t.Fatal("deployer proposal not applied")
+	}
+	apply(1, "propose", governance.KindThreshold, common.Address{}, [32]byte{}, uint8(1))
+	apply(0, "vote", big.NewInt(4), true)
+	if GovernanceThreshold(statedb) != 1 {
+		t.Fatal("threshold proposal not applied")
+	}
+
+	// Validator changes are only recorded
+	ret, err = apply(0, "propose", governance.KindValidator, common.Address{}, governance.NodeName("node1"), uint8(1))
+	if err != nil {
+		t.Fatalf("proposal failed: %v", err)
+	}
+	if p := proposal(5); p.State != governance.StateApproved || governance.NodeString(p.Node) != "node1" {
+		t.Fatalf("validator proposal mismatch: %+v", p)
+	}
+
+	// Contracts may call the governance contract, the contract is the proposer
+	forwarder := common.HexToAddress("0x0f")
+	statedb.SetCode(forwarder, common.FromHex(governanceForwarderCode))
+	setPermission(statedb, forwarder, PermissionAdmin)
+	ret, err = send(3, forwarder, 500000, pack("propose", governance.KindDeployer, user, [32]byte{}, uint8(1)))
+	if err != nil || new(big.Int).SetBytes(ret).Int64() != 6 {
+		t.Fatalf("proposal through contract failed: %x, %v", ret, err)
+	}
+	if p := proposal(6); p.State != governance.StateApproved || p.Proposer != forwarder {
+		t.Fatalf("contract proposal mismatch: %+v", p)
+	}
+
+	// Storage writes are charged, a proposal needs more gas than a transfer
+	send(0, GovernanceAddress, 60000, pack("propose", governance.KindDeployer, user, [32]byte{}, uint8(0)))
+	if ret, _ := apply(0, "proposalCount"); new(big.Int).SetBytes(ret).Int64() != 6 {
+		t.Fatalf("proposal count mismatch after running out of gas: %x", ret)
+	}
+}
+
+// governanceForwarderCode passes the call data on to the governance contract
+// with 200000 gas, returns the first word of the output and throws if the call
+// fails.
+const governanceForwarderCode = "36600060003760206000366000600061010262030d40f11560205760206000f35bfe"
+
+func TestGovernancePool(t *testing.T) {
+	pool, key := setupTxPool()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	currentState, _ := pool.currentState()
+	currentState.AddBalance(from, big.NewInt(1000000))
+
+	data, _ := governanceABI.Pack("propose", governance.KindValidator, common.Address{}, governance.NodeName("node1"), uint8(1))
+	propose, _ := types.NewTransaction(0, GovernanceAddress, big.NewInt(0), big.NewInt(100000), big.NewInt(1), data).SignECDSA(key)
+
+	// Plain transfers until the genesis deploys the contract
+	if err := pool.validateTx(propose); err != nil {
+		t.Fatalf("transaction refused without governance: %v", err)
+	}
+	deployGovernance(currentState, 1)
+	if err := pool.validateTx(propose); err != ErrPermissionAdmin {
+		t.Fatalf("proposal by non-admin error mismatch: %v", err)
+	}
+	setPermission(currentState, from, PermissionAdmin)
+	if err := pool.validateTx(propose); err != nil {
+		t.Fatalf("proposal by admin refused: %v", err)
+	}
+	bad, _ := types.NewTransaction(0, GovernanceAddress, big.NewInt(0), big.NewInt(100000), big.NewInt(1), data[:10]).SignECDSA(key)
+	if err := pool.validateTx(bad); err != ErrGovernanceCall {
+		t.Fatalf("malformed governance call error mismatch: %v", err)
+	}
+}
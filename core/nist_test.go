@@ -0,0 +1,65 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+func nistGenesis(fork string) string {
+	config := `"homesteadBlock": 0`
+	if fork != "" {
+		config += `, "nistBlock": ` + fork
+	}
+	return fmt.Sprintf(`{
+		"config": {%s},
+		"nonce": "0x0000000000000042", "difficulty": "0x1", "gasLimit": "0x1000000",
+		"alloc": {}
+	}`, config)
+}
+
+func TestNISTForkScheduling(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	genesis, err := WriteGenesisBlock(db, strings.NewReader(nistGenesis("")))
+	if err != nil {
+		t.Fatalf("failed to write genesis: %v", err)
+	}
+	config, _ := GetChainConfig(db, genesis.Hash())
+	if config.IsNIST(big.NewInt(1000000)) {
+		t.Fatal("NIST fork active on a chain not scheduling it")
+	}
+	blocks, _ := GenerateChain(config, genesis, db, 3, func(int, *BlockGen) {})
+	blockchain, _ := NewBlockChain(db, config, FakePow{}, new(event.TypeMux))
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+
+	// Existing chains schedule the fork by initializing their genesis again
+	if _, err := WriteGenesisBlock(db, strings.NewReader(nistGenesis("3"))); err == nil {
+		t.Error("fork scheduled at a block the chain already reached")
+	}
+	if _, err := WriteGenesisBlock(db, strings.NewReader(nistGenesis("10"))); err != nil {
+		t.Fatalf("failed to schedule fork: %v", err)
+	}
+	config, _ = GetChainConfig(db, genesis.Hash())
+	if config.IsNIST(big.NewInt(9)) || !config.IsNIST(big.NewInt(10)) {
+		t.Errorf("fork block mismatch: have %v, want 10", config.NISTBlock)
+	}
+	if _, err := WriteGenesisBlock(db, strings.NewReader(nistGenesis("10"))); err != nil {
+		t.Errorf("same fork block refused: %v", err)
+	}
+	if _, err := WriteGenesisBlock(db, strings.NewReader(nistGenesis("20"))); err == nil {
+		t.Error("scheduled fork moved")
+	}
+}
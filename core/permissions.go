@@ -0,0 +1,269 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// PermissionLevel is the authority of an account. Every level includes the
+// rights of the levels below it.
+type PermissionLevel byte
+
+const (
+	// PermissionNone marks accounts without an entry in the registry, they
+	// get the default level.
+	PermissionNone PermissionLevel = 0
+
+	// PermissionReadOnly accounts may only query the chain.
+	PermissionReadOnly PermissionLevel = 1
+
+	// PermissionTransact accounts may send transactions and call contracts.
+	PermissionTransact PermissionLevel = 2
+
+	// PermissionDeploy accounts may also deploy contracts.
+	PermissionDeploy PermissionLevel = 3
+
+	// PermissionAdmin accounts may also change the permission levels.
+	PermissionAdmin PermissionLevel = 4
+)
+
+func (l PermissionLevel) String() string {
+	switch l {
+	case PermissionNone:
+		return "none"
+	case PermissionReadOnly:
+		return "readonly"
+	case PermissionTransact:
+		return "transact"
+	case PermissionDeploy:
+		return "deploy"
+	case PermissionAdmin:
+		return "admin"
+	}
+	return fmt.Sprintf("PermissionLevel(%d)", byte(l))
+}
+
+// ParsePermissionLevel returns the level with the given name.
+func ParsePermissionLevel(name string) (PermissionLevel, error) {
+	for l := PermissionReadOnly; l <= PermissionAdmin; l++ {
+		if l.String() == name {
+			return l, nil
+		}
+	}
+	return PermissionNone, fmt.Errorf("unknown permission level %q", name)
+}
+
+// PermissionRegistryAddress is the reserved account whose storage holds the
+// permission registry. The slot of an account is its address left padded to
+// 32 bytes and holds its level. The slot of the zero address holds the
+// default level of accounts without an entry; permissions are not enforced
+// while it is unset.
+//
+// Admin accounts change levels with a transaction to this address carrying
+// the 20 byte address followed by the new level as data, see
+// PermissionChangeData. Initial levels, including the first admin, are set
+// by the storage of the account in the genesis block.
+var PermissionRegistryAddress = common.HexToAddress("0x0000000000000000000000000000000000000100")
+
+// DeployAllowListAddress is the reserved account whose storage holds the
+// deployment allow-list. The slot of an account, its address left padded to
+// 32 bytes, is 1 if the account may create contracts, including by CREATE
+// from a contract. The list is enforced while the slot of the zero address
+// is 1, which is set for the deployers listed in the genesis file.
+//
+// Admin accounts change the list with a transaction to this address carrying
+// the 20 byte address followed by 1 to allow or 0 to remove it, see
+// AllowListChangeData. The zero address turns enforcement on and off.
+var DeployAllowListAddress = common.HexToAddress("0x0000000000000000000000000000000000000101")
+
+// PermissionErr is returned for transactions whose sender lacks the level the
+// transaction requires. The code tells the causes apart and is passed on to
+// RPC clients.
+type PermissionErr struct {
+	code    int
+	message string
+}
+
+func (err *PermissionErr) Error() string {
+	return err.message
+}
+
+// Code returns the RPC error code of the error.
+func (err *PermissionErr) Code() int {
+	return err.code
+}
+
+func IsPermissionErr(err error) bool {
+	_, ok := err.(*PermissionErr)
+	return ok
+}
+
+var (
+	ErrPermissionTransact = &PermissionErr{-32010, "account is not permitted to send transactions"}
+	ErrPermissionDeploy   = &PermissionErr{-32011, "account is not permitted to deploy contracts"}
+	ErrPermissionAdmin    = &PermissionErr{-32012, "account is not permitted to change permissions"}
+	ErrPermissionChange   = &PermissionErr{-32013, "invalid permission change"}
+	ErrDeployNotAllowed   = &PermissionErr{-32014, "account is not on the deployment allow-list"}
+	ErrAllowListChange    = &PermissionErr{-32015, "invalid deployment allow-list change"}
+)
+
+// PermissionsEnabled reports whether the registry holds a default level and
+// account permissions are enforced.
+func PermissionsEnabled(db vm.Database) bool {
+	return permissionAt(db, common.Address{}) != PermissionNone
+}
+
+// GetPermission returns the level of addr, which is the default level if the
+// account has no entry. Accounts without an entry have PermissionNone while
+// permissions are not enforced.
+func GetPermission(db vm.Database, addr common.Address) PermissionLevel {
+	if level := permissionAt(db, addr); level != PermissionNone {
+		return level
+	}
+	return permissionAt(db, common.Address{})
+}
+
+func permissionAt(db vm.Database, addr common.Address) PermissionLevel {
+	value := db.GetState(PermissionRegistryAddress, common.BytesToHash(addr[:]))
+	return PermissionLevel(value[common.HashLength-1])
+}
+
+// RequiredPermission returns the level needed to send a transaction to the
+// given recipient, nil meaning contract creation.
+func RequiredPermission(to *common.Address) PermissionLevel {
+	switch {
+	case to == nil:
+		return PermissionDeploy
+	case *to == PermissionRegistryAddress, *to == DeployAllowListAddress:
+		return PermissionAdmin
+	}
+	return PermissionTransact
+}
+
+// CheckPermission returns an error if from may not send a transaction with
+// the given recipient, data and value.
+func CheckPermission(db vm.Database, from common.Address, to *common.Address, data []byte, value *big.Int) error {
+	if to != nil {
+		switch *to {
+		case PermissionRegistryAddress:
+			if _, _, err := ParsePermissionChange(data); err != nil || value.Sign() != 0 {
+				return ErrPermissionChange
+			}
+			// Only admins change levels, also before permissions are
+			// enforced, or anyone could make itself admin in advance
+			if GetPermission(db, from) < PermissionAdmin {
+				return ErrPermissionAdmin
+			}
+		case DeployAllowListAddress:
+			if _, _, err := ParseAllowListChange(data); err != nil || value.Sign() != 0 {
+				return ErrAllowListChange
+			}
+			// The allow-list is governed by admins even if account
+			// permissions are not enforced
+			if GetPermission(db, from) < PermissionAdmin {
+				return ErrPermissionAdmin
+			}
+		case GovernanceAddress:
+			if !GovernanceDeployed(db) {
+				break
+			}
+			if value.Sign() != 0 {
+				return ErrGovernanceCall
+			}
+			if err := CheckGovernanceCall(db, from, data); err != nil {
+				return err
+			}
+		}
+	}
+	if PermissionsEnabled(db) {
+		switch required := RequiredPermission(to); {
+		case GetPermission(db, from) >= required:
+		case required == PermissionDeploy:
+			return ErrPermissionDeploy
+		case required == PermissionAdmin:
+			return ErrPermissionAdmin
+		default:
+			return ErrPermissionTransact
+		}
+	}
+	if to == nil && !CanDeploy(db, from) {
+		return ErrDeployNotAllowed
+	}
+	return nil
+}
+
+// DeployAllowListEnabled reports whether contract creation is restricted to
+// the accounts on the deployment allow-list.
+func DeployAllowListEnabled(db vm.Database) bool {
+	return deployAllowed(db, common.Address{})
+}
+
+// CanDeploy reports whether addr may create contracts.
+func CanDeploy(db vm.Database, addr common.Address) bool {
+	return !DeployAllowListEnabled(db) || deployAllowed(db, addr)
+}
+
+func deployAllowed(db vm.Database, addr common.Address) bool {
+	value := db.GetState(DeployAllowListAddress, common.BytesToHash(addr[:]))
+	return value[common.HashLength-1] == 1
+}
+
+// AllowListChangeData returns the transaction data that adds addr to or
+// removes it from the deployment allow-list. For the zero address it turns
+// enforcement of the list on or off.
+func AllowListChangeData(addr common.Address, allowed bool) []byte {
+	if allowed {
+		return append(addr.Bytes(), 1)
+	}
+	return append(addr.Bytes(), 0)
+}
+
+// ParseAllowListChange decodes transaction data made by AllowListChangeData.
+func ParseAllowListChange(data []byte) (common.Address, bool, error) {
+	if len(data) != common.AddressLength+1 || data[common.AddressLength] > 1 {
+		return common.Address{}, false, ErrAllowListChange
+	}
+	return common.BytesToAddress(data[:common.AddressLength]), data[common.AddressLength] == 1, nil
+}
+
+// setDeployAllowed adds addr to or removes it from the deployment allow-list.
+func setDeployAllowed(db vm.Database, addr common.Address, allowed bool) {
+	var value common.Hash
+	if allowed {
+		value[common.HashLength-1] = 1
+	}
+	db.SetState(DeployAllowListAddress, common.BytesToHash(addr[:]), value)
+}
+
+// PermissionChangeData returns the transaction data that sets the level of
+// addr, or the default level if addr is the zero address.
+func PermissionChangeData(addr common.Address, level PermissionLevel) []byte {
+	return append(addr.Bytes(), byte(level))
+}
+
+// ParsePermissionChange decodes transaction data made by PermissionChangeData.
+func ParsePermissionChange(data []byte) (common.Address, PermissionLevel, error) {
+	if len(data) != common.AddressLength+1 {
+		return common.Address{}, PermissionNone, ErrPermissionChange
+	}
+	level := PermissionLevel(data[common.AddressLength])
+	if level > PermissionAdmin {
+		return common.Address{}, PermissionNone, ErrPermissionChange
+	}
+	return common.BytesToAddress(data[:common.AddressLength]), level, nil
+}
+
+// setPermission stores the level of addr in the registry.
+func setPermission(db vm.Database, addr common.Address, level PermissionLevel) {
+	db.SetState(PermissionRegistryAddress, common.BytesToHash(addr[:]), common.BigToHash(big.NewInt(int64(level))))
+}
@@ -0,0 +1,187 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestPermissionPool(t *testing.T) {
+	pool, key := setupTxPool()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	currentState, _ := pool.currentState()
+	currentState.AddBalance(from, big.NewInt(1000000))
+
+	create, _ := types.NewContractCreation(0, big.NewInt(0), big.NewInt(100000), big.NewInt(1), nil).SignECDSA(key)
+	change, _ := types.NewTransaction(0, PermissionRegistryAddress, big.NewInt(0), big.NewInt(100000), big.NewInt(1), PermissionChangeData(from, PermissionAdmin)).SignECDSA(key)
+
+	// Unrestricted until the registry holds a default level
+	if err := pool.validateTx(create); err != nil {
+		t.Fatalf("transaction refused without registry: %v", err)
+	}
+
+	setPermission(currentState, common.Address{}, PermissionReadOnly)
+	tests := []struct {
+		level PermissionLevel
+		tx    *types.Transaction
+		err   error
+	}{
+		{PermissionNone, transaction(0, big.NewInt(100000), key), ErrPermissionTransact},
+		{PermissionTransact, transaction(0, big.NewInt(100000), key), nil},
+		{PermissionTransact, create, ErrPermissionDeploy},
+		{PermissionDeploy, create, nil},
+		{PermissionDeploy, change, ErrPermissionAdmin},
+		{PermissionAdmin, change, nil},
+	}
+	for i, tt := range tests {
+		setPermission(currentState, from, tt.level)
+		if err := pool.validateTx(tt.tx); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+
+	bad, _ := types.NewTransaction(0, PermissionRegistryAddress, big.NewInt(0), big.NewInt(100000), big.NewInt(1), []byte{1}).SignECDSA(key)
+	if err := pool.validateTx(bad); err != ErrPermissionChange {
+		t.Errorf("malformed permission change error mismatch: %v", err)
+	}
+}
+
+func TestPermissionTransition(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, db)
+	admin, _ := crypto.GenerateKey()
+	user, _ := crypto.GenerateKey()
+	adminAddr, userAddr := crypto.PubkeyToAddress(admin.PublicKey), crypto.PubkeyToAddress(user.PublicKey)
+	statedb.AddBalance(adminAddr, big.NewInt(1000000))
+	statedb.AddBalance(userAddr, big.NewInt(1000000))
+	setPermission(statedb, common.Address{}, PermissionReadOnly)
+	setPermission(statedb, adminAddr, PermissionAdmin)
+
+	header := &types.Header{Number: big.NewInt(1), GasLimit: big.NewInt(1000000), Difficulty: big.NewInt(1), Time: big.NewInt(0)}
+	apply := func(tx *types.Transaction) error {
+		gp := new(GasPool).AddGas(header.GasLimit)
+		_, _, err := ApplyMessage(NewEnv(statedb, testChainConfig(), nil, tx, header, vm.Config{}), tx, gp)
+		return err
+	}
+
+	// Levels cannot be changed by non-admins before permissions are enforced
+	setPermission(statedb, common.Address{}, PermissionNone)
+	if PermissionsEnabled(statedb) {
+		t.Fatal("permissions enforced without a default level")
+	}
+	claim, _ := types.NewTransaction(0, PermissionRegistryAddress, big.NewInt(0), big.NewInt(100000), big.NewInt(1), PermissionChangeData(userAddr, PermissionAdmin)).SignECDSA(user)
+	if err := apply(claim); err != ErrPermissionAdmin {
+		t.Fatalf("permission change by non-admin before enforcement error mismatch: %v", err)
+	}
+	setPermission(statedb, common.Address{}, PermissionReadOnly)
+
+	send, _ := types.NewTransaction(0, adminAddr, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil).SignECDSA(user)
+	if err := apply(send); err != ErrPermissionTransact {
+		t.Fatalf("read-only account error mismatch: %v", err)
+	}
+	grant, _ := types.NewTransaction(0, PermissionRegistryAddress, big.NewInt(0), big.NewInt(100000), big.NewInt(1), PermissionChangeData(userAddr, PermissionTransact)).SignECDSA(admin)
+	if err := apply(grant); err != nil {
+		t.Fatalf("permission change failed: %v", err)
+	}
+	if level := GetPermission(statedb, userAddr); level != PermissionTransact {
+		t.Fatalf("permission level mismatch: have %v, want %v", level, PermissionTransact)
+	}
+	if err := apply(send); err != nil {
+		t.Fatalf("transaction refused after grant: %v", err)
+	}
+	regrant, _ := types.NewTransaction(1, PermissionRegistryAddress, big.NewInt(0), big.NewInt(100000), big.NewInt(1), PermissionChangeData(userAddr, PermissionAdmin)).SignECDSA(user)
+	if err := apply(regrant); err != ErrPermissionAdmin {
+		t.Fatalf("permission change by non-admin error mismatch: %v", err)
+	}
+}
+
+func TestDeployAllowList(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, db)
+	admin, _ := crypto.GenerateKey()
+	user, _ := crypto.GenerateKey()
+	adminAddr, userAddr := crypto.PubkeyToAddress(admin.PublicKey), crypto.PubkeyToAddress(user.PublicKey)
+	statedb.AddBalance(adminAddr, big.NewInt(1000000))
+	statedb.AddBalance(userAddr, big.NewInt(1000000))
+	setPermission(statedb, adminAddr, PermissionAdmin)
+	setDeployAllowed(statedb, common.Address{}, true)
+	setDeployAllowed(statedb, adminAddr, true)
+
+	// factory creates an empty contract and stores its address in slot 0
+	factory := common.Address{0xfa}
+	statedb.SetCode(factory, common.Hex2Bytes("600060006000f060005500"))
+
+	header := &types.Header{Number: big.NewInt(1), GasLimit: big.NewInt(1000000), Difficulty: big.NewInt(1), Time: big.NewInt(0)}
+	apply := func(tx *types.Transaction) error {
+		gp := new(GasPool).AddGas(header.GasLimit)
+		_, _, err := ApplyMessage(NewEnv(statedb, testChainConfig(), nil, tx, header, vm.Config{}), tx, gp)
+		return err
+	}
+
+	create, _ := types.NewContractCreation(0, big.NewInt(0), big.NewInt(100000), big.NewInt(1), nil).SignECDSA(user)
+	if err := apply(create); err != ErrDeployNotAllowed {
+		t.Fatalf("deployment by unlisted account error mismatch: %v", err)
+	}
+	allow, _ := types.NewTransaction(0, DeployAllowListAddress, big.NewInt(0), big.NewInt(100000), big.NewInt(1), AllowListChangeData(userAddr, true)).SignECDSA(user)
+	if err := apply(allow); err != ErrPermissionAdmin {
+		t.Fatalf("allow-list change by non-admin error mismatch: %v", err)
+	}
+	allow, _ = types.NewTransaction(0, DeployAllowListAddress, big.NewInt(0), big.NewInt(100000), big.NewInt(1), AllowListChangeData(userAddr, true)).SignECDSA(admin)
+	if err := apply(allow); err != nil {
+		t.Fatalf("allow-list change failed: %v", err)
+	}
+	if err := apply(create); err != nil {
+		t.Fatalf("deployment by listed account refused: %v", err)
+	}
+
+	// Contracts need to be listed themselves to CREATE, refused creations use
+	// up their gas
+	call, _ := types.NewTransaction(1, factory, big.NewInt(0), big.NewInt(200000), big.NewInt(1), nil).SignECDSA(user)
+	_, gas, err := ApplyMessage(NewEnv(statedb, testChainConfig(), nil, call, header, vm.Config{}), call, new(GasPool).AddGas(header.GasLimit))
+	if err != nil {
+		t.Fatalf("factory call failed: %v", err)
+	}
+	if created := statedb.GetState(factory, common.Hash{}); created != (common.Hash{}) {
+		t.Fatalf("unlisted contract created %x", created)
+	}
+	if gas.Cmp(big.NewInt(190000)) < 0 {
+		t.Fatalf("refused creation used %v gas, want nearly all", gas)
+	}
+	setDeployAllowed(statedb, factory, true)
+	call, _ = types.NewTransaction(2, factory, big.NewInt(0), big.NewInt(200000), big.NewInt(1), nil).SignECDSA(user)
+	if err := apply(call); err != nil {
+		t.Fatalf("factory call failed: %v", err)
+	}
+	if created := statedb.GetState(factory, common.Hash{}); created == (common.Hash{}) {
+		t.Fatal("listed contract failed to create")
+	}
+}
+
+func TestGenesisDeployers(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	genesis, err := WriteGenesisBlock(db, strings.NewReader(`{
+		"nonce": "0x0000000000000042", "difficulty": "0x1", "gasLimit": "0x1000000",
+		"alloc": {}, "deployers": ["0x00000000000000000000000000000000000000aa"]
+	}`))
+	if err != nil {
+		t.Fatalf("failed to write genesis: %v", err)
+	}
+	statedb, _ := state.New(genesis.Root(), db)
+	if !DeployAllowListEnabled(statedb) || !CanDeploy(statedb, common.HexToAddress("0xaa")) || CanDeploy(statedb, common.HexToAddress("0xbb")) {
+		t.Fatal("genesis deployers not applied")
+	}
+}
@@ -0,0 +1,374 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+	"github.com/ethereum/go-ethereum/private"
+)
+
+// PrivateTxPrefix starts the data of private transactions, it is followed by
+// the hash of the encrypted payload. 0xfe is an invalid instruction, so no
+// meaningful contract creation starts with it.
+//
+// Publicly a private transaction only increments the nonce of the sender and
+// pays the intrinsic gas. The participants execute the decrypted payload, with
+// the recipient of the transaction, against their private state.
+var PrivateTxPrefix = []byte{0xfe, 'p', 't', 'x'}
+
+var ErrPrivateTx = errors.New("invalid private transaction")
+
+// PrivateTxData returns the data of a private transaction whose payload has
+// the given hash.
+func PrivateTxData(hash common.Hash) []byte {
+	return append(common.CopyBytes(PrivateTxPrefix), hash[:]...)
+}
+
+// ParsePrivateTx returns the payload hash of the private transaction data, or
+// false if data is not the data of a private transaction.
+func ParsePrivateTx(data []byte) (common.Hash, bool) {
+	if len(data) != len(PrivateTxPrefix)+common.HashLength || !bytes.HasPrefix(data, PrivateTxPrefix) {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(data[len(PrivateTxPrefix):]), true
+}
+
+// ValidatePrivateTx returns an error if data is the data of a private
+// transaction that transfers value or is sent to a system contract.
+func ValidatePrivateTx(to *common.Address, data []byte, value *big.Int) error {
+	if _, ok := ParsePrivateTx(data); !ok {
+		return nil
+	}
+	if value.Sign() != 0 {
+		return ErrPrivateTx
+	}
+	if to != nil {
+		switch *to {
+		case PermissionRegistryAddress, DeployAllowListAddress, GovernanceAddress:
+			return ErrPrivateTx
+		}
+	}
+	return nil
+}
+
+var (
+	privateRootPrefix    = []byte("private-root-")    // block hash -> private state root
+	privateMissingPrefix = []byte("private-missing-") // payload hash -> number of the first block missing it
+	privateResumeKey     = []byte("private-resume")   // number of the first block to execute again
+)
+
+// PrivatePayloads is the source of the payloads of private transactions.
+type PrivatePayloads interface {
+	// Get returns the payload with the given hash, nil if it is not available.
+	Get(hash common.Hash) *private.Envelope
+}
+
+// PrivateState tracks the private state of the node. The root of the private
+// state after each block is kept in a separate database, next to the private
+// tries, and never leaves the node.
+//
+// Private transactions only see the private state: public contracts can't
+// read it and private contracts can't read public state. Private
+// transactions have no receipts or logs. Blocks that were not processed,
+// e.g. after a fast sync, start from an empty private state.
+//
+// Written blocks are executed in the background, retrieving payloads from
+// peers must not hold up block import. Until a block is executed its private
+// state is the empty state. Payloads missing when a block is executed are
+// recorded; once one arrives, the chain is executed again from the block
+// that missed it.
+type PrivateState struct {
+	db       ethdb.Database
+	payloads PrivatePayloads
+	identity func() (string, *ecdsa.PrivateKey)
+
+	mu      sync.Mutex
+	chain   *BlockChain          // set by start, nil while not running
+	queue   []*types.Block       // written blocks waiting to be executed
+	arrived map[common.Hash]bool // payloads arrived since the last block was started
+
+	wake chan struct{}
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPrivateState returns the private state kept in db. Payloads are read
+// from payloads and opened with the certificate name and enrollment key
+// returned by identity; a nil key skips all private transactions.
+func NewPrivateState(db ethdb.Database, payloads PrivatePayloads, identity func() (string, *ecdsa.PrivateKey)) *PrivateState {
+	return &PrivateState{db: db, payloads: payloads, identity: identity, wake: make(chan struct{}, 1)}
+}
+
+// Root returns the root of the private state after the given block.
+func (p *PrivateState) Root(block common.Hash) common.Hash {
+	root, _ := p.db.Get(append(privateRootPrefix, block[:]...))
+	return common.BytesToHash(root)
+}
+
+// State returns the private state after the given block.
+func (p *PrivateState) State(block common.Hash) (*state.StateDB, error) {
+	return state.New(p.Root(block), p.db)
+}
+
+// start executes the blocks written to chain in the background, resuming
+// the work left when the node stopped.
+func (p *PrivateState) start(chain *BlockChain) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.chain != nil {
+		return
+	}
+	p.chain, p.quit = chain, make(chan struct{})
+	p.wg.Add(1)
+	go p.loop(chain, p.quit)
+	p.signal()
+}
+
+// stop terminates the background execution. Blocks that were not executed
+// yet are executed again on the next start.
+func (p *PrivateState) stop() {
+	p.mu.Lock()
+	if p.chain == nil {
+		p.mu.Unlock()
+		return
+	}
+	close(p.quit)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) > 0 {
+		p.resumeFrom(p.queue[0].NumberU64())
+		p.queue = nil
+	}
+	p.chain = nil
+}
+
+// enqueue schedules the execution of a written block.
+func (p *PrivateState) enqueue(block *types.Block) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.queue = append(p.queue, block)
+	p.signal()
+}
+
+// PayloadArrived schedules the execution of the chain from the first block
+// that missed the payload with the given hash, if any did.
+func (p *PrivateState) PayloadArrived(hash common.Hash) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := append(privateMissingPrefix, hash[:]...)
+	enc, err := p.db.Get(key)
+	if err != nil || len(enc) != 8 {
+		// The block being executed may not have recorded it yet
+		if p.arrived != nil {
+			p.arrived[hash] = true
+		}
+		return
+	}
+	p.db.Delete(key)
+	p.resumeFrom(binary.BigEndian.Uint64(enc))
+	p.signal()
+}
+
+// signal wakes up the background execution, p.mu must be held.
+func (p *PrivateState) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// resumeFrom records that the chain must be executed again from the block
+// with the given number, p.mu must be held.
+func (p *PrivateState) resumeFrom(number uint64) {
+	if resume, ok := p.resume(); ok && resume <= number {
+		return
+	}
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	p.db.Put(privateResumeKey, enc)
+}
+
+func (p *PrivateState) resume() (uint64, bool) {
+	enc, err := p.db.Get(privateResumeKey)
+	if err != nil || len(enc) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(enc), true
+}
+
+func (p *PrivateState) loop(chain *BlockChain, quit chan struct{}) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.wake:
+		case <-quit:
+			return
+		}
+		// Execute the canonical chain again from the last good private root
+		p.mu.Lock()
+		resume, ok := p.resume()
+		p.db.Delete(privateResumeKey)
+		p.mu.Unlock()
+
+		for number := resume; ok; number++ {
+			block := chain.GetBlockByNumber(number)
+			if block == nil {
+				break
+			}
+			select {
+			case <-quit:
+				p.mu.Lock()
+				p.resumeFrom(number)
+				p.mu.Unlock()
+				return
+			default:
+			}
+			p.execute(chain, block)
+		}
+		// Execute the written blocks in order
+		for {
+			p.mu.Lock()
+			if len(p.queue) == 0 {
+				p.mu.Unlock()
+				break
+			}
+			select {
+			case <-quit:
+				p.mu.Unlock()
+				return
+			default:
+			}
+			block := p.queue[0]
+			p.queue = p.queue[1:]
+			p.mu.Unlock()
+
+			p.execute(chain, block)
+		}
+	}
+}
+
+func (p *PrivateState) execute(chain *BlockChain, block *types.Block) {
+	if err := p.Process(chain, block); err != nil {
+		glog.V(logger.Error).Infof("private state of block #%d [%x…]: %v", block.Number(), block.Hash().Bytes()[:4], err)
+	}
+}
+
+// Process executes the private transactions of block the node participates
+// in and records the resulting private root. Failing private transactions
+// are skipped, they don't affect the validity of the block. Transactions
+// whose payload is missing are skipped as well, the block is recorded to be
+// executed again once the payload arrives.
+func (p *PrivateState) Process(chain *BlockChain, block *types.Block) error {
+	statedb, err := p.State(block.ParentHash())
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.arrived = make(map[common.Hash]bool)
+	p.mu.Unlock()
+
+	name, key := p.identity()
+	for i, tx := range block.Transactions() {
+		hash, ok := ParsePrivateTx(tx.Data())
+		if !ok || key == nil {
+			continue
+		}
+		envelope := p.payloads.Get(hash)
+		if envelope == nil {
+			glog.V(logger.Debug).Infof("private tx %x: payload %x unknown", tx.Hash().Bytes()[:4], hash.Bytes()[:4])
+			p.missing(hash, block.NumberU64())
+			continue
+		}
+		payload, err := envelope.Open(name, key)
+		if err == private.ErrNotParticipant {
+			continue
+		}
+		if err != nil {
+			glog.V(logger.Warn).Infof("private tx %x: %v", tx.Hash().Bytes()[:4], err)
+			continue
+		}
+		from, err := tx.From()
+		if err != nil {
+			continue
+		}
+		// The private nonce follows the public one, which keeps the
+		// addresses of private contracts unique.
+		statedb.SetNonce(from, tx.Nonce())
+		statedb.StartRecord(tx.Hash(), block.Hash(), i)
+
+		msg := privateMessage{tx, payload}
+		env := NewEnv(statedb, chain.Config(), chain, msg, block.Header(), vm.Config{})
+		if _, _, err := ApplyMessage(env, msg, new(GasPool).AddGas(tx.Gas())); err != nil {
+			glog.V(logger.Debug).Infof("private tx %x: %v", tx.Hash().Bytes()[:4], err)
+		}
+	}
+	root, err := statedb.Commit()
+	if err != nil {
+		return err
+	}
+	return p.db.Put(append(privateRootPrefix, block.Hash().Bytes()...), root[:])
+}
+
+// missing records that the block with the given number misses a payload.
+// Payloads of transactions the node doesn't participate in never arrive.
+func (p *PrivateState) missing(hash common.Hash, number uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.arrived[hash] {
+		p.resumeFrom(number)
+		p.signal()
+		return
+	}
+	key := append(privateMissingPrefix, hash[:]...)
+	if enc, err := p.db.Get(key); err == nil && len(enc) == 8 && binary.BigEndian.Uint64(enc) <= number {
+		return
+	}
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	p.db.Put(key, enc)
+}
+
+// privateMessage executes the payload of a private transaction. It pays no
+// gas and transfers no value; like calls it is not subject to permissions,
+// which were checked on the public transaction.
+type privateMessage struct {
+	tx      *types.Transaction
+	payload []byte
+}
+
+func (m privateMessage) From() (common.Address, error)         { return m.tx.From() }
+func (m privateMessage) FromFrontier() (common.Address, error) { return m.tx.FromFrontier() }
+func (m privateMessage) To() *common.Address                   { return m.tx.To() }
+func (m privateMessage) GasPrice() *big.Int                    { return new(big.Int) }
+func (m privateMessage) Gas() *big.Int                         { return m.tx.Gas() }
+func (m privateMessage) Value() *big.Int                       { return new(big.Int) }
+func (m privateMessage) Nonce() uint64                         { return m.tx.Nonce() }
+func (m privateMessage) Data() []byte                          { return m.payload }
+func (m privateMessage) IsCall() bool                          { return true }
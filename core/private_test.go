@@ -0,0 +1,190 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/private"
+)
+
+func TestPrivateTransaction(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = WriteGenesisBlockForTesting(db, GenesisAccount{addr, big.NewInt(1000000)})
+	)
+	// Two nodes with their own private state, only the first participates
+	newNode := func(name string) (*PrivateState, *private.Store, private.Participant) {
+		enrollment, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		privateDb, _ := ethdb.NewMemDatabase()
+		store := private.NewStore(privateDb)
+		identity := func() (string, *ecdsa.PrivateKey) { return name, enrollment }
+		return NewPrivateState(privateDb, store, identity), store, private.Participant{Name: name, Key: &enrollment.PublicKey}
+	}
+	participant, participantStore, self := newNode("node1")
+	outsider, outsiderStore, _ := newNode("node2")
+
+	// The payload creates a contract storing 42 at slot 0
+	envelope, err := private.Seal(common.FromHex("0x602a60005500"), []private.Participant{self})
+	if err != nil {
+		t.Fatalf("failed to seal payload: %v", err)
+	}
+	hash, _ := participantStore.Put(envelope)
+	outsiderStore.Put(envelope)
+
+	tx, _ := types.NewContractCreation(0, new(big.Int), big.NewInt(100000), big.NewInt(1), PrivateTxData(hash)).SignECDSA(key)
+	blocks, _ := GenerateChain(nil, genesis, db, 1, func(i int, gen *BlockGen) {
+		gen.AddTx(tx)
+	})
+	blockchain, err := NewBlockChain(db, testChainConfig(), FakePow{}, new(event.TypeMux))
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+	blockchain.SetPrivateState(participant)
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitPrivateRoot(t, participant, blocks[0].Hash())
+	if err := outsider.Process(blockchain, blocks[0]); err != nil {
+		t.Fatalf("outsider failed to process block: %v", err)
+	}
+
+	// Publicly only the nonce changes
+	contract := crypto.CreateAddress(addr, 0)
+	public, _ := blockchain.State()
+	if public.GetNonce(addr) != 1 {
+		t.Fatalf("public nonce mismatch: have %d, want 1", public.GetNonce(addr))
+	}
+	if public.Exist(contract) {
+		t.Fatal("private contract created in public state")
+	}
+	// Only participants execute the payload
+	check := func(name string, p *PrivateState, want common.Hash) *state.StateDB {
+		statedb, err := p.State(blocks[0].Hash())
+		if err != nil {
+			t.Fatalf("%s: failed to open private state: %v", name, err)
+		}
+		if have := statedb.GetState(contract, common.Hash{}); have != want {
+			t.Errorf("%s: private storage mismatch: have %x, want %x", name, have, want)
+		}
+		return statedb
+	}
+	check("participant", participant, common.BigToHash(big.NewInt(42)))
+	check("outsider", outsider, common.Hash{})
+}
+
+// waitPrivateRoot waits until the private state of the block was executed.
+func waitPrivateRoot(t *testing.T, p *PrivateState, block common.Hash) {
+	for deadline := time.Now().Add(2 * time.Second); p.Root(block) == (common.Hash{}); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("private state of block %x not executed", block[:4])
+		}
+	}
+}
+
+// Tests that blocks missing a payload are executed again once it arrives,
+// together with the blocks built on them.
+func TestPrivatePayloadArrival(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = WriteGenesisBlockForTesting(db, GenesisAccount{addr, big.NewInt(1000000)})
+	)
+	enrollment, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	privateDb, _ := ethdb.NewMemDatabase()
+	store := private.NewStore(privateDb)
+	participant := NewPrivateState(privateDb, store, func() (string, *ecdsa.PrivateKey) { return "node1", enrollment })
+
+	envelope, _ := private.Seal(common.FromHex("0x602a60005500"), []private.Participant{{Name: "node1", Key: &enrollment.PublicKey}})
+	hash := envelope.Hash()
+
+	tx, _ := types.NewContractCreation(0, new(big.Int), big.NewInt(100000), big.NewInt(1), PrivateTxData(hash)).SignECDSA(key)
+	blocks, _ := GenerateChain(nil, genesis, db, 2, func(i int, gen *BlockGen) {
+		if i == 0 {
+			gen.AddTx(tx)
+		}
+	})
+	blockchain, err := NewBlockChain(db, testChainConfig(), FakePow{}, new(event.TypeMux))
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+	blockchain.SetPrivateState(participant)
+
+	// The blocks are imported and executed without the payload
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	head := blocks[1].Hash()
+	waitPrivateRoot(t, participant, head)
+	contract := crypto.CreateAddress(addr, 0)
+	if statedb, _ := participant.State(head); statedb.Exist(contract) {
+		t.Fatal("private contract created without payload")
+	}
+
+	// Once the payload arrives, the chain is executed again from the block
+	// that missed it
+	store.Put(envelope)
+	participant.PayloadArrived(hash)
+	want := common.BigToHash(big.NewInt(42))
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		statedb, _ := participant.State(head)
+		if statedb.GetState(contract, common.Hash{}) == want {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("private state not executed again after the payload arrived")
+		}
+	}
+}
+
+func TestPrivateTransactionValidation(t *testing.T) {
+	data := PrivateTxData(common.Hash{1})
+	if hash, ok := ParsePrivateTx(data); !ok || hash != (common.Hash{1}) {
+		t.Fatalf("private tx data not parsed: %x, %v", hash, ok)
+	}
+	if _, ok := ParsePrivateTx(append(data, 0)); ok {
+		t.Fatal("longer data parsed as private tx")
+	}
+	to := common.HexToAddress("0x01")
+	if err := ValidatePrivateTx(&to, data, new(big.Int)); err != nil {
+		t.Fatalf("valid private tx refused: %v", err)
+	}
+	if err := ValidatePrivateTx(&to, data, big.NewInt(1)); err != ErrPrivateTx {
+		t.Fatalf("private value transfer error mismatch: %v", err)
+	}
+	if err := ValidatePrivateTx(&PermissionRegistryAddress, data, new(big.Int)); err != ErrPrivateTx {
+		t.Fatalf("private permission change error mismatch: %v", err)
+	}
+	if err := ValidatePrivateTx(&PermissionRegistryAddress, PermissionChangeData(to, PermissionTransact), new(big.Int)); err != nil {
+		t.Fatalf("public tx refused: %v", err)
+	}
+
+	pool, key := setupTxPool()
+	currentState, _ := pool.currentState()
+	currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	tx, _ := types.NewTransaction(0, to, big.NewInt(1), big.NewInt(100000), big.NewInt(1), data).SignECDSA(key)
+	if err := pool.validateTx(tx); err != ErrPrivateTx {
+		t.Fatalf("pool error mismatch: %v", err)
+	}
+}
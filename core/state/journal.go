@@ -67,6 +67,9 @@ type (
 	addLogChange struct {
 		txhash common.Hash
 	}
+	addTransferChange struct {
+		txhash common.Hash
+	}
 )
 
 func (ch createObjectChange) undo(s *StateDB) {
@@ -107,6 +110,15 @@ func (ch refundChange) undo(s *StateDB) {
 	s.refund = ch.prev
 }
 
+func (ch addTransferChange) undo(s *StateDB) {
+	transfers := s.transfers[ch.txhash]
+	if len(transfers) == 1 {
+		delete(s.transfers, ch.txhash)
+	} else {
+		s.transfers[ch.txhash] = transfers[:len(transfers)-1]
+	}
+}
+
 func (ch addLogChange) undo(s *StateDB) {
 	logs := s.logs[ch.txhash]
 	if len(logs) == 1 {
@@ -24,6 +24,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -77,6 +78,7 @@ type StateDB struct {
 	txIndex      int
 	logs         map[common.Hash]vm.Logs
 	logSize      uint
+	transfers    map[common.Hash][]*types.InternalTransfer
 
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
@@ -102,6 +104,7 @@ func New(root common.Hash, db ethdb.Database) (*StateDB, error) {
 		stateObjectsDirty: make(map[common.Address]struct{}),
 		refund:            new(big.Int),
 		logs:              make(map[common.Hash]vm.Logs),
+		transfers:         make(map[common.Hash][]*types.InternalTransfer),
 	}, nil
 }
 
@@ -123,6 +126,7 @@ func (self *StateDB) New(root common.Hash) (*StateDB, error) {
 		stateObjectsDirty: make(map[common.Address]struct{}),
 		refund:            new(big.Int),
 		logs:              make(map[common.Hash]vm.Logs),
+		transfers:         make(map[common.Hash][]*types.InternalTransfer),
 	}, nil
 }
 
@@ -144,6 +148,7 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.txIndex = 0
 	self.logs = make(map[common.Hash]vm.Logs)
 	self.logSize = 0
+	self.transfers = make(map[common.Hash][]*types.InternalTransfer)
 	self.clearJournalAndRefund()
 
 	return nil
@@ -202,6 +207,21 @@ func (self *StateDB) Logs() vm.Logs {
 	return logs
 }
 
+// AddTransfer records a transfer of value made by a contract during the
+// current transaction. Transfers are reverted along with the state.
+func (self *StateDB) AddTransfer(from, to common.Address, value *big.Int) {
+	self.journal = append(self.journal, addTransferChange{txhash: self.thash})
+
+	transfer := &types.InternalTransfer{TxIndex: uint(self.txIndex), From: from, To: to, Value: new(big.Int).Set(value)}
+	self.transfers[self.thash] = append(self.transfers[self.thash], transfer)
+}
+
+// GetTransfers returns the transfers made by contracts during the transaction
+// with the given hash.
+func (self *StateDB) GetTransfers(hash common.Hash) []*types.InternalTransfer {
+	return self.transfers[hash]
+}
+
 func (self *StateDB) AddRefund(gas *big.Int) {
 	self.journal = append(self.journal, refundChange{prev: new(big.Int).Set(self.refund)})
 	self.refund.Add(self.refund, gas)
@@ -464,6 +484,7 @@ func (self *StateDB) Copy() *StateDB {
 		refund:            new(big.Int).Set(self.refund),
 		logs:              make(map[common.Hash]vm.Logs, len(self.logs)),
 		logSize:           self.logSize,
+		transfers:         make(map[common.Hash][]*types.InternalTransfer, len(self.transfers)),
 	}
 	// Copy the dirty states and logs
 	for addr, _ := range self.stateObjectsDirty {
@@ -474,6 +495,10 @@ func (self *StateDB) Copy() *StateDB {
 		state.logs[hash] = make(vm.Logs, len(logs))
 		copy(state.logs[hash], logs)
 	}
+	for hash, transfers := range self.transfers {
+		state.transfers[hash] = make([]*types.InternalTransfer, len(transfers))
+		copy(state.transfers[hash], transfers)
+	}
 	return state
 }
 
@@ -74,6 +74,13 @@ type Message interface {
 	Data() []byte
 }
 
+// callMessage is implemented by messages that are executed on a throwaway
+// state and never included in a block, e.g. by eth_call. They are not
+// subject to account permissions.
+type callMessage interface {
+	IsCall() bool
+}
+
 func MessageCreatesContract(msg Message) bool {
 	return msg.To() == nil
 }
@@ -106,12 +113,16 @@ func IntrinsicGas(data []byte, contractCreation, homestead bool) *big.Int {
 
 // NewStateTransition initialises and returns a new state transition object.
 func NewStateTransition(env vm.Environment, msg Message, gp *GasPool) *StateTransition {
+	gasPrice := msg.GasPrice()
+	if gasIsFree(env) {
+		gasPrice = new(big.Int)
+	}
 	return &StateTransition{
 		gp:         gp,
 		env:        env,
 		msg:        msg,
 		gas:        new(big.Int),
-		gasPrice:   msg.GasPrice(),
+		gasPrice:   gasPrice,
 		initialGas: new(big.Int),
 		value:      msg.Value(),
 		data:       msg.Data(),
@@ -119,6 +130,13 @@ func NewStateTransition(env vm.Environment, msg Message, gp *GasPool) *StateTran
 	}
 }
 
+// gasIsFree reports whether env runs on a chain in free gas mode, where gas is
+// bought and refunded at a price of zero.
+func gasIsFree(env vm.Environment) bool {
+	config, ok := env.RuleSet().(*ChainConfig)
+	return ok && config.FreeGas
+}
+
 // ApplyMessage computes the new state by applying the given message
 // against the old state within the environment.
 //
@@ -212,6 +230,16 @@ func (self *StateTransition) preCheck() (err error) {
 		return NonceError(msg.Nonce(), n)
 	}
 
+	// Make sure the sender holds the permission level the transaction requires
+	if call, ok := msg.(callMessage); !ok || !call.IsCall() {
+		if err := CheckPermission(self.state, sender.Address(), msg.To(), self.data, self.value); err != nil {
+			return err
+		}
+	}
+	if err := ValidatePrivateTx(msg.To(), self.data, self.value); err != nil {
+		return err
+	}
+
 	// Pre-pay gas
 	if err = self.buyGas(); err != nil {
 		if IsGasLimitErr(err) {
@@ -240,7 +268,10 @@ func (self *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *b
 
 	vmenv := self.env
 	//var addr common.Address
-	if contractCreation {
+	if _, private := ParsePrivateTx(self.data); private {
+		// The payload is executed by the participants on their private state
+		self.state.SetNonce(sender.Address(), self.state.GetNonce(sender.Address())+1)
+	} else if contractCreation {
 		ret, _, err = vmenv.Create(sender, self.data, self.gas, self.gasPrice, self.value)
 		if homestead && err == vm.CodeStoreOutOfGasError {
 			self.gas = Big0
@@ -253,9 +284,19 @@ func (self *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *b
 	} else {
 		// Increment the nonce for the next transaction
 		self.state.SetNonce(sender.Address(), self.state.GetNonce(sender.Address())+1)
-		ret, err = vmenv.Call(sender, self.to().Address(), self.data, self.gas, self.gasPrice, self.value)
-		if err != nil {
-			glog.V(logger.Core).Infoln("VM call err:", err)
+		// Permission changes are applied natively, the data was checked in preCheck
+		switch *msg.To() {
+		case PermissionRegistryAddress:
+			addr, level, _ := ParsePermissionChange(self.data)
+			setPermission(self.state, addr, level)
+		case DeployAllowListAddress:
+			addr, allowed, _ := ParseAllowListChange(self.data)
+			setDeployAllowed(self.state, addr, allowed)
+		default:
+			ret, err = vmenv.Call(sender, self.to().Address(), self.data, self.gas, self.gasPrice, self.value)
+			if err != nil {
+				glog.V(logger.Core).Infoln("VM call err:", err)
+			}
 		}
 	}
 
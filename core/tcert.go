@@ -0,0 +1,34 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package core
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var errTCertUnsupported = errors.New("transaction certificates are not accepted without a CA root")
+
+// ValidateTCert checks the transaction certificate carried by tx, if any: it
+// must sign the transaction and be issued to an enrolled member at the given
+// time. Blocks are validated at their timestamp so that all nodes agree.
+func ValidateTCert(config *ChainConfig, tx *types.Transaction, now time.Time) error {
+	if !tx.HasTCert() {
+		return nil
+	}
+	if config.TCertVerifier == nil {
+		return errTCertUnsupported
+	}
+	cert, intermediates, err := tx.TCert()
+	if err != nil {
+		return err
+	}
+	return config.TCertVerifier(cert, intermediates, now)
+}
@@ -219,15 +219,17 @@ func (m *txSortedMap) Flatten() types.Transactions {
 // executable/future queue, with minor behavoiral changes.
 type txList struct {
 	strict  bool         // Whether nonces are strictly continuous or not
+	config  *ChainConfig // Chain configuration pricing the transactions
 	txs     *txSortedMap // Heap indexed sorted hash map of the transactions
 	costcap *big.Int     // Price of the highest costing transaction (reset only if exceeds balance)
 }
 
 // newTxList create a new transaction list for maintaining nonce-indexable fast,
 // gapped, sortable transaction lists.
-func newTxList(strict bool) *txList {
+func newTxList(strict bool, config *ChainConfig) *txList {
 	return &txList{
 		strict:  strict,
+		config:  config,
 		txs:     newTxSortedMap(),
 		costcap: new(big.Int),
 	}
@@ -246,7 +248,7 @@ func (l *txList) Add(tx *types.Transaction) (bool, *types.Transaction) {
 	}
 	// Otherwise overwrite the old transaction with the current one
 	l.txs.Put(tx)
-	if cost := tx.Cost(); l.costcap.Cmp(cost) < 0 {
+	if cost := l.config.TxCost(tx); l.costcap.Cmp(cost) < 0 {
 		l.costcap = cost
 	}
 	return true, old
@@ -275,7 +277,7 @@ func (l *txList) Filter(threshold *big.Int) (types.Transactions, types.Transacti
 	l.costcap = new(big.Int).Set(threshold) // Lower the cap to the threshold
 
 	// Filter out all the transactions above the account's funds
-	removed := l.txs.Filter(func(tx *types.Transaction) bool { return tx.Cost().Cmp(threshold) > 0 })
+	removed := l.txs.Filter(func(tx *types.Transaction) bool { return l.config.TxCost(tx).Cmp(threshold) > 0 })
 
 	// If the list was strict, filter anything above the lowest nonce
 	var invalids types.Transactions
@@ -36,7 +36,7 @@ func TestStrictTxListAdd(t *testing.T) {
 		txs[i] = transaction(uint64(i), new(big.Int), key)
 	}
 	// Insert the transactions in a random order
-	list := newTxList(true)
+	list := newTxList(true, testChainConfig())
 	for _, v := range rand.Perm(len(txs)) {
 		list.Add(txs[v])
 	}
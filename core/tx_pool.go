@@ -24,6 +24,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/audit"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -44,6 +45,7 @@ var (
 	ErrIntrinsicGas       = errors.New("Intrinsic gas too low")
 	ErrGasLimit           = errors.New("Exceeds block gas limit")
 	ErrNegativeValue      = errors.New("Negative value")
+	ErrTCert              = errors.New("Transaction certificate not accepted")
 )
 
 var (
@@ -244,7 +246,7 @@ func (pool *TxPool) SetLocal(tx *types.Transaction) {
 func (pool *TxPool) validateTx(tx *types.Transaction) error {
 	local := pool.localTx.contains(tx.Hash())
 	// Drop transactions under our own minimal accepted gas price
-	if !local && pool.minGasPrice.Cmp(tx.GasPrice()) > 0 {
+	if !local && !pool.config.FreeGas && pool.minGasPrice.Cmp(tx.GasPrice()) > 0 {
 		return ErrCheap
 	}
 
@@ -258,9 +260,25 @@ func (pool *TxPool) validateTx(tx *types.Transaction) error {
 		return ErrInvalidSender
 	}
 
+	if err := ValidateTCert(pool.config, tx, time.Now()); err != nil {
+		glog.V(logger.Debug).Infof("tx %x: %v", tx.Hash().Bytes()[:4], err)
+		audit.Record(audit.TxRejected, from.Hex(), fmt.Sprintf("tx %x: %v", tx.Hash(), err))
+		return ErrTCert
+	}
+
+	// Make sure the sender may send this kind of transaction
+	if err := CheckPermission(currentState, from, tx.To(), tx.Data(), tx.Value()); err != nil {
+		audit.Record(audit.TxRejected, from.Hex(), fmt.Sprintf("tx %x: %v", tx.Hash(), err))
+		return err
+	}
+	if err := ValidatePrivateTx(tx.To(), tx.Data(), tx.Value()); err != nil {
+		return err
+	}
+
 	// Make sure the account exist. Non existent accounts
-	// haven't got funds and well therefor never pass.
-	if !currentState.Exist(from) {
+	// haven't got funds and well therefor never pass,
+	// unless gas is free.
+	if !pool.config.FreeGas && !currentState.Exist(from) {
 		return ErrNonExistentAccount
 	}
 
@@ -283,8 +301,8 @@ func (pool *TxPool) validateTx(tx *types.Transaction) error {
 	}
 
 	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
-	if currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
+	// cost == V + GP * GL, or V if gas is free
+	if currentState.GetBalance(from).Cmp(pool.config.TxCost(tx)) < 0 {
 		return ErrInsufficientFunds
 	}
 
@@ -329,7 +347,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) {
 	// Try to insert the transaction into the future queue
 	from, _ := tx.From() // already validated
 	if pool.queue[from] == nil {
-		pool.queue[from] = newTxList(false)
+		pool.queue[from] = newTxList(false, pool.config)
 	}
 	inserted, old := pool.queue[from].Add(tx)
 	if !inserted {
@@ -352,7 +370,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	}
 	// Try to insert the transaction into the pending queue
 	if pool.pending[addr] == nil {
-		pool.pending[addr] = newTxList(true)
+		pool.pending[addr] = newTxList(true, pool.config)
 	}
 	list := pool.pending[addr]
 
@@ -0,0 +1,118 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package types
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrNoTCert      = errors.New("transaction carries no tcert")
+	ErrInvalidTCert = errors.New("invalid tcert signature")
+)
+
+// tcertdata is the transaction certificate attached to a transaction. It
+// proves that the sender belongs to an enrolled member without revealing
+// which one, so senders wanting to stay anonymous use a fresh account and
+// tcert for every transaction.
+type tcertdata struct {
+	Certs [][]byte // DER encoded tcert followed by the intermediate CAs that issued it
+	Sig   []byte   // ASN.1 encoded ECDSA signature of TCertHash by the tcert key
+}
+
+type tcertSignature struct {
+	R, S *big.Int
+}
+
+// HasTCert reports whether the transaction carries a transaction certificate.
+func (tx *Transaction) HasTCert() bool {
+	return len(tx.data.TCert) > 0
+}
+
+// TCertHash returns the hash signed with the tcert key. It binds the tcert to
+// the transaction and its sender, so the signature cannot be moved to a
+// transaction sent from another account.
+func (tx *Transaction) TCertHash() (common.Hash, error) {
+	from, err := tx.From()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return rlpHash([]interface{}{tx.SigHash(), from}), nil
+}
+
+// WithTCert returns a copy of the signed transaction carrying the given tcert
+// and intermediate CA certificates, signed with the tcert private key.
+func (tx *Transaction) WithTCert(cert *x509.Certificate, intermediates []*x509.Certificate, prv *ecdsa.PrivateKey) (*Transaction, error) {
+	h, err := tx.TCertHash()
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, prv, h[:])
+	if err != nil {
+		return nil, err
+	}
+	sig, err := asn1.Marshal(tcertSignature{r, s})
+	if err != nil {
+		return nil, err
+	}
+	certs := [][]byte{cert.Raw}
+	for _, c := range intermediates {
+		certs = append(certs, c.Raw)
+	}
+	cpy := &Transaction{data: tx.data}
+	cpy.data.TCert = []tcertdata{{Certs: certs, Sig: sig}}
+	return cpy, nil
+}
+
+// TCert returns the transaction certificate and the intermediate CA
+// certificates carried by the transaction after checking the tcert signature.
+// Whether the tcert is issued to an enrolled member is up to the caller.
+func (tx *Transaction) TCert() (*x509.Certificate, []*x509.Certificate, error) {
+	if !tx.HasTCert() {
+		return nil, nil, ErrNoTCert
+	}
+	if len(tx.data.TCert) > 1 || len(tx.data.TCert[0].Certs) == 0 {
+		return nil, nil, ErrInvalidTCert
+	}
+	data := tx.data.TCert[0]
+	certs := make([]*x509.Certificate, len(data.Certs))
+	for i, raw := range data.Certs {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		certs[i] = cert
+	}
+	pub, ok := certs[0].PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, nil, ErrInvalidTCert
+	}
+
+	var sig tcertSignature
+	if rest, err := asn1.Unmarshal(data.Sig, &sig); err != nil || len(rest) > 0 {
+		return nil, nil, ErrInvalidTCert
+	}
+	if sig.R == nil || sig.S == nil || sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return nil, nil, ErrInvalidTCert
+	}
+	h, err := tx.TCertHash()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ecdsa.Verify(pub, h[:], sig.R, sig.S) {
+		return nil, nil, ErrInvalidTCert
+	}
+	return certs[0], certs[1:], nil
+}
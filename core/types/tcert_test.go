@@ -0,0 +1,91 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package types
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func newTestTCert(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "tcert"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(raw)
+	return cert, priv
+}
+
+func TestTransactionTCert(t *testing.T) {
+	key, _ := defaultTestKey()
+	tx, err := NewTransaction(0, common.Address{1}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil).SignECDSA(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tx.TCert(); err != ErrNoTCert {
+		t.Fatalf("plain transaction tcert error mismatch: %v", err)
+	}
+	plain, _ := rlp.EncodeToBytes(tx)
+
+	cert, priv := newTestTCert(t)
+	ttx, err := tx.WithTCert(cert, []*x509.Certificate{cert}, priv)
+	if err != nil {
+		t.Fatalf("failed to attach tcert: %v", err)
+	}
+	if ttx.SigHash() != tx.SigHash() || ttx.Hash() == tx.Hash() {
+		t.Error("tcert should change the hash but not the signature hash")
+	}
+
+	// The tcert survives encoding, plain transactions encode as before
+	enc, err := rlp.EncodeToBytes(ttx)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	dec, err := decodeTx(enc)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	got, intermediates, err := dec.TCert()
+	if err != nil {
+		t.Fatalf("tcert refused: %v", err)
+	}
+	if !got.Equal(cert) || len(intermediates) != 1 {
+		t.Error("decoded tcert mismatch")
+	}
+	if dec, err = decodeTx(plain); err != nil || dec.HasTCert() {
+		t.Errorf("plain transaction decoding mismatch: %v", err)
+	}
+
+	// The tcert signature is bound to the sender
+	other, _ := crypto.GenerateKey()
+	otx, _ := tx.SignECDSA(other)
+	otx.data.TCert = ttx.data.TCert
+	if _, _, err := otx.TCert(); err != ErrInvalidTCert {
+		t.Errorf("tcert of another sender accepted: %v", err)
+	}
+}
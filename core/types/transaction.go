@@ -50,6 +50,10 @@ type txdata struct {
 	Payload         []byte
 	V               byte     // signature
 	R, S            *big.Int // signature
+
+	// Transaction certificate, at most one. Transactions without it encode
+	// exactly like before.
+	TCert []tcertdata `rlp:"tail"`
 }
 
 func NewContractCreation(nonce uint64, amount, gasLimit, gasPrice *big.Int, data []byte) *Transaction {
@@ -0,0 +1,25 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// InternalTransfer is a transfer of value made by a contract while executing
+// a transaction, by calling or creating another account with value. Unlike
+// the value of the transaction itself, these transfers leave no trace in the
+// block or the receipts.
+type InternalTransfer struct {
+	TxIndex uint           // index of the transaction in the block
+	From    common.Address // contract sending the value
+	To      common.Address // account receiving the value
+	Value   *big.Int       // amount transferred
+}
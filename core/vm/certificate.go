@@ -0,0 +1,96 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package vm
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/caserver/certs"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+)
+
+// CertificateVerifierAddress is the precompiled contract checking that a
+// certificate was issued to an enrolled member by the CA the genesis block
+// commits to.
+//
+// The input is a DER or PEM encoded certificate, optionally followed by the
+// certificates of the intermediate CAs that issued it. The output is six
+// words:
+//
+//	0: 1 if the certificate chains up to the CA root and is valid at the
+//	   time of the block, 0 otherwise
+//	1: node type (OID 1.33.80): 0 client, 1 peer, 2 validator, 3 admin
+//	2: peer id (OID 1.33.81)
+//	3: Keccak-256 hash of the DER encoded subject
+//	4: organization of the subject, left aligned and cut to 32 bytes
+//	5: common name of the subject, left aligned and cut to 32 bytes
+//
+// Input that is not a certificate yields six zero words. Revocations are not
+// known to the chain, contracts needing them must track them separately.
+//
+// The contract only exists on chains whose genesis block commits to a CA
+// root, elsewhere the address is a plain account.
+var CertificateVerifierAddress = common.BytesToAddress([]byte{0x01, 0x03})
+
+// CARootRuleSet is implemented by the rule sets of chains whose genesis block
+// commits to the root certificate of the CA.
+type CARootRuleSet interface {
+	CARoot() *x509.Certificate
+}
+
+const certificateVerifierOutputLength = 6 * 32
+
+func hasCARoot(env Environment) bool {
+	rules, ok := env.RuleSet().(CARootRuleSet)
+	return ok && rules.CARoot() != nil
+}
+
+func verifyCertificateFunc(env Environment, in []byte) []byte {
+	out := make([]byte, certificateVerifierOutputLength)
+
+	var (
+		chain []*x509.Certificate
+		err   error
+	)
+	if bytes.HasPrefix(bytes.TrimSpace(in), []byte("-----BEGIN")) {
+		chain, err = certs.ParseCertificates(in)
+	} else {
+		chain, err = x509.ParseCertificates(in)
+	}
+	if err != nil || len(chain) == 0 {
+		glog.V(logger.Detail).Infof("CERTVERIFY error: invalid certificate: %v", err)
+		return out
+	}
+	cert := chain[0]
+
+	if rules, ok := env.RuleSet().(CARootRuleSet); ok {
+		if root := rules.CARoot(); root != nil {
+			// The security level is a setting of the node, the result
+			// must only depend on the chain
+			now := time.Unix(env.Time().Int64(), 0)
+			if err := certs.VerifyChainAt(cert, chain[1:], root, now, nil); err == nil {
+				out[31] = 1
+			} else {
+				glog.V(logger.Detail).Infof("CERTVERIFY %q: %v", cert.Subject.CommonName, err)
+			}
+		}
+	}
+	nodetype, peerid := certs.NodeInfo(cert)
+	out[63] = byte(nodetype)
+	binary.BigEndian.PutUint32(out[92:96], peerid)
+	copy(out[96:128], crypto.Keccak256(cert.RawSubject))
+	copy(out[128:160], certs.Organization(cert))
+	copy(out[160:192], cert.Subject.CommonName)
+	return out
+}
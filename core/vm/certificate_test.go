@@ -0,0 +1,139 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package vm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/caserver/certs"
+)
+
+type caRuleSet struct {
+	ruleSet
+	root *x509.Certificate
+}
+
+func (r caRuleSet) CARoot() *x509.Certificate { return r.root }
+
+// certEnv runs at a fixed block time under a rule set committing to root.
+type certEnv struct {
+	*Env
+	root *x509.Certificate
+	time time.Time
+}
+
+func (e *certEnv) RuleSet() RuleSet { return caRuleSet{ruleSet{new(big.Int)}, e.root} }
+func (e *certEnv) Time() *big.Int   { return big.NewInt(e.time.Unix()) }
+
+func issue(t *testing.T, name, org string, nodetype int32, peerid uint32, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	peer := make([]byte, 4)
+	binary.LittleEndian.PutUint32(peer, peerid)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{org}},
+		NotBefore:    time.Unix(1000, 0),
+		NotAfter:     time.Unix(2000, 0),
+		ExtraExtensions: []pkix.Extension{
+			{Id: certs.NodeTypeOID, Critical: true, Value: []byte{byte(nodetype)}},
+			{Id: certs.PeerIdOID, Critical: true, Value: peer},
+		},
+	}
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.MaxPathLen = -1
+		parent, parentKey = template, key
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(raw)
+	return cert, key
+}
+
+func TestCertificateVerifier(t *testing.T) {
+	root, rootKey := issue(t, "root", "Dianrong", certs.Admin, 0, nil, nil)
+	other, otherKey := issue(t, "other", "Other", certs.Admin, 0, nil, nil)
+	member, _ := issue(t, "validator7", "BankA", certs.Validator, 7, root, rootKey)
+	outsider, _ := issue(t, "validator8", "BankB", certs.Validator, 8, other, otherKey)
+
+	p := Precompiled[string(CertificateVerifierAddress.Bytes())]
+	if p == nil {
+		t.Fatal("certificate verifier not registered")
+	}
+	env := &certEnv{NewEnv(true, false), root, time.Unix(1500, 0)}
+	pemMember := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: member.Raw})
+
+	for i, tt := range []struct {
+		in    []byte
+		time  int64
+		valid bool
+		cert  *x509.Certificate
+	}{
+		{member.Raw, 1500, true, member},
+		{pemMember, 1500, true, member},
+		{member.Raw, 2500, false, member}, // expired at the block time
+		{outsider.Raw, 1500, false, outsider},
+	} {
+		env.time = time.Unix(tt.time, 0)
+		out := p.Call(env, tt.in)
+		if len(out) != certificateVerifierOutputLength {
+			t.Fatalf("test %d: output length mismatch: have %d, want %d", i, len(out), certificateVerifierOutputLength)
+		}
+		if valid := out[31] == 1; valid != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want %v", i, valid, tt.valid)
+		}
+		nodetype, peerid := certs.NodeInfo(tt.cert)
+		if got := common.BytesToBig(out[32:64]).Uint64(); got != uint64(nodetype) {
+			t.Errorf("test %d: node type mismatch: have %d, want %d", i, got, nodetype)
+		}
+		if got := common.BytesToBig(out[64:96]).Uint64(); got != uint64(peerid) {
+			t.Errorf("test %d: peer id mismatch: have %d, want %d", i, got, peerid)
+		}
+		if !bytes.Equal(out[96:128], crypto.Keccak256(tt.cert.RawSubject)) {
+			t.Errorf("test %d: subject hash mismatch", i)
+		}
+		if org := string(bytes.TrimRight(out[128:160], "\x00")); org != tt.cert.Subject.Organization[0] {
+			t.Errorf("test %d: organization mismatch: have %q", i, org)
+		}
+		if cn := string(bytes.TrimRight(out[160:192], "\x00")); cn != tt.cert.Subject.CommonName {
+			t.Errorf("test %d: common name mismatch: have %q", i, cn)
+		}
+	}
+
+	// Garbage yields zero words
+	if out := p.Call(env, []byte("not a certificate")); !bytes.Equal(out, make([]byte, certificateVerifierOutputLength)) {
+		t.Errorf("garbage output mismatch: %x", out)
+	}
+
+	// Chains without a CA root don't have the contract
+	if !p.Active(env) {
+		t.Error("verifier inactive with CA root")
+	}
+	if p.Active(NewEnv(true, false)) || p.Active(&certEnv{NewEnv(true, false), nil, time.Unix(1500, 0)}) {
+		t.Error("verifier active without CA root")
+	}
+
+	// Gas grows with the input
+	if small, large := p.Gas(32), p.Gas(1024); small.Cmp(large) >= 0 {
+		t.Errorf("gas not priced by input size: %v >= %v", small, large)
+	}
+}
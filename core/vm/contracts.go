@@ -28,12 +28,34 @@ import (
 
 // PrecompiledAccount represents a native ethereum contract
 type PrecompiledAccount struct {
-	Gas func(l int) *big.Int
-	fn  func(in []byte) []byte
+	Gas    func(l int) *big.Int
+	fn     func(in []byte) []byte
+	envFn  func(env Environment, in []byte) []byte // used instead of fn by contracts reading the chain
+	active func(env Environment) bool              // whether the contract exists yet (nil = always)
+
+	// used instead of fn by system contracts, which know their caller,
+	// charge gas as they go and may fail
+	systemFn func(env Environment, contract *Contract, in []byte) ([]byte, error)
+}
+
+// NewSystemContract returns a native contract implemented outside of the vm.
+// The gas returned by gas is charged before fn runs, fn charges the gas of its
+// state accesses from the contract itself. The contract exists while active
+// returns true.
+func NewSystemContract(gas func(l int) *big.Int, fn func(env Environment, contract *Contract, in []byte) ([]byte, error), active func(env Environment) bool) *PrecompiledAccount {
+	return &PrecompiledAccount{Gas: gas, systemFn: fn, active: active}
+}
+
+// Active returns whether the contract is enabled by the rules of env.
+func (self PrecompiledAccount) Active(env Environment) bool {
+	return self.active == nil || self.active(env)
 }
 
 // Call calls the native function
-func (self PrecompiledAccount) Call(in []byte) []byte {
+func (self PrecompiledAccount) Call(env Environment, in []byte) []byte {
+	if self.envFn != nil {
+		return self.envFn(env, in)
+	}
 	return self.fn(in)
 }
 
@@ -47,28 +69,48 @@ func PrecompiledContracts() map[string]*PrecompiledAccount {
 		// ECRECOVER
 		string(common.LeftPadBytes([]byte{1}, 20)): &PrecompiledAccount{func(l int) *big.Int {
 			return params.EcrecoverGas
-		}, ecrecoverFunc},
+		}, ecrecoverFunc, nil, nil, nil},
 
 		// SHA256
 		string(common.LeftPadBytes([]byte{2}, 20)): &PrecompiledAccount{func(l int) *big.Int {
 			n := big.NewInt(int64(l+31) / 32)
 			n.Mul(n, params.Sha256WordGas)
 			return n.Add(n, params.Sha256Gas)
-		}, sha256Func},
+		}, sha256Func, nil, nil, nil},
 
 		// RIPEMD160
 		string(common.LeftPadBytes([]byte{3}, 20)): &PrecompiledAccount{func(l int) *big.Int {
 			n := big.NewInt(int64(l+31) / 32)
 			n.Mul(n, params.Ripemd160WordGas)
 			return n.Add(n, params.Ripemd160Gas)
-		}, ripemd160Func},
+		}, ripemd160Func, nil, nil, nil},
 
 		string(common.LeftPadBytes([]byte{4}, 20)): &PrecompiledAccount{func(l int) *big.Int {
 			n := big.NewInt(int64(l+31) / 32)
 			n.Mul(n, params.IdentityWordGas)
 
 			return n.Add(n, params.IdentityGas)
-		}, memCpy},
+		}, memCpy, nil, nil, nil},
+
+		// DChain certificate verification, on chains committing to a CA root
+		string(CertificateVerifierAddress.Bytes()): &PrecompiledAccount{Gas: func(l int) *big.Int {
+			n := big.NewInt(int64(l+31) / 32)
+			n.Mul(n, params.CertificateVerifyWordGas)
+			return n.Add(n, params.CertificateVerifyGas)
+		}, envFn: verifyCertificateFunc, active: hasCARoot},
+
+		// DChain NIST curves, from the NIST fork block on
+		string(P256VerifyAddress.Bytes()): &PrecompiledAccount{Gas: func(l int) *big.Int {
+			return params.P256VerifyGas
+		}, fn: p256VerifyFunc, active: isNIST},
+		string(P384VerifyAddress.Bytes()): &PrecompiledAccount{Gas: func(l int) *big.Int {
+			return params.P384VerifyGas
+		}, fn: p384VerifyFunc, active: isNIST},
+		string(Sha384Address.Bytes()): &PrecompiledAccount{Gas: func(l int) *big.Int {
+			n := big.NewInt(int64(l+31) / 32)
+			n.Mul(n, params.Sha384WordGas)
+			return n.Add(n, params.Sha384Gas)
+		}, fn: sha384Func, active: isNIST},
 	}
 }
 
@@ -59,6 +59,8 @@ type Environment interface {
 	GasLimit() *big.Int
 	// Determines whether it's possible to transact
 	CanTransfer(from common.Address, balance *big.Int) bool
+	// Determines whether the account may create contracts
+	CanCreate(creator common.Address) bool
 	// Transfers amount from one account to the other
 	Transfer(from, to Account, amount *big.Int)
 	// Adds a LOG to the state
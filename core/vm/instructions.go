@@ -520,6 +520,12 @@ func opCreate(instr instruction, pc *uint64, env Environment, contract *Contract
 	}
 
 	contract.UseGas(gas)
+	// Contracts not allowed to deploy fail like an out of gas creation,
+	// using up the gas given to it
+	if !env.CanCreate(contract.Address()) {
+		stack.push(new(big.Int))
+		return
+	}
 	_, addr, suberr := env.Create(contract, input, gas, contract.Price, value)
 	// Push item on the stack based on the returned error. If the ruleset is
 	// homestead we must check for CodeStoreOutOfGasError (homestead only
@@ -0,0 +1,77 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package vm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha512"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+)
+
+// Precompiled contracts verifying signatures made with the NIST curves of the
+// enrollment keys. They are only active from the NIST fork block on, before it
+// their addresses are plain accounts.
+//
+// P256VerifyAddress takes hash, r, s, x and y of 32 bytes each, where (x, y)
+// is the public key, and P384VerifyAddress the same values of 48 bytes each.
+// Short input is right padded with zeros. Both return a word holding 1 if the
+// signature is valid and 0 otherwise. Sha384Address returns the 48 byte
+// SHA-384 digest of the input.
+var (
+	P256VerifyAddress = common.BytesToAddress([]byte{0x01, 0x04})
+	P384VerifyAddress = common.BytesToAddress([]byte{0x01, 0x05})
+	Sha384Address     = common.BytesToAddress([]byte{0x01, 0x06})
+)
+
+// NISTRuleSet is implemented by the rule sets of chains scheduling the fork
+// that activates the NIST curve precompiled contracts.
+type NISTRuleSet interface {
+	IsNIST(*big.Int) bool
+}
+
+func isNIST(env Environment) bool {
+	rules, ok := env.RuleSet().(NISTRuleSet)
+	return ok && rules.IsNIST(env.BlockNumber())
+}
+
+func p256VerifyFunc(in []byte) []byte {
+	return ecdsaVerify(elliptic.P256(), 32, in)
+}
+
+func p384VerifyFunc(in []byte) []byte {
+	return ecdsaVerify(elliptic.P384(), 48, in)
+}
+
+// ecdsaVerify checks the signature in of words of size bytes: hash, r, s and
+// the coordinates of the public key.
+func ecdsaVerify(curve elliptic.Curve, size int, in []byte) []byte {
+	in = common.RightPadBytes(in, 5*size)
+	word := func(i int) *big.Int { return new(big.Int).SetBytes(in[i*size : (i+1)*size]) }
+
+	var (
+		pub  = &ecdsa.PublicKey{Curve: curve, X: word(3), Y: word(4)}
+		r, s = word(1), word(2)
+		out  = make([]byte, 32)
+	)
+	if ecdsa.Verify(pub, in[:size], r, s) {
+		out[31] = 1
+	} else {
+		glog.V(logger.Detail).Infof("%s VERIFY: invalid signature", curve.Params().Name)
+	}
+	return out
+}
+
+func sha384Func(in []byte) []byte {
+	h := sha512.Sum384(in)
+	return h[:]
+}
@@ -0,0 +1,160 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package vm
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type nistRuleSet struct {
+	ruleSet
+	fork *big.Int
+}
+
+func (r nistRuleSet) IsNIST(n *big.Int) bool { return r.fork != nil && n.Cmp(r.fork) >= 0 }
+
+// nistEnv runs at block number under a rule set forking at fork.
+type nistEnv struct {
+	*Env
+	fork, number *big.Int
+}
+
+func (e *nistEnv) RuleSet() RuleSet      { return nistRuleSet{ruleSet{new(big.Int)}, e.fork} }
+func (e *nistEnv) BlockNumber() *big.Int { return e.number }
+
+// TestNISTSigVer runs the P-256 and P-384 precompiles against the NIST CAVS
+// 11.0 SigVer vectors for P-256 with SHA-256 and P-384 with SHA-384.
+func TestNISTSigVer(t *testing.T) {
+	file, err := os.Open("testdata/SigVer.rsp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var (
+		env     = &nistEnv{NewEnv(true, false), common.Big0, common.Big0}
+		p       *PrecompiledAccount
+		hash    func([]byte) []byte
+		size    int
+		vector  = make(map[string][]byte)
+		tested  int
+		scanner = bufio.NewScanner(file)
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "[P-256,SHA-256]":
+			p, size = Precompiled[string(P256VerifyAddress.Bytes())], 32
+			hash = func(msg []byte) []byte { h := sha256.Sum256(msg); return h[:] }
+			continue
+		case line == "[P-384,SHA-384]":
+			p, size = Precompiled[string(P384VerifyAddress.Bytes())], 48
+			hash = func(msg []byte) []byte { return Precompiled[string(Sha384Address.Bytes())].Call(env, msg) }
+			continue
+		case p == nil || !strings.Contains(line, " = "):
+			continue
+		}
+		parts := strings.SplitN(line, " = ", 2)
+		if parts[0] == "Result" {
+			in := hash(vector["Msg"])
+			for _, key := range []string{"R", "S", "Qx", "Qy"} {
+				in = append(in, common.LeftPadBytes(vector[key], size)...)
+			}
+			out := p.Call(env, in)
+			if valid := out[31] == 1; valid != (parts[1][0] == 'P') {
+				t.Errorf("vector %d: validity mismatch: have %v, want %s", tested, valid, parts[1])
+			}
+			tested++
+			continue
+		}
+		value, err := hex.DecodeString(parts[1])
+		if err != nil {
+			t.Fatalf("invalid %s: %v", parts[0], err)
+		}
+		vector[parts[0]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if tested != 30 {
+		t.Errorf("vector count mismatch: have %d, want 30", tested)
+	}
+}
+
+func TestSha384(t *testing.T) {
+	p := Precompiled[string(Sha384Address.Bytes())]
+	for i, tt := range []struct {
+		in   string
+		want string
+	}{
+		{"", "38b060a751ac96384cd9327eb1b1e36a21fdb71114be07434c0cc7bf63f6e1da274edebfe76f65fbd51ad2f14898b95b"},
+		{"abc", "cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed8086072ba1e7cc2358baeca134c825a7"},
+		{"abcdefghbcdefghicdefghijdefghijkefghijklfghijklmghijklmnhijklmnoijklmnopjklmnopqklmnopqrlmnopqrsmnopqrstnopqrstu",
+			"09330c33f71147e83d192fc782cd1b4753111b173b3b05d22fa08086e3b0f712fcc7c71a557e2db966c3e9fa91746039"},
+	} {
+		if out := hex.EncodeToString(p.Call(NewEnv(true, false), []byte(tt.in))); out != tt.want {
+			t.Errorf("test %d: digest mismatch: have %s, want %s", i, out, tt.want)
+		}
+	}
+}
+
+func TestNISTFork(t *testing.T) {
+	// The first valid P-256 vector: hash, r, s, x and y
+	in := common.FromHex("" +
+		"d1b8ef21eb4182ee270638061063a3f3c16c114e33937f69fb232cc833965a94" +
+		"bf96b99aa49c705c910be33142017c642ff540c76349b9dab72f981fd9347f4f" +
+		"17c55095819089c2e03b9cd415abdf12444e323075d98f31920b9e0f57ec871c" +
+		"e424dc61d4bb3cb7ef4344a7f8957a0c5134e16f7a67c074f82e6e12f49abf3c" +
+		"970eed7aa2bc48651545949de1dddaf0127e5965ac85d1243d6f60e7dfaee927")
+
+	var sender account
+	for i, tt := range []struct {
+		fork, number *big.Int
+		active       bool
+	}{
+		{nil, big.NewInt(100), false},
+		{big.NewInt(10), big.NewInt(9), false},
+		{big.NewInt(10), big.NewInt(10), true},
+		{big.NewInt(0), big.NewInt(100), true},
+	} {
+		env := &nistEnv{NewEnv(true, false), tt.fork, tt.number}
+		evm := New(env, Config{})
+		for _, addr := range []common.Address{P256VerifyAddress, P384VerifyAddress, Sha384Address} {
+			if active := Precompiled[string(addr.Bytes())].Active(env); active != tt.active {
+				t.Errorf("test %d: %x active mismatch: have %v, want %v", i, addr, active, tt.active)
+			}
+		}
+		contract := NewContract(sender, sender, new(big.Int), big.NewInt(100000), new(big.Int))
+		contract.CodeAddr = &P256VerifyAddress
+		out, err := evm.Run(contract, in)
+		if err != nil {
+			t.Fatalf("test %d: run failed: %v", i, err)
+		}
+		// Before the fork the address is an account without code
+		want := []byte(nil)
+		if tt.active {
+			want = common.LeftPadBytes([]byte{1}, 32)
+		}
+		if !bytes.Equal(out, want) {
+			t.Errorf("test %d: output mismatch: have %x, want %x", i, out, want)
+		}
+	}
+	// Existing precompiled contracts stay active regardless of the fork
+	if !Precompiled[string(common.LeftPadBytes([]byte{1}, 20))].Active(&nistEnv{NewEnv(true, false), nil, common.Big0}) {
+		t.Error("ecrecover not active without the fork")
+	}
+}
@@ -100,6 +100,9 @@ func (self *Env) SetDepth(i int) { self.depth = i }
 func (self *Env) CanTransfer(from common.Address, balance *big.Int) bool {
 	return self.state.GetBalance(from).Cmp(balance) >= 0
 }
+func (self *Env) CanCreate(creator common.Address) bool {
+	return true
+}
 func (self *Env) SnapshotDatabase() int {
 	return self.state.Snapshot()
 }
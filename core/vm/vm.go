@@ -70,7 +70,7 @@ func (evm *EVM) Run(contract *Contract, input []byte) (ret []byte, err error) {
 	defer evm.env.SetDepth(evm.env.Depth() - 1)
 
 	if contract.CodeAddr != nil {
-		if p := Precompiled[contract.CodeAddr.Str()]; p != nil {
+		if p := Precompiled[contract.CodeAddr.Str()]; p != nil && p.Active(evm.env) {
 			return evm.RunPrecompiled(p, input, contract)
 		}
 	}
@@ -433,7 +433,10 @@ func calculateGasAndSize(gasTable params.GasTable, env Environment, contract *Co
 func (evm *EVM) RunPrecompiled(p *PrecompiledAccount, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.Gas(len(input))
 	if contract.UseGas(gas) {
-		ret = p.Call(input)
+		if p.systemFn != nil {
+			return p.systemFn(evm.env, contract, input)
+		}
+		ret = p.Call(evm.env, input)
 
 		return ret, nil
 	} else {
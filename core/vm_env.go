@@ -95,6 +95,10 @@ func (self *VMEnv) CanTransfer(from common.Address, balance *big.Int) bool {
 	return self.state.GetBalance(from).Cmp(balance) >= 0
 }
 
+func (self *VMEnv) CanCreate(creator common.Address) bool {
+	return CanDeploy(self.state, creator)
+}
+
 func (self *VMEnv) SnapshotDatabase() int {
 	return self.state.Snapshot()
 }
@@ -105,6 +109,10 @@ func (self *VMEnv) RevertToSnapshot(snapshot int) {
 
 func (self *VMEnv) Transfer(from, to vm.Account, amount *big.Int) {
 	Transfer(from, to, amount)
+	// Transfers below the transaction itself are made by contracts
+	if self.depth > 0 && amount.Sign() > 0 {
+		self.state.AddTransfer(from.Address(), to.Address(), amount)
+	}
 }
 
 func (self *VMEnv) Call(me vm.ContractRef, addr common.Address, data []byte, gas, price, value *big.Int) ([]byte, error) {
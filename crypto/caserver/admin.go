@@ -0,0 +1,383 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	pb "github.com/ethereum/go-ethereum/crypto/caserver/ca/protos"
+	"golang.org/x/net/context"
+)
+
+const adminUsage = `usage: caserver admin <command> [flags]
+
+Commands:
+   init      issue an Admin certificate and export the CA root on the CA host (-name, -out)
+   intermediate
+             issue the certificate of an intermediate CA on the root CA host
+             (-name, -org, -pub, -out)
+   list      list certificates (-type, -status)
+   get       show a certificate (-name or -serial, -pem)
+   nodetype  reissue a certificate with another node type (-name, -type)
+   revoke    revoke a certificate (-name or -serial)
+   whitelist [add|remove]
+             show or edit the networks and node ids allowed to join (-ip, -node)
+
+All commands but init and intermediate talk to caserver.address and must be authorized with
+an Admin certificate (-cert) and its private key (-key). With TLS enabled the
+CA is verified against the root given by -root, caserver.tls.rootcert or
+caserver.tls.fingerprint.`
+
+var nodeTypeNames = map[ca.NodeType]string{
+	ca.Client:         "client",
+	ca.Peer:           "peer",
+	ca.Validator:      "validator",
+	ca.Admin:          "admin",
+	ca.IntermediateCA: "intermediate",
+}
+
+var statusNames = map[ca.CertificateStatus]string{
+	ca.Active:     "active",
+	ca.Revoked:    "revoked",
+	ca.Superseded: "superseded",
+}
+
+func parseNodeTypes(list string) ([]int32, error) {
+	var types []int32
+	for _, name := range splitList(list) {
+		found := false
+		for t, n := range nodeTypeNames {
+			if n == name {
+				types, found = append(types, int32(t)), true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown node type %q", name)
+		}
+	}
+	return types, nil
+}
+
+func parseStatuses(list string) ([]pb.CertificateStatus, error) {
+	var statuses []pb.CertificateStatus
+	for _, name := range splitList(list) {
+		found := false
+		for st, n := range statusNames {
+			if n == name {
+				statuses, found = append(statuses, pb.CertificateStatus(st)), true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown certificate status %q", name)
+		}
+	}
+	return statuses, nil
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// adminCredentials holds the certificate and key used to sign admin requests.
+type adminCredentials struct {
+	cert []byte
+	priv *ecdsa.PrivateKey
+}
+
+func loadAdminCredentials(certFile, keyFile string) (*adminCredentials, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("admin certificate (-cert) and private key (-key) are required")
+	}
+	cert, err := ca.LoadCertificate(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load admin certificate: %v", err)
+	}
+	priv, err := ca.LoadPrivateKey(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load admin private key: %v", err)
+	}
+	// Authenticate with the admin certificate on mutual TLS connections
+	ca.SetClientCertificate(priv, cert)
+
+	cooked := pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: cert.Raw,
+		})
+	return &adminCredentials{cert: cooked, priv: priv}, nil
+}
+
+func runAdmin(args []string) error {
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+	cmd, args := args[0], args[1:]
+	var action string
+	if cmd == "whitelist" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("caserver admin "+cmd, flag.ContinueOnError)
+	var (
+		certFile = fs.String("cert", "", "admin certificate file")
+		keyFile  = fs.String("key", "", "admin private key file")
+		rootFile = fs.String("root", "", "pinned CA root certificate, overrides caserver.tls.rootcert")
+		name     = fs.String("name", "", "certificate name")
+		serial   = fs.String("serial", "", "certificate serial number")
+		types    = fs.String("type", "", "comma separated node types (client, peer, validator, admin, intermediate)")
+		statuses = fs.String("status", "", "comma separated statuses (active, revoked, superseded)")
+		out      = fs.String("out", ".", "output directory of init and intermediate")
+		org      = fs.String("org", "", "organization of the intermediate CA")
+		pubFile  = fs.String("pub", "", "public key file of the intermediate CA")
+		showPEM  = fs.Bool("pem", false, "print the PEM encoded certificate")
+		ips      = fs.String("ip", "", "comma separated networks in CIDR notation or IP addresses")
+		nodes    = fs.String("node", "", "comma separated hex encoded node ids")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if cmd == "init" {
+		return adminInit(*name, *out)
+	}
+	if cmd == "intermediate" {
+		return adminIntermediate(*name, *org, *pubFile, *out)
+	}
+	if *rootFile != "" {
+		conf.CAServer.TLS.RootCert = *rootFile
+		if err := ca.Init(conf); err != nil {
+			return err
+		}
+	}
+
+	creds, err := loadAdminCredentials(*certFile, *keyFile)
+	if err != nil {
+		return err
+	}
+	conn, client, err := ca.GetCAAdminClient()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	switch cmd {
+	case "list":
+		req := &pb.ListCertificatesRequest{Auth: &pb.AdminAuth{}}
+		if req.NodeTypes, err = parseNodeTypes(*types); err != nil {
+			return err
+		}
+		if req.Statuses, err = parseStatuses(*statuses); err != nil {
+			return err
+		}
+		if err := ca.SignAdminRequest(req, req.Auth, creds.cert, creds.priv); err != nil {
+			return err
+		}
+		reply, err := client.ListCertificates(context.Background(), req)
+		if err != nil {
+			return err
+		}
+		printCertificates(reply.Certificates)
+
+	case "get":
+		req := &pb.GetCertificateRequest{Auth: &pb.AdminAuth{}, Name: *name, Serial: *serial}
+		if err := ca.SignAdminRequest(req, req.Auth, creds.cert, creds.priv); err != nil {
+			return err
+		}
+		info, err := client.GetCertificate(context.Background(), req)
+		if err != nil {
+			return err
+		}
+		printCertificates([]*pb.CertificateInfo{info})
+		if *showPEM {
+			fmt.Printf("\n%s", info.Cert)
+		}
+
+	case "nodetype":
+		t, err := parseNodeTypes(*types)
+		if err != nil {
+			return err
+		}
+		if len(t) != 1 || *name == "" {
+			return fmt.Errorf("nodetype requires -name and exactly one -type")
+		}
+		req := &pb.ChangeNodeTypeRequest{Auth: &pb.AdminAuth{}, Name: *name, NodeType: t[0]}
+		if err := ca.SignAdminRequest(req, req.Auth, creds.cert, creds.priv); err != nil {
+			return err
+		}
+		info, err := client.ChangeNodeType(context.Background(), req)
+		if err != nil {
+			return err
+		}
+		printCertificates([]*pb.CertificateInfo{info})
+
+	case "revoke":
+		if *name == "" && *serial == "" {
+			return fmt.Errorf("revoke requires -name or -serial")
+		}
+		req := &pb.RevokeRequest{Auth: &pb.AdminAuth{}, Name: *name, Serial: *serial}
+		if err := ca.SignAdminRequest(req, req.Auth, creds.cert, creds.priv); err != nil {
+			return err
+		}
+		info, err := client.Revoke(context.Background(), req)
+		if err != nil {
+			return err
+		}
+		printCertificates([]*pb.CertificateInfo{info})
+
+	case "whitelist":
+		var list *pb.IPList
+		switch action {
+		case "":
+			req := &pb.ListWhitelistRequest{Auth: &pb.AdminAuth{}}
+			if err := ca.SignAdminRequest(req, req.Auth, creds.cert, creds.priv); err != nil {
+				return err
+			}
+			list, err = client.ListWhitelist(context.Background(), req)
+		case "add", "remove":
+			req := &pb.WhitelistRequest{Auth: &pb.AdminAuth{}, Ip: splitList(*ips), NodeIds: splitList(*nodes)}
+			if len(req.Ip) == 0 && len(req.NodeIds) == 0 {
+				return fmt.Errorf("whitelist %s requires -ip or -node", action)
+			}
+			if err := ca.SignAdminRequest(req, req.Auth, creds.cert, creds.priv); err != nil {
+				return err
+			}
+			if action == "add" {
+				list, err = client.AddWhitelist(context.Background(), req)
+			} else {
+				list, err = client.RemoveWhitelist(context.Background(), req)
+			}
+		default:
+			return fmt.Errorf("unknown whitelist action %q", action)
+		}
+		if err != nil {
+			return err
+		}
+		printWhitelist(list)
+
+	default:
+		return fmt.Errorf("unknown admin command %q\n\n%s", cmd, adminUsage)
+	}
+	return nil
+}
+
+// adminInit issues an Admin certificate straight from the CA database. It
+// must run on the CA host and bootstraps the first operator credentials.
+func adminInit(name, out string) error {
+	if name == "" {
+		return fmt.Errorf("init requires -name")
+	}
+	authority := ca.NewCA("Blockchain", ca.InitializeCommonTables)
+	if authority == nil {
+		return fmt.Errorf("could not open the CA")
+	}
+	defer authority.Stop()
+
+	priv := ca.CreateCAKeyPair(name, out)
+	raw, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		return err
+	}
+	cooked := pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "ECDSA PUBLIC KEY",
+			Bytes: raw,
+		})
+	cert, err := authority.IssueCertificate(cooked, name, ca.Admin)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(out, name+".cert")
+	if err := ioutil.WriteFile(file, cert, 0644); err != nil {
+		return err
+	}
+	root := filepath.Join(out, ca.CARootFile)
+	if err := ioutil.WriteFile(root, authority.GetRootCertificate(), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Admin certificate written to %s, private key to %s, CA root to %s\n", file, filepath.Join(out, name+".priv"), root)
+	return nil
+}
+
+// adminIntermediate issues the certificate of an intermediate CA from the
+// root CA database. It must run on the root CA host; the certificate and the
+// chain up to the root are written for installation on the intermediate CA.
+func adminIntermediate(name, org, pubFile, out string) error {
+	if name == "" || org == "" || pubFile == "" {
+		return fmt.Errorf("intermediate requires -name, -org and -pub")
+	}
+	pub, err := ioutil.ReadFile(pubFile)
+	if err != nil {
+		return fmt.Errorf("could not read the public key: %v", err)
+	}
+	authority := ca.NewCA("Blockchain", ca.InitializeCommonTables)
+	if authority == nil {
+		return fmt.Errorf("could not open the CA")
+	}
+	defer authority.Stop()
+
+	cert, err := authority.IssueIntermediateCertificate(pub, name, org)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(out, name+".cert")
+	if err := ioutil.WriteFile(file, cert, 0644); err != nil {
+		return err
+	}
+	chain := filepath.Join(out, ca.ChainFile)
+	if err := ioutil.WriteFile(chain, bytes.Join(authority.GetCertificateChain(), nil), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Intermediate CA certificate written to %s, chain to %s\n", file, chain)
+	fmt.Printf("Install them as Blockchain.cert and %s in the CA directory of the intermediate CA\n", ca.ChainFile)
+	return nil
+}
+
+func printCertificates(certs []*pb.CertificateInfo) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SERIAL\tNAME\tTYPE\tPEER ID\tORGANIZATION\tSTATUS\tNOT AFTER")
+	for _, c := range certs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", c.Serial, c.Name, nodeTypeNames[ca.NodeType(c.NodeType)],
+			c.PeerId, c.Organization, statusNames[ca.CertificateStatus(c.Status)], time.Unix(c.NotAfter, 0).UTC().Format(time.RFC3339))
+	}
+	w.Flush()
+}
+
+func printWhitelist(list *pb.IPList) {
+	if len(list.Ip) == 0 && len(list.NodeIds) == 0 {
+		fmt.Println("Whitelist is empty, all enrolled nodes may connect")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tVALUE")
+	for _, ip := range list.Ip {
+		fmt.Fprintf(w, "network\t%s\n", ip)
+	}
+	for _, id := range list.NodeIds {
+		fmt.Fprintf(w, "node\t%s\n", id)
+	}
+	w.Flush()
+}
@@ -0,0 +1,356 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+	pb "github.com/ethereum/go-ethereum/crypto/caserver/ca/protos"
+)
+
+const (
+	// adminAuthWindow is the maximum clock difference accepted between the
+	// timestamp of a signed admin or renewal request and the CA.
+	adminAuthWindow = 5 * time.Minute
+
+	// nonceLength is the minimum length of the nonce of a signed request.
+	nonceLength = 16
+
+	// crlValidity is the time after which nodes should fetch a fresh CRL.
+	crlValidity = time.Hour
+)
+
+const certificateRecordColumns = "id, name, nodetype, peerid, status, notbefore, notafter, organization, revoked, cert, pubkey"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCertificateRecord(row rowScanner) (*CertificateRecord, error) {
+	var (
+		rec                          CertificateRecord
+		notbefore, notafter, revoked int64
+	)
+	if err := row.Scan(&rec.Serial, &rec.Name, &rec.NodeType, &rec.PeerId, &rec.Status, &notbefore, &notafter, &rec.Organization, &revoked, &rec.Raw, &rec.pubkey); err != nil {
+		return nil, err
+	}
+	rec.NotBefore = time.Unix(notbefore, 0)
+	rec.NotAfter = time.Unix(notafter, 0)
+	if revoked != 0 {
+		rec.RevokedAt = time.Unix(revoked, 0)
+	}
+	return &rec, nil
+}
+
+func (ca *CA) queryCertificateRecord(query string, args ...interface{}) (*CertificateRecord, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	rec, err := scanCertificateRecord(ca.db.QueryRow("SELECT "+certificateRecordColumns+" FROM Certificates "+query, args...))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("certificate not found")
+	}
+	return rec, err
+}
+
+// readCertificateByRaw looks up the database entry of a certificate.
+func (ca *CA) readCertificateByRaw(raw []byte) (*CertificateRecord, error) {
+	return ca.queryCertificateRecord("WHERE cert=? ORDER BY row DESC LIMIT 1", raw)
+}
+
+// readActiveCertificate returns the current certificate of a node.
+func (ca *CA) readActiveCertificate(name string) (*CertificateRecord, error) {
+	return ca.queryCertificateRecord("WHERE name=? AND status=? ORDER BY row DESC LIMIT 1", name, Active)
+}
+
+// ListCertificates returns the certificates matching any of the given node
+// types and any of the given statuses. Empty filters match everything.
+func (ca *CA) ListCertificates(nodetypes []NodeType, statuses []CertificateStatus) ([]*CertificateRecord, error) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if len(nodetypes) > 0 {
+		conds = append(conds, "nodetype IN (?"+strings.Repeat(", ?", len(nodetypes)-1)+")")
+		for _, t := range nodetypes {
+			args = append(args, t)
+		}
+	}
+	if len(statuses) > 0 {
+		conds = append(conds, "status IN (?"+strings.Repeat(", ?", len(statuses)-1)+")")
+		for _, s := range statuses {
+			args = append(args, s)
+		}
+	}
+	query := "SELECT " + certificateRecordColumns + " FROM Certificates"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY row"
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	rows, err := ca.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recs []*CertificateRecord
+	for rows.Next() {
+		rec, err := scanCertificateRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+// GetCertificate returns the certificate with the given serial number or, if
+// serial is empty, the most recent certificate issued under name.
+func (ca *CA) GetCertificate(name, serial string) (*CertificateRecord, error) {
+	switch {
+	case serial != "":
+		return ca.queryCertificateRecord("WHERE id=? ORDER BY row DESC LIMIT 1", serial)
+	case name != "":
+		return ca.queryCertificateRecord("WHERE name=? ORDER BY row DESC LIMIT 1", name)
+	}
+	return nil, fmt.Errorf("certificate name or serial required")
+}
+
+// ChangeNodeType reissues the active certificate of a node with a new node
+// type. The peer id and public key are kept, the old certificate is marked
+// as superseded. Nothing changes if the node already has the type.
+func (ca *CA) ChangeNodeType(name string, nodetype NodeType) (*CertificateRecord, error) {
+	if nodetype < Client || nodetype > Admin {
+		return nil, fmt.Errorf("invalid node type %d", nodetype)
+	}
+	rec, err := ca.readActiveCertificate(name)
+	if err != nil {
+		return nil, fmt.Errorf("no active certificate for %s: %v", name, err)
+	}
+	if rec.NodeType == nodetype {
+		return rec, nil
+	}
+	pub, err := x509.ParsePKIXPublicKey(rec.pubkey)
+	if err != nil {
+		return nil, err
+	}
+	pubkey, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key of %s is not an ECDSA key", name)
+	}
+	peerid, err := ca.allocatePeerId(name)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ca.createPeerCertificate(name, pubkey, nodetype, peerid)
+	if err != nil {
+		return nil, err
+	}
+
+	caLogger.Infof("Changed node type of %s from %d to %d", name, rec.NodeType, nodetype)
+	return ca.readCertificateByRaw(raw)
+}
+
+// RevokeCertificate revokes the certificate with the given serial number or,
+// if serial is empty, the active certificate of name.
+func (ca *CA) RevokeCertificate(name, serial string) (*CertificateRecord, error) {
+	var (
+		rec *CertificateRecord
+		err error
+	)
+	if serial != "" {
+		rec, err = ca.GetCertificate("", serial)
+	} else {
+		rec, err = ca.readActiveCertificate(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	mutex.Lock()
+	_, err = ca.db.Exec("UPDATE Certificates SET status=?, revoked=? WHERE cert=?", Revoked, now.Unix(), rec.Raw)
+	mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	caLogger.Infof("Revoked certificate %s of %s", rec.Serial, rec.Name)
+
+	rec.Status, rec.RevokedAt = Revoked, time.Unix(now.Unix(), 0)
+	return rec, nil
+}
+
+// RevocationList returns a DER encoded CRL of the unexpired certificates
+// revoked by this CA, signed with its key. Nodes refuse peers presenting a
+// certificate it lists.
+func (ca *CA) RevocationList() ([]byte, error) {
+	recs, err := ca.ListCertificates(nil, []CertificateStatus{Revoked})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	var revoked []pkix.RevokedCertificate
+	for _, rec := range recs {
+		if rec.NotAfter.Before(now) {
+			continue
+		}
+		serial, ok := new(big.Int).SetString(rec.Serial, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid serial number %q of %s", rec.Serial, rec.Name)
+		}
+		at := rec.RevokedAt
+		if at.IsZero() {
+			at = now
+		}
+		revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: serial, RevocationTime: at.UTC()})
+	}
+	return ca.cert.CreateCRL(rand.Reader, ca.priv, revoked, now.UTC(), now.Add(crlValidity).UTC())
+}
+
+// useNonce records the nonce of a signed request valid until expires. It
+// fails if the nonce was used before, so that a captured request cannot be
+// replayed.
+func (ca *CA) useNonce(nonce []byte, expires time.Time) error {
+	if len(nonce) < nonceLength {
+		return fmt.Errorf("request nonce missing or shorter than %d bytes", nonceLength)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, err := ca.db.Exec("DELETE FROM Nonces WHERE expires<?", time.Now().Unix()); err != nil {
+		return err
+	}
+	var count int
+	if err := ca.db.QueryRow("SELECT COUNT(*) FROM Nonces WHERE nonce=?", nonce).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("request replayed")
+	}
+	_, err := ca.db.Exec("INSERT INTO Nonces (nonce, expires) VALUES (?, ?)", nonce, expires.Unix())
+	return err
+}
+
+// newNonce returns a fresh random request nonce.
+func newNonce() ([]byte, error) {
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+// AuthorizeAdmin checks that req is signed by the holder of an active,
+// unexpired Admin certificate issued by this CA.
+func (ca *CA) AuthorizeAdmin(req proto.Message, auth *pb.AdminAuth) error {
+	if auth == nil {
+		return fmt.Errorf("admin credentials missing")
+	}
+	block, _ := pem.Decode(auth.Cert)
+	if block == nil {
+		return fmt.Errorf("admin certificate format error")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("admin certificate format error: %v", err)
+	}
+	if err := cert.CheckSignatureFrom(ca.cert); err != nil {
+		return fmt.Errorf("admin certificate not issued by this CA: %v", err)
+	}
+	if err := CheckCertificateSecurity(cert); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := VerifyValidity(cert, now); err != nil {
+		return err
+	}
+	if nodetype, _ := GetNodeInfo(cert); nodetype != Admin {
+		return fmt.Errorf("certificate %q is not an admin certificate", cert.Subject.CommonName)
+	}
+	if rec, err := ca.readCertificateByRaw(cert.Raw); err != nil || rec.Status != Active {
+		return fmt.Errorf("admin certificate %q is not active", cert.Subject.CommonName)
+	}
+	if ts := time.Unix(auth.Timestamp, 0); ts.Before(now.Add(-adminAuthWindow)) || ts.After(now.Add(adminAuthWindow)) {
+		return fmt.Errorf("admin request timestamp %v out of range", ts)
+	}
+	pubkey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("admin public key format error")
+	}
+
+	sign := auth.Sign
+	auth.Sign = nil
+	msg, err := proto.Marshal(req)
+	auth.Sign = sign
+	if err != nil {
+		return err
+	}
+	valid, err := primitives.ECDSAVerify(pubkey, msg, sign)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("admin request signature is invalid")
+	}
+	return ca.useNonce(auth.Nonce, time.Unix(auth.Timestamp, 0).Add(adminAuthWindow))
+}
+
+// SignAdminRequest fills auth, which must be part of req, with the admin
+// certificate and a signature over the request made with priv.
+func SignAdminRequest(req proto.Message, auth *pb.AdminAuth, cert []byte, priv *ecdsa.PrivateKey) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	auth.Cert = cert
+	auth.Timestamp = time.Now().Unix()
+	auth.Nonce = nonce
+	auth.Sign = nil
+
+	msg, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	auth.Sign, err = primitives.ECDSASign(priv, msg)
+	return err
+}
+
+// ToCertificateInfo converts a database record to its wire representation.
+func (rec *CertificateRecord) ToCertificateInfo() *pb.CertificateInfo {
+	return &pb.CertificateInfo{
+		Serial:       rec.Serial,
+		Name:         rec.Name,
+		NodeType:     int32(rec.NodeType),
+		PeerId:       rec.PeerId,
+		Status:       pb.CertificateStatus(rec.Status),
+		NotBefore:    rec.NotBefore.Unix(),
+		NotAfter:     rec.NotAfter.Unix(),
+		Organization: rec.Organization,
+		Cert: pem.EncodeToMemory(
+			&pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: rec.Raw,
+			}),
+	}
+}
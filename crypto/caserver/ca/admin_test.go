@@ -0,0 +1,208 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	pb "github.com/ethereum/go-ethereum/crypto/caserver/ca/protos"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+)
+
+func newTestCA(t *testing.T) (*CA, func()) {
+	if err := Init(nil); err != nil {
+		t.Fatalf("failed to init crypto: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "dchain-ca-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	ca := newCA(dir, "Blockchain", InitializeCommonTables)
+	return ca, func() {
+		ca.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+// enroll creates a key pair and issues a certificate of the given type for it.
+func enroll(t *testing.T, ca *CA, name string, nodetype NodeType) (*ecdsa.PrivateKey, []byte) {
+	priv, err := primitives.NewECDSAKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	raw, _ := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	pub := pem.EncodeToMemory(&pem.Block{Type: "ECDSA PUBLIC KEY", Bytes: raw})
+
+	cert, err := ca.IssueCertificate(pub, name, nodetype)
+	if err != nil {
+		t.Fatalf("failed to issue certificate for %s: %v", name, err)
+	}
+	return priv, cert
+}
+
+// signRenewRequest signs a fresh renewal request for the PEM certificate cooked.
+func signRenewRequest(t *testing.T, priv *ecdsa.PrivateKey, cooked []byte) ([]byte, int64, []byte) {
+	nonce, err := newNonce()
+	if err != nil {
+		t.Fatalf("failed to create nonce: %v", err)
+	}
+	ts := time.Now().Unix()
+	sign, err := SignRenewRequest(priv, BuildCertificateFromBytes(cooked).Raw, ts, nonce)
+	if err != nil {
+		t.Fatalf("failed to sign renew request: %v", err)
+	}
+	return sign, ts, nonce
+}
+
+func TestCertificateLifecycle(t *testing.T) {
+	ca, cleanup := newTestCA(t)
+	defer cleanup()
+
+	priv, cooked := enroll(t, ca, "node1", Validator)
+	enroll(t, ca, "node2", Peer)
+
+	validators, err := ca.ListCertificates([]NodeType{Validator}, []CertificateStatus{Active})
+	if err != nil || len(validators) != 1 || validators[0].Name != "node1" {
+		t.Fatalf("validator listing mismatch: %v, %v", validators, err)
+	}
+
+	// Reissue node1 as a client, the old certificate gets superseded
+	rec, err := ca.ChangeNodeType("node1", Client)
+	if err != nil {
+		t.Fatalf("failed to change node type: %v", err)
+	}
+	if rec.NodeType != Client || rec.PeerId != validators[0].PeerId || rec.Status != Active {
+		t.Fatalf("reissued certificate mismatch: %+v", rec)
+	}
+	old, err := ca.GetCertificate("", validators[0].Serial)
+	if err != nil || old.Status != Superseded {
+		t.Fatalf("old certificate not superseded: %+v, %v", old, err)
+	}
+
+	// Renewing the superseded certificate picks up the new node type
+	sign, ts, nonce := signRenewRequest(t, priv, cooked)
+	renewed, err := ca.RenewCertificate(cooked, sign, "node1", ts, nonce)
+	if err != nil {
+		t.Fatalf("failed to renew certificate: %v", err)
+	}
+	if _, err := ca.RenewCertificate(cooked, sign, "node1", ts, nonce); err == nil {
+		t.Fatalf("replayed renewal accepted")
+	}
+	stale := time.Now().Add(-2 * adminAuthWindow).Unix()
+	sign, _ = SignRenewRequest(priv, BuildCertificateFromBytes(cooked).Raw, stale, nonce)
+	if _, err := ca.RenewCertificate(cooked, sign, "node1", stale, nonce); err == nil {
+		t.Fatalf("stale renewal accepted")
+	}
+	if nodetype, peerid := GetNodeInfo(BuildCertificateFromBytes(renewed)); nodetype != Client || peerid != rec.PeerId {
+		t.Fatalf("renewed certificate mismatch: type %d, peer id %d", nodetype, peerid)
+	}
+
+	// Revoked certificates can neither be renewed nor reissued
+	if _, err := ca.RevokeCertificate("node1", ""); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	sign, ts, nonce = signRenewRequest(t, priv, renewed)
+	if _, err := ca.RenewCertificate(renewed, sign, "node1", ts, nonce); err == nil {
+		t.Fatalf("revoked certificate renewed")
+	}
+	revoked, err := ca.ListCertificates(nil, []CertificateStatus{Revoked})
+	if err != nil || len(revoked) != 1 {
+		t.Fatalf("revoked listing mismatch: %v, %v", revoked, err)
+	}
+}
+
+func TestAuthorizeAdmin(t *testing.T) {
+	ca, cleanup := newTestCA(t)
+	defer cleanup()
+
+	adminKey, adminCert := enroll(t, ca, "ops", Admin)
+	nodeKey, nodeCert := enroll(t, ca, "node", Validator)
+
+	req := &pb.GetCertificateRequest{Auth: &pb.AdminAuth{}, Name: "node"}
+	if err := SignAdminRequest(req, req.Auth, adminCert, adminKey); err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	if err := ca.AuthorizeAdmin(req, req.Auth); err != nil {
+		t.Fatalf("admin request refused: %v", err)
+	}
+	// Replayed requests are refused, as are requests without nonce
+	if err := ca.AuthorizeAdmin(req, req.Auth); err == nil {
+		t.Fatalf("replayed request accepted")
+	}
+	if err := SignAdminRequest(req, req.Auth, adminCert, adminKey); err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	req.Auth.Nonce, req.Auth.Sign = nil, nil
+	msg, _ := proto.Marshal(req)
+	req.Auth.Sign, _ = primitives.ECDSASign(adminKey, msg)
+	if err := ca.AuthorizeAdmin(req, req.Auth); err == nil {
+		t.Fatalf("request without nonce accepted")
+	}
+	// Tampering with the request invalidates the signature
+	if err := SignAdminRequest(req, req.Auth, adminCert, adminKey); err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	req.Name = "ops"
+	if err := ca.AuthorizeAdmin(req, req.Auth); err == nil {
+		t.Fatalf("tampered request accepted")
+	}
+	// Non-admin certificates are refused
+	if err := SignAdminRequest(req, req.Auth, nodeCert, nodeKey); err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	if err := ca.AuthorizeAdmin(req, req.Auth); err == nil {
+		t.Fatalf("validator certificate accepted as admin")
+	}
+	// Revoked admins are refused
+	ca.RevokeCertificate("ops", "")
+	if err := SignAdminRequest(req, req.Auth, adminCert, adminKey); err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	if err := ca.AuthorizeAdmin(req, req.Auth); err == nil {
+		t.Fatalf("revoked admin accepted")
+	}
+}
+
+func TestRevocationList(t *testing.T) {
+	ca, cleanup := newTestCA(t)
+	defer cleanup()
+
+	enroll(t, ca, "node1", Validator)
+	_, cooked := enroll(t, ca, "node2", Validator)
+
+	rec, err := ca.RevokeCertificate("node2", "")
+	if err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	raw, err := ca.RevocationList()
+	if err != nil {
+		t.Fatalf("failed to create revocation list: %v", err)
+	}
+	crl, err := x509.ParseDERCRL(raw)
+	if err != nil {
+		t.Fatalf("failed to parse revocation list: %v", err)
+	}
+	if err := ca.cert.CheckCRLSignature(crl); err != nil {
+		t.Fatalf("revocation list signature invalid: %v", err)
+	}
+	// Only the revoked certificate is listed
+	revoked := crl.TBSCertList.RevokedCertificates
+	if len(revoked) != 1 || revoked[0].SerialNumber.Cmp(BuildCertificateFromBytes(cooked).SerialNumber) != 0 {
+		t.Fatalf("revoked certificates mismatch: %v", revoked)
+	}
+	if !revoked[0].RevocationTime.Equal(rec.RevokedAt) {
+		t.Errorf("revocation time mismatch: have %v, want %v", revoked[0].RevocationTime, rec.RevokedAt)
+	}
+}
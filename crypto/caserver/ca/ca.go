@@ -8,6 +8,7 @@
 package ca
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/x509"
@@ -25,24 +26,82 @@ import (
 	"math"
 	"encoding/binary"
 
+	"github.com/ethereum/go-ethereum/crypto/caserver/certs"
 	_ "github.com/mattn/go-sqlite3" // This blank import is required to load sqlite3 driver
 	"github.com/op/go-logging"
-	"github.com/spf13/viper"
 	"os/user"
 )
 
 type NodeType int32
 
 const (
-	Client NodeType = 0
+	Client NodeType = certs.Client
 
-	Peer NodeType = 1
+	Peer NodeType = certs.Peer
 
-	Validator NodeType = 2
+	Validator NodeType = certs.Validator
 
-	Admin NodeType = 3
+	Admin NodeType = certs.Admin
+
+	// IntermediateCA certificates are issued by the root to the CAs of
+	// member organizations. Their peer id selects the range of peer ids the
+	// intermediate CA hands out.
+	IntermediateCA NodeType = certs.IntermediateCA
+)
+
+const (
+	// ChainFile holds the certificates of the CAs above an intermediate CA,
+	// from its issuer up to the root, in the CA directory.
+	ChainFile = "chain.cert"
+
+	// peerIdRangeBits is the size of the peer id range of every CA. The root
+	// CA hands out ids below 1<<peerIdRangeBits, the intermediate CA with
+	// index i the ones from i<<peerIdRangeBits on, which keeps peer ids
+	// unique across organizations.
+	peerIdRangeBits = 20
+
+	// maxIntermediates is the number of intermediate CAs a root can issue.
+	maxIntermediates = 1<<(32-peerIdRangeBits) - 1
+
+	// MaxTCertBatch is the number of transaction certificates issued per
+	// request.
+	MaxTCertBatch = 64
+
+	// defaultTCertValidity is the lifetime of transaction certificates if
+	// caserver.tcert.validity is not set.
+	defaultTCertValidity = 24 * time.Hour
+)
+
+// CertificateStatus is the lifecycle state of an issued certificate.
+type CertificateStatus int32
+
+const (
+	// Active certificates are the current certificate of a node.
+	Active CertificateStatus = 0
+
+	// Revoked certificates have been withdrawn by an administrator.
+	Revoked CertificateStatus = 1
+
+	// Superseded certificates have been replaced by a renewal or reissue.
+	Superseded CertificateStatus = 2
 )
 
+// CertificateRecord is a certificate stored in the CA database.
+type CertificateRecord struct {
+	Serial       string
+	Name         string
+	NodeType     NodeType
+	PeerId       uint32
+	Status       CertificateStatus
+	NotBefore    time.Time
+	NotAfter     time.Time
+	Organization string
+	RevokedAt    time.Time // zero unless revoked
+	Raw          []byte
+
+	pubkey []byte
+}
+
 // Hash is the common interface implemented by all hash functions.
 type Hash interface {
 	// Write (via the embedded io.Writer interface) adds more data to the running hash.
@@ -74,11 +133,15 @@ type CA struct {
 
 	path string
 
-	peerid uint32
-	peerCount uint32
 	priv *ecdsa.PrivateKey
 	cert *x509.Certificate
 	raw  []byte
+
+	chain      []*x509.Certificate // CAs above an intermediate CA up to the root
+	peerIdBase uint32              // first peer id of the range of this CA
+
+	watchLock sync.Mutex             // protects watchers
+	watchers  map[chan struct{}]bool // whitelist subscribers
 }
 
 // CertificateSpec defines the parameter used to create a new certificate.
@@ -94,11 +157,7 @@ type CertificateSpec struct {
 }
 
 var (
-	mutex          = &sync.RWMutex{}
-	caOrganization string
-	caCountry      string
-	rootPath       string
-	caDir          string
+	mutex = &sync.RWMutex{}
 )
 
 // NewCertificateSpec creates a new certificate spec
@@ -143,12 +202,12 @@ func NewDefaultCertificateSpecWithCommonName(id string, commonName string, pub i
 	return NewDefaultPeriodCertificateSpecWithCommonName(id, commonName, serialNumber, pub, usage, opt...)
 }
 
-// CacheConfiguration caches the viper configuration
-func CacheConfiguration() {
-	caOrganization = viper.GetString("pki.ca.subject.organization")
-	caCountry = viper.GetString("pki.ca.subject.country")
-	rootPath = viper.GetString("caserver.rootpath")
-	caDir = viper.GetString("caserver.cadir")
+// tcertValidity returns the lifetime of transaction certificates.
+func tcertValidity() time.Duration {
+	if validity := currentSettings().CAServer.TCertValidity; validity > 0 {
+		return validity
+	}
+	return defaultTCertValidity
 }
 
 // GetID returns the spec's ID field/value
@@ -196,13 +255,13 @@ func (spec *CertificateSpec) GetNotAfter() *time.Time {
 // GetOrganization returns the spec's Organization field/value
 //
 func (spec *CertificateSpec) GetOrganization() string {
-	return caOrganization
+	return currentSettings().PKI.Organization
 }
 
 // GetCountry returns the spec's Country field/value
 //
 func (spec *CertificateSpec) GetCountry() string {
-	return caCountry
+	return currentSettings().PKI.Country
 }
 
 // GetSubjectKeyID returns the spec's subject KeyID
@@ -214,7 +273,7 @@ func (spec *CertificateSpec) GetSubjectKeyID() *[]byte {
 // GetSignatureAlgorithm returns the X509.SignatureAlgorithm field/value
 //
 func (spec *CertificateSpec) GetSignatureAlgorithm() x509.SignatureAlgorithm {
-	return x509.ECDSAWithSHA384
+	return signatureAlgorithm()
 }
 
 // GetExtensions returns the sepc's extensions
@@ -230,21 +289,192 @@ func InitializeCommonTables(db *sql.DB) error {
 	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS Certificates (row INTEGER PRIMARY KEY, id VARCHAR(64), name TEXT, cert BLOB, pubkey BLOB)"); err != nil {
 		return err
 	}
+	if err := migrateCertificatesTable(db); err != nil {
+		return err
+	}
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS PeerIds (peerid INTEGER PRIMARY KEY, name TEXT UNIQUE)"); err != nil {
+		return err
+	}
+	if err := migratePeerIds(db); err != nil {
+		return err
+	}
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS Intermediates (idx INTEGER PRIMARY KEY, name TEXT UNIQUE)"); err != nil {
+		return err
+	}
+	// Transaction certificates are kept apart from the node certificates;
+	// the name they were issued to is only known to the CA.
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS TCertificates (serial TEXT PRIMARY KEY, name TEXT, notafter INTEGER, cert BLOB)"); err != nil {
+		return err
+	}
+	// Nonces of signed requests are kept until their timestamp expires, so
+	// that every instance sharing the store refuses a replay.
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS Nonces (nonce BLOB PRIMARY KEY, expires INTEGER)"); err != nil {
+		return err
+	}
+	return initializeWhitelistTable(db)
+}
+
+// certificateColumns are the columns added to the Certificates table after
+// its initial layout, together with their definition.
+var certificateColumns = []struct{ name, def string }{
+	{"nodetype", "INTEGER DEFAULT 2"},
+	{"peerid", "INTEGER DEFAULT 0"},
+	{"status", "INTEGER DEFAULT 0"},
+	{"notbefore", "INTEGER DEFAULT 0"},
+	{"notafter", "INTEGER DEFAULT 0"},
+	{"organization", "TEXT DEFAULT ''"},
+	{"revoked", "INTEGER DEFAULT 0"},
+}
+
+// migrateCertificatesTable adds the missing columns to a Certificates table
+// created by an older version and fills them from the stored certificates.
+func migrateCertificatesTable(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(Certificates)")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid, notnull, pk int
+			name, ctype      string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	added := false
+	for _, col := range certificateColumns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE Certificates ADD COLUMN " + col.name + " " + col.def); err != nil {
+			return err
+		}
+		added = true
+	}
+	if !added {
+		return nil
+	}
+	caLogger.Info("Migrating certificate database.")
+
+	rows, err = db.Query("SELECT row, cert FROM Certificates")
+	if err != nil {
+		return err
+	}
+	type update struct {
+		row  int64
+		cert *x509.Certificate
+	}
+	var updates []update
+	for rows.Next() {
+		var (
+			row int64
+			raw []byte
+		)
+		if err := rows.Scan(&row, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		if cert, err := x509.ParseCertificate(raw); err == nil {
+			updates = append(updates, update{row, cert})
+		}
+	}
+	rows.Close()
+
+	for _, u := range updates {
+		nodetype, peerid := GetNodeInfo(u.cert)
+		if _, err := db.Exec("UPDATE Certificates SET nodetype=?, peerid=?, notbefore=?, notafter=?, organization=? WHERE row=?",
+			nodetype, peerid, u.cert.NotBefore.Unix(), u.cert.NotAfter.Unix(), GetOrganization(u.cert), u.row); err != nil {
+			return err
+		}
+	}
+	// Only the most recent certificate of every name stays active
+	_, err = db.Exec("UPDATE Certificates SET status=? WHERE row NOT IN (SELECT MAX(row) FROM Certificates GROUP BY name)", Superseded)
+	return err
+}
+
+// migratePeerIds fills an empty PeerIds table from the certificates issued by
+// an older version, which kept the peer id counter in memory only. Names are
+// visited in the order they first enrolled and keep the peer id of their most
+// recent certificate; a name whose id was already handed out to an earlier
+// name is assigned a fresh one, which it receives with its next certificate.
+func migratePeerIds(db *sql.DB) error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM PeerIds").Scan(&count); err != nil || count > 0 {
+		return err
+	}
+	rows, err := db.Query("SELECT name, (SELECT peerid FROM Certificates c WHERE c.name=Certificates.name ORDER BY row DESC LIMIT 1) FROM Certificates GROUP BY name ORDER BY MIN(row)")
+	if err != nil {
+		return err
+	}
+	type entry struct {
+		name   string
+		peerid uint32
+	}
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.name, &e.peerid); err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if len(entries) == 0 {
+		return nil
+	}
+	caLogger.Info("Migrating peer id allocation.")
+
+	used := make(map[uint32]bool)
+	var collisions []string
+	for _, e := range entries {
+		if e.peerid == 0 || used[e.peerid] {
+			collisions = append(collisions, e.name)
+			continue
+		}
+		used[e.peerid] = true
+		if _, err := db.Exec("INSERT INTO PeerIds (peerid, name) VALUES (?, ?)", e.peerid, e.name); err != nil {
+			return err
+		}
+	}
+	for _, name := range collisions {
+		if _, err := db.Exec("INSERT INTO PeerIds (peerid, name) SELECT COALESCE(MAX(peerid), 0) + 1, ? FROM PeerIds", name); err != nil {
+			return err
+		}
+		caLogger.Warningf("Peer id of %s collides with another node, a new one is assigned on its next certificate.", name)
+	}
 	return nil
 }
 
-// NewCA sets up a new CA.
+// NewCA sets up a new CA. Its database, keys and certificates are kept in
+// caserver.datadir, or under caserver.rootpath in the home directory. Several
+// CA instances pointed at the same directory, e.g. on a shared volume, serve
+// the same CA.
 func NewCA(name string, initTables TableInitializer) *CA {
-	ca := new(CA)
-
+	conf := currentSettings().CAServer
+	if conf.DataDir != "" {
+		return newCA(conf.DataDir, name, initTables)
+	}
 	user, err := user.Current()
 	if err != nil {
 		return nil
 	}
 
-	ca.peerid = 1
-	ca.peerCount = 0
-	ca.path = filepath.Join(user.HomeDir, rootPath, caDir)
+	return newCA(filepath.Join(user.HomeDir, conf.RootPath, conf.CADir), name, initTables)
+}
+
+// newCA sets up a new CA keeping its database, keys and certificates in path.
+func newCA(path string, name string, initTables TableInitializer) *CA {
+	ca := new(CA)
+
+	ca.path = path
 
 	caLogger.Info(ca.path)
 
@@ -256,8 +486,10 @@ func NewCA(name string, initTables TableInitializer) *CA {
 		}
 	}
 
-	// open or create certificate database
-	db, err := sql.Open("sqlite3", ca.path+"/"+name+".db")
+	// open or create certificate database; other CA instances may share it,
+	// transactions take the write lock up front so they wait for each other
+	// instead of failing
+	db, err := sql.Open("sqlite3", ca.path+"/"+name+".db?_busy_timeout=10000&_txlock=immediate")
 	if err != nil {
 		caLogger.Panic(err)
 	}
@@ -276,17 +508,50 @@ func NewCA(name string, initTables TableInitializer) *CA {
 	if err != nil {
 		priv = ca.createCAKeyPair(name)
 	}
+	if err := CheckPublicKey(&priv.PublicKey); err != nil {
+		caLogger.Panicf("CA key %s does not match the configured security level: %v", name, err)
+	}
 	ca.priv = priv
 
 	// read CA certificate, or create a self-signed CA certificate
 	raw, err := ca.readCACertificate(name)
 	if err != nil {
-		raw = ca.createCACertificate(name, &ca.priv.PublicKey, Admin)
+		if currentSettings().CAServer.Intermediate {
+			caLogger.Panicf("Intermediate CA certificate missing: have the root CA issue one for %s/%s.pub with 'caserver admin intermediate' and install it as %s/%s.cert together with %s/%s",
+				ca.path, name, ca.path, name, ca.path, ChainFile)
+		}
+		if raw, err = ca.createCACertificate(name, &ca.priv.PublicKey, Admin); err != nil {
+			caLogger.Panic(err)
+		}
 	}
 	cert, err := x509.ParseCertificate(raw)
 	if err != nil {
 		caLogger.Panic(err)
 	}
+	if err := CheckCertificateSecurity(cert); err != nil {
+		caLogger.Panicf("CA certificate does not match the configured security level: %v", err)
+	}
+	if !bytes.Equal(cert.RawSubjectPublicKeyInfo, mustMarshalPublicKey(&ca.priv.PublicKey)) {
+		caLogger.Panicf("CA certificate %s/%s.cert does not match the CA key", ca.path, name)
+	}
+
+	// an intermediate CA needs the certificates up to the root
+	if !isSelfSigned(cert) {
+		chain, err := readCertificateChain(filepath.Join(ca.path, ChainFile))
+		if err != nil {
+			caLogger.Panicf("Could not read the certificate chain of the intermediate CA: %v", err)
+		}
+		if err := VerifyChain(cert, chain[:len(chain)-1], chain[len(chain)-1]); err != nil {
+			caLogger.Panicf("Invalid intermediate CA certificate: %v", err)
+		}
+		_, index := GetNodeInfo(cert)
+		if index == 0 || index > maxIntermediates {
+			caLogger.Panicf("Invalid intermediate CA index %d", index)
+		}
+		ca.chain = chain
+		ca.peerIdBase = index << peerIdRangeBits
+		caLogger.Infof("Running as intermediate CA of %s under root %s", GetOrganization(cert), chain[len(chain)-1].Subject.CommonName)
+	}
 
 	ca.raw = raw
 	ca.cert = cert
@@ -294,8 +559,55 @@ func NewCA(name string, initTables TableInitializer) *CA {
 	return ca
 }
 
+// isSelfSigned reports whether cert is a root certificate.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+func mustMarshalPublicKey(pub *ecdsa.PublicKey) []byte {
+	raw, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		caLogger.Panic(err)
+	}
+	return raw
+}
+
+// readCertificateChain reads the certificates of the CAs above an
+// intermediate CA. The last one must be a self-signed root.
+func readCertificateChain(file string) ([]*x509.Certificate, error) {
+	cooked, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := ParseCertificates(cooked)
+	if err != nil {
+		return nil, err
+	}
+	if !isSelfSigned(chain[len(chain)-1]) {
+		return nil, fmt.Errorf("%s does not end with a root certificate", file)
+	}
+	return chain, nil
+}
+
+// IsIntermediate reports whether the CA is an intermediate CA issued by
+// another CA rather than a root.
+func (ca *CA) IsIntermediate() bool {
+	return len(ca.chain) > 0
+}
+
+// GetOrganization returns the organization of the CA, which all certificates
+// it issues are issued to.
+func (ca *CA) GetOrganization() string {
+	return GetOrganization(ca.cert)
+}
+
 func (ca *CA) IssueCertificate(in []byte, name string, nodetype NodeType) ([]byte, error) {
 	raw, err := ca.readCACertificate(name)
+	if err == nil {
+		if rec, err := ca.readCertificateByRaw(raw); err == nil && rec.Status == Revoked {
+			return nil, fmt.Errorf("Create Certificate failed, the certificate of %s has been revoked.", name)
+		}
+	}
 
 	if err != nil {
 		block, _ := pem.Decode(in)
@@ -309,10 +621,93 @@ func (ca *CA) IssueCertificate(in []byte, name string, nodetype NodeType) ([]byt
 			return nil, fmt.Errorf("Create Certificate failed for the public key format error.")
 		}
 
+		if err := CheckPublicKey(pub); err != nil {
+			return nil, fmt.Errorf("Create Certificate failed, %v", err)
+		}
+
 		pubkey := pub.(*ecdsa.PublicKey)
-		raw = ca.createCACertificate(name, (pubkey), nodetype)
+		if raw, err = ca.createCACertificate(name, (pubkey), nodetype); err != nil {
+			return nil, fmt.Errorf("Create Certificate failed, %v", err)
+		}
+	}
+
+	cooked := pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: raw,
+		})
+
+	return cooked, nil
+}
+
+// RenewCertificate reissues a certificate previously signed by this CA with a
+// fresh validity period. The public key and node type are carried over, the
+// peer id is the one assigned to the node's name. The request must be signed
+// by the private key belonging to the certificate, which proves the caller is
+// the enrolled node, over a recent timestamp and a nonce not used before.
+func (ca *CA) RenewCertificate(in []byte, sign []byte, name string, timestamp int64, nonce []byte) ([]byte, error) {
+	block, _ := pem.Decode(in)
+	if block == nil {
+		return nil, fmt.Errorf("Renew Certificate failed for the certificate format error.")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		caLogger.Debug(err)
+		return nil, fmt.Errorf("Renew Certificate failed for the certificate format error.")
+	}
+
+	if err := cert.CheckSignatureFrom(ca.cert); err != nil {
+		return nil, fmt.Errorf("Renew Certificate failed, certificate not issued by this CA: %v", err)
+	}
+
+	pubkey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("Renew Certificate failed for the public key format error.")
+	}
+
+	if err := CheckPublicKey(pubkey); err != nil {
+		return nil, fmt.Errorf("Renew Certificate failed, %v", err)
+	}
+
+	now := time.Now()
+	if ts := time.Unix(timestamp, 0); ts.Before(now.Add(-adminAuthWindow)) || ts.After(now.Add(adminAuthWindow)) {
+		return nil, fmt.Errorf("Renew Certificate failed, request timestamp %v out of range.", ts)
+	}
+	if err := VerifyRenewRequest(pubkey, cert.Raw, timestamp, nonce, sign); err != nil {
+		return nil, err
+	}
+	if err := ca.useNonce(nonce, time.Unix(timestamp, 0).Add(adminAuthWindow)); err != nil {
+		return nil, fmt.Errorf("Renew Certificate failed, %v", err)
 	}
 
+	nodetype, _ := GetNodeInfo(cert)
+	if rec, err := ca.readCertificateByRaw(cert.Raw); err == nil {
+		switch rec.Status {
+		case Revoked:
+			return nil, fmt.Errorf("Renew Certificate failed, certificate %s has been revoked.", rec.Serial)
+		case Superseded:
+			// Carry over changes made by an administrator since the
+			// certificate was issued, e.g. a new node type.
+			if active, err := ca.readActiveCertificate(rec.Name); err == nil && bytes.Equal(active.pubkey, rec.pubkey) {
+				nodetype = active.NodeType
+			} else {
+				return nil, fmt.Errorf("Renew Certificate failed, certificate %s has been superseded.", rec.Serial)
+			}
+		}
+		name = rec.Name
+	}
+	peerid, err := ca.allocatePeerId(name)
+	if err != nil {
+		return nil, fmt.Errorf("Renew Certificate failed: %v", err)
+	}
+	raw, err := ca.createPeerCertificate(name, pubkey, nodetype, peerid)
+	if err != nil {
+		return nil, fmt.Errorf("Renew Certificate failed: %v", err)
+	}
+
+	caLogger.Infof("Renewed certificate for %s, previously valid until %v", name, cert.NotAfter)
+
 	cooked := pem.EncodeToMemory(
 		&pem.Block{
 			Type:  "CERTIFICATE",
@@ -322,6 +717,113 @@ func (ca *CA) IssueCertificate(in []byte, name string, nodetype NodeType) ([]byt
 	return cooked, nil
 }
 
+// IssueTCerts issues a batch of transaction certificates for the given public
+// keys to the holder of the enrollment certificate in. The request must be
+// signed with the enrollment private key over the certificate and the keys.
+//
+// Transaction certificates carry neither the name nor the peer id of the
+// node, only the organization of the CA, so transactions signed with
+// different tcerts cannot be linked to each other or to the node.
+func (ca *CA) IssueTCerts(in []byte, sign []byte, keys [][]byte) ([][]byte, error) {
+	if len(keys) == 0 || len(keys) > MaxTCertBatch {
+		return nil, fmt.Errorf("Issue TCerts failed, between 1 and %d keys must be requested.", MaxTCertBatch)
+	}
+
+	block, _ := pem.Decode(in)
+	if block == nil {
+		return nil, fmt.Errorf("Issue TCerts failed for the certificate format error.")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		caLogger.Debug(err)
+		return nil, fmt.Errorf("Issue TCerts failed for the certificate format error.")
+	}
+	if err := cert.CheckSignatureFrom(ca.cert); err != nil {
+		return nil, fmt.Errorf("Issue TCerts failed, certificate not issued by this CA: %v", err)
+	}
+	if err := VerifyValidity(cert, time.Now()); err != nil {
+		return nil, fmt.Errorf("Issue TCerts failed, %v", err)
+	}
+	pubkey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("Issue TCerts failed for the public key format error.")
+	}
+	if err := VerifyTCertRequest(pubkey, cert.Raw, keys, sign); err != nil {
+		return nil, err
+	}
+
+	// Only the active enrollment certificate of a node may request tcerts
+	rec, err := ca.readCertificateByRaw(cert.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("Issue TCerts failed, unknown certificate.")
+	}
+	if rec.Status != Active {
+		return nil, fmt.Errorf("Issue TCerts failed, certificate %s is not active.", rec.Serial)
+	}
+	if rec.NodeType == IntermediateCA {
+		return nil, fmt.Errorf("Issue TCerts failed, certificate %s belongs to a CA.", rec.Serial)
+	}
+
+	pubs := make([]*ecdsa.PublicKey, len(keys))
+	for i, key := range keys {
+		pub, err := x509.ParsePKIXPublicKey(key)
+		if err != nil {
+			caLogger.Debug(err)
+			return nil, fmt.Errorf("Issue TCerts failed for the public key format error.")
+		}
+		if err := CheckPublicKey(pub); err != nil {
+			return nil, fmt.Errorf("Issue TCerts failed, %v", err)
+		}
+		pubs[i] = pub.(*ecdsa.PublicKey)
+	}
+
+	certs := make([][]byte, len(pubs))
+	for i, pub := range pubs {
+		raw, err := ca.createTCertificate(rec.Name, pub)
+		if err != nil {
+			return nil, fmt.Errorf("Issue TCerts failed: %v", err)
+		}
+		certs[i] = raw
+	}
+	caLogger.Infof("Issued %d transaction certificates for %s", len(certs), rec.Name)
+
+	return certs, nil
+}
+
+// createTCertificate signs a transaction certificate for pub and records
+// that it was issued to name.
+func (ca *CA) createTCertificate(name string, pub *ecdsa.PublicKey) ([]byte, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	ext := []pkix.Extension{
+		{Id: TCertOID, Critical: true, Value: []byte{1}},
+		// tcerts never grant more than a client node
+		{Id: NodeTypeOID, Critical: true, Value: []byte{byte(Client)}},
+	}
+	notBefore := time.Now().Add(-1 * time.Minute)
+	notAfter := notBefore.Add(tcertValidity())
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+	spec := NewCertificateSpec(serialNumber.String(), "tcert", serialNumber, pub, x509.KeyUsageDigitalSignature, &notBefore, &notAfter, ext...)
+	raw, err := ca.newCertificateFromSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, err := ca.db.Exec("INSERT INTO TCertificates (serial, name, notafter, cert) VALUES (?, ?, ?, ?)",
+		serialNumber.String(), name, notAfter.Unix(), raw); err != nil {
+		caLogger.Error(err)
+		return nil, err
+	}
+	return raw, nil
+}
+
 func (ca *CA) GetCACertificate() ([]byte) {
 	raw := ca.cert.Raw
 
@@ -334,8 +836,149 @@ func (ca *CA) GetCACertificate() ([]byte) {
 	return cooked
 }
 
-func (ca *CA) GetReplicaCount() uint32 {
-	return ca.peerCount
+// GetCertificateChain returns the PEM encoded certificates from the CA up to
+// the root. For a root CA it only holds the CA certificate.
+func (ca *CA) GetCertificateChain() [][]byte {
+	chain := [][]byte{ca.GetCACertificate()}
+	for _, cert := range ca.chain {
+		chain = append(chain, EncodeCertificates(cert))
+	}
+	return chain
+}
+
+// GetRootCertificate returns the PEM encoded root certificate all
+// certificates issued by the CA chain up to.
+func (ca *CA) GetRootCertificate() []byte {
+	if len(ca.chain) == 0 {
+		return ca.GetCACertificate()
+	}
+	return EncodeCertificates(ca.chain[len(ca.chain)-1])
+}
+
+// IssueIntermediateCertificate issues the certificate of an intermediate CA
+// run by organization. Only a root CA issues intermediate CAs, which in turn
+// may only issue node certificates. Every intermediate CA is assigned its own
+// range of peer ids; reissuing the certificate for the same name keeps it.
+func (ca *CA) IssueIntermediateCertificate(in []byte, name, organization string) ([]byte, error) {
+	if ca.IsIntermediate() {
+		return nil, fmt.Errorf("Create intermediate CA certificate failed, only a root CA can issue intermediate CAs.")
+	}
+	if name == "" || organization == "" {
+		return nil, fmt.Errorf("Create intermediate CA certificate failed, name and organization are required.")
+	}
+	if rec, err := ca.readActiveCertificate(name); err == nil && rec.NodeType != IntermediateCA {
+		return nil, fmt.Errorf("Create intermediate CA certificate failed, %s is the name of a node.", name)
+	}
+
+	block, _ := pem.Decode(in)
+	if block == nil {
+		return nil, fmt.Errorf("Create intermediate CA certificate failed for the public key format error.")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		caLogger.Debug(err)
+		return nil, fmt.Errorf("Create intermediate CA certificate failed for the public key format error.")
+	}
+	if err := CheckPublicKey(pub); err != nil {
+		return nil, fmt.Errorf("Create intermediate CA certificate failed, %v", err)
+	}
+
+	index, err := ca.allocateIntermediateIndex(name)
+	if err != nil {
+		return nil, fmt.Errorf("Create intermediate CA certificate failed: %v", err)
+	}
+	bs := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bs, index)
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:   name,
+			Organization: []string{organization},
+			Country:      []string{currentSettings().PKI.Country},
+		},
+		// An intermediate CA expires together with the root
+		NotBefore: time.Now().Add(-1 * time.Minute),
+		NotAfter:  ca.cert.NotAfter,
+
+		SignatureAlgorithm: signatureAlgorithm(),
+		KeyUsage:           x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+
+		BasicConstraintsValid: true,
+		IsCA:           true,
+		MaxPathLen:     0,
+		MaxPathLenZero: true,
+
+		ExtraExtensions: []pkix.Extension{
+			{Id: NodeTypeOID, Critical: true, Value: []byte{byte(IntermediateCA)}},
+			{Id: PeerIdOID, Critical: true, Value: bs},
+		},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, &tmpl, ca.cert, pub, ca.priv)
+	if err != nil {
+		return nil, err
+	}
+	if err := ca.persistCertificate(serialNumber.String(), name, raw, block.Bytes); err != nil {
+		return nil, err
+	}
+	caLogger.Infof("Issued intermediate CA certificate for %s of %s, peer id range %d", name, organization, index)
+
+	cooked := pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: raw,
+		})
+
+	return cooked, nil
+}
+
+// allocateIntermediateIndex returns the index assigned to the intermediate
+// CA name, assigning the next unused one on first issuance.
+func (ca *CA) allocateIntermediateIndex(name string) (uint32, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	tx, err := ca.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var index int64
+	err = tx.QueryRow("SELECT idx FROM Intermediates WHERE name=?", name).Scan(&index)
+	if err == nil {
+		return uint32(index), nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+	if err = tx.QueryRow("SELECT COALESCE(MAX(idx), 0) + 1 FROM Intermediates").Scan(&index); err != nil {
+		return 0, err
+	}
+	if index > maxIntermediates {
+		return 0, fmt.Errorf("intermediate CA indexes exhausted.")
+	}
+	if _, err = tx.Exec("INSERT INTO Intermediates (idx, name) VALUES (?, ?)", index, name); err != nil {
+		return 0, err
+	}
+	return uint32(index), tx.Commit()
+}
+
+// GetReplicaCount returns the number of consensus replicas, i.e. the nodes
+// holding an active, unexpired Validator or Admin certificate. The CA's own
+// certificate is not counted.
+func (ca *CA) GetReplicaCount() (uint32, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var count uint32
+	err := ca.db.QueryRow("SELECT COUNT(DISTINCT name) FROM Certificates WHERE status=? AND nodetype IN (?, ?) AND notafter>? AND cert!=?",
+		Active, Validator, Admin, time.Now().Unix(), ca.cert.Raw).Scan(&count)
+	return count, err
 }
 
 // Stop Close closes down the CA.
@@ -372,29 +1015,66 @@ func (ca *CA) readCAPrivateKey(name string) (*ecdsa.PrivateKey, error) {
 	return x509.ParseECPrivateKey(block.Bytes)
 }
 
-func (ca *CA) createCACertificate(name string, pub *ecdsa.PublicKey, nodetype NodeType) []byte {
+func (ca *CA) createCACertificate(name string, pub *ecdsa.PublicKey, nodetype NodeType) ([]byte, error) {
 	caLogger.Debug("Creating CA certificate.")
 
-	bs := make([]byte, 4)
-	binary.LittleEndian.PutUint32(bs, ca.peerid)
+	peerid, err := ca.allocatePeerId(name)
+	if err != nil {
+		return nil, err
+	}
 
-	if ca.peerid < math.MaxUint32 - 1 {
-		ca.peerid += 1
-	} else {
-		ca.peerid = 1
+	return ca.createPeerCertificate(name, pub, nodetype, peerid)
+}
+
+// allocatePeerId returns the peer id assigned to name, assigning the next
+// unused one on first enrollment. Peer ids are handed out in enrollment order
+// from the range of the CA and never reused, even after the certificate of a
+// node has been revoked.
+func (ca *CA) allocatePeerId(name string) (uint32, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	tx, err := ca.db.Begin()
+	if err != nil {
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	ca.peerCount += 1
+	var peerid int64
+	err = tx.QueryRow("SELECT peerid FROM PeerIds WHERE name=?", name).Scan(&peerid)
+	if err == nil {
+		return uint32(peerid), nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+	if err = tx.QueryRow("SELECT MAX(COALESCE(MAX(peerid), 0), ?) + 1 FROM PeerIds", ca.peerIdBase).Scan(&peerid); err != nil {
+		return 0, err
+	}
+	if peerid >= int64(ca.peerIdBase)+1<<peerIdRangeBits || peerid > math.MaxUint32 {
+		return 0, fmt.Errorf("peer ids exhausted.")
+	}
+	if _, err = tx.Exec("INSERT INTO PeerIds (peerid, name) VALUES (?, ?)", peerid, name); err != nil {
+		return 0, err
+	}
+	return uint32(peerid), tx.Commit()
+}
+
+// createPeerCertificate signs a certificate carrying the given node type and
+// peer id extensions and stores it under the node's name.
+func (ca *CA) createPeerCertificate(name string, pub *ecdsa.PublicKey, nodetype NodeType, peerid uint32) ([]byte, error) {
+	bs := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bs, peerid)
 
 	var ext []pkix.Extension
 	ext = [] pkix.Extension {
 		pkix.Extension {
-			Id: [] int {1, 33, 80},
+			Id: NodeTypeOID,
 			Critical: true,
 			Value: [] byte {byte(nodetype)},
 		},
 		pkix.Extension {
-			Id: [] int {1, 33, 81},
+			Id: PeerIdOID,
 			Critical: true,
 			Value: bs,
 		},
@@ -402,7 +1082,7 @@ func (ca *CA) createCACertificate(name string, pub *ecdsa.PublicKey, nodetype No
 
 	raw, err := ca.newCertificate(name, pub, x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign, ext)
 	if err != nil {
-		caLogger.Panic(err)
+		return nil, err
 	}
 
 	cooked := pem.EncodeToMemory(
@@ -413,10 +1093,10 @@ func (ca *CA) createCACertificate(name string, pub *ecdsa.PublicKey, nodetype No
 	err = ioutil.WriteFile(ca.path+"/"+name+".cert", cooked, 0644)
 
 	if err != nil {
-		caLogger.Panic(err)
+		return nil, err
 	}
 
-	return raw
+	return raw, nil
 }
 
 func (ca *CA) readCACertificate(name string) ([]byte, error) {
@@ -442,7 +1122,11 @@ func (ca *CA) readCACertificate(name string) ([]byte, error) {
 }
 
 func (ca *CA) newCertificate(id string, pub interface{}, usage x509.KeyUsage, ext []pkix.Extension) ([]byte, error) {
-	spec := NewDefaultCertificateSpec(id, pub, usage, ext...)
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	spec := NewDefaultPeriodCertificateSpec(id, serialNumber, pub, usage, ext...)
 	return ca.createCertificateFromSpec(spec)
 }
 
@@ -473,11 +1157,17 @@ func (ca *CA) newCertificateFromSpec(spec *CertificateSpec) ([]byte, error) {
 	parent := ca.cert
 	isCA := parent == nil
 
+	// Certificates are issued to the organization of the CA
+	organization := spec.GetOrganization()
+	if parent != nil && len(parent.Subject.Organization) > 0 {
+		organization = parent.Subject.Organization[0]
+	}
+
 	tmpl := x509.Certificate{
 		SerialNumber: spec.GetSerialNumber(),
 		Subject: pkix.Name{
 			CommonName:   spec.GetCommonName(),
-			Organization: []string{spec.GetOrganization()},
+			Organization: []string{organization},
 			Country:      []string{spec.GetCountry()},
 		},
 		NotBefore: *notBefore,
@@ -517,12 +1207,31 @@ func (ca *CA) persistCertificate(id string, name string, certRaw []byte, pubkey
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	var err error
+	cert, err := x509.ParseCertificate(certRaw)
+	if err != nil {
+		caLogger.Error(err)
+		return err
+	}
+	nodetype, peerid := GetNodeInfo(cert)
 
-	if _, err = ca.db.Exec("INSERT INTO Certificates (id, name, cert, pubkey) VALUES (?, ?, ?, ?)", id, name, certRaw, pubkey); err != nil {
+	tx, err := ca.db.Begin()
+	if err != nil {
 		caLogger.Error(err)
+		return err
 	}
-	return err
+	// A new certificate replaces the active one of the same name
+	if _, err = tx.Exec("UPDATE Certificates SET status=? WHERE name=? AND status=?", Superseded, name, Active); err != nil {
+		caLogger.Error(err)
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.Exec("INSERT INTO Certificates (id, name, cert, pubkey, nodetype, peerid, status, notbefore, notafter, organization) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id, name, certRaw, pubkey, nodetype, peerid, Active, cert.NotBefore.Unix(), cert.NotAfter.Unix(), GetOrganization(cert)); err != nil {
+		caLogger.Error(err)
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 func (ca *CA) readCertificates(name string) (*sql.Rows, error) {
@@ -533,4 +1242,3 @@ func (ca *CA) readCertificates(name string) (*sql.Rows, error) {
 
 	return ca.db.Query("SELECT cert, pubkey FROM Certificates WHERE name=?", name)
 }
-
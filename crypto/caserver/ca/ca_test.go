@@ -0,0 +1,422 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package ca
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+)
+
+func peerIdOf(t *testing.T, cooked []byte) uint32 {
+	_, peerid := GetNodeInfo(BuildCertificateFromBytes(cooked))
+	return peerid
+}
+
+func TestPeerIdAllocation(t *testing.T) {
+	if err := Init(nil); err != nil {
+		t.Fatalf("failed to init crypto: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "dchain-ca-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newCA(dir, "Blockchain", InitializeCommonTables)
+	_, cert1 := enroll(t, ca, "node1", Validator)
+	_, cert2 := enroll(t, ca, "node2", Validator)
+	id1, id2 := peerIdOf(t, cert1), peerIdOf(t, cert2)
+	if id1 == 0 || id2 != id1+1 {
+		t.Fatalf("peer ids not allocated in enrollment order: %d, %d", id1, id2)
+	}
+	if _, err := ca.RevokeCertificate("node2", ""); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	ca.Stop()
+
+	// A restarted CA continues the allocation, revoked ids are not reused
+	ca = newCA(dir, "Blockchain", InitializeCommonTables)
+	defer ca.Stop()
+
+	_, cert3 := enroll(t, ca, "node3", Validator)
+	if id3 := peerIdOf(t, cert3); id3 != id2+1 {
+		t.Fatalf("peer id after restart mismatch: have %d, want %d", id3, id2+1)
+	}
+	rec, err := ca.ChangeNodeType("node1", Admin)
+	if err != nil {
+		t.Fatalf("failed to change node type: %v", err)
+	}
+	if rec.PeerId != id1 {
+		t.Fatalf("reissued certificate changed peer id: have %d, want %d", rec.PeerId, id1)
+	}
+}
+
+func TestPeerIdMigration(t *testing.T) {
+	ca, cleanup := newTestCA(t)
+	defer cleanup()
+
+	_, cert1 := enroll(t, ca, "node1", Validator)
+	_, cert2 := enroll(t, ca, "node2", Validator)
+	id1, id2 := peerIdOf(t, cert1), peerIdOf(t, cert2)
+
+	// Simulate a database written by a CA that reused node1's id after a restart
+	if _, err := ca.db.Exec("DELETE FROM PeerIds"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ca.db.Exec("UPDATE Certificates SET peerid=? WHERE name=?", id1, "node2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitializeCommonTables(ca.db); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if id, _ := ca.allocatePeerId("node1"); id != id1 {
+		t.Fatalf("node1 peer id mismatch: have %d, want %d", id, id1)
+	}
+	if id, _ := ca.allocatePeerId("node2"); id != id2 {
+		t.Fatalf("colliding node2 not reassigned: have %d, want %d", id, id2)
+	}
+}
+
+// Tests that enrollments beyond the peer id range of the CA are refused
+// without taking the CA down.
+func TestPeerIdsExhausted(t *testing.T) {
+	ca, cleanup := newTestCA(t)
+	defer cleanup()
+
+	last := int64(ca.peerIdBase) + 1<<peerIdRangeBits - 1
+	if _, err := ca.db.Exec("INSERT INTO PeerIds (peerid, name) VALUES (?, ?)", last, "last"); err != nil {
+		t.Fatal(err)
+	}
+	priv, _ := primitives.NewECDSAKey()
+	raw, _ := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	pub := pem.EncodeToMemory(&pem.Block{Type: "ECDSA PUBLIC KEY", Bytes: raw})
+	if _, err := ca.IssueCertificate(pub, "node1", Validator); err == nil {
+		t.Fatal("certificate issued beyond the peer id range")
+	}
+}
+
+func TestReplicaCount(t *testing.T) {
+	ca, cleanup := newTestCA(t)
+	defer cleanup()
+
+	enroll(t, ca, "validator1", Validator)
+	enroll(t, ca, "validator2", Validator)
+	enroll(t, ca, "admin", Admin)
+	enroll(t, ca, "peer", Peer)
+	enroll(t, ca, "client", Client)
+
+	if n, err := ca.GetReplicaCount(); err != nil || n != 3 {
+		t.Fatalf("replica count mismatch: have %d (%v), want 3", n, err)
+	}
+	if _, err := ca.RevokeCertificate("validator2", ""); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if _, err := ca.ChangeNodeType("peer", Validator); err != nil {
+		t.Fatalf("failed to change node type: %v", err)
+	}
+	if n, err := ca.GetReplicaCount(); err != nil || n != 3 {
+		t.Fatalf("replica count mismatch: have %d (%v), want 3", n, err)
+	}
+}
+
+func TestSharedStore(t *testing.T) {
+	first, cleanup := newTestCA(t)
+	defer cleanup()
+
+	// A second instance on the same directory serves the same CA
+	second := newCA(first.path, "Blockchain", InitializeCommonTables)
+	defer second.Stop()
+
+	if !bytes.Equal(first.raw, second.raw) || first.priv.D.Cmp(second.priv.D) != 0 {
+		t.Fatal("instances sign with different keys")
+	}
+
+	// Peer ids are allocated once across instances
+	var wg sync.WaitGroup
+	certs := make([][]byte, 10)
+	pubs := make([][]byte, len(certs))
+	for i := range pubs {
+		pubs[i] = publicKeyPEM(t, first.priv.Curve)
+	}
+	for i := range certs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			instance := first
+			if i%2 == 1 {
+				instance = second
+			}
+			var err error
+			if certs[i], err = instance.IssueCertificate(pubs[i], fmt.Sprintf("node%d", i), Validator); err != nil {
+				t.Errorf("failed to issue certificate: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+	seen := make(map[uint32]bool)
+	for _, cert := range certs {
+		id := peerIdOf(t, cert)
+		if seen[id] {
+			t.Fatalf("peer id %d allocated twice", id)
+		}
+		seen[id] = true
+	}
+	if n, err := second.GetReplicaCount(); err != nil || n != uint32(len(certs)) {
+		t.Fatalf("replica count mismatch: have %d (%v), want %d", n, err, len(certs))
+	}
+
+	// Certificates issued by one instance are known to the other
+	priv, cooked := enroll(t, first, "renewed", Validator)
+	sign, ts, nonce := signRenewRequest(t, priv, cooked)
+	if _, err := second.RenewCertificate(cooked, sign, "renewed", ts, nonce); err != nil {
+		t.Fatalf("renewal on the other instance failed: %v", err)
+	}
+	if rec, err := first.readCertificateByRaw(BuildCertificateFromBytes(cooked).Raw); err != nil || rec.Status != Superseded {
+		t.Fatalf("renewal not visible to the issuing instance: %v, %v", rec, err)
+	}
+
+	if err := first.AddWhitelist([]string{"10.0.0.0/8"}, nil); err != nil {
+		t.Fatalf("failed to add whitelist entry: %v", err)
+	}
+	if cidrs, _, err := second.Whitelist(); err != nil || len(cidrs) != 1 {
+		t.Fatalf("whitelist not shared: %v, %v", cidrs, err)
+	}
+}
+
+// newIntermediateCA sets up an intermediate CA of organization issued by root.
+func newIntermediateCA(t *testing.T, root *CA, name, organization string) (*CA, func()) {
+	dir, err := ioutil.TempDir("", "dchain-ca-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	priv := CreateCAKeyPair("Blockchain", dir)
+	raw, _ := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	pub := pem.EncodeToMemory(&pem.Block{Type: "ECDSA PUBLIC KEY", Bytes: raw})
+
+	cert, err := root.IssueIntermediateCertificate(pub, name, organization)
+	if err != nil {
+		t.Fatalf("failed to issue intermediate CA certificate: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "Blockchain.cert"), cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ChainFile), bytes.Join(root.GetCertificateChain(), nil), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ca := newCA(dir, "Blockchain", InitializeCommonTables)
+	return ca, func() {
+		ca.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestIntermediateCA(t *testing.T) {
+	root, cleanup := newTestCA(t)
+	defer cleanup()
+	bankA, cleanupA := newIntermediateCA(t, root, "bankA", "BankA")
+	defer cleanupA()
+	bankB, cleanupB := newIntermediateCA(t, root, "bankB", "BankB")
+	defer cleanupB()
+
+	if root.IsIntermediate() || !bankA.IsIntermediate() {
+		t.Fatal("intermediate CA not detected")
+	}
+	if !bytes.Equal(bankA.GetRootCertificate(), root.GetCACertificate()) {
+		t.Fatal("intermediate CA root mismatch")
+	}
+
+	// Nodes of every organization verify against the shared root when
+	// presented together with their issuing CA
+	_, certA := enroll(t, bankA, "node1", Validator)
+	_, certB := enroll(t, bankB, "node1", Validator)
+	chainA := append(append([]byte{}, certA...), bankA.GetCACertificate()...)
+	if err := VerifyCertificate(chainA, root.GetCACertificate()); err != nil {
+		t.Errorf("certificate chain refused: %v", err)
+	}
+	if err := VerifySignature(chainA, root.GetCACertificate()); err != nil {
+		t.Errorf("certificate chain signature refused: %v", err)
+	}
+	if err := VerifyCertificate(certA, root.GetCACertificate()); err == nil {
+		t.Error("certificate accepted without its intermediate CA")
+	}
+	if err := VerifyCertificate(append(append([]byte{}, certA...), bankB.GetCACertificate()...), root.GetCACertificate()); err == nil {
+		t.Error("certificate accepted with another intermediate CA")
+	}
+
+	// Certificates carry the organization of the issuing CA
+	if org := GetOrganization(BuildCertificateFromBytes(certB)); org != "BankB" {
+		t.Errorf("organization mismatch: have %q, want BankB", org)
+	}
+	if rec, err := bankA.readActiveCertificate("node1"); err != nil || rec.Organization != "BankA" {
+		t.Errorf("organization not recorded: %v, %v", rec, err)
+	}
+	if rec, err := root.readActiveCertificate("bankA"); err != nil || rec.NodeType != IntermediateCA {
+		t.Errorf("intermediate CA not recorded: %v, %v", rec, err)
+	}
+
+	// Peer ids are unique across organizations
+	if id := peerIdOf(t, certA); id != 1<<peerIdRangeBits+1 {
+		t.Errorf("peer id mismatch: have %d, want %d", id, 1<<peerIdRangeBits+1)
+	}
+	if id := peerIdOf(t, certB); id != 2<<peerIdRangeBits+1 {
+		t.Errorf("peer id mismatch: have %d, want %d", id, 2<<peerIdRangeBits+1)
+	}
+
+	// Intermediate CAs may not issue further CAs
+	if _, err := bankA.IssueIntermediateCertificate(publicKeyPEM(t, bankA.priv.Curve), "sub", "Sub"); err == nil {
+		t.Error("intermediate CA issued an intermediate CA")
+	}
+	sub := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sub"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, &sub, bankA.cert, &bankA.priv.PublicKey, bankA.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subCert, _ := x509.ParseCertificate(raw)
+	leaf := sub
+	leaf.Subject.CommonName, leaf.IsCA = "leaf", false
+	raw, _ = x509.CreateCertificate(rand.Reader, &leaf, subCert, &bankA.priv.PublicKey, bankA.priv)
+	leafCert, _ := x509.ParseCertificate(raw)
+	if err := verifyChainSignatures(leafCert, []*x509.Certificate{subCert, bankA.cert}, root.cert); err == nil {
+		t.Error("path length constraint of the intermediate CA ignored")
+	}
+}
+
+func TestIssueTCerts(t *testing.T) {
+	root, cleanup := newTestCA(t)
+	defer cleanup()
+	bank, cleanupBank := newIntermediateCA(t, root, "bankA", "BankA")
+	defer cleanupBank()
+
+	priv, cooked := enroll(t, bank, "node1", Validator)
+	cert := BuildCertificateFromBytes(cooked)
+
+	keys := make([][]byte, 3)
+	for i := range keys {
+		key, err := primitives.NewECDSAKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i], _ = x509.MarshalPKIXPublicKey(&key.PublicKey)
+	}
+	sign, err := SignTCertRequest(priv, cert.Raw, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs, err := bank.IssueTCerts(cooked, sign, keys)
+	if err != nil {
+		t.Fatalf("failed to issue tcerts: %v", err)
+	}
+	if len(certs) != len(keys) {
+		t.Fatalf("tcert count mismatch: have %d, want %d", len(certs), len(keys))
+	}
+
+	// tcerts verify up to the root but do not reveal the node
+	now := time.Now()
+	for i, raw := range certs {
+		tcert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(tcert.RawSubjectPublicKeyInfo, keys[i]) {
+			t.Errorf("tcert %d issued for the wrong key", i)
+		}
+		if err := VerifyTCert(tcert, []*x509.Certificate{bank.cert}, root.cert, now); err != nil {
+			t.Errorf("tcert %d refused: %v", i, err)
+		}
+		if tcert.Subject.CommonName == "node1" || GetOrganization(tcert) != "BankA" {
+			t.Errorf("tcert %d subject mismatch: %v", i, tcert.Subject)
+		}
+		if nodetype, peerid := GetNodeInfo(tcert); nodetype != Client || peerid != 0 {
+			t.Errorf("tcert %d node info mismatch: %v, %d", i, nodetype, peerid)
+		}
+		if err := VerifyTCert(tcert, []*x509.Certificate{bank.cert}, root.cert, now.Add(tcertValidity()+time.Hour)); err == nil {
+			t.Errorf("tcert %d accepted after expiry", i)
+		}
+	}
+	if err := VerifyTCert(cert, []*x509.Certificate{bank.cert}, root.cert, now); err == nil {
+		t.Error("enrollment certificate accepted as tcert")
+	}
+	var name string
+	if err := bank.db.QueryRow("SELECT name FROM TCertificates LIMIT 1").Scan(&name); err != nil || name != "node1" {
+		t.Errorf("tcert not recorded: %q, %v", name, err)
+	}
+
+	// Requests must be signed by the enrollment key of an active certificate
+	if _, err := bank.IssueTCerts(cooked, sign, keys[:1]); err == nil {
+		t.Error("tcerts issued for keys not covered by the signature")
+	}
+	if _, err := bank.RevokeCertificate("node1", ""); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if _, err := bank.IssueTCerts(cooked, sign, keys); err == nil {
+		t.Error("tcerts issued for a revoked certificate")
+	}
+}
+
+func TestRPCToken(t *testing.T) {
+	root, cleanup := newTestCA(t)
+	defer cleanup()
+	bank, cleanupBank := newIntermediateCA(t, root, "bankA", "BankA")
+	defer cleanupBank()
+
+	priv, cooked := enroll(t, bank, "client1", Client)
+	cert := BuildCertificateFromBytes(cooked)
+
+	now := time.Now()
+	token, err := SignRPCToken(priv, now, cert, bank.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caller, err := VerifyRPCToken(token, root.cert, now)
+	if err != nil {
+		t.Fatalf("token refused: %v", err)
+	}
+	if !bytes.Equal(caller.Raw, cert.Raw) {
+		t.Errorf("token identifies the wrong caller: %v", caller.Subject)
+	}
+	if _, err := VerifyRPCToken(token, root.cert, now.Add(RPCTokenWindow+time.Minute)); err == nil {
+		t.Error("stale token accepted")
+	}
+	if _, err := VerifyRPCToken(token, bank.cert, now); err == nil {
+		t.Error("token accepted for a foreign root")
+	}
+
+	// The signature must come from the key of the certificate
+	other, _ := enroll(t, bank, "client2", Client)
+	forged, err := SignRPCToken(other, now, cert, bank.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyRPCToken(forged, root.cert, now); err == nil {
+		t.Error("token signed by a foreign key accepted")
+	}
+}
@@ -9,27 +9,91 @@ package ca
 
 
 import (
+	"bytes"
 	"google.golang.org/grpc"
 	pb "github.com/ethereum/go-ethereum/crypto/caserver/ca/protos"
 	"time"
 	"crypto/x509"
 	"crypto/ecdsa"
+	"math/big"
 	"encoding/pem"
 	"io/ioutil"
 	"strings"
+	"net"
 	"os"
+	"sync"
 	"fmt"
 	"golang.org/x/net/context"
-	"github.com/spf13/viper"
+	"github.com/hyperledger/fabric/core/crypto/primitives"
 )
 
+// dialTimeout is the time to wait for a CA instance before failing over to
+// the next one.
+const dialTimeout = 3 * time.Second
+
+// lastAddress remembers the CA instance that answered last, it is tried
+// first by the next call.
+var lastAddress struct {
+	sync.Mutex
+	addr string
+}
+
+// caAddresses returns the addresses of the CA instances, taken from
+// caserver.addresses or else from caserver.address and caserver.port.
+func caAddresses() []string {
+	conf := currentSettings().CAServer
+	addrs := append([]string{}, conf.Addresses...)
+	if len(addrs) == 0 {
+		addrs = []string{conf.Address + conf.Port}
+	}
+
+	lastAddress.Lock()
+	defer lastAddress.Unlock()
+	for i, addr := range addrs {
+		if addr == lastAddress.addr && i > 0 {
+			ordered := append([]string{addr}, addrs[:i]...)
+			return append(ordered, addrs[i+1:]...)
+		}
+	}
+	return addrs
+}
+
+// GetClientConn connects to the first reachable CA instance.
 func GetClientConn() (*grpc.ClientConn, error) {
 	var opts []grpc.DialOption
-	opts = append(opts, grpc.WithInsecure())
-	opts = append(opts, grpc.WithTimeout(time.Second*3))
-	address := viper.GetString("caserver.address") + viper.GetString("caserver.port")
+	creds, err := clientCredentials()
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		opts = append(opts, grpc.WithTransportCredentials(creds))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	opts = append(opts, grpc.WithBlock(), grpc.WithTimeout(dialTimeout))
+
+	for _, address := range caAddresses() {
+		// Instances that are down refuse a plain connection right away,
+		// skip them without waiting for the gRPC dial to time out
+		probe, dialErr := net.DialTimeout("tcp", address, dialTimeout)
+		if dialErr != nil {
+			caLogger.Warningf("CA at %s unreachable: %v", address, dialErr)
+			err = dialErr
+			continue
+		}
+		probe.Close()
 
-	return grpc.Dial(address, opts...)
+		conn, dialErr := grpc.Dial(address, opts...)
+		if dialErr == nil {
+			lastAddress.Lock()
+			lastAddress.addr = address
+			lastAddress.Unlock()
+			return conn, nil
+		}
+		caLogger.Warningf("CA at %s unreachable: %v", address, dialErr)
+		err = dialErr
+	}
+	return nil, fmt.Errorf("no CA reachable: %v", err)
 }
 
 func GetWhitelistClient() (*grpc.ClientConn, pb.WhitelistClient, error) {
@@ -52,6 +116,16 @@ func GetCAClient() (*grpc.ClientConn, pb.CAClient, error) {
 	return conn, client, nil
 }
 
+func GetCAAdminClient() (*grpc.ClientConn, pb.CAAdminClient, error) {
+	conn, err := GetClientConn()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := pb.NewCAAdminClient(conn)
+	return conn, client, nil
+}
+
 func GetCACertificate() ([]byte, error) {
 
 	sock, caClient, err := GetCAClient()
@@ -68,6 +142,76 @@ func GetCACertificate() ([]byte, error) {
 	return resp.In, nil
 }
 
+// GetCertificateChain returns the certificates from the CA up to the root.
+// The first one is the certificate of the CA nodes are enrolled with, the
+// last one the root shared by all CAs of the network.
+func GetCertificateChain() ([]*x509.Certificate, error) {
+	sock, caClient, err := GetCAClient()
+	if err != nil {
+		return nil, err
+	}
+	defer sock.Close()
+
+	resp, err := caClient.GetCertificateChain(context.Background(), &pb.NoParam{})
+	if err != nil {
+		return nil, fmt.Errorf("could not GetCertificateChain: %v", err)
+	}
+
+	var chain []*x509.Certificate
+	for _, cooked := range resp.Certs {
+		certs, err := ParseCertificates(cooked)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, certs...)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("CA returned no certificate")
+	}
+	return chain, nil
+}
+
+// GetRootCertificate returns the PEM encoded root certificate of the CA.
+func GetRootCertificate() ([]byte, error) {
+	chain, err := GetCertificateChain()
+	if err != nil {
+		return nil, err
+	}
+	return EncodeCertificates(chain[len(chain)-1]), nil
+}
+
+// GetRevocationList returns the serial numbers of the certificates revoked by
+// the CA, together with the certificate of the CA that issued them. The
+// signature of the list is checked against that certificate.
+func GetRevocationList() (*x509.Certificate, []*big.Int, error) {
+	chain, err := GetCertificateChain()
+	if err != nil {
+		return nil, nil, err
+	}
+	sock, caClient, err := GetCAClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer sock.Close()
+
+	resp, err := caClient.GetRevocationList(context.Background(), &pb.NoParam{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not GetRevocationList: %v", err)
+	}
+	crl, err := x509.ParseDERCRL(resp.Crl)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid revocation list: %v", err)
+	}
+	if err := chain[0].CheckCRLSignature(crl); err != nil {
+		return nil, nil, fmt.Errorf("invalid revocation list: %v", err)
+	}
+	serials := make([]*big.Int, len(crl.TBSCertList.RevokedCertificates))
+	for i, rc := range crl.TBSCertList.RevokedCertificates {
+		serials[i] = rc.SerialNumber
+	}
+	return chain[0], serials, nil
+}
+
 func GetReplicaCount() (uint32, error) {
 	sock, caClient, err := GetCAClient()
 	if err != nil {
@@ -83,10 +227,15 @@ func GetReplicaCount() (uint32, error) {
 	return resp.Count, nil
 }
 
-func IssueCertificate(pubKey *ecdsa.PublicKey, name, path string) (*x509.Certificate, error) {
+// certificateName returns the file name the node certificate is stored under.
+func certificateName(name string) string {
 	name = strings.Replace(name, "/", "_", -1)
 	host, _ := os.Hostname()
-	name += host
+	return name + host
+}
+
+func IssueCertificate(pubKey *ecdsa.PublicKey, name, path string) (*x509.Certificate, error) {
+	name = certificateName(name)
 
 	sock, caClient, err := GetCAClient()
 	if err != nil {
@@ -103,7 +252,8 @@ func IssueCertificate(pubKey *ecdsa.PublicKey, name, path string) (*x509.Certifi
 
 	req := &pb.CertificateRequest{
 		In:	[]byte(cooked),
-		Name:	name}
+		Name:	name,
+		Security:	SecuritySetting()}
 
 	resp, err := caClient.IssueCertificate(context.Background(), req)
 	if err != nil {
@@ -121,15 +271,19 @@ func IssueCertificate(pubKey *ecdsa.PublicKey, name, path string) (*x509.Certifi
 	}
 
 	cert, err := x509.ParseCertificate(block.Bytes)
-	return cert, err
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckCertificateSecurity(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
 }
 
 func ReadCACertificate(name, path string) (*x509.Certificate, error) {
 	caLogger.Debug("Reading CA certificate.")
 
-	name = strings.Replace(name, "/", "_", -1)
-	host, _ := os.Hostname()
-	name += host
+	name = certificateName(name)
 
 	path = path + "/cakeystore/" + name + ".cert"
 
@@ -149,4 +303,207 @@ func ReadCACertificate(name, path string) (*x509.Certificate, error) {
 
 	cert, err := x509.ParseCertificate(block.Bytes)
 	return cert, err
-}
\ No newline at end of file
+}
+
+// RenewCertificate asks the CA to reissue the given certificate with a fresh
+// validity period. The request is signed with the enrollment private key and
+// the renewed certificate replaces the one stored in the keystore.
+func RenewCertificate(priv *ecdsa.PrivateKey, cert *x509.Certificate, name, path string) (*x509.Certificate, error) {
+	name = certificateName(name)
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().Unix()
+	sign, err := SignRenewRequest(priv, cert.Raw, timestamp, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	sock, caClient, err := GetCAClient()
+	if err != nil {
+		return nil, err
+	}
+	defer sock.Close()
+
+	cooked := pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: cert.Raw,
+		})
+
+	req := &pb.RenewRequest{
+		Cert:	cooked,
+		Name:	name,
+		Sign:	sign,
+		Security:	SecuritySetting(),
+		Timestamp:	timestamp,
+		Nonce:	nonce}
+
+	resp, err := caClient.RenewCertificate(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("could not RenewCertificate: %v", err)
+	}
+
+	block, _ := pem.Decode(resp.In)
+	if block == nil {
+		return nil, fmt.Errorf("certificate data error.")
+	}
+
+	renewed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ioutil.WriteFile(path+"/cakeystore/"+name+".cert", resp.In, 0644); err != nil {
+		return nil, err
+	}
+	return renewed, nil
+}
+
+// TCert is a transaction certificate together with its private key and the
+// certificates of the intermediate CAs it was issued by.
+type TCert struct {
+	Cert          *x509.Certificate
+	Intermediates []*x509.Certificate
+	Priv          *ecdsa.PrivateKey
+}
+
+// IssueTCerts generates count fresh key pairs and asks the CA to certify
+// them as transaction certificates of the node enrolled with cert. The
+// private keys never leave the node.
+func IssueTCerts(priv *ecdsa.PrivateKey, cert *x509.Certificate, count int) ([]*TCert, error) {
+	if count <= 0 || count > MaxTCertBatch {
+		return nil, fmt.Errorf("between 1 and %d tcerts can be requested at once", MaxTCertBatch)
+	}
+
+	tcerts := make([]*TCert, count)
+	keys := make([][]byte, count)
+	for i := range tcerts {
+		key, err := primitives.NewECDSAKey()
+		if err != nil {
+			return nil, err
+		}
+		if keys[i], err = x509.MarshalPKIXPublicKey(&key.PublicKey); err != nil {
+			return nil, err
+		}
+		tcerts[i] = &TCert{Priv: key}
+	}
+
+	sign, err := SignTCertRequest(priv, cert.Raw, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := GetCertificateChain()
+	if err != nil {
+		return nil, err
+	}
+
+	sock, caClient, err := GetCAClient()
+	if err != nil {
+		return nil, err
+	}
+	defer sock.Close()
+
+	req := &pb.TCertRequest{
+		Cert:     EncodeCertificates(cert),
+		Keys:     keys,
+		Sign:     sign,
+		Security: SecuritySetting()}
+
+	resp, err := caClient.IssueTCerts(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("could not IssueTCerts: %v", err)
+	}
+	if len(resp.Certs) != count {
+		return nil, fmt.Errorf("CA returned %d tcerts, requested %d", len(resp.Certs), count)
+	}
+
+	for i, raw := range resp.Certs {
+		tcert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(tcert.RawSubjectPublicKeyInfo, keys[i]) {
+			return nil, fmt.Errorf("CA returned a tcert for the wrong key")
+		}
+		tcerts[i].Cert = tcert
+		tcerts[i].Intermediates = chain[:len(chain)-1]
+	}
+	return tcerts, nil
+}
+
+// ChangeNodeType asks the CA to reissue the active certificate with the
+// given common name with a new node type. The request is signed with the
+// admin certificate cert and its private key.
+func ChangeNodeType(priv *ecdsa.PrivateKey, cert *x509.Certificate, name string, nodetype NodeType) error {
+	sock, client, err := GetCAAdminClient()
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	cooked := pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: cert.Raw,
+		})
+
+	req := &pb.ChangeNodeTypeRequest{Auth: &pb.AdminAuth{}, Name: name, NodeType: int32(nodetype)}
+	if err := SignAdminRequest(req, req.Auth, cooked, priv); err != nil {
+		return err
+	}
+	if _, err := client.ChangeNodeType(context.Background(), req); err != nil {
+		return fmt.Errorf("could not ChangeNodeType: %v", err)
+	}
+	return nil
+}
+
+// GetWhitelist returns the networks and node ids allowed to join the network.
+func GetWhitelist() ([]string, []string, error) {
+	sock, client, err := GetWhitelistClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer sock.Close()
+
+	resp, err := client.GetWhitelist(context.Background(), &pb.NoParam{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not GetWhitelist: %v", err)
+	}
+	return resp.Ip, resp.NodeIds, nil
+}
+
+// WatchWhitelist passes the current whitelist and every later change of it to
+// update. It returns when the stream fails or quit is closed.
+func WatchWhitelist(quit <-chan struct{}, update func(cidrs, ids []string)) error {
+	sock, client, err := GetWhitelistClient()
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	stream, err := client.WatchWhitelist(ctx, &pb.NoParam{})
+	if err != nil {
+		return fmt.Errorf("could not WatchWhitelist: %v", err)
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		update(resp.Ip, resp.NodeIds)
+	}
+}
@@ -8,9 +8,12 @@
 package ca
 
 import (
+	"net"
 	"testing"
 	pb "github.com/ethereum/go-ethereum/crypto/caserver/ca/protos"
-	"github.com/ethereum/go-ethereum/Godeps/_workspace/src/golang.org/x/net/context"
+	"github.com/ethereum/go-ethereum/settings"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 )
 
 const (
@@ -96,5 +99,73 @@ func TestVerifySignature(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error executing test: %v", err)
 	}
-	t.Logf("VerifySignature: %s", resp.Valid)
-}
\ No newline at end of file
+	t.Logf("VerifySignature: %v", resp.Valid)
+}
+// replicaServer serves the replica count of one CA instance.
+type replicaServer struct {
+	pb.CAServer
+	ca *CA
+}
+
+func (s *replicaServer) GetReplicaCount(ctx context.Context, np *pb.NoParam) (*pb.ReplicaCount, error) {
+	count, err := s.ca.GetReplicaCount()
+	return &pb.ReplicaCount{Count: count}, err
+}
+
+// serveInstance serves ca on a local port and returns its address.
+func serveInstance(t *testing.T, ca *CA) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterCAServer(server, &replicaServer{ca: ca})
+	go server.Serve(listener)
+	return listener.Addr().String(), server.Stop
+}
+
+func TestClientFailover(t *testing.T) {
+	first, cleanup := newTestCA(t)
+	defer cleanup()
+	second := newCA(first.path, "Blockchain", InitializeCommonTables)
+	defer second.Stop()
+
+	// An address nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	down := listener.Addr().String()
+	listener.Close()
+
+	addr1, stop1 := serveInstance(t, first)
+	addr2, stop2 := serveInstance(t, second)
+	defer stop2()
+
+	conf := settings.Default()
+	conf.CAServer.Addresses = []string{down, addr1, addr2}
+	if err := Init(conf); err != nil {
+		t.Fatalf("failed to init crypto: %v", err)
+	}
+	defer Init(nil)
+
+	enroll(t, first, "node1", Validator)
+	if n, err := GetReplicaCount(); err != nil || n != 1 {
+		t.Fatalf("replica count mismatch: have %d (%v), want 1", n, err)
+	}
+	if addrs := caAddresses(); addrs[0] != addr1 {
+		t.Fatalf("answering instance not preferred: %v", addrs)
+	}
+
+	// The other instance takes over and sees the shared state
+	stop1()
+	enroll(t, first, "node2", Validator)
+	if n, err := GetReplicaCount(); err != nil || n != 2 {
+		t.Fatalf("replica count after failover mismatch: have %d (%v), want 2", n, err)
+	}
+
+	stop2()
+	if _, err := GetReplicaCount(); err == nil {
+		t.Fatal("call succeeded with all instances down")
+	}
+}
@@ -8,42 +8,51 @@
 package ca
 
 import (
-	"github.com/op/go-logging"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/settings"
 	"github.com/hyperledger/fabric/core/crypto/primitives"
-	"github.com/spf13/viper"
+	"github.com/op/go-logging"
 )
 
 var (
 	log = logging.MustGetLogger("crypto")
 )
 
-// Init initializes the crypto layer. It load from viper the security level
-// and the logging setting.
-func Init() (err error) {
-	// Init security level
-	securityLevel := 256
-	if viper.IsSet("security.level") {
-		ovveride := viper.GetInt("security.level")
-		if ovveride != 0 {
-			securityLevel = ovveride
-		}
-	}
+// current holds the settings passed to Init.
+var current struct {
+	sync.RWMutex
+	conf *settings.Config
+}
 
-	hashAlgorithm := "SHA3"
-	if viper.IsSet("security.hashAlgorithm") {
-		ovveride := viper.GetString("security.hashAlgorithm")
-		if ovveride != "" {
-			hashAlgorithm = ovveride
-		}
+// Init initializes the crypto layer with the security level and hash
+// algorithm of conf, and keeps the CA settings of conf for the CA client and
+// server. A nil conf selects the default settings.
+func Init(conf *settings.Config) (err error) {
+	if conf == nil {
+		conf = settings.Default()
 	}
+	log.Debugf("Working at security level [%d]", conf.Security.Level)
 
-	log.Debugf("Working at security level [%d]", securityLevel)
-
-	if err = primitives.InitSecurityLevel(hashAlgorithm, securityLevel); err != nil {
+	if err = primitives.InitSecurityLevel(conf.Security.HashAlgorithm, conf.Security.Level); err != nil {
 		log.Errorf("Failed setting security level: [%s]", err)
 
 		return
 	}
+	current.Lock()
+	current.conf = conf
+	current.Unlock()
 
 	return
 }
+
+// currentSettings returns the settings passed to Init, or the default ones.
+func currentSettings() *settings.Config {
+	current.RLock()
+	defer current.RUnlock()
+
+	if current.conf == nil {
+		return settings.Default()
+	}
+	return current.conf
+}
@@ -12,10 +12,24 @@ It has these top-level messages:
 	NoParam
 	IPList
 	CertificateRequest
+	RenewRequest
+	TCertRequest
+	TCertReply
 	CertificateReply
 	CertificateData
+	CertificateChain
 	SignatureValid
 	ReplicaCount
+	RevocationList
+	AdminAuth
+	ListCertificatesRequest
+	GetCertificateRequest
+	ChangeNodeTypeRequest
+	RevokeRequest
+	CertificateInfo
+	CertificateList
+	ListWhitelistRequest
+	WhitelistRequest
 */
 package protos
 
@@ -39,6 +53,30 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type CertificateStatus int32
+
+const (
+	CertificateStatus_ACTIVE     CertificateStatus = 0
+	CertificateStatus_REVOKED    CertificateStatus = 1
+	CertificateStatus_SUPERSEDED CertificateStatus = 2
+)
+
+var CertificateStatus_name = map[int32]string{
+	0: "ACTIVE",
+	1: "REVOKED",
+	2: "SUPERSEDED",
+}
+var CertificateStatus_value = map[string]int32{
+	"ACTIVE":     0,
+	"REVOKED":    1,
+	"SUPERSEDED": 2,
+}
+
+func (x CertificateStatus) String() string {
+	return proto.EnumName(CertificateStatus_name, int32(x))
+}
+func (CertificateStatus) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
 type NoParam struct {
 }
 
@@ -48,7 +86,8 @@ func (*NoParam) ProtoMessage()               {}
 func (*NoParam) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
 type IPList struct {
-	Ip []string `protobuf:"bytes,1,rep,name=ip" json:"ip,omitempty"`
+	Ip      []string `protobuf:"bytes,1,rep,name=ip" json:"ip,omitempty"`
+	NodeIds []string `protobuf:"bytes,2,rep,name=node_ids,json=nodeIds" json:"node_ids,omitempty"`
 }
 
 func (m *IPList) Reset()                    { *m = IPList{} }
@@ -63,9 +102,17 @@ func (m *IPList) GetIp() []string {
 	return nil
 }
 
+func (m *IPList) GetNodeIds() []string {
+	if m != nil {
+		return m.NodeIds
+	}
+	return nil
+}
+
 type CertificateRequest struct {
-	In   []byte `protobuf:"bytes,1,opt,name=in,proto3" json:"in,omitempty"`
-	Name string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	In       []byte `protobuf:"bytes,1,opt,name=in,proto3" json:"in,omitempty"`
+	Name     string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Security string `protobuf:"bytes,3,opt,name=security" json:"security,omitempty"`
 }
 
 func (m *CertificateRequest) Reset()                    { *m = CertificateRequest{} }
@@ -87,6 +134,125 @@ func (m *CertificateRequest) GetName() string {
 	return ""
 }
 
+func (m *CertificateRequest) GetSecurity() string {
+	if m != nil {
+		return m.Security
+	}
+	return ""
+}
+
+type RenewRequest struct {
+	Cert      []byte `protobuf:"bytes,1,opt,name=cert,proto3" json:"cert,omitempty"`
+	Name      string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Sign      []byte `protobuf:"bytes,3,opt,name=sign,proto3" json:"sign,omitempty"`
+	Security  string `protobuf:"bytes,4,opt,name=security" json:"security,omitempty"`
+	Timestamp int64  `protobuf:"varint,5,opt,name=timestamp" json:"timestamp,omitempty"`
+	Nonce     []byte `protobuf:"bytes,6,opt,name=nonce,proto3" json:"nonce,omitempty"`
+}
+
+func (m *RenewRequest) Reset()                    { *m = RenewRequest{} }
+func (m *RenewRequest) String() string            { return proto.CompactTextString(m) }
+func (*RenewRequest) ProtoMessage()               {}
+func (*RenewRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *RenewRequest) GetCert() []byte {
+	if m != nil {
+		return m.Cert
+	}
+	return nil
+}
+
+func (m *RenewRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RenewRequest) GetSign() []byte {
+	if m != nil {
+		return m.Sign
+	}
+	return nil
+}
+
+func (m *RenewRequest) GetSecurity() string {
+	if m != nil {
+		return m.Security
+	}
+	return ""
+}
+
+func (m *RenewRequest) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *RenewRequest) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
+}
+
+type TCertRequest struct {
+	Cert     []byte   `protobuf:"bytes,1,opt,name=cert,proto3" json:"cert,omitempty"`
+	Keys     [][]byte `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	Sign     []byte   `protobuf:"bytes,3,opt,name=sign,proto3" json:"sign,omitempty"`
+	Security string   `protobuf:"bytes,4,opt,name=security" json:"security,omitempty"`
+}
+
+func (m *TCertRequest) Reset()                    { *m = TCertRequest{} }
+func (m *TCertRequest) String() string            { return proto.CompactTextString(m) }
+func (*TCertRequest) ProtoMessage()               {}
+func (*TCertRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *TCertRequest) GetCert() []byte {
+	if m != nil {
+		return m.Cert
+	}
+	return nil
+}
+
+func (m *TCertRequest) GetKeys() [][]byte {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *TCertRequest) GetSign() []byte {
+	if m != nil {
+		return m.Sign
+	}
+	return nil
+}
+
+func (m *TCertRequest) GetSecurity() string {
+	if m != nil {
+		return m.Security
+	}
+	return ""
+}
+
+type TCertReply struct {
+	Certs [][]byte `protobuf:"bytes,1,rep,name=certs,proto3" json:"certs,omitempty"`
+}
+
+func (m *TCertReply) Reset()                    { *m = TCertReply{} }
+func (m *TCertReply) String() string            { return proto.CompactTextString(m) }
+func (*TCertReply) ProtoMessage()               {}
+func (*TCertReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *TCertReply) GetCerts() [][]byte {
+	if m != nil {
+		return m.Certs
+	}
+	return nil
+}
+
 type CertificateReply struct {
 	In []byte `protobuf:"bytes,1,opt,name=in,proto3" json:"in,omitempty"`
 }
@@ -94,7 +260,7 @@ type CertificateReply struct {
 func (m *CertificateReply) Reset()                    { *m = CertificateReply{} }
 func (m *CertificateReply) String() string            { return proto.CompactTextString(m) }
 func (*CertificateReply) ProtoMessage()               {}
-func (*CertificateReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*CertificateReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 func (m *CertificateReply) GetIn() []byte {
 	if m != nil {
@@ -111,7 +277,7 @@ type CertificateData struct {
 func (m *CertificateData) Reset()                    { *m = CertificateData{} }
 func (m *CertificateData) String() string            { return proto.CompactTextString(m) }
 func (*CertificateData) ProtoMessage()               {}
-func (*CertificateData) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*CertificateData) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
 
 func (m *CertificateData) GetCert() []byte {
 	if m != nil {
@@ -127,6 +293,22 @@ func (m *CertificateData) GetRoot() []byte {
 	return nil
 }
 
+type CertificateChain struct {
+	Certs [][]byte `protobuf:"bytes,1,rep,name=certs,proto3" json:"certs,omitempty"`
+}
+
+func (m *CertificateChain) Reset()                    { *m = CertificateChain{} }
+func (m *CertificateChain) String() string            { return proto.CompactTextString(m) }
+func (*CertificateChain) ProtoMessage()               {}
+func (*CertificateChain) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *CertificateChain) GetCerts() [][]byte {
+	if m != nil {
+		return m.Certs
+	}
+	return nil
+}
+
 type SignatureValid struct {
 	Valid bool `protobuf:"varint,1,opt,name=valid" json:"valid,omitempty"`
 }
@@ -134,7 +316,7 @@ type SignatureValid struct {
 func (m *SignatureValid) Reset()                    { *m = SignatureValid{} }
 func (m *SignatureValid) String() string            { return proto.CompactTextString(m) }
 func (*SignatureValid) ProtoMessage()               {}
-func (*SignatureValid) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*SignatureValid) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
 
 func (m *SignatureValid) GetValid() bool {
 	if m != nil {
@@ -150,7 +332,7 @@ type ReplicaCount struct {
 func (m *ReplicaCount) Reset()                    { *m = ReplicaCount{} }
 func (m *ReplicaCount) String() string            { return proto.CompactTextString(m) }
 func (*ReplicaCount) ProtoMessage()               {}
-func (*ReplicaCount) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*ReplicaCount) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
 
 func (m *ReplicaCount) GetCount() uint32 {
 	if m != nil {
@@ -159,160 +341,608 @@ func (m *ReplicaCount) GetCount() uint32 {
 	return 0
 }
 
-func init() {
-	proto.RegisterType((*NoParam)(nil), "protos.NoParam")
-	proto.RegisterType((*IPList)(nil), "protos.IPList")
-	proto.RegisterType((*CertificateRequest)(nil), "protos.CertificateRequest")
-	proto.RegisterType((*CertificateReply)(nil), "protos.CertificateReply")
-	proto.RegisterType((*CertificateData)(nil), "protos.CertificateData")
-	proto.RegisterType((*SignatureValid)(nil), "protos.SignatureValid")
-	proto.RegisterType((*ReplicaCount)(nil), "protos.ReplicaCount")
+type RevocationList struct {
+	Crl []byte `protobuf:"bytes,1,opt,name=crl,proto3" json:"crl,omitempty"`
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-
-// Client API for Whitelist service
+func (m *RevocationList) Reset()                    { *m = RevocationList{} }
+func (m *RevocationList) String() string            { return proto.CompactTextString(m) }
+func (*RevocationList) ProtoMessage()               {}
+func (*RevocationList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
 
-type WhitelistClient interface {
-	GetWhitelist(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*IPList, error)
+func (m *RevocationList) GetCrl() []byte {
+	if m != nil {
+		return m.Crl
+	}
+	return nil
 }
 
-type whitelistClient struct {
-	cc *grpc.ClientConn
+type AdminAuth struct {
+	Cert      []byte `protobuf:"bytes,1,opt,name=cert,proto3" json:"cert,omitempty"`
+	Timestamp int64  `protobuf:"varint,2,opt,name=timestamp" json:"timestamp,omitempty"`
+	Sign      []byte `protobuf:"bytes,3,opt,name=sign,proto3" json:"sign,omitempty"`
+	Nonce     []byte `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
 }
 
-func NewWhitelistClient(cc *grpc.ClientConn) WhitelistClient {
-	return &whitelistClient{cc}
+func (m *AdminAuth) Reset()                    { *m = AdminAuth{} }
+func (m *AdminAuth) String() string            { return proto.CompactTextString(m) }
+func (*AdminAuth) ProtoMessage()               {}
+func (*AdminAuth) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *AdminAuth) GetCert() []byte {
+	if m != nil {
+		return m.Cert
+	}
+	return nil
 }
 
-func (c *whitelistClient) GetWhitelist(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*IPList, error) {
-	out := new(IPList)
-	err := grpc.Invoke(ctx, "/protos.Whitelist/GetWhitelist", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *AdminAuth) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
 	}
-	return out, nil
+	return 0
 }
 
-// Server API for Whitelist service
+func (m *AdminAuth) GetSign() []byte {
+	if m != nil {
+		return m.Sign
+	}
+	return nil
+}
 
-type WhitelistServer interface {
-	GetWhitelist(context.Context, *NoParam) (*IPList, error)
+func (m *AdminAuth) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
 }
 
-func RegisterWhitelistServer(s *grpc.Server, srv WhitelistServer) {
-	s.RegisterService(&_Whitelist_serviceDesc, srv)
+type ListCertificatesRequest struct {
+	Auth      *AdminAuth          `protobuf:"bytes,1,opt,name=auth" json:"auth,omitempty"`
+	NodeTypes []int32             `protobuf:"varint,2,rep,packed,name=node_types,json=nodeTypes" json:"node_types,omitempty"`
+	Statuses  []CertificateStatus `protobuf:"varint,3,rep,packed,name=statuses,enum=protos.CertificateStatus" json:"statuses,omitempty"`
 }
 
-func _Whitelist_GetWhitelist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(NoParam)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(WhitelistServer).GetWhitelist(ctx, in)
+func (m *ListCertificatesRequest) Reset()                    { *m = ListCertificatesRequest{} }
+func (m *ListCertificatesRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListCertificatesRequest) ProtoMessage()               {}
+func (*ListCertificatesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *ListCertificatesRequest) GetAuth() *AdminAuth {
+	if m != nil {
+		return m.Auth
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/protos.Whitelist/GetWhitelist",
+	return nil
+}
+
+func (m *ListCertificatesRequest) GetNodeTypes() []int32 {
+	if m != nil {
+		return m.NodeTypes
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WhitelistServer).GetWhitelist(ctx, req.(*NoParam))
+	return nil
+}
+
+func (m *ListCertificatesRequest) GetStatuses() []CertificateStatus {
+	if m != nil {
+		return m.Statuses
 	}
-	return interceptor(ctx, in, info, handler)
+	return nil
 }
 
-var _Whitelist_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "protos.Whitelist",
-	HandlerType: (*WhitelistServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "GetWhitelist",
-			Handler:    _Whitelist_GetWhitelist_Handler,
-		},
-	},
-	Streams:  []grpc.StreamDesc{},
-	Metadata: "ca.proto",
+type GetCertificateRequest struct {
+	Auth   *AdminAuth `protobuf:"bytes,1,opt,name=auth" json:"auth,omitempty"`
+	Name   string     `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Serial string     `protobuf:"bytes,3,opt,name=serial" json:"serial,omitempty"`
 }
 
-// Client API for CA service
+func (m *GetCertificateRequest) Reset()                    { *m = GetCertificateRequest{} }
+func (m *GetCertificateRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetCertificateRequest) ProtoMessage()               {}
+func (*GetCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
 
-type CAClient interface {
-	IssueCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*CertificateReply, error)
-	GetCACertificate(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*CertificateReply, error)
-	VerifySignature(ctx context.Context, in *CertificateData, opts ...grpc.CallOption) (*SignatureValid, error)
-	GetReplicaCount(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*ReplicaCount, error)
+func (m *GetCertificateRequest) GetAuth() *AdminAuth {
+	if m != nil {
+		return m.Auth
+	}
+	return nil
 }
 
-type cAClient struct {
-	cc *grpc.ClientConn
+func (m *GetCertificateRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
 }
 
-func NewCAClient(cc *grpc.ClientConn) CAClient {
-	return &cAClient{cc}
+func (m *GetCertificateRequest) GetSerial() string {
+	if m != nil {
+		return m.Serial
+	}
+	return ""
 }
 
-func (c *cAClient) IssueCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*CertificateReply, error) {
-	out := new(CertificateReply)
-	err := grpc.Invoke(ctx, "/protos.CA/IssueCertificate", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+type ChangeNodeTypeRequest struct {
+	Auth     *AdminAuth `protobuf:"bytes,1,opt,name=auth" json:"auth,omitempty"`
+	Name     string     `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	NodeType int32      `protobuf:"varint,3,opt,name=node_type,json=nodeType" json:"node_type,omitempty"`
 }
 
-func (c *cAClient) GetCACertificate(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*CertificateReply, error) {
-	out := new(CertificateReply)
-	err := grpc.Invoke(ctx, "/protos.CA/GetCACertificate", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *ChangeNodeTypeRequest) Reset()                    { *m = ChangeNodeTypeRequest{} }
+func (m *ChangeNodeTypeRequest) String() string            { return proto.CompactTextString(m) }
+func (*ChangeNodeTypeRequest) ProtoMessage()               {}
+func (*ChangeNodeTypeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *ChangeNodeTypeRequest) GetAuth() *AdminAuth {
+	if m != nil {
+		return m.Auth
 	}
-	return out, nil
+	return nil
 }
 
-func (c *cAClient) VerifySignature(ctx context.Context, in *CertificateData, opts ...grpc.CallOption) (*SignatureValid, error) {
-	out := new(SignatureValid)
-	err := grpc.Invoke(ctx, "/protos.CA/VerifySignature", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *ChangeNodeTypeRequest) GetName() string {
+	if m != nil {
+		return m.Name
 	}
-	return out, nil
+	return ""
 }
 
-func (c *cAClient) GetReplicaCount(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*ReplicaCount, error) {
-	out := new(ReplicaCount)
-	err := grpc.Invoke(ctx, "/protos.CA/GetReplicaCount", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *ChangeNodeTypeRequest) GetNodeType() int32 {
+	if m != nil {
+		return m.NodeType
 	}
-	return out, nil
+	return 0
 }
 
-// Server API for CA service
-
-type CAServer interface {
-	IssueCertificate(context.Context, *CertificateRequest) (*CertificateReply, error)
-	GetCACertificate(context.Context, *NoParam) (*CertificateReply, error)
-	VerifySignature(context.Context, *CertificateData) (*SignatureValid, error)
-	GetReplicaCount(context.Context, *NoParam) (*ReplicaCount, error)
+type RevokeRequest struct {
+	Auth   *AdminAuth `protobuf:"bytes,1,opt,name=auth" json:"auth,omitempty"`
+	Name   string     `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Serial string     `protobuf:"bytes,3,opt,name=serial" json:"serial,omitempty"`
 }
 
-func RegisterCAServer(s *grpc.Server, srv CAServer) {
-	s.RegisterService(&_CA_serviceDesc, srv)
+func (m *RevokeRequest) Reset()                    { *m = RevokeRequest{} }
+func (m *RevokeRequest) String() string            { return proto.CompactTextString(m) }
+func (*RevokeRequest) ProtoMessage()               {}
+func (*RevokeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *RevokeRequest) GetAuth() *AdminAuth {
+	if m != nil {
+		return m.Auth
+	}
+	return nil
 }
 
-func _CA_IssueCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CertificateRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *RevokeRequest) GetName() string {
+	if m != nil {
+		return m.Name
 	}
-	if interceptor == nil {
-		return srv.(CAServer).IssueCertificate(ctx, in)
+	return ""
+}
+
+func (m *RevokeRequest) GetSerial() string {
+	if m != nil {
+		return m.Serial
+	}
+	return ""
+}
+
+type CertificateInfo struct {
+	Serial       string            `protobuf:"bytes,1,opt,name=serial" json:"serial,omitempty"`
+	Name         string            `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	NodeType     int32             `protobuf:"varint,3,opt,name=node_type,json=nodeType" json:"node_type,omitempty"`
+	PeerId       uint32            `protobuf:"varint,4,opt,name=peer_id,json=peerId" json:"peer_id,omitempty"`
+	Status       CertificateStatus `protobuf:"varint,5,opt,name=status,enum=protos.CertificateStatus" json:"status,omitempty"`
+	NotBefore    int64             `protobuf:"varint,6,opt,name=not_before,json=notBefore" json:"not_before,omitempty"`
+	NotAfter     int64             `protobuf:"varint,7,opt,name=not_after,json=notAfter" json:"not_after,omitempty"`
+	Cert         []byte            `protobuf:"bytes,8,opt,name=cert,proto3" json:"cert,omitempty"`
+	Organization string            `protobuf:"bytes,9,opt,name=organization" json:"organization,omitempty"`
+}
+
+func (m *CertificateInfo) Reset()                    { *m = CertificateInfo{} }
+func (m *CertificateInfo) String() string            { return proto.CompactTextString(m) }
+func (*CertificateInfo) ProtoMessage()               {}
+func (*CertificateInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *CertificateInfo) GetSerial() string {
+	if m != nil {
+		return m.Serial
+	}
+	return ""
+}
+
+func (m *CertificateInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CertificateInfo) GetNodeType() int32 {
+	if m != nil {
+		return m.NodeType
+	}
+	return 0
+}
+
+func (m *CertificateInfo) GetPeerId() uint32 {
+	if m != nil {
+		return m.PeerId
+	}
+	return 0
+}
+
+func (m *CertificateInfo) GetStatus() CertificateStatus {
+	if m != nil {
+		return m.Status
+	}
+	return CertificateStatus_ACTIVE
+}
+
+func (m *CertificateInfo) GetNotBefore() int64 {
+	if m != nil {
+		return m.NotBefore
+	}
+	return 0
+}
+
+func (m *CertificateInfo) GetNotAfter() int64 {
+	if m != nil {
+		return m.NotAfter
+	}
+	return 0
+}
+
+func (m *CertificateInfo) GetCert() []byte {
+	if m != nil {
+		return m.Cert
+	}
+	return nil
+}
+
+func (m *CertificateInfo) GetOrganization() string {
+	if m != nil {
+		return m.Organization
+	}
+	return ""
+}
+
+type CertificateList struct {
+	Certificates []*CertificateInfo `protobuf:"bytes,1,rep,name=certificates" json:"certificates,omitempty"`
+}
+
+func (m *CertificateList) Reset()                    { *m = CertificateList{} }
+func (m *CertificateList) String() string            { return proto.CompactTextString(m) }
+func (*CertificateList) ProtoMessage()               {}
+func (*CertificateList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *CertificateList) GetCertificates() []*CertificateInfo {
+	if m != nil {
+		return m.Certificates
+	}
+	return nil
+}
+
+type ListWhitelistRequest struct {
+	Auth *AdminAuth `protobuf:"bytes,1,opt,name=auth" json:"auth,omitempty"`
+}
+
+func (m *ListWhitelistRequest) Reset()                    { *m = ListWhitelistRequest{} }
+func (m *ListWhitelistRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListWhitelistRequest) ProtoMessage()               {}
+func (*ListWhitelistRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *ListWhitelistRequest) GetAuth() *AdminAuth {
+	if m != nil {
+		return m.Auth
+	}
+	return nil
+}
+
+type WhitelistRequest struct {
+	Auth    *AdminAuth `protobuf:"bytes,1,opt,name=auth" json:"auth,omitempty"`
+	Ip      []string   `protobuf:"bytes,2,rep,name=ip" json:"ip,omitempty"`
+	NodeIds []string   `protobuf:"bytes,3,rep,name=node_ids,json=nodeIds" json:"node_ids,omitempty"`
+}
+
+func (m *WhitelistRequest) Reset()                    { *m = WhitelistRequest{} }
+func (m *WhitelistRequest) String() string            { return proto.CompactTextString(m) }
+func (*WhitelistRequest) ProtoMessage()               {}
+func (*WhitelistRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *WhitelistRequest) GetAuth() *AdminAuth {
+	if m != nil {
+		return m.Auth
+	}
+	return nil
+}
+
+func (m *WhitelistRequest) GetIp() []string {
+	if m != nil {
+		return m.Ip
+	}
+	return nil
+}
+
+func (m *WhitelistRequest) GetNodeIds() []string {
+	if m != nil {
+		return m.NodeIds
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*NoParam)(nil), "protos.NoParam")
+	proto.RegisterType((*IPList)(nil), "protos.IPList")
+	proto.RegisterType((*CertificateRequest)(nil), "protos.CertificateRequest")
+	proto.RegisterType((*RenewRequest)(nil), "protos.RenewRequest")
+	proto.RegisterType((*TCertRequest)(nil), "protos.TCertRequest")
+	proto.RegisterType((*TCertReply)(nil), "protos.TCertReply")
+	proto.RegisterType((*CertificateReply)(nil), "protos.CertificateReply")
+	proto.RegisterType((*CertificateData)(nil), "protos.CertificateData")
+	proto.RegisterType((*CertificateChain)(nil), "protos.CertificateChain")
+	proto.RegisterType((*SignatureValid)(nil), "protos.SignatureValid")
+	proto.RegisterType((*ReplicaCount)(nil), "protos.ReplicaCount")
+	proto.RegisterType((*RevocationList)(nil), "protos.RevocationList")
+	proto.RegisterType((*AdminAuth)(nil), "protos.AdminAuth")
+	proto.RegisterType((*ListCertificatesRequest)(nil), "protos.ListCertificatesRequest")
+	proto.RegisterType((*GetCertificateRequest)(nil), "protos.GetCertificateRequest")
+	proto.RegisterType((*ChangeNodeTypeRequest)(nil), "protos.ChangeNodeTypeRequest")
+	proto.RegisterType((*RevokeRequest)(nil), "protos.RevokeRequest")
+	proto.RegisterType((*CertificateInfo)(nil), "protos.CertificateInfo")
+	proto.RegisterType((*CertificateList)(nil), "protos.CertificateList")
+	proto.RegisterType((*ListWhitelistRequest)(nil), "protos.ListWhitelistRequest")
+	proto.RegisterType((*WhitelistRequest)(nil), "protos.WhitelistRequest")
+	proto.RegisterEnum("protos.CertificateStatus", CertificateStatus_name, CertificateStatus_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+
+// Client API for Whitelist service
+
+type WhitelistClient interface {
+	GetWhitelist(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*IPList, error)
+	WatchWhitelist(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (Whitelist_WatchWhitelistClient, error)
+}
+
+type whitelistClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewWhitelistClient(cc *grpc.ClientConn) WhitelistClient {
+	return &whitelistClient{cc}
+}
+
+func (c *whitelistClient) GetWhitelist(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*IPList, error) {
+	out := new(IPList)
+	err := grpc.Invoke(ctx, "/protos.Whitelist/GetWhitelist", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *whitelistClient) WatchWhitelist(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (Whitelist_WatchWhitelistClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Whitelist_serviceDesc.Streams[0], c.cc, "/protos.Whitelist/WatchWhitelist", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &whitelistWatchWhitelistClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Whitelist_WatchWhitelistClient interface {
+	Recv() (*IPList, error)
+	grpc.ClientStream
+}
+
+type whitelistWatchWhitelistClient struct {
+	grpc.ClientStream
+}
+
+func (x *whitelistWatchWhitelistClient) Recv() (*IPList, error) {
+	m := new(IPList)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Whitelist service
+
+type WhitelistServer interface {
+	GetWhitelist(context.Context, *NoParam) (*IPList, error)
+	WatchWhitelist(*NoParam, Whitelist_WatchWhitelistServer) error
+}
+
+func RegisterWhitelistServer(s *grpc.Server, srv WhitelistServer) {
+	s.RegisterService(&_Whitelist_serviceDesc, srv)
+}
+
+func _Whitelist_GetWhitelist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NoParam)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WhitelistServer).GetWhitelist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.Whitelist/GetWhitelist",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WhitelistServer).GetWhitelist(ctx, req.(*NoParam))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Whitelist_WatchWhitelist_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(NoParam)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WhitelistServer).WatchWhitelist(m, &whitelistWatchWhitelistServer{stream})
+}
+
+type Whitelist_WatchWhitelistServer interface {
+	Send(*IPList) error
+	grpc.ServerStream
+}
+
+type whitelistWatchWhitelistServer struct {
+	grpc.ServerStream
+}
+
+func (x *whitelistWatchWhitelistServer) Send(m *IPList) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Whitelist_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "protos.Whitelist",
+	HandlerType: (*WhitelistServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetWhitelist",
+			Handler:    _Whitelist_GetWhitelist_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchWhitelist",
+			Handler:       _Whitelist_WatchWhitelist_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "ca.proto",
+}
+
+// Client API for CA service
+
+type CAClient interface {
+	IssueCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*CertificateReply, error)
+	GetCACertificate(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*CertificateReply, error)
+	VerifySignature(ctx context.Context, in *CertificateData, opts ...grpc.CallOption) (*SignatureValid, error)
+	GetReplicaCount(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*ReplicaCount, error)
+	RenewCertificate(ctx context.Context, in *RenewRequest, opts ...grpc.CallOption) (*CertificateReply, error)
+	GetCertificateChain(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*CertificateChain, error)
+	IssueTCerts(ctx context.Context, in *TCertRequest, opts ...grpc.CallOption) (*TCertReply, error)
+	GetRevocationList(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*RevocationList, error)
+}
+
+type cAClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewCAClient(cc *grpc.ClientConn) CAClient {
+	return &cAClient{cc}
+}
+
+func (c *cAClient) IssueCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*CertificateReply, error) {
+	out := new(CertificateReply)
+	err := grpc.Invoke(ctx, "/protos.CA/IssueCertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAClient) GetCACertificate(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*CertificateReply, error) {
+	out := new(CertificateReply)
+	err := grpc.Invoke(ctx, "/protos.CA/GetCACertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAClient) VerifySignature(ctx context.Context, in *CertificateData, opts ...grpc.CallOption) (*SignatureValid, error) {
+	out := new(SignatureValid)
+	err := grpc.Invoke(ctx, "/protos.CA/VerifySignature", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAClient) GetReplicaCount(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*ReplicaCount, error) {
+	out := new(ReplicaCount)
+	err := grpc.Invoke(ctx, "/protos.CA/GetReplicaCount", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAClient) RenewCertificate(ctx context.Context, in *RenewRequest, opts ...grpc.CallOption) (*CertificateReply, error) {
+	out := new(CertificateReply)
+	err := grpc.Invoke(ctx, "/protos.CA/RenewCertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAClient) GetCertificateChain(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*CertificateChain, error) {
+	out := new(CertificateChain)
+	err := grpc.Invoke(ctx, "/protos.CA/GetCertificateChain", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAClient) IssueTCerts(ctx context.Context, in *TCertRequest, opts ...grpc.CallOption) (*TCertReply, error) {
+	out := new(TCertReply)
+	err := grpc.Invoke(ctx, "/protos.CA/IssueTCerts", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAClient) GetRevocationList(ctx context.Context, in *NoParam, opts ...grpc.CallOption) (*RevocationList, error) {
+	out := new(RevocationList)
+	err := grpc.Invoke(ctx, "/protos.CA/GetRevocationList", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for CA service
+
+type CAServer interface {
+	IssueCertificate(context.Context, *CertificateRequest) (*CertificateReply, error)
+	GetCACertificate(context.Context, *NoParam) (*CertificateReply, error)
+	VerifySignature(context.Context, *CertificateData) (*SignatureValid, error)
+	GetReplicaCount(context.Context, *NoParam) (*ReplicaCount, error)
+	RenewCertificate(context.Context, *RenewRequest) (*CertificateReply, error)
+	GetCertificateChain(context.Context, *NoParam) (*CertificateChain, error)
+	IssueTCerts(context.Context, *TCertRequest) (*TCertReply, error)
+	GetRevocationList(context.Context, *NoParam) (*RevocationList, error)
+}
+
+func RegisterCAServer(s *grpc.Server, srv CAServer) {
+	s.RegisterService(&_CA_serviceDesc, srv)
+}
+
+func _CA_IssueCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAServer).IssueCertificate(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
@@ -378,6 +1008,78 @@ func _CA_GetReplicaCount_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CA_RenewCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAServer).RenewCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CA/RenewCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAServer).RenewCertificate(ctx, req.(*RenewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CA_GetCertificateChain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NoParam)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAServer).GetCertificateChain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CA/GetCertificateChain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAServer).GetCertificateChain(ctx, req.(*NoParam))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CA_IssueTCerts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TCertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAServer).IssueTCerts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CA/IssueTCerts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAServer).IssueTCerts(ctx, req.(*TCertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CA_GetRevocationList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NoParam)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAServer).GetRevocationList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CA/GetRevocationList",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAServer).GetRevocationList(ctx, req.(*NoParam))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _CA_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.CA",
 	HandlerType: (*CAServer)(nil),
@@ -398,6 +1100,284 @@ var _CA_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetReplicaCount",
 			Handler:    _CA_GetReplicaCount_Handler,
 		},
+		{
+			MethodName: "RenewCertificate",
+			Handler:    _CA_RenewCertificate_Handler,
+		},
+		{
+			MethodName: "GetCertificateChain",
+			Handler:    _CA_GetCertificateChain_Handler,
+		},
+		{
+			MethodName: "IssueTCerts",
+			Handler:    _CA_IssueTCerts_Handler,
+		},
+		{
+			MethodName: "GetRevocationList",
+			Handler:    _CA_GetRevocationList_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ca.proto",
+}
+
+// Client API for CAAdmin service
+
+type CAAdminClient interface {
+	ListCertificates(ctx context.Context, in *ListCertificatesRequest, opts ...grpc.CallOption) (*CertificateList, error)
+	GetCertificate(ctx context.Context, in *GetCertificateRequest, opts ...grpc.CallOption) (*CertificateInfo, error)
+	ChangeNodeType(ctx context.Context, in *ChangeNodeTypeRequest, opts ...grpc.CallOption) (*CertificateInfo, error)
+	Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*CertificateInfo, error)
+	ListWhitelist(ctx context.Context, in *ListWhitelistRequest, opts ...grpc.CallOption) (*IPList, error)
+	AddWhitelist(ctx context.Context, in *WhitelistRequest, opts ...grpc.CallOption) (*IPList, error)
+	RemoveWhitelist(ctx context.Context, in *WhitelistRequest, opts ...grpc.CallOption) (*IPList, error)
+}
+
+type cAAdminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewCAAdminClient(cc *grpc.ClientConn) CAAdminClient {
+	return &cAAdminClient{cc}
+}
+
+func (c *cAAdminClient) ListCertificates(ctx context.Context, in *ListCertificatesRequest, opts ...grpc.CallOption) (*CertificateList, error) {
+	out := new(CertificateList)
+	err := grpc.Invoke(ctx, "/protos.CAAdmin/ListCertificates", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAAdminClient) GetCertificate(ctx context.Context, in *GetCertificateRequest, opts ...grpc.CallOption) (*CertificateInfo, error) {
+	out := new(CertificateInfo)
+	err := grpc.Invoke(ctx, "/protos.CAAdmin/GetCertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAAdminClient) ChangeNodeType(ctx context.Context, in *ChangeNodeTypeRequest, opts ...grpc.CallOption) (*CertificateInfo, error) {
+	out := new(CertificateInfo)
+	err := grpc.Invoke(ctx, "/protos.CAAdmin/ChangeNodeType", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAAdminClient) Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*CertificateInfo, error) {
+	out := new(CertificateInfo)
+	err := grpc.Invoke(ctx, "/protos.CAAdmin/Revoke", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAAdminClient) ListWhitelist(ctx context.Context, in *ListWhitelistRequest, opts ...grpc.CallOption) (*IPList, error) {
+	out := new(IPList)
+	err := grpc.Invoke(ctx, "/protos.CAAdmin/ListWhitelist", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAAdminClient) AddWhitelist(ctx context.Context, in *WhitelistRequest, opts ...grpc.CallOption) (*IPList, error) {
+	out := new(IPList)
+	err := grpc.Invoke(ctx, "/protos.CAAdmin/AddWhitelist", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cAAdminClient) RemoveWhitelist(ctx context.Context, in *WhitelistRequest, opts ...grpc.CallOption) (*IPList, error) {
+	out := new(IPList)
+	err := grpc.Invoke(ctx, "/protos.CAAdmin/RemoveWhitelist", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for CAAdmin service
+
+type CAAdminServer interface {
+	ListCertificates(context.Context, *ListCertificatesRequest) (*CertificateList, error)
+	GetCertificate(context.Context, *GetCertificateRequest) (*CertificateInfo, error)
+	ChangeNodeType(context.Context, *ChangeNodeTypeRequest) (*CertificateInfo, error)
+	Revoke(context.Context, *RevokeRequest) (*CertificateInfo, error)
+	ListWhitelist(context.Context, *ListWhitelistRequest) (*IPList, error)
+	AddWhitelist(context.Context, *WhitelistRequest) (*IPList, error)
+	RemoveWhitelist(context.Context, *WhitelistRequest) (*IPList, error)
+}
+
+func RegisterCAAdminServer(s *grpc.Server, srv CAAdminServer) {
+	s.RegisterService(&_CAAdmin_serviceDesc, srv)
+}
+
+func _CAAdmin_ListCertificates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCertificatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAAdminServer).ListCertificates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CAAdmin/ListCertificates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAAdminServer).ListCertificates(ctx, req.(*ListCertificatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CAAdmin_GetCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAAdminServer).GetCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CAAdmin/GetCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAAdminServer).GetCertificate(ctx, req.(*GetCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CAAdmin_ChangeNodeType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeNodeTypeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAAdminServer).ChangeNodeType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CAAdmin/ChangeNodeType",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAAdminServer).ChangeNodeType(ctx, req.(*ChangeNodeTypeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CAAdmin_Revoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAAdminServer).Revoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CAAdmin/Revoke",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAAdminServer).Revoke(ctx, req.(*RevokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CAAdmin_ListWhitelist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWhitelistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAAdminServer).ListWhitelist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CAAdmin/ListWhitelist",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAAdminServer).ListWhitelist(ctx, req.(*ListWhitelistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CAAdmin_AddWhitelist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WhitelistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAAdminServer).AddWhitelist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CAAdmin/AddWhitelist",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAAdminServer).AddWhitelist(ctx, req.(*WhitelistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CAAdmin_RemoveWhitelist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WhitelistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CAAdminServer).RemoveWhitelist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.CAAdmin/RemoveWhitelist",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CAAdminServer).RemoveWhitelist(ctx, req.(*WhitelistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _CAAdmin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "protos.CAAdmin",
+	HandlerType: (*CAAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListCertificates",
+			Handler:    _CAAdmin_ListCertificates_Handler,
+		},
+		{
+			MethodName: "GetCertificate",
+			Handler:    _CAAdmin_GetCertificate_Handler,
+		},
+		{
+			MethodName: "ChangeNodeType",
+			Handler:    _CAAdmin_ChangeNodeType_Handler,
+		},
+		{
+			MethodName: "Revoke",
+			Handler:    _CAAdmin_Revoke_Handler,
+		},
+		{
+			MethodName: "ListWhitelist",
+			Handler:    _CAAdmin_ListWhitelist_Handler,
+		},
+		{
+			MethodName: "AddWhitelist",
+			Handler:    _CAAdmin_AddWhitelist_Handler,
+		},
+		{
+			MethodName: "RemoveWhitelist",
+			Handler:    _CAAdmin_RemoveWhitelist_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "ca.proto",
@@ -406,27 +1386,69 @@ var _CA_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("ca.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 341 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x7c, 0x92, 0x41, 0x4b, 0xf3, 0x40,
-	0x10, 0x86, 0x93, 0x7c, 0x9f, 0xb5, 0x19, 0x62, 0x53, 0x96, 0xa2, 0x21, 0xa7, 0xb2, 0x88, 0xf4,
-	0x54, 0xb0, 0x5e, 0xd4, 0x83, 0x52, 0x52, 0x28, 0x15, 0x91, 0xb2, 0x42, 0x3d, 0xaf, 0x71, 0xab,
-	0x0b, 0x69, 0x12, 0x37, 0x13, 0xa1, 0xbf, 0xc3, 0x3f, 0x2c, 0xbb, 0x69, 0x34, 0xb5, 0xc5, 0x53,
-	0xde, 0x99, 0x7d, 0x66, 0xb2, 0x79, 0x08, 0xb4, 0x63, 0x3e, 0xcc, 0x55, 0x86, 0x19, 0x69, 0x99,
-	0x47, 0x41, 0x5d, 0x38, 0x7c, 0xc8, 0xe6, 0x5c, 0xf1, 0x15, 0x0d, 0xa0, 0x35, 0x9b, 0xdf, 0xcb,
-	0x02, 0x49, 0x07, 0x1c, 0x99, 0x07, 0x76, 0xff, 0xdf, 0xc0, 0x65, 0x8e, 0xcc, 0xe9, 0x25, 0x90,
-	0x48, 0x28, 0x94, 0x4b, 0x19, 0x73, 0x14, 0x4c, 0xbc, 0x97, 0x62, 0x43, 0xa5, 0x81, 0xdd, 0xb7,
-	0x07, 0x1e, 0x73, 0x64, 0x4a, 0x08, 0xfc, 0x4f, 0xf9, 0x4a, 0x04, 0x4e, 0xdf, 0x1e, 0xb8, 0xcc,
-	0x64, 0x4a, 0xa1, 0xbb, 0x35, 0x99, 0x27, 0xeb, 0xdf, 0x73, 0xf4, 0x0a, 0xfc, 0x06, 0x33, 0xe1,
-	0xc8, 0xf5, 0xaa, 0x58, 0x28, 0xdc, 0x40, 0x26, 0xeb, 0x9e, 0xca, 0x32, 0x34, 0xeb, 0x3d, 0x66,
-	0x32, 0x3d, 0x83, 0xce, 0xa3, 0x7c, 0x4d, 0x39, 0x96, 0x4a, 0x2c, 0x78, 0x22, 0x5f, 0x48, 0x0f,
-	0x0e, 0x3e, 0x74, 0x30, 0xa3, 0x6d, 0x56, 0x15, 0xf4, 0x14, 0x3c, 0xfd, 0x6e, 0x19, 0xf3, 0x28,
-	0x2b, 0x53, 0xd4, 0x54, 0xac, 0x83, 0xa1, 0x8e, 0x58, 0x55, 0x8c, 0x6e, 0xc0, 0x7d, 0x7a, 0x93,
-	0x28, 0x12, 0xed, 0xe0, 0x1c, 0xbc, 0xa9, 0xc0, 0x9f, 0xda, 0xaf, 0xc4, 0x15, 0xc3, 0x8d, 0xae,
-	0xb0, 0x53, 0x37, 0x2a, 0x69, 0xd4, 0x1a, 0x7d, 0x3a, 0xe0, 0x44, 0x63, 0x72, 0x07, 0xdd, 0x59,
-	0x51, 0x94, 0xa2, 0xf1, 0x51, 0x24, 0xac, 0xe1, 0x5d, 0x8f, 0x61, 0xb0, 0xf7, 0x2c, 0x4f, 0xd6,
-	0xd4, 0x22, 0xb7, 0xd0, 0x9d, 0x0a, 0x8c, 0xc6, 0xcd, 0x5d, 0x3b, 0x37, 0xf9, 0x6b, 0xc1, 0x04,
-	0xfc, 0x85, 0x50, 0x72, 0xb9, 0xfe, 0xf6, 0x44, 0x4e, 0xf6, 0xe0, 0xda, 0x7a, 0x78, 0x5c, 0x1f,
-	0x6c, 0x3b, 0xa5, 0x16, 0xb9, 0x06, 0x7f, 0x2a, 0x70, 0x4b, 0xe1, 0xce, 0x2d, 0x7a, 0x75, 0xa3,
-	0x89, 0x51, 0xeb, 0xb9, 0xfa, 0xd3, 0x2e, 0xbe, 0x02, 0x00, 0x00, 0xff, 0xff, 0x27, 0x5a, 0x07,
-	0xbe, 0x7c, 0x02, 0x00, 0x00,
+	// 1009 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0xeb, 0x6e, 0xe2, 0x46,
+	0x14, 0xae, 0x0d, 0xe1, 0x72, 0xe2, 0x80, 0x33, 0xcd, 0xc5, 0x4b, 0x77, 0x55, 0x34, 0x6a, 0x2b,
+	0xd4, 0x1f, 0xdb, 0x96, 0xa8, 0x6a, 0xaa, 0xdd, 0x48, 0x25, 0x04, 0x65, 0x51, 0x57, 0x69, 0x34,
+	0x49, 0xb3, 0x3f, 0xa3, 0x89, 0x3d, 0xc0, 0x34, 0x60, 0xb3, 0xf6, 0x90, 0x8a, 0x3e, 0x41, 0x9f,
+	0xa0, 0x3f, 0xf6, 0xd5, 0xfa, 0x32, 0xd5, 0x8c, 0x2f, 0xd8, 0x60, 0x36, 0x17, 0x75, 0x7f, 0x31,
+	0xe7, 0x70, 0xe6, 0x9c, 0xef, 0xdc, 0xe6, 0x33, 0x54, 0x6c, 0xfa, 0x72, 0xea, 0x7b, 0xc2, 0x43,
+	0x25, 0xf5, 0x13, 0xe0, 0x2a, 0x94, 0xcf, 0xbc, 0x73, 0xea, 0xd3, 0x09, 0x3e, 0x80, 0x52, 0xff,
+	0xfc, 0x2d, 0x0f, 0x04, 0xaa, 0x81, 0xce, 0xa7, 0x96, 0xd6, 0x2c, 0xb4, 0xaa, 0x44, 0xe7, 0x53,
+	0xf4, 0x0c, 0x2a, 0xae, 0xe7, 0xb0, 0x6b, 0xee, 0x04, 0x96, 0xae, 0xb4, 0x65, 0x29, 0xf7, 0x9d,
+	0x00, 0x5f, 0x02, 0xea, 0x32, 0x5f, 0xf0, 0x01, 0xb7, 0xa9, 0x60, 0x84, 0xbd, 0x9f, 0xb1, 0xc8,
+	0x81, 0x6b, 0x69, 0x4d, 0xad, 0x65, 0x10, 0x9d, 0xbb, 0x08, 0x41, 0xd1, 0xa5, 0x13, 0x66, 0xe9,
+	0x4d, 0xad, 0x55, 0x25, 0xea, 0x8c, 0x1a, 0x50, 0x09, 0x98, 0x3d, 0xf3, 0xb9, 0x98, 0x5b, 0x05,
+	0xa5, 0x4f, 0x64, 0xfc, 0x41, 0x03, 0x83, 0x30, 0x97, 0xfd, 0x19, 0x3b, 0x44, 0x50, 0xb4, 0x99,
+	0x2f, 0x22, 0x97, 0xea, 0x9c, 0xeb, 0x14, 0x41, 0x31, 0xe0, 0x43, 0x57, 0x39, 0x34, 0x88, 0x3a,
+	0x67, 0x02, 0x15, 0xb3, 0x81, 0xd0, 0x73, 0xa8, 0x0a, 0x3e, 0x61, 0x81, 0xa0, 0x93, 0xa9, 0xb5,
+	0xd1, 0xd4, 0x5a, 0x05, 0xb2, 0x50, 0xa0, 0x1d, 0xd8, 0x70, 0x3d, 0xd7, 0x66, 0x56, 0x49, 0xb9,
+	0x0b, 0x05, 0x3c, 0x00, 0xe3, 0x52, 0xe6, 0x7c, 0x0f, 0xb6, 0x5b, 0x36, 0x0f, 0xab, 0x65, 0x10,
+	0x75, 0x7e, 0x2c, 0x36, 0x8c, 0x01, 0xa2, 0x38, 0xd3, 0xf1, 0x5c, 0x62, 0x91, 0x9e, 0x03, 0xd5,
+	0x16, 0x83, 0x84, 0x02, 0xc6, 0x60, 0x66, 0xca, 0x2f, 0x2d, 0x97, 0x8a, 0x8f, 0x7f, 0x86, 0x7a,
+	0xca, 0xe6, 0x84, 0x0a, 0xba, 0x0e, 0xb2, 0xef, 0x79, 0x42, 0x95, 0xd3, 0x20, 0xea, 0x8c, 0x5b,
+	0x19, 0xf7, 0xdd, 0x11, 0xe5, 0xee, 0x1a, 0x20, 0xdf, 0x40, 0xed, 0x82, 0x0f, 0x5d, 0x2a, 0x66,
+	0x3e, 0xbb, 0xa2, 0x63, 0xee, 0x48, 0xbb, 0x3b, 0x79, 0x50, 0x41, 0x2a, 0x24, 0x14, 0xf0, 0x57,
+	0xb2, 0xb1, 0xd3, 0x31, 0xb7, 0x69, 0xd7, 0x9b, 0xb9, 0x42, 0x79, 0x93, 0x07, 0x65, 0xb5, 0x45,
+	0x42, 0x01, 0x63, 0xa8, 0x11, 0x76, 0xe7, 0xd9, 0x54, 0x70, 0xcf, 0x55, 0x23, 0x69, 0x42, 0xc1,
+	0xf6, 0xc7, 0x11, 0x60, 0x79, 0xc4, 0x43, 0xa8, 0x76, 0x9c, 0x09, 0x77, 0x3b, 0x33, 0x31, 0xca,
+	0x4d, 0x28, 0xd3, 0x5b, 0x7d, 0xb9, 0xb7, 0x79, 0xdd, 0x48, 0xfa, 0x5d, 0x4c, 0xf7, 0xfb, 0x1f,
+	0x0d, 0xf6, 0x25, 0x86, 0x54, 0x25, 0x82, 0xb8, 0xf7, 0x5f, 0x43, 0x91, 0xce, 0xc4, 0x48, 0xc5,
+	0xdd, 0x6c, 0x6f, 0x87, 0xcb, 0x15, 0xbc, 0x4c, 0x80, 0x11, 0xf5, 0x37, 0x7a, 0x01, 0xa0, 0x16,
+	0x48, 0xcc, 0xa7, 0x2c, 0x1c, 0x8a, 0x0d, 0x52, 0x95, 0x9a, 0x4b, 0xa9, 0x40, 0x3f, 0x42, 0x25,
+	0x10, 0x54, 0xcc, 0x02, 0x16, 0x58, 0x85, 0x66, 0xa1, 0x55, 0x6b, 0x3f, 0x8b, 0x3d, 0xa5, 0x82,
+	0x5e, 0x28, 0x13, 0x92, 0x98, 0xe2, 0x3f, 0x60, 0xf7, 0x94, 0x89, 0x9c, 0xf5, 0x7b, 0x20, 0xaa,
+	0xbc, 0x05, 0xda, 0x83, 0x52, 0xc0, 0x7c, 0x4e, 0xc7, 0xd1, 0x4e, 0x46, 0x12, 0xf6, 0x60, 0xb7,
+	0x3b, 0xa2, 0xee, 0x90, 0x9d, 0x45, 0xa8, 0xff, 0x87, 0x58, 0x5f, 0x40, 0x35, 0xa9, 0x8a, 0x0a,
+	0xb7, 0x41, 0x2a, 0x71, 0x51, 0xf0, 0x0d, 0x6c, 0xc9, 0x11, 0xb8, 0xfd, 0x94, 0x49, 0x7d, 0xd0,
+	0x33, 0xab, 0xd1, 0x77, 0x07, 0x5e, 0xca, 0x56, 0x4b, 0xdb, 0x3e, 0x3a, 0x01, 0xb4, 0x0f, 0xe5,
+	0x29, 0x63, 0xfe, 0x35, 0x77, 0xd4, 0x38, 0x6d, 0x91, 0x92, 0x14, 0xfb, 0x0e, 0xfa, 0x01, 0x4a,
+	0x61, 0x0b, 0xd5, 0x83, 0xf3, 0xd1, 0x5e, 0x47, 0x86, 0xe1, 0xfc, 0x88, 0xeb, 0x1b, 0x36, 0xf0,
+	0xfc, 0xf0, 0x35, 0x2a, 0xc8, 0xf9, 0x11, 0xc7, 0x4a, 0x11, 0xe2, 0x10, 0xd7, 0x74, 0x20, 0x98,
+	0x6f, 0x95, 0xd5, 0xbf, 0x15, 0xd7, 0x13, 0x1d, 0x29, 0x27, 0xab, 0x51, 0x49, 0xad, 0x06, 0x06,
+	0xc3, 0xf3, 0x87, 0xd4, 0xe5, 0x7f, 0xa9, 0x0d, 0xb3, 0xaa, 0x2a, 0xa9, 0x8c, 0x0e, 0x9f, 0x65,
+	0x6a, 0xa3, 0x96, 0xf0, 0x15, 0x18, 0xf6, 0x42, 0x15, 0xbe, 0x00, 0x9b, 0xed, 0xfd, 0x1c, 0xfc,
+	0xb2, 0x94, 0x24, 0x63, 0x8c, 0x8f, 0x60, 0x47, 0x3a, 0x79, 0x37, 0xe2, 0x82, 0x8d, 0x79, 0x20,
+	0x1e, 0xd7, 0x57, 0xec, 0x80, 0xf9, 0xc4, 0xab, 0x11, 0x9d, 0xe9, 0xb9, 0x74, 0x56, 0xc8, 0xd0,
+	0xd9, 0xb7, 0xaf, 0x61, 0x7b, 0xa5, 0x0b, 0x08, 0xa0, 0xd4, 0xe9, 0x5e, 0xf6, 0xaf, 0x7a, 0xe6,
+	0x67, 0x68, 0x13, 0xca, 0xa4, 0x77, 0xf5, 0xdb, 0xaf, 0xbd, 0x13, 0x53, 0x43, 0x35, 0x80, 0x8b,
+	0xdf, 0xcf, 0x7b, 0xe4, 0xa2, 0x77, 0xd2, 0x3b, 0x31, 0xf5, 0xf6, 0x7b, 0xa8, 0x26, 0x18, 0xd1,
+	0x77, 0x60, 0x9c, 0xb2, 0x45, 0xba, 0xa8, 0x1e, 0xc3, 0x8b, 0xf8, 0xb6, 0x51, 0x8b, 0x15, 0x11,
+	0xeb, 0x1e, 0x40, 0xed, 0x1d, 0x15, 0xf6, 0xe8, 0xe1, 0x57, 0xbe, 0xd7, 0xda, 0x7f, 0x17, 0x41,
+	0xef, 0x76, 0xd0, 0x1b, 0x30, 0xfb, 0x41, 0x30, 0x63, 0x29, 0xf0, 0xa8, 0x91, 0xd3, 0x97, 0xa8,
+	0x72, 0x0d, 0x2b, 0xf7, 0x3f, 0xc9, 0x1e, 0x47, 0x60, 0xca, 0x47, 0xa5, 0x93, 0xf6, 0xb4, 0x82,
+	0x63, 0xfd, 0xf5, 0x63, 0xa8, 0x5f, 0x31, 0x9f, 0x0f, 0xe6, 0x09, 0x1b, 0xa0, 0xbc, 0xf9, 0x90,
+	0x2c, 0xd4, 0xd8, 0x8b, 0xff, 0x58, 0x62, 0x8e, 0x43, 0xa8, 0x9f, 0x32, 0x91, 0xa1, 0x89, 0x15,
+	0x04, 0x3b, 0xb1, 0x22, 0x63, 0x76, 0x0c, 0xa6, 0xfa, 0x6c, 0x48, 0x83, 0x4f, 0x59, 0x2e, 0x3e,
+	0x28, 0x3e, 0x92, 0xc1, 0x2f, 0xf0, 0x79, 0xf6, 0x55, 0x0d, 0x69, 0xef, 0x41, 0x35, 0x08, 0x4d,
+	0x7f, 0x82, 0x4d, 0xd5, 0x0c, 0xc5, 0xde, 0xc1, 0x02, 0x40, 0xfa, 0xab, 0xa1, 0x81, 0x96, 0xb4,
+	0x32, 0xf4, 0x6b, 0xd8, 0x56, 0x89, 0x67, 0x98, 0x6f, 0x25, 0xf0, 0xde, 0x22, 0xa1, 0xb4, 0x61,
+	0xfb, 0xdf, 0x02, 0x94, 0xbb, 0x1d, 0x35, 0xfc, 0xe8, 0x2d, 0x98, 0xcb, 0x94, 0x85, 0xbe, 0x8c,
+	0xef, 0xad, 0x21, 0xb3, 0x46, 0x5e, 0xa3, 0x14, 0x84, 0x37, 0x50, 0xcb, 0x96, 0x04, 0xbd, 0x88,
+	0x4d, 0x73, 0x09, 0xa8, 0xb1, 0xee, 0x49, 0x90, 0x9e, 0xb2, 0x34, 0xb2, 0xf0, 0x94, 0x4b, 0x2f,
+	0xeb, 0x3d, 0x1d, 0x42, 0x29, 0xe4, 0x07, 0xb4, 0x9b, 0xae, 0xc7, 0xed, 0xfd, 0x37, 0x8f, 0x60,
+	0x2b, 0xf3, 0x10, 0xa1, 0xe7, 0xe9, 0xc2, 0x2c, 0x3f, 0x32, 0x2b, 0x6b, 0x7a, 0x08, 0x46, 0xc7,
+	0x71, 0x16, 0xb7, 0x93, 0x39, 0xb8, 0xf7, 0xe6, 0x2b, 0xa8, 0x13, 0x36, 0xf1, 0xee, 0xd8, 0x13,
+	0x2e, 0xdf, 0x84, 0x1f, 0xec, 0x07, 0xff, 0x0d, 0x00, 0xcc, 0xcd, 0xb2, 0xae, 0xc3, 0x0b, 0x00,
+	0x00,
 }
@@ -0,0 +1,98 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+)
+
+// securityLevel returns the configured security level, the bit size of the
+// curve selected by Init.
+func securityLevel() (int, error) {
+	curve := primitives.GetDefaultCurve()
+	if curve == nil {
+		return 0, fmt.Errorf("crypto layer not initialized")
+	}
+	return curve.Params().BitSize, nil
+}
+
+// SecuritySetting describes the configured security level and hash algorithm,
+// e.g. "SHA3-384". CA and nodes announce it to detect a mismatch.
+func SecuritySetting() string {
+	level, err := securityLevel()
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s-%d", primitives.GetHashAlgorithm(), level)
+}
+
+// CheckSecuritySetting refuses a remote security setting differing from the
+// local one. An empty setting is sent by older versions and only checked
+// through the keys and certificates they present.
+func CheckSecuritySetting(remote string) error {
+	if local := SecuritySetting(); remote != "" && remote != local {
+		return fmt.Errorf("security setting mismatch: remote uses %s, local security.hashAlgorithm and security.level are %s", remote, local)
+	}
+	return nil
+}
+
+// signatureAlgorithm returns the certificate signature algorithm matching the
+// security level. X.509 has no SHA3 based ECDSA signatures, SHA2 of the same
+// strength is used instead.
+func signatureAlgorithm() x509.SignatureAlgorithm {
+	if level, _ := securityLevel(); level == 384 {
+		return x509.ECDSAWithSHA384
+	}
+	return x509.ECDSAWithSHA256
+}
+
+func curveName(curve elliptic.Curve) string {
+	if curve == nil || curve.Params().Name == "" {
+		return "unknown"
+	}
+	return curve.Params().Name
+}
+
+// CheckPublicKey refuses keys that are not on the curve of the configured
+// security level.
+func CheckPublicKey(pub interface{}) error {
+	level, err := securityLevel()
+	if err != nil {
+		return err
+	}
+	key, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+	want := primitives.GetDefaultCurve()
+	if curveName(key.Curve) != curveName(want) {
+		return fmt.Errorf("key uses curve %s, security.level %d requires %s", curveName(key.Curve), level, curveName(want))
+	}
+	return nil
+}
+
+// CheckCertificateSecurity refuses certificates whose key does not match the
+// configured security level or whose signature is weaker than it.
+func CheckCertificateSecurity(cert *x509.Certificate) error {
+	if err := CheckPublicKey(cert.PublicKey); err != nil {
+		return fmt.Errorf("certificate %q: %v", cert.Subject.CommonName, err)
+	}
+	if level, _ := securityLevel(); level == 384 {
+		switch cert.SignatureAlgorithm {
+		case x509.ECDSAWithSHA384, x509.ECDSAWithSHA512:
+		default:
+			return fmt.Errorf("certificate %q is signed with %v, security.level 384 requires ECDSA-SHA384", cert.Subject.CommonName, cert.SignatureAlgorithm)
+		}
+	}
+	return nil
+}
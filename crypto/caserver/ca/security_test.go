@@ -0,0 +1,88 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+)
+
+func publicKeyPEM(t *testing.T, curve elliptic.Curve) []byte {
+	priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	raw, _ := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	return pem.EncodeToMemory(&pem.Block{Type: "ECDSA PUBLIC KEY", Bytes: raw})
+}
+
+func TestSecurityLevel256(t *testing.T) {
+	ca, cleanup := newTestCA(t)
+	defer cleanup()
+
+	_, cooked := enroll(t, ca, "node1", Validator)
+	cert := BuildCertificateFromBytes(cooked)
+	if cert.SignatureAlgorithm != x509.ECDSAWithSHA256 {
+		t.Errorf("signature algorithm mismatch: have %v, want %v", cert.SignatureAlgorithm, x509.ECDSAWithSHA256)
+	}
+	if _, err := ca.IssueCertificate(publicKeyPEM(t, elliptic.P384()), "node2", Validator); err == nil {
+		t.Error("P-384 key accepted at security level 256")
+	}
+}
+
+func TestSecurityLevel384(t *testing.T) {
+	// Certificates of a CA running at level 256
+	old, cleanupOld := newTestCA(t)
+	defer cleanupOld()
+	_, oldCert := enroll(t, old, "node1", Validator)
+
+	if err := primitives.SetSecurityLevel("SHA2", 384); err != nil {
+		t.Fatalf("failed to set security level: %v", err)
+	}
+	defer primitives.SetSecurityLevel("SHA3", 256)
+
+	if setting := SecuritySetting(); setting != "SHA2-384" {
+		t.Fatalf("security setting mismatch: have %s, want SHA2-384", setting)
+	}
+	if err := CheckSecuritySetting("SHA3-256"); err == nil {
+		t.Error("mismatching security setting accepted")
+	}
+
+	ca, cleanup := newTestCA(t)
+	defer cleanup()
+
+	if curve := ca.priv.Curve.Params().Name; curve != "P-384" {
+		t.Fatalf("CA key curve mismatch: have %s, want P-384", curve)
+	}
+	priv, cooked := enroll(t, ca, "node1", Validator)
+	cert := BuildCertificateFromBytes(cooked)
+	if cert.SignatureAlgorithm != x509.ECDSAWithSHA384 {
+		t.Errorf("signature algorithm mismatch: have %v, want %v", cert.SignatureAlgorithm, x509.ECDSAWithSHA384)
+	}
+	if err := VerifyCertificate(cooked, ca.GetCACertificate()); err != nil {
+		t.Errorf("P-384 certificate refused: %v", err)
+	}
+	sign, ts, nonce := signRenewRequest(t, priv, cooked)
+	if _, err := ca.RenewCertificate(cooked, sign, "node1", ts, nonce); err != nil {
+		t.Errorf("P-384 renewal failed: %v", err)
+	}
+
+	// Keys and certificates of the old level are refused
+	if _, err := ca.IssueCertificate(publicKeyPEM(t, elliptic.P256()), "node2", Validator); err == nil {
+		t.Error("P-256 key accepted at security level 384")
+	}
+	if err := VerifyCertificate(oldCert, old.GetCACertificate()); err == nil {
+		t.Error("P-256 certificate accepted at security level 384")
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package ca
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	// CARootFile is the name of the pinned CA root certificate in the
+	// cakeystore of a node's data directory.
+	CARootFile = "caroot.cert"
+
+	// defaultTLSServerName is the name the TLS certificate of the CA is
+	// issued for if caserver.tls.servername is not set.
+	defaultTLSServerName = "caserver"
+)
+
+// clientTLS holds the state used to secure connections to the CA.
+var clientTLS struct {
+	sync.RWMutex
+	datadir string           // Data directory holding the pinned root
+	cert    *tls.Certificate // Enrollment certificate presented to the CA
+}
+
+// SetClientDataDir sets the data directory whose cakeystore holds the pinned
+// CA root certificate.
+func SetClientDataDir(datadir string) {
+	clientTLS.Lock()
+	defer clientTLS.Unlock()
+
+	clientTLS.datadir = datadir
+}
+
+// SetClientCertificate sets the certificate presented to the CA on mutual TLS
+// connections. It must be updated whenever the certificate is renewed.
+func SetClientCertificate(priv *ecdsa.PrivateKey, cert *x509.Certificate) {
+	clientTLS.Lock()
+	defer clientTLS.Unlock()
+
+	clientTLS.cert = &tls.Certificate{
+		Certificate: [][]byte{cert.Raw},
+		PrivateKey:  priv,
+		Leaf:        cert,
+	}
+}
+
+// ClientCertificate returns the private key and certificate set by
+// SetClientCertificate, or nil if none was set.
+func ClientCertificate() (*ecdsa.PrivateKey, *x509.Certificate) {
+	clientTLS.RLock()
+	defer clientTLS.RUnlock()
+
+	if clientTLS.cert == nil {
+		return nil, nil
+	}
+	return clientTLS.cert.PrivateKey.(*ecdsa.PrivateKey), clientTLS.cert.Leaf
+}
+
+// Fingerprint returns the hex encoded SHA-256 hash of a certificate, as used
+// by caserver.tls.fingerprint.
+func Fingerprint(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(hash[:])
+}
+
+// normalizeFingerprint accepts fingerprints with colons and in any case.
+func normalizeFingerprint(fp string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(fp), ":", "", -1))
+}
+
+func tlsServerName() string {
+	if name := currentSettings().CAServer.TLS.ServerName; name != "" {
+		return name
+	}
+	return defaultTLSServerName
+}
+
+// clientCredentials returns the transport credentials used to dial the CA, or
+// nil if caserver.tls.enabled is off.
+func clientCredentials() (credentials.TransportCredentials, error) {
+	if !currentSettings().CAServer.TLS.Enabled {
+		return nil, nil
+	}
+	root, err := pinnedRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	clientTLS.RLock()
+	cert := clientTLS.cert
+	clientTLS.RUnlock()
+
+	return credentials.NewTLS(newClientTLSConfig(root, tlsServerName(), cert)), nil
+}
+
+// newClientTLSConfig only trusts servers presenting a certificate for
+// servername issued by the pinned root.
+func newClientTLSConfig(root *x509.Certificate, servername string, cert *tls.Certificate) *tls.Config {
+	config := &tls.Config{
+		ServerName: servername,
+		// The root carries the critical node extensions which the standard
+		// chain verification rejects, the chain is checked against the pin
+		// by verifyServerCertificate instead.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifyServerCertificate(root, servername),
+	}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	return config
+}
+
+// verifyServerCertificate checks that the certificate presented by the CA is
+// valid for servername and issued by root, directly or through the
+// intermediate CA certificates sent along with it.
+func verifyServerCertificate(root *x509.Certificate, servername string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("CA presented no certificate")
+		}
+		leaf, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		var intermediates []*x509.Certificate
+		for _, raw := range rawCerts[1:] {
+			if bytes.Equal(raw, root.Raw) {
+				break
+			}
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			intermediates = append(intermediates, cert)
+		}
+		if err := verifyChainSignatures(leaf, intermediates, root); err != nil {
+			return fmt.Errorf("CA certificate not issued by the pinned root: %v", err)
+		}
+		if err := leaf.VerifyHostname(servername); err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, cert := range append(intermediates, root) {
+			if err := VerifyValidity(cert, now); err != nil {
+				return fmt.Errorf("ca %v", err)
+			}
+		}
+		return VerifyValidity(leaf, now)
+	}
+}
+
+// pinnedRoot loads the CA root certificate to verify the CA server against.
+// The root is read from caserver.tls.rootcert or the cakeystore of the data
+// directory. If neither exists, it is fetched from the CA and accepted only if
+// it matches caserver.tls.fingerprint; it is then stored in the cakeystore.
+func pinnedRoot() (*x509.Certificate, error) {
+	clientTLS.RLock()
+	datadir := clientTLS.datadir
+	clientTLS.RUnlock()
+
+	file := currentSettings().CAServer.TLS.RootCert
+	if file == "" && datadir != "" {
+		file = filepath.Join(datadir, "cakeystore", CARootFile)
+	}
+	if file != "" {
+		if _, err := os.Stat(file); err == nil {
+			return LoadCertificate(file)
+		}
+	}
+
+	fingerprint := currentSettings().CAServer.TLS.Fingerprint
+	if fingerprint == "" {
+		return nil, fmt.Errorf("no pinned CA root: bundle %s or set caserver.tls.fingerprint", CARootFile)
+	}
+	var (
+		root *x509.Certificate
+		err  error
+	)
+	for _, address := range caAddresses() {
+		if root, err = fetchPinnedRoot(address, tlsServerName(), fingerprint); err == nil {
+			break
+		}
+		caLogger.Warningf("Could not fetch the CA root from %s: %v", address, err)
+	}
+	if root == nil {
+		return nil, err
+	}
+	if file != "" {
+		cooked := pem.EncodeToMemory(
+			&pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: root.Raw,
+			})
+		if err := ioutil.WriteFile(file, cooked, 0644); err != nil {
+			caLogger.Warningf("Could not store the pinned CA root in %s: %v", file, err)
+		}
+	}
+	return root, nil
+}
+
+// fetchPinnedRoot retrieves the root certificate from the chain presented by
+// the CA and checks it against the pinned fingerprint.
+func fetchPinnedRoot(address, servername, fingerprint string) (*x509.Certificate, error) {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 3 * time.Second}, "tcp", address, &tls.Config{
+		ServerName:         servername,
+		InsecureSkipVerify: true, // verified against the fingerprint below
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not reach the CA: %v", err)
+	}
+	defer conn.Close()
+
+	chain := conn.ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("CA presented no certificate")
+	}
+	rawCerts := make([][]byte, len(chain))
+	for i, cert := range chain {
+		rawCerts[i] = cert.Raw
+	}
+	fingerprint = normalizeFingerprint(fingerprint)
+	for _, cert := range chain {
+		if !cert.IsCA || Fingerprint(cert) != fingerprint {
+			continue
+		}
+		if err := verifyServerCertificate(cert, servername)(rawCerts, nil); err != nil {
+			return nil, err
+		}
+		return cert, nil
+	}
+	return nil, fmt.Errorf("CA root certificate does not match fingerprint %s", fingerprint)
+}
+
+// ServerTLSConfig returns the TLS configuration of the CA server. Callers may
+// present their enrollment certificate, which is checked by AuthenticateCaller
+// rather than during the handshake as the node extensions are not understood
+// by the TLS stack.
+func (ca *CA) ServerTLSConfig() (*tls.Config, error) {
+	cert, err := ca.tlsCertificate(tlsServerName())
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequestClientCert,
+	}, nil
+}
+
+// tlsCertificate loads the TLS server certificate of the CA, issuing a new one
+// from the root if it is missing, expired or issued for another name.
+func (ca *CA) tlsCertificate(servername string) (tls.Certificate, error) {
+	dir := filepath.Join(ca.path, "tls")
+	certFile, keyFile := filepath.Join(dir, "server.cert"), filepath.Join(dir, "server.priv")
+
+	if cert, err := LoadCertificate(certFile); err == nil {
+		if err := cert.VerifyHostname(servername); err == nil && VerifyValidity(cert, time.Now()) == nil && cert.CheckSignatureFrom(ca.cert) == nil {
+			if priv, err := LoadPrivateKey(keyFile); err == nil {
+				return tls.Certificate{Certificate: ca.tlsChain(cert.Raw), PrivateKey: priv, Leaf: cert}, nil
+			}
+		}
+	}
+	caLogger.Info("Creating TLS certificate for " + servername + ".")
+
+	priv, err := ecdsa.GenerateKey(primitives.GetDefaultCurve(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:   servername,
+			Organization: ca.cert.Subject.Organization,
+			Country:      ca.cert.Subject.Country,
+		},
+		NotBefore:   time.Now().Add(-time.Minute),
+		NotAfter:    ca.cert.NotAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(servername); ip != nil {
+		tmpl.IPAddresses = []net.IP{ip}
+	} else {
+		tmpl.DNSNames = []string{servername}
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, &tmpl, ca.cert, &priv.PublicKey, ca.priv)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return tls.Certificate{}, err
+	}
+	cooked := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw})
+	if err := ioutil.WriteFile(certFile, cooked, 0644); err != nil {
+		return tls.Certificate{}, err
+	}
+	der, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cooked = pem.EncodeToMemory(&pem.Block{Type: "ECDSA PRIVATE KEY", Bytes: der})
+	if err := ioutil.WriteFile(keyFile, cooked, 0600); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: ca.tlsChain(raw), PrivateKey: priv, Leaf: cert}, nil
+}
+
+// tlsChain returns the chain presented by the CA server: its TLS certificate
+// followed by the CA certificates up to the root.
+func (ca *CA) tlsChain(leaf []byte) [][]byte {
+	chain := [][]byte{leaf, ca.raw}
+	for _, cert := range ca.chain {
+		chain = append(chain, cert.Raw)
+	}
+	return chain
+}
+
+// AuthenticateCaller checks the certificate a caller presented on a mutual
+// TLS connection: it must be issued by this CA and must not be revoked. The
+// validity period is not checked so that nodes can renew a lapsed
+// certificate.
+func (ca *CA) AuthenticateCaller(cert *x509.Certificate) (*CertificateRecord, error) {
+	if err := cert.CheckSignatureFrom(ca.cert); err != nil {
+		return nil, fmt.Errorf("client certificate not issued by this CA: %v", err)
+	}
+	rec, err := ca.readCertificateByRaw(cert.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("unknown client certificate: %v", err)
+	}
+	if rec.Status == Revoked {
+		return nil, fmt.Errorf("client certificate %s has been revoked", rec.Serial)
+	}
+	return rec, nil
+}
+
+// SameKey reports whether two certificates certify the same public key.
+func SameKey(a, b *x509.Certificate) bool {
+	return bytes.Equal(a.RawSubjectPublicKeyInfo, b.RawSubjectPublicKeyInfo)
+}
@@ -0,0 +1,133 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package ca
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"testing"
+)
+
+// serveTLS accepts TLS connections with the CA's server configuration and
+// reports the certificate presented by each client.
+func serveTLS(t *testing.T, ca *CA) (string, <-chan *x509.Certificate, func()) {
+	config, err := ca.ServerTLSConfig()
+	if err != nil {
+		t.Fatalf("failed to create TLS config: %v", err)
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	callers := make(chan *x509.Certificate, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			tconn := conn.(*tls.Conn)
+			if err := tconn.Handshake(); err == nil {
+				var caller *x509.Certificate
+				if certs := tconn.ConnectionState().PeerCertificates; len(certs) > 0 {
+					caller = certs[0]
+				}
+				callers <- caller
+			}
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String(), callers, func() { listener.Close() }
+}
+
+func TestTLSPinnedRoot(t *testing.T) {
+	ca, cleanup := newTestCA(t)
+	defer cleanup()
+
+	addr, callers, stop := serveTLS(t, ca)
+	defer stop()
+
+	// Fetching the root only succeeds with the right fingerprint, which may
+	// be given in upper case and colon separated
+	var parts []string
+	for fp := strings.ToUpper(Fingerprint(ca.cert)); fp != ""; fp = fp[2:] {
+		parts = append(parts, fp[:2])
+	}
+	root, err := fetchPinnedRoot(addr, defaultTLSServerName, strings.Join(parts, ":"))
+	if err != nil {
+		t.Fatalf("failed to fetch pinned root: %v", err)
+	}
+	if !root.Equal(ca.cert) {
+		t.Fatal("fetched root mismatch")
+	}
+	<-callers
+	if _, err := fetchPinnedRoot(addr, defaultTLSServerName, strings.Repeat("00", 32)); err == nil {
+		t.Fatal("root accepted with a wrong fingerprint")
+	}
+	<-callers
+
+	// A server not issued by the pinned root is refused
+	other, cleanupOther := newTestCA(t)
+	defer cleanupOther()
+	conn, err := tls.Dial("tcp", addr, newClientTLSConfig(other.cert, defaultTLSServerName, nil))
+	if err == nil {
+		conn.Close()
+		t.Fatal("handshake succeeded against the wrong root")
+	}
+
+	// Enrolled clients are authenticated with their certificate
+	priv, cooked := enroll(t, ca, "node1", Validator)
+	cert := BuildCertificateFromBytes(cooked)
+	client := &tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: priv}
+	conn, err = tls.Dial("tcp", addr, newClientTLSConfig(root, defaultTLSServerName, client))
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	conn.Close()
+
+	caller := <-callers
+	if caller == nil || !SameKey(caller, cert) {
+		t.Fatal("client certificate not presented")
+	}
+	if _, err := ca.AuthenticateCaller(caller); err != nil {
+		t.Fatalf("enrolled caller not authenticated: %v", err)
+	}
+	if _, err := ca.RevokeCertificate("node1", ""); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if _, err := ca.AuthenticateCaller(caller); err == nil {
+		t.Fatal("revoked caller authenticated")
+	}
+}
+
+func TestTLSIntermediateCA(t *testing.T) {
+	root, cleanup := newTestCA(t)
+	defer cleanup()
+	bank, cleanupBank := newIntermediateCA(t, root, "bankA", "BankA")
+	defer cleanupBank()
+
+	addr, callers, stop := serveTLS(t, bank)
+	defer stop()
+
+	// The server chain is verified up to the shared root
+	conn, err := tls.Dial("tcp", addr, newClientTLSConfig(root.cert, defaultTLSServerName, nil))
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	conn.Close()
+	<-callers
+
+	fetched, err := fetchPinnedRoot(addr, defaultTLSServerName, Fingerprint(root.cert))
+	if err != nil {
+		t.Fatalf("failed to fetch pinned root: %v", err)
+	}
+	if !fetched.Equal(root.cert) {
+		t.Fatal("fetched root mismatch")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+)
+
+// RPCTokenWindow is the maximum clock difference accepted between the
+// timestamp of an RPC token and the node verifying it.
+const RPCTokenWindow = 5 * time.Minute
+
+// SignRPCToken creates a token that identifies the holder of an enrollment
+// certificate to the RPC interface of a node. certs is the certificate
+// followed by the intermediate CAs it was issued by. The token is valid for
+// RPCTokenWindow around now.
+func SignRPCToken(priv *ecdsa.PrivateKey, now time.Time, certs ...*x509.Certificate) (string, error) {
+	if len(certs) == 0 {
+		return "", fmt.Errorf("no certificate to sign the token for.")
+	}
+	chain := base64.RawURLEncoding.EncodeToString(EncodeCertificates(certs...))
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sign, err := primitives.ECDSASign(priv, []byte(chain+"."+ts))
+	if err != nil {
+		return "", err
+	}
+	return chain + "." + ts + "." + base64.RawURLEncoding.EncodeToString(sign), nil
+}
+
+// VerifyRPCToken checks a token made by SignRPCToken against the root CA and
+// returns the certificate of the caller.
+func VerifyRPCToken(token string, root *x509.Certificate, now time.Time) (*x509.Certificate, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("rpc token format error")
+	}
+	chain, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("rpc token format error: %v", err)
+	}
+	unix, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("rpc token format error: %v", err)
+	}
+	sign, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("rpc token format error: %v", err)
+	}
+	if ts := time.Unix(unix, 0); ts.Before(now.Add(-RPCTokenWindow)) || ts.After(now.Add(RPCTokenWindow)) {
+		return nil, fmt.Errorf("rpc token timestamp %v out of range", ts)
+	}
+
+	certs, err := ParseCertificates(chain)
+	if err != nil {
+		return nil, err
+	}
+	if IsTCert(certs[0]) {
+		return nil, fmt.Errorf("transaction certificates cannot sign rpc tokens")
+	}
+	if err := VerifyChainAt(certs[0], certs[1:], root, now); err != nil {
+		return nil, err
+	}
+	pubkey, ok := certs[0].PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("rpc token public key format error")
+	}
+	valid, err := primitives.ECDSAVerify(pubkey, []byte(parts[0]+"."+parts[1]), sign)
+	if err != nil {
+		return nil, fmt.Errorf("rpc token signature error: %v", err)
+	}
+	if !valid {
+		return nil, fmt.Errorf("rpc token signature is invalid")
+	}
+	return certs[0], nil
+}
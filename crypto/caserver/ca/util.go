@@ -16,13 +16,196 @@ import (
 	"strings"
 	"crypto/rand"
 	"os"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto/caserver/certs"
+)
+
+var (
+	// NodeTypeOID identifies the certificate extension holding the node type.
+	NodeTypeOID = certs.NodeTypeOID
+
+	// PeerIdOID identifies the certificate extension holding the peer id.
+	PeerIdOID = certs.PeerIdOID
+
+	// TCertOID marks transaction certificates.
+	TCertOID = certs.TCertOID
 )
 
+// MaxChainLength is the maximum number of intermediate CA certificates
+// accepted between a certificate and the root.
+const MaxChainLength = certs.MaxChainLength
+
+// VerifySignature checks that cert is signed by the root caCert. cert may be
+// followed by the certificates of the intermediate CAs it was issued by,
+// ordered towards the root.
 func VerifySignature(cert []byte, caCert []byte) error {
-	c := BuildCertificateFromBytes(cert)
+	chain, err := ParseCertificates(cert)
+	if err != nil {
+		return err
+	}
+	caC := BuildCertificateFromBytes(caCert)
+	if caC == nil {
+		return fmt.Errorf("certificate data error.")
+	}
+
+	return verifyChainSignatures(chain[0], chain[1:], caC)
+}
+
+// VerifyCertificate checks that cert is signed by caCert and that both match
+// the configured security level and are within their validity period at the
+// current time. Like in VerifySignature, cert may be followed by the
+// certificates of intermediate CAs.
+func VerifyCertificate(cert []byte, caCert []byte) error {
+	certs, err := ParseCertificates(cert)
+	if err != nil {
+		return err
+	}
 	caC := BuildCertificateFromBytes(caCert)
+	if caC == nil {
+		return fmt.Errorf("certificate data error.")
+	}
+
+	return VerifyChain(certs[0], certs[1:], caC)
+}
+
+// VerifyChain checks that cert is issued by root through the intermediate CA
+// certificates, which are ordered from the issuer of cert towards the root.
+// All certificates must match the configured security level and be within
+// their validity period at the current time.
+func VerifyChain(cert *x509.Certificate, intermediates []*x509.Certificate, root *x509.Certificate) error {
+	return VerifyChainAt(cert, intermediates, root, time.Now())
+}
+
+// VerifyChainAt is like VerifyChain but checks the validity periods at the
+// given time.
+func VerifyChainAt(cert *x509.Certificate, intermediates []*x509.Certificate, root *x509.Certificate, now time.Time) error {
+	return certs.VerifyChainAt(cert, intermediates, root, now, CheckCertificateSecurity)
+}
+
+// VerifyTCert checks that cert is a transaction certificate issued to an
+// enrolled member by root or one of the intermediate CAs below it, and that
+// it is valid at the given time.
+func VerifyTCert(cert *x509.Certificate, intermediates []*x509.Certificate, root *x509.Certificate, now time.Time) error {
+	if !IsTCert(cert) {
+		return fmt.Errorf("certificate %q is not a transaction certificate", cert.Subject.CommonName)
+	}
+	return VerifyChainAt(cert, intermediates, root, now)
+}
+
+
+// verifyChainSignatures checks the signatures along the chain from cert to
+// root and the path length constraints of the issuing CAs.
+func verifyChainSignatures(cert *x509.Certificate, intermediates []*x509.Certificate, root *x509.Certificate) error {
+	return certs.VerifyChainSignatures(cert, intermediates, root)
+}
+
+// ParseCertificates decodes a sequence of PEM encoded certificates.
+func ParseCertificates(cooked []byte) ([]*x509.Certificate, error) {
+	return certs.ParseCertificates(cooked)
+}
+
+// EncodeCertificates PEM encodes a sequence of certificates.
+func EncodeCertificates(chain ...*x509.Certificate) []byte {
+	return certs.EncodeCertificates(chain...)
+}
+
+// GetOrganization returns the organization a certificate is issued to. Node
+// certificates carry the organization of the CA that issued them.
+func GetOrganization(cert *x509.Certificate) string {
+	return certs.Organization(cert)
+}
+
+// VerifyValidity returns an error if the certificate is not valid at the
+// given time.
+func VerifyValidity(cert *x509.Certificate, now time.Time) error {
+	return certs.VerifyValidity(cert, now)
+}
+
+// GetNodeInfo extracts the node type and peer id carried by the certificate
+// extensions. Certificates without a node type are treated as validators.
+func GetNodeInfo(cert *x509.Certificate) (NodeType, uint32) {
+	nodetype, peerid := certs.NodeInfo(cert)
+	return NodeType(nodetype), peerid
+}
+
+// IsTCert reports whether cert is a transaction certificate.
+func IsTCert(cert *x509.Certificate) bool {
+	return certs.IsTCert(cert)
+}
+
+// SignRenewRequest signs the raw certificate, the request timestamp and nonce
+// with the enrollment private key to prove possession of the key when asking
+// the CA for a renewal.
+func SignRenewRequest(priv *ecdsa.PrivateKey, raw []byte, timestamp int64, nonce []byte) ([]byte, error) {
+	return primitives.ECDSASign(priv, renewRequestData(raw, timestamp, nonce))
+}
+
+// VerifyRenewRequest checks a signature produced by SignRenewRequest.
+func VerifyRenewRequest(pub *ecdsa.PublicKey, raw []byte, timestamp int64, nonce []byte, sign []byte) error {
+	valid, err := primitives.ECDSAVerify(pub, renewRequestData(raw, timestamp, nonce), sign)
+	if err != nil {
+		return fmt.Errorf("renew request signature error: %v", err)
+	}
+	if !valid {
+		return fmt.Errorf("renew request signature is invalid")
+	}
+	return nil
+}
+
+// SignTCertRequest signs the raw enrollment certificate together with the
+// public keys to be certified to ask the CA for transaction certificates.
+func SignTCertRequest(priv *ecdsa.PrivateKey, raw []byte, keys [][]byte) ([]byte, error) {
+	return primitives.ECDSASign(priv, tcertRequestData(raw, keys))
+}
 
-	return c.CheckSignatureFrom(caC)
+// VerifyTCertRequest checks a signature produced by SignTCertRequest.
+func VerifyTCertRequest(pub *ecdsa.PublicKey, raw []byte, keys [][]byte, sign []byte) error {
+	valid, err := primitives.ECDSAVerify(pub, tcertRequestData(raw, keys), sign)
+	if err != nil {
+		return fmt.Errorf("tcert request signature error: %v", err)
+	}
+	if !valid {
+		return fmt.Errorf("tcert request signature is invalid")
+	}
+	return nil
+}
+
+// SignEnrollmentProof signs data identifying a p2p connection with the
+// enrollment private key, proving the node holds the key of the enrollment
+// certificate it presented.
+func SignEnrollmentProof(priv *ecdsa.PrivateKey, data []byte) ([]byte, error) {
+	return primitives.ECDSASign(priv, data)
+}
+
+// VerifyEnrollmentProof checks a signature produced by SignEnrollmentProof.
+func VerifyEnrollmentProof(pub *ecdsa.PublicKey, data []byte, sign []byte) error {
+	valid, err := primitives.ECDSAVerify(pub, data, sign)
+	if err != nil {
+		return fmt.Errorf("enrollment proof signature error: %v", err)
+	}
+	if !valid {
+		return fmt.Errorf("enrollment proof signature is invalid")
+	}
+	return nil
+}
+
+func renewRequestData(raw []byte, timestamp int64, nonce []byte) []byte {
+	data := append([]byte{}, raw...)
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(timestamp))
+	data = append(data, ts[:]...)
+	return append(data, nonce...)
+}
+
+func tcertRequestData(raw []byte, keys [][]byte) []byte {
+	data := append([]byte{}, raw...)
+	for _, key := range keys {
+		data = append(data, key...)
+	}
+	return data
 }
 
 func BuildCertificateFromBytes(cooked []byte) *x509.Certificate {
@@ -40,6 +223,32 @@ func BuildCertificateFromBytes(cooked []byte) *x509.Certificate {
 	return cert
 }
 
+// LoadCertificate reads a PEM encoded certificate from file.
+func LoadCertificate(file string) (*x509.Certificate, error) {
+	cooked, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(cooked)
+	if block == nil {
+		return nil, fmt.Errorf("certificate data error.")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// LoadPrivateKey reads a PEM encoded ECDSA private key from file.
+func LoadPrivateKey(file string) (*ecdsa.PrivateKey, error) {
+	cooked, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(cooked)
+	if block == nil {
+		return nil, fmt.Errorf("private key data error.")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
 func CreateCAKeyPair(name, path string) *ecdsa.PrivateKey {
 	name = strings.Replace(name, "/", "_", -1)
 
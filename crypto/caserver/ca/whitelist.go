@@ -0,0 +1,191 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package ca
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Kinds of whitelist entries.
+const (
+	whitelistNetwork = iota // Network in CIDR notation
+	whitelistNode           // Hex encoded node id
+)
+
+// nodeIdLength is the length of a node id, an uncompressed secp256k1 public
+// key without its prefix byte.
+const nodeIdLength = 64
+
+func initializeWhitelistTable(db *sql.DB) error {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS Whitelist (row INTEGER PRIMARY KEY, kind INTEGER, value TEXT UNIQUE)")
+	return err
+}
+
+// normalizeNetwork returns the canonical CIDR notation of a network. A plain
+// IP address stands for itself only.
+func normalizeNetwork(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return "", fmt.Errorf("invalid IP address %q", s)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4.String() + "/32", nil
+		}
+		return ip.String() + "/128", nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid network %q: %v", s, err)
+	}
+	return n.String(), nil
+}
+
+// normalizeNodeId returns the lower case hex encoding of a node id.
+func normalizeNodeId(s string) (string, error) {
+	s = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if b, err := hex.DecodeString(s); err != nil || len(b) != nodeIdLength {
+		return "", fmt.Errorf("invalid node id %q", s)
+	}
+	return s, nil
+}
+
+// whitelistEntries validates and normalizes networks and node ids.
+func whitelistEntries(cidrs, ids []string) (map[string]int, error) {
+	entries := make(map[string]int)
+	for _, cidr := range cidrs {
+		n, err := normalizeNetwork(cidr)
+		if err != nil {
+			return nil, err
+		}
+		entries[n] = whitelistNetwork
+	}
+	for _, id := range ids {
+		n, err := normalizeNodeId(id)
+		if err != nil {
+			return nil, err
+		}
+		entries[n] = whitelistNode
+	}
+	return entries, nil
+}
+
+// Whitelist returns the networks and node ids allowed to join the network.
+func (ca *CA) Whitelist() (cidrs []string, ids []string, err error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	rows, err := ca.db.Query("SELECT kind, value FROM Whitelist ORDER BY row")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			kind  int
+			value string
+		)
+		if err := rows.Scan(&kind, &value); err != nil {
+			return nil, nil, err
+		}
+		if kind == whitelistNode {
+			ids = append(ids, value)
+		} else {
+			cidrs = append(cidrs, value)
+		}
+	}
+	return cidrs, ids, rows.Err()
+}
+
+// AddWhitelist adds networks and node ids to the whitelist.
+func (ca *CA) AddWhitelist(cidrs, ids []string) error {
+	entries, err := whitelistEntries(cidrs, ids)
+	if err != nil {
+		return err
+	}
+	err = ca.updateWhitelist(entries, "INSERT OR IGNORE INTO Whitelist (kind, value) VALUES (?, ?)")
+	if err == nil {
+		caLogger.Infof("Whitelisted networks %v and nodes %v", cidrs, ids)
+	}
+	return err
+}
+
+// RemoveWhitelist removes networks and node ids from the whitelist.
+func (ca *CA) RemoveWhitelist(cidrs, ids []string) error {
+	entries, err := whitelistEntries(cidrs, ids)
+	if err != nil {
+		return err
+	}
+	err = ca.updateWhitelist(entries, "DELETE FROM Whitelist WHERE kind=? AND value=?")
+	if err == nil {
+		caLogger.Infof("Removed networks %v and nodes %v from the whitelist", cidrs, ids)
+	}
+	return err
+}
+
+// updateWhitelist runs stmt for every entry in one transaction and notifies
+// the subscribers.
+func (ca *CA) updateWhitelist(entries map[string]int, stmt string) error {
+	mutex.Lock()
+	tx, err := ca.db.Begin()
+	if err != nil {
+		mutex.Unlock()
+		return err
+	}
+	for value, kind := range entries {
+		if _, err = tx.Exec(stmt, kind, value); err != nil {
+			tx.Rollback()
+			mutex.Unlock()
+			return err
+		}
+	}
+	err = tx.Commit()
+	mutex.Unlock()
+
+	if err == nil {
+		ca.notifyWhitelist()
+	}
+	return err
+}
+
+// SubscribeWhitelist returns a channel signalled whenever the whitelist
+// changes, and a function cancelling the subscription.
+func (ca *CA) SubscribeWhitelist() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	ca.watchLock.Lock()
+	if ca.watchers == nil {
+		ca.watchers = make(map[chan struct{}]bool)
+	}
+	ca.watchers[ch] = true
+	ca.watchLock.Unlock()
+
+	return ch, func() {
+		ca.watchLock.Lock()
+		delete(ca.watchers, ch)
+		ca.watchLock.Unlock()
+	}
+}
+
+func (ca *CA) notifyWhitelist() {
+	ca.watchLock.Lock()
+	defer ca.watchLock.Unlock()
+
+	for ch := range ca.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
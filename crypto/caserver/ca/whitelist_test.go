@@ -0,0 +1,62 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package ca
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWhitelist(t *testing.T) {
+	ca, cleanup := newTestCA(t)
+	defer cleanup()
+
+	updates, unsubscribe := ca.SubscribeWhitelist()
+	defer unsubscribe()
+
+	node := strings.Repeat("ab", nodeIdLength)
+	if err := ca.AddWhitelist([]string{"10.1.2.3/16", "192.168.0.7"}, []string{"0x" + strings.ToUpper(node)}); err != nil {
+		t.Fatalf("failed to add entries: %v", err)
+	}
+	select {
+	case <-updates:
+	case <-time.After(time.Second):
+		t.Fatal("no update notification")
+	}
+	cidrs, ids, err := ca.Whitelist()
+	if err != nil {
+		t.Fatalf("failed to read whitelist: %v", err)
+	}
+	if want := []string{"10.1.0.0/16", "192.168.0.7/32"}; !reflect.DeepEqual(cidrs, want) {
+		t.Errorf("networks mismatch: have %v, want %v", cidrs, want)
+	}
+	if want := []string{node}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("node ids mismatch: have %v, want %v", ids, want)
+	}
+
+	// Invalid entries are refused as a whole
+	if err := ca.AddWhitelist([]string{"10.9.0.0/16", "10.0.0.0/40"}, nil); err == nil {
+		t.Error("invalid network accepted")
+	}
+	if err := ca.AddWhitelist(nil, []string{"abcd"}); err == nil {
+		t.Error("invalid node id accepted")
+	}
+	if cidrs, _, _ := ca.Whitelist(); len(cidrs) != 2 {
+		t.Errorf("whitelist changed by invalid entries: %v", cidrs)
+	}
+
+	if err := ca.RemoveWhitelist([]string{"10.1.0.0/16"}, []string{node}); err != nil {
+		t.Fatalf("failed to remove entries: %v", err)
+	}
+	cidrs, ids, _ = ca.Whitelist()
+	if len(cidrs) != 1 || cidrs[0] != "192.168.0.7/32" || len(ids) != 0 {
+		t.Errorf("entries not removed: %v, %v", cidrs, ids)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// Package certs reads and verifies the certificates issued by the CA. It only
+// depends on the standard library, so that consensus code can check
+// certificates without linking the CA server, its database and its client.
+package certs
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+// Node types carried by the NodeTypeOID extension, see ca.NodeType.
+const (
+	Client         = 0
+	Peer           = 1
+	Validator      = 2
+	Admin          = 3
+	IntermediateCA = 4
+)
+
+var (
+	// NodeTypeOID identifies the certificate extension holding the node type.
+	NodeTypeOID = asn1.ObjectIdentifier{1, 33, 80}
+
+	// PeerIdOID identifies the certificate extension holding the peer id.
+	PeerIdOID = asn1.ObjectIdentifier{1, 33, 81}
+
+	// TCertOID marks transaction certificates.
+	TCertOID = asn1.ObjectIdentifier{1, 33, 82}
+)
+
+// MaxChainLength is the maximum number of intermediate CA certificates
+// accepted between a certificate and the root.
+const MaxChainLength = 4
+
+// VerifyChainAt checks that cert is issued by root through the intermediate
+// CA certificates, which are ordered from the issuer of cert towards the root,
+// and that all of them are within their validity period at the given time.
+// If check is not nil, it is applied to every certificate of the chain, e.g.
+// to enforce a security level.
+func VerifyChainAt(cert *x509.Certificate, intermediates []*x509.Certificate, root *x509.Certificate, now time.Time, check func(*x509.Certificate) error) error {
+	if err := VerifyChainSignatures(cert, intermediates, root); err != nil {
+		return err
+	}
+	if check == nil {
+		check = func(*x509.Certificate) error { return nil }
+	}
+
+	for _, c := range intermediates {
+		if err := check(c); err != nil {
+			return fmt.Errorf("intermediate ca %v", err)
+		}
+		if err := VerifyValidity(c, now); err != nil {
+			return fmt.Errorf("intermediate ca %v", err)
+		}
+	}
+	if err := check(root); err != nil {
+		return fmt.Errorf("ca %v", err)
+	}
+	if err := check(cert); err != nil {
+		return err
+	}
+	if err := VerifyValidity(root, now); err != nil {
+		return fmt.Errorf("ca %v", err)
+	}
+	return VerifyValidity(cert, now)
+}
+
+// VerifyChainSignatures checks the signatures along the chain from cert to
+// root and the path length constraints of the issuing CAs.
+func VerifyChainSignatures(cert *x509.Certificate, intermediates []*x509.Certificate, root *x509.Certificate) error {
+	if len(intermediates) > MaxChainLength {
+		return fmt.Errorf("certificate chain too long: %d intermediate CAs", len(intermediates))
+	}
+	issuers := make([]*x509.Certificate, 0, len(intermediates)+1)
+	issuers = append(append(issuers, intermediates...), root)
+
+	child := cert
+	for depth, issuer := range issuers {
+		// depth is the number of CA certificates below the issuer
+		if issuer.MaxPathLen >= 0 && depth > issuer.MaxPathLen {
+			return fmt.Errorf("ca %q may not issue intermediate CAs", issuer.Subject.CommonName)
+		}
+		if err := child.CheckSignatureFrom(issuer); err != nil {
+			return fmt.Errorf("certificate %q not issued by %q: %v", child.Subject.CommonName, issuer.Subject.CommonName, err)
+		}
+		child = issuer
+	}
+	return nil
+}
+
+// VerifyValidity returns an error if the certificate is not valid at the
+// given time.
+func VerifyValidity(cert *x509.Certificate, now time.Time) error {
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("certificate %q is not valid before %v", cert.Subject.CommonName, cert.NotBefore)
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("certificate %q expired at %v", cert.Subject.CommonName, cert.NotAfter)
+	}
+	return nil
+}
+
+// ParseCertificates decodes a sequence of PEM encoded certificates.
+func ParseCertificates(cooked []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		block, rest := pem.Decode(cooked)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+		cooked = rest
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("certificate data error.")
+	}
+	return certs, nil
+}
+
+// EncodeCertificates PEM encodes a sequence of certificates.
+func EncodeCertificates(certs ...*x509.Certificate) []byte {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buf.Bytes()
+}
+
+// Organization returns the organization a certificate is issued to. Node
+// certificates carry the organization of the CA that issued them.
+func Organization(cert *x509.Certificate) string {
+	if len(cert.Subject.Organization) == 0 {
+		return ""
+	}
+	return cert.Subject.Organization[0]
+}
+
+// NodeInfo extracts the node type and peer id carried by the certificate
+// extensions. Certificates without a node type are treated as validators.
+func NodeInfo(cert *x509.Certificate) (int32, uint32) {
+	nodetype, peerid := int32(Validator), uint32(0)
+	for _, ext := range cert.Extensions {
+		if ext.Critical && ext.Id.Equal(NodeTypeOID) && len(ext.Value) > 0 {
+			if val := int32(ext.Value[0]); val >= Client && val <= IntermediateCA {
+				nodetype = val
+			}
+		} else if ext.Critical && ext.Id.Equal(PeerIdOID) && len(ext.Value) >= 4 {
+			peerid = binary.LittleEndian.Uint32(ext.Value)
+		}
+	}
+	return nodetype, peerid
+}
+
+// IsTCert reports whether cert is a transaction certificate.
+func IsTCert(cert *x509.Certificate) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Critical && ext.Id.Equal(TCertOID) {
+			return true
+		}
+	}
+	return false
+}
@@ -12,50 +12,157 @@ package main
 import (
 	"net"
 
+	"crypto/x509"
+
+	"github.com/golang/protobuf/proto"
 	"github.com/op/go-logging"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 	pb "github.com/ethereum/go-ethereum/crypto/caserver/ca/protos"
 	"log"
 	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/settings"
 	"os"
 	"strings"
-	"github.com/spf13/viper"
 	"fmt"
+	"time"
 )
 
 const (
-	envPrefix = "BC_CONF"
+	// whitelistPollInterval is how often whitelist streams check for
+	// changes made through other CA instances.
+	whitelistPollInterval = 5 * time.Second
 )
 
 var slogger = logging.MustGetLogger("server")
 var cap *ca.CA
+var conf *settings.Config // settings loaded from properties.yaml
 
 
 type whitelistServer struct{}
 
+func whitelistReply() (*pb.IPList, error) {
+	cidrs, ids, err := cap.Whitelist()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.IPList{Ip: cidrs, NodeIds: ids}, nil
+}
+
 func (s *whitelistServer) GetWhitelist(ctx context.Context, in *pb.NoParam) (*pb.IPList, error) {
-	res := &pb.IPList{}
-	res.Ip = make([]string, 2)
-	res.Ip[0] = "127.0.0.1"
-	res.Ip[1] = "192.168.0.1"
+	if _, err := callerCertificate(ctx); err != nil {
+		slogger.Warningf("Unauthorized GetWhitelist [%s]", err)
+		return nil, err
+	}
+	return whitelistReply()
+}
 
-	return res, nil
+func (s *whitelistServer) WatchWhitelist(in *pb.NoParam, stream pb.Whitelist_WatchWhitelistServer) error {
+	if _, err := callerCertificate(stream.Context()); err != nil {
+		slogger.Warningf("Unauthorized WatchWhitelist [%s]", err)
+		return err
+	}
+	updates, unsubscribe := cap.SubscribeWhitelist()
+	defer unsubscribe()
+
+	// Changes made through other CA instances sharing the database are
+	// not signalled, they are picked up by polling
+	ticker := time.NewTicker(whitelistPollInterval)
+	defer ticker.Stop()
+
+	var last *pb.IPList
+	for {
+		reply, err := whitelistReply()
+		if err != nil {
+			return err
+		}
+		if last == nil || !proto.Equal(reply, last) {
+			if err := stream.Send(reply); err != nil {
+				return err
+			}
+			last = reply
+		}
+		select {
+		case <-updates:
+		case <-ticker.C:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// callerCertificate returns the enrollment certificate a caller presented on
+// a mutual TLS connection, or nil if it presented none. A caller without a
+// certificate is only accepted if caserver.tls.clientauth is off.
+func callerCertificate(ctx context.Context) (*x509.Certificate, error) {
+	var caller *x509.Certificate
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+			caller = info.State.PeerCertificates[0]
+		}
+	}
+	if caller == nil {
+		if conf.CAServer.TLS.ClientAuth {
+			return nil, fmt.Errorf("client certificate required")
+		}
+		return nil, nil
+	}
+	if _, err := cap.AuthenticateCaller(caller); err != nil {
+		return nil, err
+	}
+	return caller, nil
+}
+
+// authenticateCaller checks that the caller holds the key of the certificate
+// it acts for.
+func authenticateCaller(ctx context.Context, cooked []byte) error {
+	caller, err := callerCertificate(ctx)
+	if err != nil || caller == nil {
+		return err
+	}
+	cert := ca.BuildCertificateFromBytes(cooked)
+	if cert == nil || !ca.SameKey(caller, cert) {
+		return fmt.Errorf("client certificate does not match the request")
+	}
+	return nil
+}
+
+// authorizeAdmin authenticates the caller and checks the admin credentials
+// of req.
+func authorizeAdmin(ctx context.Context, req proto.Message, auth *pb.AdminAuth) error {
+	if auth == nil {
+		return fmt.Errorf("admin credentials missing")
+	}
+	if err := authenticateCaller(ctx, auth.Cert); err != nil {
+		return err
+	}
+	return cap.AuthorizeAdmin(req, auth)
 }
 
 type CAServer struct{}
 
+// IssueCertificate enrolls the caller as a client. Enrollment needs no
+// credentials, so higher node types are only given by an admin through
+// CAAdmin.ChangeNodeType, e.g. on a governance approval; the node picks up
+// its new certificate when it enrolls again on its next start.
 func (s *CAServer)IssueCertificate(ctx context.Context, cr *pb.CertificateRequest) (*pb.CertificateReply, error) {
 	if cap == nil {
 		return nil, nil
 	}
 
+	if err := ca.CheckSecuritySetting(cr.Security); err != nil {
+		slogger.Warningf("Refused IssueCertificate [%s]", err)
+		return nil, err
+	}
+
 	reply := pb.CertificateReply{}
 
 	name := strings.Replace(cr.Name, "/", "_", -1)
 
-	if cert, err := cap.IssueCertificate(cr.In, name, ca.Validator); err != nil {
-		slogger.Panicf("Failed IssueCertificate [%s]", err)
+	if cert, err := cap.IssueCertificate(cr.In, name, ca.Client); err != nil {
+		slogger.Errorf("Failed IssueCertificate [%s]", err)
 		return nil, err
 	}else {
 		reply.In = cert
@@ -64,6 +171,60 @@ func (s *CAServer)IssueCertificate(ctx context.Context, cr *pb.CertificateReques
 	return &reply, nil
 }
 
+func (s *CAServer)RenewCertificate(ctx context.Context, rr *pb.RenewRequest) (*pb.CertificateReply, error) {
+	if cap == nil {
+		return nil, nil
+	}
+
+	reply := pb.CertificateReply{}
+
+	if err := ca.CheckSecuritySetting(rr.Security); err != nil {
+		slogger.Warningf("Refused RenewCertificate [%s]", err)
+		return nil, err
+	}
+	if err := authenticateCaller(ctx, rr.Cert); err != nil {
+		slogger.Warningf("Unauthorized RenewCertificate [%s]", err)
+		return nil, err
+	}
+
+	name := strings.Replace(rr.Name, "/", "_", -1)
+
+	cert, err := cap.RenewCertificate(rr.Cert, rr.Sign, name, rr.Timestamp, rr.Nonce)
+	if err != nil {
+		slogger.Errorf("Failed RenewCertificate [%s]", err)
+		return nil, err
+	}
+	reply.In = cert
+
+	return &reply, nil
+}
+
+func (s *CAServer)IssueTCerts(ctx context.Context, tr *pb.TCertRequest) (*pb.TCertReply, error) {
+	if cap == nil {
+		return nil, nil
+	}
+
+	reply := pb.TCertReply{}
+
+	if err := ca.CheckSecuritySetting(tr.Security); err != nil {
+		slogger.Warningf("Refused IssueTCerts [%s]", err)
+		return nil, err
+	}
+	if err := authenticateCaller(ctx, tr.Cert); err != nil {
+		slogger.Warningf("Unauthorized IssueTCerts [%s]", err)
+		return nil, err
+	}
+
+	certs, err := cap.IssueTCerts(tr.Cert, tr.Sign, tr.Keys)
+	if err != nil {
+		slogger.Errorf("Failed IssueTCerts [%s]", err)
+		return nil, err
+	}
+	reply.Certs = certs
+
+	return &reply, nil
+}
+
 func (s *CAServer)GetCACertificate(ctx context.Context, np *pb.NoParam) (*pb.CertificateReply, error) {
 	if cap == nil {
 		return nil, nil
@@ -75,17 +236,45 @@ func (s *CAServer)GetCACertificate(ctx context.Context, np *pb.NoParam) (*pb.Cer
 	return &reply, nil
 }
 
+func (s *CAServer)GetCertificateChain(ctx context.Context, np *pb.NoParam) (*pb.CertificateChain, error) {
+	if cap == nil {
+		return nil, nil
+	}
+
+	reply := pb.CertificateChain{}
+	reply.Certs = cap.GetCertificateChain()
+
+	return &reply, nil
+}
+
 func (s *CAServer)GetReplicaCount(ctx context.Context, np *pb.NoParam) (*pb.ReplicaCount, error) {
 	if cap == nil {
 		return nil, nil
 	}
 
+	count, err := cap.GetReplicaCount()
+	if err != nil {
+		return nil, err
+	}
 	reply := pb.ReplicaCount{}
-	reply.Count = cap.GetReplicaCount()
+	reply.Count = count
 
 	return &reply, nil
 }
 
+func (s *CAServer)GetRevocationList(ctx context.Context, np *pb.NoParam) (*pb.RevocationList, error) {
+	if cap == nil {
+		return nil, nil
+	}
+
+	crl, err := cap.RevocationList()
+	if err != nil {
+		slogger.Errorf("Failed GetRevocationList [%s]", err)
+		return nil, err
+	}
+	return &pb.RevocationList{Crl: crl}, nil
+}
+
 func (s *CAServer)VerifySignature(ctx context.Context, certData *pb.CertificateData) (*pb.SignatureValid, error) {
 	valid := pb.SignatureValid{}
 
@@ -95,41 +284,146 @@ func (s *CAServer)VerifySignature(ctx context.Context, certData *pb.CertificateD
 	return &valid, err
 }
 
-func main() {
+type CAAdminServer struct{}
 
-	viper.SetEnvPrefix(envPrefix)
-	viper.AutomaticEnv()
-	replacer := strings.NewReplacer(".", "_")
-	viper.SetEnvKeyReplacer(replacer)
-	viper.SetConfigName("properties")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("./common/")
-	viper.AddConfigPath("../../common/")
+func (s *CAAdminServer) ListCertificates(ctx context.Context, req *pb.ListCertificatesRequest) (*pb.CertificateList, error) {
+	if err := authorizeAdmin(ctx, req, req.Auth); err != nil {
+		slogger.Warningf("Unauthorized ListCertificates [%s]", err)
+		return nil, err
+	}
 
-	err := viper.ReadInConfig()
+	var nodetypes []ca.NodeType
+	for _, t := range req.NodeTypes {
+		nodetypes = append(nodetypes, ca.NodeType(t))
+	}
+	var statuses []ca.CertificateStatus
+	for _, st := range req.Statuses {
+		statuses = append(statuses, ca.CertificateStatus(st))
+	}
+
+	recs, err := cap.ListCertificates(nodetypes, statuses)
 	if err != nil {
-		slogger.Panicf("Fatal error when reading config file: %s", err)
+		return nil, err
 	}
 
-	fmt.Println(viper.GetString("caserver.cadir"))
+	reply := &pb.CertificateList{}
+	for _, rec := range recs {
+		reply.Certificates = append(reply.Certificates, rec.ToCertificateInfo())
+	}
+	return reply, nil
+}
 
-	s := grpc.NewServer()
+func (s *CAAdminServer) GetCertificate(ctx context.Context, req *pb.GetCertificateRequest) (*pb.CertificateInfo, error) {
+	if err := authorizeAdmin(ctx, req, req.Auth); err != nil {
+		slogger.Warningf("Unauthorized GetCertificate [%s]", err)
+		return nil, err
+	}
 
-	pb.RegisterWhitelistServer(s, &whitelistServer{})
-	pb.RegisterCAServer(s, &CAServer{})
+	rec, err := cap.GetCertificate(req.Name, req.Serial)
+	if err != nil {
+		return nil, err
+	}
+	return rec.ToCertificateInfo(), nil
+}
+
+func (s *CAAdminServer) ChangeNodeType(ctx context.Context, req *pb.ChangeNodeTypeRequest) (*pb.CertificateInfo, error) {
+	if err := authorizeAdmin(ctx, req, req.Auth); err != nil {
+		slogger.Warningf("Unauthorized ChangeNodeType [%s]", err)
+		return nil, err
+	}
+
+	rec, err := cap.ChangeNodeType(req.Name, ca.NodeType(req.NodeType))
+	if err != nil {
+		return nil, err
+	}
+	return rec.ToCertificateInfo(), nil
+}
+
+func (s *CAAdminServer) Revoke(ctx context.Context, req *pb.RevokeRequest) (*pb.CertificateInfo, error) {
+	if err := authorizeAdmin(ctx, req, req.Auth); err != nil {
+		slogger.Warningf("Unauthorized Revoke [%s]", err)
+		return nil, err
+	}
+
+	rec, err := cap.RevokeCertificate(req.Name, req.Serial)
+	if err != nil {
+		return nil, err
+	}
+	return rec.ToCertificateInfo(), nil
+}
+
+func (s *CAAdminServer) ListWhitelist(ctx context.Context, req *pb.ListWhitelistRequest) (*pb.IPList, error) {
+	if err := authorizeAdmin(ctx, req, req.Auth); err != nil {
+		slogger.Warningf("Unauthorized ListWhitelist [%s]", err)
+		return nil, err
+	}
+	return whitelistReply()
+}
+
+func (s *CAAdminServer) AddWhitelist(ctx context.Context, req *pb.WhitelistRequest) (*pb.IPList, error) {
+	if err := authorizeAdmin(ctx, req, req.Auth); err != nil {
+		slogger.Warningf("Unauthorized AddWhitelist [%s]", err)
+		return nil, err
+	}
+	if err := cap.AddWhitelist(req.Ip, req.NodeIds); err != nil {
+		return nil, err
+	}
+	return whitelistReply()
+}
+
+func (s *CAAdminServer) RemoveWhitelist(ctx context.Context, req *pb.WhitelistRequest) (*pb.IPList, error) {
+	if err := authorizeAdmin(ctx, req, req.Auth); err != nil {
+		slogger.Warningf("Unauthorized RemoveWhitelist [%s]", err)
+		return nil, err
+	}
+	if err := cap.RemoveWhitelist(req.Ip, req.NodeIds); err != nil {
+		return nil, err
+	}
+	return whitelistReply()
+}
+
+func main() {
+
+	var err error
+	if conf, err = settings.Load("", nil); err != nil {
+		slogger.Panicf("Fatal error when reading config file: %s", err)
+	}
 
 	// Init the crypto layer
-	if err := ca.Init(); err != nil {
+	if err := ca.Init(conf); err != nil {
 		slogger.Panicf("Failed initializing the crypto layer [%s]", err)
 	}
 
-	ca.CacheConfiguration()
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdmin(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Println(conf.CAServer.CADir)
+
 	cap = ca.NewCA("Blockchain", ca.InitializeCommonTables)
 
-	port := viper.GetString("caserver.port")
-	if port == "" {
-		slogger.Panicf("ca server port is undefined")
+	var opts []grpc.ServerOption
+	if conf.CAServer.TLS.Enabled {
+		config, err := cap.ServerTLSConfig()
+		if err != nil {
+			slogger.Panicf("Failed setting up TLS [%s]", err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+		slogger.Infof("Serving over TLS, CA root certificate fingerprint %s", ca.Fingerprint(ca.BuildCertificateFromBytes(cap.GetRootCertificate())))
+	} else {
+		slogger.Warning("TLS is disabled, CA traffic can be intercepted")
 	}
+	s := grpc.NewServer(opts...)
+
+	pb.RegisterWhitelistServer(s, &whitelistServer{})
+	pb.RegisterCAServer(s, &CAServer{})
+	pb.RegisterCAAdminServer(s, &CAAdminServer{})
+
+	port := conf.CAServer.Port
 
 	if sock, err := net.Listen("tcp", port); err != nil {
 		log.Fatalf("failed to listen: %v", err)
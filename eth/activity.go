@@ -0,0 +1,156 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var errNoActivityIndex = errors.New("account activity is not indexed, start geth with --index")
+
+// ActivityArgs selects a page of the activity of an account. All fields are
+// optional: the range defaults to the whole chain and a page to the oldest
+// core.MaxActivityEntries entries.
+type ActivityArgs struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock"` // first block of the range
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`   // last block of the range
+	Cursor    *rpc.HexNumber   `json:"cursor"`    // "next" of the previous page
+	Limit     *rpc.HexNumber   `json:"limit"`     // maximum number of entries
+	Reverse   bool             `json:"reverse"`   // newest entries first
+}
+
+// query converts the arguments into a query of the index.
+func (args *ActivityArgs) query() core.ActivityQuery {
+	var q core.ActivityQuery
+	if args == nil {
+		return q
+	}
+	if args.FromBlock != nil && *args.FromBlock > 0 {
+		q.FromBlock = uint64(*args.FromBlock)
+	}
+	if args.ToBlock != nil && *args.ToBlock >= 0 {
+		to := uint64(*args.ToBlock)
+		q.ToBlock = &to
+	}
+	if args.Cursor != nil {
+		cursor := args.Cursor.Uint64()
+		q.Cursor = &cursor
+	}
+	if args.Limit != nil {
+		q.Limit = args.Limit.Int()
+	}
+	q.Reverse = args.Reverse
+	return q
+}
+
+// PublicActivityAPI pages through the activity of accounts kept by the
+// activity index of the chain.
+type PublicActivityAPI struct {
+	bc      *core.BlockChain
+	chainDb ethdb.Database
+}
+
+// NewPublicActivityAPI creates a new API for the activity index.
+func NewPublicActivityAPI(bc *core.BlockChain, chainDb ethdb.Database) *PublicActivityAPI {
+	return &PublicActivityAPI{bc: bc, chainDb: chainDb}
+}
+
+// query returns a page of the list of the given kind of address, passing each
+// entry to output to build the result.
+func (s *PublicActivityAPI) query(kind core.ActivityKind, address common.Address, args *ActivityArgs, output func(*core.ActivityEntry) (interface{}, error)) (map[string]interface{}, error) {
+	index := s.bc.ActivityIndex()
+	if index == nil {
+		return nil, errNoActivityIndex
+	}
+	entries, next, err := index.Query(kind, address, args.query())
+	if err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		result, err := output(entry)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	page := map[string]interface{}{
+		"entries": results,
+		"next":    nil,
+	}
+	if next != nil {
+		page["next"] = rpc.NewHexNumber(*next)
+	}
+	return page, nil
+}
+
+// entryFields returns the position of an entry in the chain.
+func (s *PublicActivityAPI) entryFields(entry *core.ActivityEntry) (map[string]interface{}, error) {
+	block := s.bc.GetBlock(entry.BlockHash)
+	if block == nil || int(entry.TxIndex) >= len(block.Transactions()) {
+		return nil, fmt.Errorf("transaction %d of block #%d missing", entry.TxIndex, entry.BlockNumber)
+	}
+	return map[string]interface{}{
+		"blockNumber":      rpc.NewHexNumber(entry.BlockNumber),
+		"blockHash":        entry.BlockHash,
+		"transactionIndex": rpc.NewHexNumber(entry.TxIndex),
+		"transactionHash":  block.Transactions()[entry.TxIndex].Hash(),
+		"role":             entry.Role.String(),
+	}, nil
+}
+
+// GetTransactionsByAddress returns a page of the transactions the address
+// took part in, in block order: as sender, recipient, created contract or
+// sender or recipient of internal transfers. A transaction appears once per
+// role of the address.
+func (s *PublicActivityAPI) GetTransactionsByAddress(address common.Address, args *ActivityArgs) (map[string]interface{}, error) {
+	return s.query(core.ActivityTransactions, address, args, func(entry *core.ActivityEntry) (interface{}, error) {
+		return s.entryFields(entry)
+	})
+}
+
+// GetInternalTransfers returns a page of the transfers of value the address
+// sent or received from contracts, in block order.
+func (s *PublicActivityAPI) GetInternalTransfers(address common.Address, args *ActivityArgs) (map[string]interface{}, error) {
+	return s.query(core.ActivityTransfers, address, args, func(entry *core.ActivityEntry) (interface{}, error) {
+		fields, err := s.entryFields(entry)
+		if err != nil {
+			return nil, err
+		}
+		transfers := core.GetBlockTransfers(s.chainDb, entry.BlockHash)
+		if int(entry.Index) >= len(transfers) {
+			return nil, fmt.Errorf("transfer %d of block #%d missing", entry.Index, entry.BlockNumber)
+		}
+		transfer := transfers[entry.Index]
+		fields["from"] = transfer.From
+		fields["to"] = transfer.To
+		fields["value"] = rpc.NewHexNumber(transfer.Value)
+		return fields, nil
+	})
+}
+
+// GetLogsByAddress returns a page of the logs emitted by the contract at the
+// address, in block order.
+func (s *PublicActivityAPI) GetLogsByAddress(address common.Address, args *ActivityArgs) (map[string]interface{}, error) {
+	return s.query(core.ActivityLogs, address, args, func(entry *core.ActivityEntry) (interface{}, error) {
+		for _, receipt := range core.GetBlockReceipts(s.chainDb, entry.BlockHash) {
+			for _, log := range receipt.Logs {
+				if log.Index == entry.Index {
+					return log, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("log %d of block #%d missing", entry.Index, entry.BlockNumber)
+	})
+}
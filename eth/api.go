@@ -51,7 +51,6 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/syndtr/goleveldb/leveldb"
 	"golang.org/x/net/context"
-	"github.com/spf13/viper"
 	"github.com/ethereum/go-ethereum/pbft"
 )
 
@@ -158,7 +157,7 @@ func (s *PublicEthereumAPI) Hashrate() *rpc.HexNumber {
 		return nil
 	}
 
-	if viper.GetString("consensus.algorithm") != "POW" {
+	if s.e.consensus.Algorithm != "POW" {
 		return nil
 	}
 
@@ -202,7 +201,7 @@ func NewPublicMinerAPI(e *Ethereum) *PublicMinerAPI {
 		return nil
 	}
 
-	if viper.GetString("consensus.algorithm") != "POW" {
+	if e.consensus.Algorithm != "POW" {
 		return nil
 	}
 
@@ -429,8 +428,9 @@ func (s *PublicAccountAPI) Accounts() []accounts.Account {
 type PrivateAccountAPI struct {
 	am     *accounts.Manager
 	txPool *core.TxPool
-	txMu   *sync.Mutex
-	gpo    *GasPriceOracle
+	txMu    *sync.Mutex
+	gpo     *GasPriceOracle
+	private *privateTransactions
 }
 
 // NewPrivateAccountAPI create a new PrivateAccountAPI.
@@ -438,8 +438,9 @@ func NewPrivateAccountAPI(e *Ethereum) *PrivateAccountAPI {
 	return &PrivateAccountAPI{
 		am:     e.accountManager,
 		txPool: e.txPool,
-		txMu:   &e.txMu,
-		gpo:    e.gpo,
+		txMu:    &e.txMu,
+		gpo:     e.gpo,
+		private: e.private,
 	}
 }
 
@@ -507,11 +508,16 @@ func (s *PrivateAccountAPI) SignAndSendTransaction(args SendTxArgs, passwd strin
 		args.Nonce = rpc.NewHexNumber(s.txPool.State().GetNonce(args.From))
 	}
 
+	data, err := s.private.txData(args)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
 	var tx *types.Transaction
 	if args.To == nil {
-		tx = types.NewContractCreation(args.Nonce.Uint64(), args.Value.BigInt(), args.Gas.BigInt(), args.GasPrice.BigInt(), common.FromHex(args.Data))
+		tx = types.NewContractCreation(args.Nonce.Uint64(), args.Value.BigInt(), args.Gas.BigInt(), args.GasPrice.BigInt(), data)
 	} else {
-		tx = types.NewTransaction(args.Nonce.Uint64(), *args.To, args.Value.BigInt(), args.Gas.BigInt(), args.GasPrice.BigInt(), common.FromHex(args.Data))
+		tx = types.NewTransaction(args.Nonce.Uint64(), *args.To, args.Value.BigInt(), args.Gas.BigInt(), args.GasPrice.BigInt(), data)
 	}
 
 	signature, err := s.am.SignWithPassphrase(args.From, passwd, tx.SigHash().Bytes())
@@ -586,6 +592,16 @@ func (s *PublicBlockChainAPI) GetBalance(address common.Address, blockNr rpc.Blo
 	return state.GetBalance(address), nil
 }
 
+// GetPermission returns the permission level of the given address in the state
+// of the given block number, "none" if account permissions are not enforced.
+func (s *PublicBlockChainAPI) GetPermission(address common.Address, blockNr rpc.BlockNumber) (string, error) {
+	state, _, err := stateAndBlockByNumber(s.miner, s.bc, blockNr, s.chainDb)
+	if state == nil || err != nil {
+		return "", err
+	}
+	return core.GetPermission(state, address).String(), nil
+}
+
 // GetBlockByNumber returns the requested block. When blockNr is -1 the chain head is returned. When fullTx is true all
 // transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetBlockByNumber(blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
@@ -738,6 +754,7 @@ func (m callmsg) GasPrice() *big.Int                    { return m.gasPrice }
 func (m callmsg) Gas() *big.Int                         { return m.gas }
 func (m callmsg) Value() *big.Int                       { return m.value }
 func (m callmsg) Data() []byte                          { return m.data }
+func (m callmsg) IsCall() bool                          { return true }
 
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
@@ -947,6 +964,8 @@ type PublicTransactionPoolAPI struct {
 	txPool          *core.TxPool
 	txMu            *sync.Mutex
 	pbft		pbft.Consenter
+	consensus       string // consensus algorithm, POW or PBFT
+	private         *privateTransactions
 	muPendingTxSubs sync.Mutex
 	pendingTxSubs   map[string]rpc.Subscription
 }
@@ -963,6 +982,8 @@ func NewPublicTransactionPoolAPI(e *Ethereum) *PublicTransactionPoolAPI {
 		txMu:          &e.txMu,
 		miner:         e.miner,
 		pbft:	       e.pbft,
+		consensus:     e.consensus.Algorithm,
+		private:       e.private,
 		pendingTxSubs: make(map[string]rpc.Subscription),
 	}
 	go api.subscriptionLoop()
@@ -1170,6 +1191,10 @@ type SendTxArgs struct {
 	Value    *rpc.HexNumber  `json:"value"`
 	Data     string          `json:"data"`
 	Nonce    *rpc.HexNumber  `json:"nonce"`
+
+	// PrivateFor lists the certificate names of the participants of a
+	// private transaction, the data is then only revealed to them.
+	PrivateFor []string `json:"privateFor"`
 }
 
 // prepareSendTxArgs is a helper function that fills in default values for unspecified tx fields.
@@ -1236,11 +1261,16 @@ func (s *PublicTransactionPoolAPI) SendTransaction(args SendTxArgs) (common.Hash
 		args.Nonce = rpc.NewHexNumber(s.txPool.State().GetNonce(args.From))
 	}
 
+	data, err := s.private.txData(args)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
 	var tx *types.Transaction
 	if args.To == nil {
-		tx = types.NewContractCreation(args.Nonce.Uint64(), args.Value.BigInt(), args.Gas.BigInt(), args.GasPrice.BigInt(), common.FromHex(args.Data))
+		tx = types.NewContractCreation(args.Nonce.Uint64(), args.Value.BigInt(), args.Gas.BigInt(), args.GasPrice.BigInt(), data)
 	} else {
-		tx = types.NewTransaction(args.Nonce.Uint64(), *args.To, args.Value.BigInt(), args.Gas.BigInt(), args.GasPrice.BigInt(), common.FromHex(args.Data))
+		tx = types.NewTransaction(args.Nonce.Uint64(), *args.To, args.Value.BigInt(), args.Gas.BigInt(), args.GasPrice.BigInt(), data)
 	}
 
 	signature, err := s.am.Sign(args.From, tx.SigHash().Bytes())
@@ -1248,7 +1278,7 @@ func (s *PublicTransactionPoolAPI) SendTransaction(args SendTxArgs) (common.Hash
 		return common.Hash{}, err
 	}
 
-	algorithm := viper.GetString("consensus.algorithm")
+	algorithm := s.consensus
 	if algorithm == "POW" {
 		return submitTransaction(s.txPool, tx, signature)
 	} else if algorithm == "PBFT" {
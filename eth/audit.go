@@ -0,0 +1,117 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/audit"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/governance"
+)
+
+// auditWatcher records the permission and governance changes of the blocks
+// added to the chain in the audit log.
+type auditWatcher struct {
+	sub event.Subscription
+}
+
+func newAuditWatcher(mux *event.TypeMux) *auditWatcher {
+	w := &auditWatcher{sub: mux.Subscribe(core.ChainEvent{})}
+	go w.loop()
+	return w
+}
+
+func (w *auditWatcher) loop() {
+	for ev := range w.sub.Chan() {
+		if block, ok := ev.Data.(core.ChainEvent); ok {
+			recordChanges(block)
+		}
+	}
+}
+
+func (w *auditWatcher) stop() {
+	w.sub.Unsubscribe()
+}
+
+// recordChanges records the changes of the registries made by the
+// transactions of a block and the events of the governance contract.
+func recordChanges(block core.ChainEvent) {
+	number := block.Block.NumberU64()
+	for _, tx := range block.Block.Transactions() {
+		if tx.To() == nil {
+			continue
+		}
+		switch *tx.To() {
+		case core.PermissionRegistryAddress:
+			if addr, level, err := core.ParsePermissionChange(tx.Data()); err == nil {
+				audit.Record(audit.PermissionChanged, addr.Hex(), fmt.Sprintf("level set to %v by %s (block %d, tx %x)", level, txSender(tx), number, tx.Hash()))
+			}
+		case core.DeployAllowListAddress:
+			if addr, allowed, err := core.ParseAllowListChange(tx.Data()); err == nil {
+				change := "removed from"
+				if allowed {
+					change = "added to"
+				}
+				audit.Record(audit.PermissionChanged, addr.Hex(), fmt.Sprintf("%s deployment allow-list by %s (block %d, tx %x)", change, txSender(tx), number, tx.Hash()))
+			}
+		}
+	}
+	for _, log := range block.Logs {
+		ev := core.ParseGovernanceEvent(log)
+		if ev == nil {
+			continue
+		}
+		switch ev.Name {
+		case "Proposed":
+			audit.Record(audit.GovernanceChanged, ev.Sender.Hex(), fmt.Sprintf("proposal %d: %s (block %d)", ev.Id, describeProposal(ev), number))
+		case "Voted":
+			vote := "rejected"
+			if ev.Approve {
+				vote = "approved"
+			}
+			audit.Record(audit.GovernanceChanged, ev.Sender.Hex(), fmt.Sprintf("proposal %d %s (block %d)", ev.Id, vote, number))
+		case "Approved":
+			audit.Record(audit.GovernanceChanged, fmt.Sprintf("proposal %d", ev.Id), fmt.Sprintf("approved: %s (block %d)", describeProposal(ev), number))
+		case "Rejected":
+			audit.Record(audit.GovernanceChanged, fmt.Sprintf("proposal %d", ev.Id), fmt.Sprintf("rejected (block %d)", number))
+		}
+	}
+}
+
+func txSender(tx *types.Transaction) string {
+	from, err := tx.From()
+	if err != nil {
+		return "unknown sender"
+	}
+	return from.Hex()
+}
+
+// describeProposal returns a readable form of the change a governance event
+// is about.
+func describeProposal(ev *core.GovernanceEvent) string {
+	switch ev.Kind {
+	case governance.KindPermission:
+		return fmt.Sprintf("set level of %s to %v", ev.Account.Hex(), core.PermissionLevel(ev.Value))
+	case governance.KindDeployer:
+		if ev.Value == 1 {
+			return fmt.Sprintf("add %s to deployment allow-list", ev.Account.Hex())
+		}
+		return fmt.Sprintf("remove %s from deployment allow-list", ev.Account.Hex())
+	case governance.KindValidator:
+		if ev.Value == 1 {
+			return fmt.Sprintf("make %s a validator", ev.Node)
+		}
+		return fmt.Sprintf("make %s a peer", ev.Node)
+	case governance.KindThreshold:
+		return fmt.Sprintf("set threshold to %d", ev.Value)
+	}
+	return fmt.Sprintf("unknown kind %d", ev.Kind)
+}
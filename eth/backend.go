@@ -19,6 +19,7 @@ package eth
 
 import (
 	"bytes"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"math/big"
@@ -48,10 +49,10 @@ import (
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/pbft"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
-	"github.com/spf13/viper"
-	"github.com/ethereum/go-ethereum/pbft"
+	"github.com/ethereum/go-ethereum/settings"
 )
 
 const (
@@ -74,6 +75,8 @@ type Config struct {
 	Genesis   string // Genesis JSON to seed the chain database with
 	FastSync  bool   // Enables the state download based fast synchronisation algorithm
 
+	ActivityIndex bool // Index the transactions, internal transfers and logs of accounts
+
 	BlockChainVersion  int
 	SkipBcVersionCheck bool // e.g. blockchain export
 	DatabaseCache      int
@@ -112,11 +115,13 @@ type Ethereum struct {
 	// Channel for shutting down the ethereum
 	shutdownChan chan bool
 	nodetype     ca.NodeType
+	consensus    *settings.Consensus // Consensus settings of the node
 	configHash   common.Hash	// Hash value of the file properties.yaml
 
 	// DB interfaces
-	chainDb ethdb.Database // Block chain database
-	dappDb  ethdb.Database // Dapp database
+	chainDb   ethdb.Database // Block chain database
+	dappDb    ethdb.Database // Dapp database
+	privateDb ethdb.Database // Private payloads and state
 
 	// Handlers
 	txPool          *core.TxPool
@@ -125,6 +130,9 @@ type Ethereum struct {
 	accountManager  *accounts.Manager
 	pow             *ethash.Ethash
 	protocolManager *ProtocolManager
+	governance      *governanceWatcher
+	audit           *auditWatcher
+	private         *privateTransactions
 	SolcPath        string
 	solc            *compiler.Solidity
 	gpo             *GasPriceOracle
@@ -176,6 +184,10 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if db, ok := dappDb.(*ethdb.LDBDatabase); ok {
 		db.Meter("eth/db/dapp/")
 	}
+	privateDb, err := ctx.OpenDatabase("privatedata", config.DatabaseCache, config.DatabaseHandles)
+	if err != nil {
+		return nil, err
+	}
 	glog.V(logger.Info).Infof("Protocol Versions: %v, Network Id: %v", ProtocolVersions, config.NetworkId)
 
 	// Load up any custom genesis block if requested
@@ -207,12 +219,19 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	}
 	glog.V(logger.Info).Infof("Blockchain DB Version: %d", config.BlockChainVersion)
 
+	dchain := ctx.DChain
+	if dchain == nil {
+		dchain = settings.Default()
+	}
 	eth := &Ethereum{
 		shutdownChan:            make(chan bool),
 		chainDb:                 chainDb,
 		nodetype:                ctx.NodeType,
+		consensus:               &dchain.Consensus,
 		configHash:		 config.ConfigHash,
 		dappDb:                  dappDb,
+		privateDb:               privateDb,
+		private:                 newPrivateTransactions(privateDb),
 		eventMux:                ctx.EventMux,
 		accountManager:          config.AccountManager,
 		etherbase:               config.Etherbase,
@@ -259,6 +278,26 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if config.ChainConfig == nil {
 		return nil, errors.New("missing chain config")
 	}
+	// The network parameters are the ones committed by the genesis file
+	if stored, err := core.GetChainConfig(chainDb, genesis.Hash()); err == nil {
+		config.ChainConfig.FreeGas = stored.FreeGas
+		config.ChainConfig.NISTBlock = stored.NISTBlock
+		config.ChainConfig.DChain = stored.DChain
+	}
+	replica := ctx.PeerId
+	if genesisParams := config.ChainConfig.DChain; genesisParams != nil {
+		if err := genesisParams.Check(eth.consensus.Algorithm, eth.consensus.N, eth.consensus.F, ctx.CARoot); err != nil {
+			return nil, fmt.Errorf("configuration disagrees with the genesis block: %v", err)
+		}
+		if ctx.NodeType == ca.Validator && len(genesisParams.Validators) > 0 && !genesisParams.IsValidator(ctx.PeerId) {
+			return nil, fmt.Errorf("peer id %d is not an initial validator of the genesis block", ctx.PeerId)
+		}
+		// PBFT picks the primary by replica index, peer ids issued by
+		// intermediate CAs are far beyond the number of replicas
+		if index, ok := genesisParams.ReplicaIndex(ctx.PeerId); ok {
+			replica = index
+		}
+	}
 	core.WriteChainConfig(chainDb, genesis.Hash(), config.ChainConfig)
 
 	eth.chainConfig = config.ChainConfig
@@ -266,6 +305,11 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		EnableJit: config.EnableJit,
 		ForceJit:  config.ForceJit,
 	}
+	if root := ctx.CARoot; root != nil {
+		eth.chainConfig.TCertVerifier = func(cert *x509.Certificate, intermediates []*x509.Certificate, now time.Time) error {
+			return ca.VerifyTCert(cert, intermediates, root, now)
+		}
+	}
 
 	eth.blockchain, err = core.NewBlockChain(chainDb, eth.chainConfig, eth.pow, eth.EventMux())
 	if err != nil {
@@ -274,16 +318,22 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		}
 		return nil, err
 	}
+	eth.blockchain.SetPrivateState(eth.private.state)
+	if config.ActivityIndex {
+		if err := eth.blockchain.SetActivityIndex(core.NewActivityIndex(chainDb)); err != nil {
+			return nil, fmt.Errorf("failed to index account activity: %v", err)
+		}
+	}
 	eth.gpo = NewGasPriceOracle(eth)
 
 	newPool := core.NewTxPool(eth.chainConfig, eth.EventMux(), eth.blockchain.State, eth.blockchain.GasLimit)
 	eth.txPool = newPool
 
-	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, eth.configHash, config.FastSync, config.NetworkId, eth.eventMux, eth.txPool, eth.pow, eth.blockchain, chainDb, ctx.NodeType); err != nil {
+	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, eth.configHash, config.FastSync, config.NetworkId, eth.eventMux, eth.txPool, eth.pow, eth.blockchain, chainDb, ctx.NodeType, eth.consensus.Algorithm); err != nil {
 		return nil, err
 	}
 	if ctx.NodeType == ca.Validator || ctx.NodeType == ca.Admin {
-		algorithm := viper.GetString("consensus.algorithm")
+		algorithm := eth.consensus.Algorithm
 		if algorithm == "POW" {
 			eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.pow)
 			eth.miner.SetGasPrice(config.GasPrice)
@@ -291,7 +341,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		} else if algorithm == "PBFT" {
 			// TODO: pbft consensus implement
 			eth.txPool.Pending()
-			eth.pbft = pbft.New(eth.eventMux, ctx.PeerId, ctx.PeerCount)
+			eth.pbft = pbft.New(eth.eventMux, replica, ctx.PeerCount, eth.consensus)
 			eth.protocolManager.SetPbft(eth.pbft)
 		}
 	}
@@ -328,6 +378,16 @@ func (s *Ethereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicTransactionPoolAPI(s),
 			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicPrivateStateAPI(s.blockchain, s.private.state),
+			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicActivityAPI(s.blockchain, s.chainDb),
+			Public:    true,
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
@@ -374,7 +434,7 @@ func (s *Ethereum) APIs() []rpc.API {
 	}
 
 	if s.nodetype == ca.Validator || s.nodetype == ca.Admin {
-		if viper.GetString("consensus.algorithm") == "POW" {
+		if s.consensus.Algorithm == "POW" {
 			apis = append(apis, rpc.API{
 				Namespace: "eth",
 				Version:   "1.0",
@@ -407,7 +467,7 @@ func (s *Ethereum) Etherbase() (eb common.Address, err error) {
 func (self *Ethereum) SetEtherbase(etherbase common.Address) {
 	self.etherbase = etherbase
 	if self.nodetype == ca.Validator || self.nodetype == ca.Admin {
-		if viper.GetString("consensus.algorithm") == "POW" {
+		if self.consensus.Algorithm == "POW" {
 			self.miner.SetEtherbase(etherbase)
 		}
 	}
@@ -415,7 +475,7 @@ func (self *Ethereum) SetEtherbase(etherbase common.Address) {
 
 func (s *Ethereum) IsMining() bool {
 	if s.nodetype == ca.Validator || s.nodetype == ca.Admin {
-		if viper.GetString("consensus.algorithm") == "POW" {
+		if s.consensus.Algorithm == "POW" {
 			return s.miner.Mining()
 		}
 	}
@@ -424,7 +484,7 @@ func (s *Ethereum) IsMining() bool {
 
 func (s *Ethereum) StopMining() {
 	if s.nodetype == ca.Validator || s.nodetype == ca.Admin {
-		if viper.GetString("consensus.algorithm") == "POW" {
+		if s.consensus.Algorithm == "POW" {
 			s.miner.Stop()
 		}
 	}
@@ -446,7 +506,7 @@ func (s *Ethereum) Downloader() *downloader.Downloader { return s.protocolManage
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
-	return s.protocolManager.SubProtocols
+	return append(s.protocolManager.SubProtocols, s.private.exchange.Protocol())
 }
 
 // Start implements node.Service, starting all internal goroutines needed by the
@@ -456,6 +516,11 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 		s.StartAutoDAG()
 	}
 	s.protocolManager.Start()
+	s.audit = newAuditWatcher(s.eventMux)
+	s.private.start(srvr)
+	if s.nodetype == ca.Admin {
+		s.governance = newGovernanceWatcher(s.eventMux, changeNodeType)
+	}
 	s.netRPCService = NewPublicNetAPI(srvr, s.NetVersion())
 	return nil
 }
@@ -466,9 +531,16 @@ func (s *Ethereum) Stop() error {
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	s.txPool.Stop()
+	if s.governance != nil {
+		s.governance.stop()
+	}
+	if s.audit != nil {
+		s.audit.stop()
+	}
+	s.private.stop()
 
 	if s.nodetype == ca.Validator || s.nodetype == ca.Admin {
-		if viper.GetString("consensus.algorithm") == "POW" {
+		if s.consensus.Algorithm == "POW" {
 			s.miner.Stop()
 		}
 	}
@@ -478,6 +550,7 @@ func (s *Ethereum) Stop() error {
 
 	s.chainDb.Close()
 	s.dappDb.Close()
+	s.privateDb.Close()
 	close(s.shutdownChan)
 
 	return nil
@@ -25,7 +25,6 @@ import (
 	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
 	"github.com/ethereum/go-ethereum/logger"
 	"github.com/ethereum/go-ethereum/logger/glog"
-	"github.com/spf13/viper"
 )
 
 const disabledInfo = "Set GO_OPENCL and re-build to enable."
@@ -37,7 +36,7 @@ func (s *Ethereum) StartMining(threads int, gpus string) error {
 		return err
 	}
 
-	if viper.GetString("consensus.algorithm") != "POW" {
+	if s.consensus.Algorithm != "POW" {
 		err := fmt.Errorf("Cannot start mining without POW mode")
 		glog.V(logger.Error).Infoln(err)
 		return err
@@ -0,0 +1,72 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/governance"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+)
+
+// governanceWatcher passes the validator changes approved by the governance
+// contract on to the CA. It runs on admin nodes, whose certificate
+// authorizes the change. Account levels and deployment rights are applied by
+// the contract itself.
+type governanceWatcher struct {
+	sub    event.Subscription
+	change func(name string, nodetype ca.NodeType) error
+}
+
+func newGovernanceWatcher(mux *event.TypeMux, change func(name string, nodetype ca.NodeType) error) *governanceWatcher {
+	w := &governanceWatcher{
+		sub:    mux.Subscribe(core.ChainEvent{}),
+		change: change,
+	}
+	go w.loop()
+	return w
+}
+
+// changeNodeType updates the CA with the enrollment certificate of the node.
+func changeNodeType(name string, nodetype ca.NodeType) error {
+	priv, cert := ca.ClientCertificate()
+	if cert == nil {
+		return nil
+	}
+	return ca.ChangeNodeType(priv, cert, name, nodetype)
+}
+
+func (w *governanceWatcher) loop() {
+	for ev := range w.sub.Chan() {
+		block, ok := ev.Data.(core.ChainEvent)
+		if !ok {
+			continue
+		}
+		for _, log := range block.Logs {
+			approval := core.ParseGovernanceEvent(log)
+			if approval == nil || approval.Name != "Approved" || approval.Kind != governance.KindValidator {
+				continue
+			}
+			nodetype := ca.Peer
+			if approval.Value == 1 {
+				nodetype = ca.Validator
+			}
+			if err := w.change(approval.Node, nodetype); err != nil {
+				glog.V(logger.Error).Infof("Failed to apply governance proposal %d to %s: %v", approval.Id, approval.Node, err)
+				continue
+			}
+			glog.V(logger.Info).Infof("Applied governance proposal %d: changed node type of %s to %d", approval.Id, approval.Node, nodetype)
+		}
+	}
+}
+
+func (w *governanceWatcher) stop() {
+	w.sub.Unsubscribe()
+}
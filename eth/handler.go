@@ -26,6 +26,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/audit"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -41,7 +42,6 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/pow"
 	"github.com/ethereum/go-ethereum/rlp"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -64,6 +64,7 @@ func errResp(code errCode, format string, v ...interface{}) error {
 type ProtocolManager struct {
 	networkId int
 	nodeType  ca.NodeType
+	consensus string // consensus algorithm, POW or PBFT
 	configHash common.Hash
 
 	fastSync uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
@@ -102,11 +103,12 @@ type ProtocolManager struct {
 
 // NewProtocolManager returns a new ethereum sub protocol manager. The Ethereum sub protocol manages peers capable
 // with the ethereum network.
-func NewProtocolManager(config *core.ChainConfig, configHash common.Hash, fastSync bool, networkId int, mux *event.TypeMux, txpool txPool, pow pow.PoW, blockchain *core.BlockChain, chaindb ethdb.Database, nodetype ca.NodeType) (*ProtocolManager, error) {
+func NewProtocolManager(config *core.ChainConfig, configHash common.Hash, fastSync bool, networkId int, mux *event.TypeMux, txpool txPool, pow pow.PoW, blockchain *core.BlockChain, chaindb ethdb.Database, nodetype ca.NodeType, consensus string) (*ProtocolManager, error) {
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
 		networkId:   networkId,
 		nodeType:    nodetype,
+		consensus:   consensus,
 		configHash:  configHash,
 		eventMux:    mux,
 		txpool:      txpool,
@@ -212,7 +214,9 @@ func (pm *ProtocolManager) removePeer(id string) {
 	glog.V(logger.Debug).Infoln("Removing peer", id)
 
 	// Unregister the peer from the downloader and Ethereum peer set
-	pm.downloader.UnregisterPeer(id)
+	if canRelay(peer.nodeType) {
+		pm.downloader.UnregisterPeer(id)
+	}
 	if err := pm.peers.Unregister(id); err != nil {
 		glog.V(logger.Error).Infoln("Removal failed:", err)
 	}
@@ -230,10 +234,10 @@ func (pm *ProtocolManager) Start() {
 	}
 	// broadcast mined blocks
 	if pm.nodeType == ca.Validator || pm.nodeType == ca.Admin {
-		if viper.GetString("consensus.algorithm") == "POW" {
+		if pm.consensus == "POW" {
 			pm.minedBlockSub = pm.eventMux.Subscribe(core.NewMinedBlockEvent{})
 			go pm.minedBroadcastLoop()
-		} else if viper.GetString("consensus.algorithm") == "PBFT" {
+		} else if pm.consensus == "PBFT" {
 			pm.txPbftSub = pm.eventMux.Subscribe(core.TxPbftEvent{})
 			go pm.txPbftBroadcastLoop()
 
@@ -254,7 +258,7 @@ func (pm *ProtocolManager) Stop() {
 		pm.txSub.Unsubscribe() // quits txBroadcastLoop
 	}
 	if pm.nodeType == ca.Validator || pm.nodeType == ca.Admin {
-		if viper.GetString("consensus.algorithm") == "POW" {
+		if pm.consensus == "POW" {
 			pm.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
 		}
 	}
@@ -305,15 +309,18 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	defer pm.removePeer(p.id)
 
 	// Register the peer in the downloader. If the downloader considers it banned, we disconnect
-	if err := pm.downloader.RegisterPeer(p.id, p.version, p.Head, p.RequestHeadersByHash, p.RequestHeadersByNumber, p.RequestBodies, p.RequestReceipts, p.RequestNodeData); err != nil {
-		return err
+	// Client nodes do not serve chain data and are never asked for it
+	if canRelay(p.nodeType) {
+		if err := pm.downloader.RegisterPeer(p.id, p.version, p.Head, p.RequestHeadersByHash, p.RequestHeadersByNumber, p.RequestBodies, p.RequestReceipts, p.RequestNodeData); err != nil {
+			return err
+		}
 	}
 	// Propagate existing transactions. new transactions appearing
 	// after this will be sent via broadcasts.
 	pm.syncTransactions(p)
 
 	// If we're DAO hard-fork aware, validate any remote peer with regard to the hard-fork
-	if daoBlock := pm.chainconfig.DAOForkBlock; daoBlock != nil {
+	if daoBlock := pm.chainconfig.DAOForkBlock; daoBlock != nil && canRelay(p.nodeType) {
 		// Request the peer's DAO fork header for extra-data validation
 		if err := p.RequestHeadersByNumber(daoBlock.Uint64(), 1, 0, false); err != nil {
 			return err
@@ -354,6 +361,14 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	}
 	defer msg.Discard()
 
+	// Only validators announce blocks and take part in consensus, client
+	// nodes neither relay transactions nor serve chain data
+	if !msgAllowed(p.nodeType, msg.Code) {
+		glog.V(logger.Debug).Infof("%v: message %d not permitted for node type %v", p, msg.Code, p.nodeType)
+		audit.Record(audit.PeerRejected, p.id, fmt.Sprintf("message %d not permitted for node type %d", msg.Code, p.nodeType))
+		return p2p.DiscRoleViolation
+	}
+
 	// Handle the message depending on its contents
 	switch {
 	case msg.Code == StatusMsg:
@@ -761,6 +776,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 // BroadcastBlock will either propagate a block to a subset of it's peers, or
 // will only announce it's availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
+	// Remote nodes only accept blocks from validators
+	if !canAnnounceBlocks(pm.nodeType) {
+		return
+	}
 	hash := block.Hash()
 	peers := pm.peers.PeersWithoutBlock(hash)
 
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -62,7 +63,7 @@ func newTestProtocolManager(fastSync bool, blocks int, generator func(int, *core
 		panic(err)
 	}
 
-	pm, err := NewProtocolManager(chainConfig, fastSync, NetworkId, evmux, &testTxPool{added: newtx}, pow, blockchain, db)
+	pm, err := NewProtocolManager(chainConfig, common.Hash{}, fastSync, NetworkId, evmux, &testTxPool{added: newtx}, pow, blockchain, db, ca.Validator, "POW")
 	if err != nil {
 		return nil, err
 	}
@@ -132,8 +133,15 @@ type testPeer struct {
 	*peer
 }
 
-// newTestPeer creates a new peer registered at the given protocol manager.
+// newTestPeer creates a new validator peer registered at the given protocol
+// manager.
 func newTestPeer(name string, version int, pm *ProtocolManager, shake bool) (*testPeer, <-chan error) {
+	return newTestPeerWithType(name, version, pm, shake, ca.Validator)
+}
+
+// newTestPeerWithType creates a new peer of the given node type registered at
+// the given protocol manager.
+func newTestPeerWithType(name string, version int, pm *ProtocolManager, shake bool, nodetype ca.NodeType) (*testPeer, <-chan error) {
 	// Create a message pipe to communicate through
 	app, net := p2p.MsgPipe()
 
@@ -142,6 +150,7 @@ func newTestPeer(name string, version int, pm *ProtocolManager, shake bool) (*te
 	rand.Read(id[:])
 
 	peer := pm.newPeer(version, p2p.NewPeer(id, name, nil), net)
+	peer.nodeType = nodetype
 
 	// Start the peer on a new thread
 	errc := make(chan error, 1)
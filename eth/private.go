@@ -0,0 +1,196 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package eth
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/private"
+	"github.com/ethereum/go-ethereum/private/exchange"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// privateTransactions seals the payloads of private transactions sent by the
+// node, exchanges them with the other participants and keeps the private
+// state of the blocks.
+type privateTransactions struct {
+	db        ethdb.Database
+	payloads  *private.Store
+	directory *private.Directory
+	exchange  *exchange.Exchange
+	state     *core.PrivateState
+
+	mu   sync.Mutex
+	srvr *p2p.Server // set when the node starts, the source of peer certificates
+}
+
+func newPrivateTransactions(db ethdb.Database) *privateTransactions {
+	payloads := private.NewStore(db)
+	x := exchange.New(db, payloads, privateName)
+	state := core.NewPrivateState(db, x, privateIdentity)
+	x.SetArrivalHandler(state.PayloadArrived)
+
+	return &privateTransactions{
+		db:        db,
+		payloads:  payloads,
+		directory: private.NewDirectory(db),
+		exchange:  x,
+		state:     state,
+	}
+}
+
+// privateIdentity returns the certificate name and enrollment key of the
+// node, which private payloads are encrypted for.
+func privateIdentity() (string, *ecdsa.PrivateKey) {
+	priv, cert := ca.ClientCertificate()
+	if cert == nil {
+		return "", nil
+	}
+	return cert.Subject.CommonName, priv
+}
+
+// privateName returns the certificate name of the node.
+func privateName() string {
+	name, _ := privateIdentity()
+	return name
+}
+
+func (p *privateTransactions) start(srvr *p2p.Server) {
+	p.mu.Lock()
+	p.srvr = srvr
+	p.mu.Unlock()
+
+	p.exchange.Start()
+}
+
+func (p *privateTransactions) stop() {
+	p.exchange.Stop()
+}
+
+// participants returns the participants with the given certificate names and
+// the node itself. Certificates of connected peers are added to the directory
+// first, participants that are not connected must have been seen before.
+func (p *privateTransactions) participants(names []string) ([]private.Participant, error) {
+	_, cert := ca.ClientCertificate()
+	if cert == nil {
+		return nil, fmt.Errorf("private transactions require an enrollment certificate")
+	}
+	if err := p.directory.Add(cert); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	if p.srvr != nil {
+		for _, peer := range p.srvr.Peers() {
+			if cert := peer.Certificate(); cert != nil {
+				p.directory.Add(cert)
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	var (
+		participants []private.Participant
+		seen         = make(map[string]bool)
+	)
+	for _, name := range append([]string{cert.Subject.CommonName}, names...) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		participant, err := p.directory.Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, participant)
+	}
+	return participants, nil
+}
+
+// seal encrypts payload for the named participants, sends it to them and
+// returns the data of the private transaction carrying it.
+func (p *privateTransactions) seal(payload []byte, names []string) ([]byte, error) {
+	participants, err := p.participants(names)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := private.Seal(payload, participants)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := p.exchange.Send(envelope)
+	if err != nil {
+		return nil, err
+	}
+	return core.PrivateTxData(hash), nil
+}
+
+// txData returns the data of the transaction described by args, the sealed
+// payload if it is private.
+func (p *privateTransactions) txData(args SendTxArgs) ([]byte, error) {
+	data := common.FromHex(args.Data)
+	if len(args.PrivateFor) == 0 {
+		return data, nil
+	}
+	return p.seal(data, args.PrivateFor)
+}
+
+// PublicPrivateStateAPI provides access to the private state of the node.
+type PublicPrivateStateAPI struct {
+	bc    *core.BlockChain
+	state *core.PrivateState
+}
+
+// NewPublicPrivateStateAPI creates a new API for the private state.
+func NewPublicPrivateStateAPI(bc *core.BlockChain, state *core.PrivateState) *PublicPrivateStateAPI {
+	return &PublicPrivateStateAPI{bc: bc, state: state}
+}
+
+// stateAt returns the private state after the given block. The pending block
+// has no private state yet, the latest one is used instead.
+func (s *PublicPrivateStateAPI) stateAt(blockNr rpc.BlockNumber) (*state.StateDB, error) {
+	block := s.bc.CurrentBlock()
+	if blockNr >= 0 {
+		block = s.bc.GetBlockByNumber(uint64(blockNr))
+	}
+	if block == nil {
+		return nil, nil
+	}
+	return s.state.State(block.Hash())
+}
+
+// GetPrivateStorageAt returns the private storage at the given address, key
+// and block number.
+func (s *PublicPrivateStateAPI) GetPrivateStorageAt(address common.Address, key string, blockNr rpc.BlockNumber) (string, error) {
+	state, err := s.stateAt(blockNr)
+	if state == nil || err != nil {
+		return "0x", err
+	}
+	return state.GetState(address, common.HexToHash(key)).Hex(), nil
+}
+
+// GetPrivateCode returns the code of a private contract at the given block
+// number.
+func (s *PublicPrivateStateAPI) GetPrivateCode(address common.Address, blockNr rpc.BlockNumber) (string, error) {
+	state, err := s.stateAt(blockNr)
+	if state == nil || err != nil {
+		return "0x", err
+	}
+	code := state.GetCode(address)
+	if len(code) == 0 {
+		return "0x", nil
+	}
+	return common.ToHex(code), nil
+}
@@ -0,0 +1,48 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// peerNodeType returns the node type of the enrollment certificate of a
+// remote node. Connections without a certificate get the most restricted
+// type.
+func peerNodeType(p *p2p.Peer) ca.NodeType {
+	cert := p.Certificate()
+	if cert == nil {
+		return ca.Client
+	}
+	nodetype, _ := ca.GetNodeInfo(cert)
+	return nodetype
+}
+
+// canAnnounceBlocks reports whether nodes of the given type may announce and
+// propagate blocks and take part in consensus.
+func canAnnounceBlocks(nodetype ca.NodeType) bool {
+	return nodetype == ca.Validator || nodetype == ca.Admin
+}
+
+// canRelay reports whether nodes of the given type may relay transactions
+// and serve chain data. Client nodes may only request data.
+func canRelay(nodetype ca.NodeType) bool {
+	return nodetype == ca.Peer || canAnnounceBlocks(nodetype)
+}
+
+// msgAllowed reports whether a node of the given type may send the message.
+func msgAllowed(nodetype ca.NodeType, code uint64) bool {
+	switch code {
+	case NewBlockHashesMsg, NewBlockMsg, PbftTxMsg, PbftPrePrepareMsg:
+		return canAnnounceBlocks(nodetype)
+	case TxMsg, BlockHeadersMsg, BlockBodiesMsg, NodeDataMsg, ReceiptsMsg:
+		return canRelay(nodetype)
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+func TestMsgAllowed(t *testing.T) {
+	tests := []struct {
+		code    uint64
+		allowed map[ca.NodeType]bool
+	}{
+		{StatusMsg, map[ca.NodeType]bool{ca.Client: true, ca.Peer: true, ca.Validator: true, ca.Admin: true}},
+		{GetBlockHeadersMsg, map[ca.NodeType]bool{ca.Client: true, ca.Peer: true, ca.Validator: true, ca.Admin: true}},
+		{TxMsg, map[ca.NodeType]bool{ca.Peer: true, ca.Validator: true, ca.Admin: true}},
+		{BlockHeadersMsg, map[ca.NodeType]bool{ca.Peer: true, ca.Validator: true, ca.Admin: true}},
+		{ReceiptsMsg, map[ca.NodeType]bool{ca.Peer: true, ca.Validator: true, ca.Admin: true}},
+		{NewBlockMsg, map[ca.NodeType]bool{ca.Validator: true, ca.Admin: true}},
+		{NewBlockHashesMsg, map[ca.NodeType]bool{ca.Validator: true, ca.Admin: true}},
+		{PbftPrePrepareMsg, map[ca.NodeType]bool{ca.Validator: true, ca.Admin: true}},
+	}
+	for _, tt := range tests {
+		for _, nodetype := range []ca.NodeType{ca.Client, ca.Peer, ca.Validator, ca.Admin} {
+			if allowed := msgAllowed(nodetype, tt.code); allowed != tt.allowed[nodetype] {
+				t.Errorf("message %d from node type %d: allowed = %v, want %v", tt.code, nodetype, allowed, tt.allowed[nodetype])
+			}
+		}
+	}
+}
+
+// Tests that a client node may query chain data but is disconnected when
+// relaying.
+func TestRoleEnforcement62(t *testing.T) { testRoleEnforcement(t, 62) }
+func TestRoleEnforcement63(t *testing.T) { testRoleEnforcement(t, 63) }
+
+func testRoleEnforcement(t *testing.T, protocol int) {
+	pm := newTestProtocolManagerMust(t, false, 1, nil, nil)
+	defer pm.Stop()
+
+	// Nodes without enrollment certificate are clients
+	if nodetype := peerNodeType(p2p.NewPeer(discover.NodeID{}, "client", nil)); nodetype != ca.Client {
+		t.Fatalf("node type mismatch: have %d, want %d", nodetype, ca.Client)
+	}
+	p, errc := newTestPeerWithType("client", protocol, pm, true, ca.Client)
+	defer p.close()
+
+	// Queries are answered
+	if err := p2p.Send(p.app, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Number: 1}, Amount: 1}); err != nil {
+		t.Fatalf("failed to send query: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, BlockHeadersMsg, []interface{}{pm.blockchain.GetBlockByNumber(1).Header()}); err != nil {
+		t.Fatalf("query not answered: %v", err)
+	}
+
+	// Relaying transactions is a role violation
+	go p2p.Send(p.app, TxMsg, []interface{}{})
+	select {
+	case err := <-errc:
+		if err != p2p.DiscRoleViolation {
+			t.Fatalf("error mismatch: have %v, want %v", err, p2p.DiscRoleViolation)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("client relaying transactions not disconnected")
+	}
+}
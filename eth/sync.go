@@ -45,6 +45,10 @@ type txsync struct {
 
 // syncTransactions starts sending all currently pending transactions to the given peer.
 func (pm *ProtocolManager) syncTransactions(p *peer) {
+	// Remote nodes do not accept transactions from clients
+	if !canRelay(pm.nodeType) {
+		return
+	}
 	var txs types.Transactions
 	for _, batch := range pm.txpool.Pending() {
 		txs = append(txs, batch...)
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discover"
 )
@@ -38,11 +39,15 @@ func TestFastSyncDisabling(t *testing.T) {
 	if atomic.LoadUint32(&pmFull.fastSync) == 1 {
 		t.Fatalf("fast sync not disabled on non-empty blockchain")
 	}
-	// Sync up the two peers
+	// Sync up the two peers, both validators
 	io1, io2 := p2p.MsgPipe()
 
-	go pmFull.handle(pmFull.newPeer(63, p2p.NewPeer(discover.NodeID{}, "empty", nil), io2))
-	go pmEmpty.handle(pmEmpty.newPeer(63, p2p.NewPeer(discover.NodeID{}, "full", nil), io1))
+	empty := pmFull.newPeer(63, p2p.NewPeer(discover.NodeID{}, "empty", nil), io2)
+	full := pmEmpty.newPeer(63, p2p.NewPeer(discover.NodeID{}, "full", nil), io1)
+	empty.nodeType, full.nodeType = ca.Validator, ca.Validator
+
+	go pmFull.handle(empty)
+	go pmEmpty.handle(full)
 
 	time.Sleep(250 * time.Millisecond)
 	pmEmpty.synchronise(pmEmpty.peers.BestPeer())
@@ -0,0 +1,31 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package exporter
+
+// PrivateAdminAPI is the administrative API of the exporter.
+type PrivateAdminAPI struct {
+	e *Exporter
+}
+
+// NewPrivateAdminAPI creates a new API for the exporter.
+func NewPrivateAdminAPI(e *Exporter) *PrivateAdminAPI {
+	return &PrivateAdminAPI{e: e}
+}
+
+// ExportStatus returns the cursor and last failure of every sink.
+func (api *PrivateAdminAPI) ExportStatus() []SinkStatus {
+	return api.e.Status()
+}
+
+// ExportReplay sends the chain from the given block to the named sink again.
+func (api *PrivateAdminAPI) ExportReplay(name string, from uint64) (bool, error) {
+	if err := api.e.Replay(name, from); err != nil {
+		return false, err
+	}
+	return true, nil
+}
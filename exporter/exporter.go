@@ -0,0 +1,474 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// Package exporter pushes the blocks, transactions, receipts and logs of the
+// canonical chain to external systems. Every sink has a cursor, kept in the
+// exportdata database of the node, holding the last block it accepted; it
+// resumes from there after a restart, so messages are delivered at least
+// once. When blocks leave the canonical chain their messages are retracted
+// before the messages of the new blocks are sent.
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxBatch is the number of messages above which a sink is sent the messages
+// collected so far. The messages of a block are never split.
+const maxBatch = 256
+
+var (
+	cursorPrefix = []byte("cursor-") // cursorPrefix + sink name -> cursor
+
+	retryDelay    = time.Second // delay before delivering messages again, doubled on every failure
+	maxRetryDelay = 5 * time.Minute
+)
+
+// SinkConfig configures a sink of the exporter.
+type SinkConfig struct {
+	Name      string           `json:"name"`      // name of the sink, its cursor is kept under it
+	Webhook   string           `json:"webhook"`   // URL to post messages to
+	File      string           `json:"file"`      // file to append messages to
+	From      uint64           `json:"from"`      // first block sent to a sink without cursor
+	Kinds     []Kind           `json:"kinds"`     // kinds of messages sent, all if empty
+	Addresses []common.Address `json:"addresses"` // contracts whose logs are sent, all if empty
+	Topics    [][]common.Hash  `json:"topics"`    // topics of the logs sent, as for eth_newFilter
+}
+
+// Config is the configuration of the exporter service.
+type Config struct {
+	Sinks []SinkConfig `json:"sinks"`
+}
+
+// LoadConfig reads the configuration file at path, e.g.
+//
+//	{ "sinks": [ { "name": "ledger", "webhook": "http://ledger/events", "kinds": ["log"] } ] }
+func LoadConfig(path string) (*Config, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := new(Config)
+	if err := json.Unmarshal(blob, config); err != nil {
+		return nil, fmt.Errorf("invalid export config %s: %v", path, err)
+	}
+	for _, sink := range config.Sinks {
+		if (sink.Webhook == "") == (sink.File == "") {
+			return nil, fmt.Errorf("sink %q needs either a webhook or a file", sink.Name)
+		}
+	}
+	return config, nil
+}
+
+// Cursor is the position of a sink in the chain.
+type Cursor struct {
+	Next   uint64      `json:"next"`   // number of the next block to send
+	Parent common.Hash `json:"parent"` // hash of the last block sent, zero when starting or replaying from Next
+	Seq    uint64      `json:"seq"`    // number of messages sent
+}
+
+// SinkStatus reports the progress of a sink.
+type SinkStatus struct {
+	Name   string `json:"name"`
+	Cursor Cursor `json:"cursor"`
+	Error  string `json:"error,omitempty"` // last delivery failure, cleared on success
+}
+
+// sink is a sink along with its delivery state.
+type sink struct {
+	name   string
+	sink   Sink
+	kinds  map[Kind]bool
+	filter *filters.Filter // log selection
+
+	cursor Cursor
+	replay *uint64 // block to continue from, set by Replay
+	err    error   // last delivery failure
+	lock   sync.Mutex
+
+	wake chan struct{}
+	quit chan struct{}
+}
+
+// Exporter sends the chain to sinks, following the chain events through a
+// filter system.
+type Exporter struct {
+	chainDb ethdb.Database
+	db      ethdb.Database // cursors of the sinks
+	mux     *event.TypeMux
+	closeDb bool
+
+	filters *filters.FilterSystem
+	sinks   map[string]*sink
+	running bool
+	lock    sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// NewExporter returns an exporter sending the chain in chainDb, with cursors
+// kept in db.
+func NewExporter(chainDb, db ethdb.Database, mux *event.TypeMux) *Exporter {
+	return &Exporter{
+		chainDb: chainDb,
+		db:      db,
+		mux:     mux,
+		sinks:   make(map[string]*sink),
+	}
+}
+
+// New creates the exporter service of a node with the sinks of config.
+func New(ctx *node.ServiceContext, config *Config) (*Exporter, error) {
+	var ethereum *eth.Ethereum
+	if err := ctx.Service(&ethereum); err != nil {
+		return nil, err
+	}
+	db, err := ctx.OpenDatabase("exportdata", 16, 16)
+	if err != nil {
+		return nil, err
+	}
+	e := NewExporter(ethereum.ChainDb(), db, ethereum.EventMux())
+	e.closeDb = true
+
+	for _, c := range config.Sinks {
+		var s Sink
+		if c.Webhook != "" {
+			s = NewWebhookSink(c.Webhook)
+		} else {
+			s = NewFileSink(c.File)
+		}
+		if err := e.AddSink(c, s); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// AddSink adds a sink, named and filtered by config. A sink added before
+// continues from its cursor, a new one starts at config.From.
+func (e *Exporter) AddSink(config SinkConfig, s Sink) error {
+	if config.Name == "" {
+		return errors.New("sink without name")
+	}
+	kinds := make(map[Kind]bool)
+	for _, kind := range config.Kinds {
+		switch kind {
+		case KindBlock, KindTransaction, KindReceipt, KindLog:
+			kinds[kind] = true
+		default:
+			return fmt.Errorf("sink %q: unknown message kind %q", config.Name, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		kinds = map[Kind]bool{KindBlock: true, KindTransaction: true, KindReceipt: true, KindLog: true}
+	}
+	filter := filters.New(e.chainDb)
+	filter.SetAddresses(config.Addresses)
+	filter.SetTopics(config.Topics)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if _, ok := e.sinks[config.Name]; ok {
+		return fmt.Errorf("duplicate sink %q", config.Name)
+	}
+	cursor := Cursor{Next: config.From}
+	if blob, _ := e.db.Get(append(cursorPrefix, config.Name...)); len(blob) > 0 {
+		if err := json.Unmarshal(blob, &cursor); err != nil {
+			return fmt.Errorf("invalid cursor of sink %q: %v", config.Name, err)
+		}
+	}
+	snk := &sink{
+		name:   config.Name,
+		sink:   s,
+		kinds:  kinds,
+		filter: filter,
+		cursor: cursor,
+		wake:   make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+	}
+	e.sinks[config.Name] = snk
+	if e.running {
+		e.wg.Add(1)
+		go e.run(snk)
+	}
+	return nil
+}
+
+// Replay makes the named sink continue from the given block, sending its
+// messages again. Messages keep their increasing sequence numbers.
+func (e *Exporter) Replay(name string, from uint64) error {
+	e.lock.Lock()
+	s, ok := e.sinks[name]
+	e.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown sink %q", name)
+	}
+	s.lock.Lock()
+	s.replay = &from
+	s.lock.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// Status reports the progress of the sinks, ordered by name.
+func (e *Exporter) Status() []SinkStatus {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	names := make([]string, 0, len(e.sinks))
+	for name := range e.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var status []SinkStatus
+	for _, name := range names {
+		s := e.sinks[name]
+		s.lock.Lock()
+		st := SinkStatus{Name: s.name, Cursor: s.cursor}
+		if s.err != nil {
+			st.Error = s.err.Error()
+		}
+		s.lock.Unlock()
+		status = append(status, st)
+	}
+	return status
+}
+
+// Protocols implements node.Service.
+func (e *Exporter) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service.
+func (e *Exporter) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   NewPrivateAdminAPI(e),
+		},
+	}
+}
+
+// Start implements node.Service, starting to send the chain to the sinks.
+func (e *Exporter) Start(server *p2p.Server) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	// Every new canonical block wakes the sinks up
+	e.filters = filters.NewFilterSystem(e.mux)
+	filter := filters.New(e.chainDb)
+	filter.BlockCallback = func(*types.Block, vm.Logs) {
+		e.lock.Lock()
+		defer e.lock.Unlock()
+		for _, s := range e.sinks {
+			s.notify()
+		}
+	}
+	e.filters.Lock()
+	_, err := e.filters.Add(filter, filters.ChainFilter)
+	e.filters.Unlock()
+	if err != nil {
+		e.filters.Stop()
+		return err
+	}
+	e.running = true
+	for _, s := range e.sinks {
+		e.wg.Add(1)
+		go e.run(s)
+	}
+	return nil
+}
+
+// Stop implements node.Service.
+func (e *Exporter) Stop() error {
+	e.lock.Lock()
+	if e.running {
+		e.filters.Stop()
+		for _, s := range e.sinks {
+			close(s.quit)
+		}
+		e.running = false
+	}
+	e.lock.Unlock()
+
+	e.wg.Wait()
+	if e.closeDb {
+		e.db.Close()
+	}
+	return nil
+}
+
+// notify wakes the delivery loop of the sink up.
+func (s *sink) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers the chain to a sink until the exporter stops, retrying failed
+// deliveries with increasing delays.
+func (e *Exporter) run(s *sink) {
+	defer e.wg.Done()
+
+	var delay time.Duration
+	for {
+		if err := e.sync(s); err != nil {
+			if delay = 2 * delay; delay < retryDelay {
+				delay = retryDelay
+			} else if delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+			glog.V(logger.Warn).Infof("export to sink %s failed, retrying in %v: %v", s.name, delay, err)
+
+			select {
+			case <-time.After(delay):
+				continue
+			case <-s.quit:
+				return
+			}
+		}
+		delay = 0
+
+		select {
+		case <-s.wake:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// sync delivers the messages between the cursor of the sink and the head of
+// the chain: retractions of the blocks sent that are no longer canonical,
+// then the canonical blocks not sent yet.
+func (e *Exporter) sync(s *sink) error {
+	s.lock.Lock()
+	if s.replay != nil {
+		s.cursor.Next, s.cursor.Parent = *s.replay, common.Hash{}
+		s.replay = nil
+	}
+	cursor := s.cursor
+	s.lock.Unlock()
+
+	head := core.GetBlock(e.chainDb, core.GetHeadBlockHash(e.chainDb))
+	if head == nil {
+		return nil
+	}
+	var batch []*Message
+	add := func(msgs []*Message) {
+		for _, msg := range msgs {
+			cursor.Seq++
+			msg.Seq = cursor.Seq
+		}
+		batch = append(batch, msgs...)
+	}
+	deliver := func() error {
+		if len(batch) > 0 {
+			if err := s.sink.Deliver(batch); err != nil {
+				s.lock.Lock()
+				s.err = err
+				s.lock.Unlock()
+				return err
+			}
+			batch = nil
+		}
+		return e.commit(s, cursor)
+	}
+	selects := func(log *vm.Log) bool {
+		return len(s.filter.FilterLogs(vm.Logs{log})) > 0
+	}
+	// Retract the blocks sent that left the canonical chain
+	for cursor.Parent != (common.Hash{}) {
+		number := cursor.Next - 1
+		if number <= head.NumberU64() && core.GetCanonicalHash(e.chainDb, number) == cursor.Parent {
+			break
+		}
+		block := core.GetBlock(e.chainDb, cursor.Parent)
+		if block == nil {
+			return fmt.Errorf("sent block %x missing", cursor.Parent)
+		}
+		add(blockMessages(e.chainDb, block, true, s.kinds, selects))
+		cursor.Next, cursor.Parent = number, block.ParentHash()
+
+		if len(batch) >= maxBatch {
+			if err := deliver(); err != nil {
+				return err
+			}
+		}
+	}
+	// Send the canonical blocks up to the head
+	for cursor.Next <= head.NumberU64() {
+		block := core.GetBlock(e.chainDb, core.GetCanonicalHash(e.chainDb, cursor.Next))
+		if block == nil {
+			return fmt.Errorf("canonical block #%d missing", cursor.Next)
+		}
+		add(blockMessages(e.chainDb, block, false, s.kinds, selects))
+		cursor.Next, cursor.Parent = cursor.Next+1, block.Hash()
+
+		if len(batch) >= maxBatch {
+			if err := deliver(); err != nil {
+				return err
+			}
+			// Stop early to act on a replay or shutdown
+			select {
+			case <-s.quit:
+				return nil
+			default:
+			}
+			s.lock.Lock()
+			replay := s.replay != nil
+			s.lock.Unlock()
+			if replay {
+				s.notify()
+				return nil
+			}
+		}
+	}
+	return deliver()
+}
+
+// commit stores the cursor of a sink once its messages were delivered.
+func (e *Exporter) commit(s *sink, cursor Cursor) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.err = nil
+	if s.cursor == cursor {
+		return nil
+	}
+	blob, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+	if err := e.db.Put(append(cursorPrefix, s.name...), blob); err != nil {
+		return err
+	}
+	s.cursor = cursor
+	return nil
+}
@@ -0,0 +1,370 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+func init() {
+	retryDelay = 10 * time.Millisecond
+}
+
+var (
+	testKey, _ = crypto.GenerateKey()
+	testLogger = common.HexToAddress("0x10")
+)
+
+// testChain is a blockchain whose blocks call testLogger, a contract emitting
+// a log on every call.
+type testChain struct {
+	db         ethdb.Database
+	mux        *event.TypeMux
+	config     *core.ChainConfig
+	genesis    *types.Block
+	blockchain *core.BlockChain
+	nonce      uint64
+}
+
+func newTestChain(t *testing.T) *testChain {
+	db, _ := ethdb.NewMemDatabase()
+	genesis, err := core.WriteGenesisBlock(db, strings.NewReader(fmt.Sprintf(`{
+		"config": {"homesteadBlock": 0},
+		"nonce": "0x0000000000000042", "difficulty": "0x1", "gasLimit": "0x1000000",
+		"alloc": {
+			"%x": {"balance": "1000000000000000000"},
+			"%x": {"balance": "0", "code": "60006000a000"}
+		}
+	}`, crypto.PubkeyToAddress(testKey.PublicKey), testLogger)))
+	if err != nil {
+		t.Fatalf("failed to write genesis: %v", err)
+	}
+	config, _ := core.GetChainConfig(db, genesis.Hash())
+	mux := new(event.TypeMux)
+	blockchain, err := core.NewBlockChain(db, config, core.FakePow{}, mux)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	return &testChain{db: db, mux: mux, config: config, genesis: genesis, blockchain: blockchain}
+}
+
+// extend inserts n blocks on top of parent, each with one transaction, and
+// returns them. Blocks with another coinbase differ from the blocks made
+// before on the same parent.
+func (c *testChain) extend(t *testing.T, parent *types.Block, n int, coinbase common.Address) []*types.Block {
+	nonce := c.nonce
+	blocks, _ := core.GenerateChain(c.config, parent, c.db, n, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(coinbase)
+		tx, _ := types.NewTransaction(nonce, testLogger, big.NewInt(1), big.NewInt(100000), big.NewInt(1), nil).SignECDSA(testKey)
+		gen.AddTx(tx)
+		nonce++
+	})
+	if _, err := c.blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return blocks
+}
+
+// collector is an in-process sink recording the messages delivered to it as
+// seq:kind:number strings, with a - prefix for retractions. It fails while
+// failures is positive.
+type collector struct {
+	msgs     []string
+	failures int
+	lock     sync.Mutex
+}
+
+func (c *collector) Deliver(msgs []*Message) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.failures > 0 {
+		c.failures--
+		return errors.New("sink down")
+	}
+	for _, msg := range msgs {
+		entry := fmt.Sprintf("%d:%s:%d", msg.Seq, msg.Kind, msg.Block.NumberU64())
+		if msg.Removed {
+			entry = "-" + entry
+		}
+		c.msgs = append(c.msgs, entry)
+	}
+	return nil
+}
+
+// wait waits until the collector received n messages and returns them.
+func (c *collector) wait(t *testing.T, n int) []string {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(5 * time.Millisecond) {
+		c.lock.Lock()
+		if len(c.msgs) >= n {
+			msgs := c.msgs
+			c.msgs = nil
+			c.lock.Unlock()
+			return msgs
+		}
+		c.lock.Unlock()
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t.Fatalf("timeout waiting for %d messages, have %v", n, c.msgs)
+	return nil
+}
+
+// blockSeq returns the messages of a block with one transaction, numbered
+// from seq+1.
+func blockSeq(seq uint64, number uint64, removed bool) []string {
+	kinds := []Kind{KindBlock, KindTransaction, KindReceipt, KindLog}
+	prefix := ""
+	if removed {
+		kinds = []Kind{KindLog, KindReceipt, KindTransaction, KindBlock}
+		prefix = "-"
+	}
+	var msgs []string
+	for i, kind := range kinds {
+		msgs = append(msgs, fmt.Sprintf("%s%d:%s:%d", prefix, seq+uint64(i)+1, kind, number))
+	}
+	return msgs
+}
+
+func TestExporterDelivery(t *testing.T) {
+	chain := newTestChain(t)
+	main := chain.extend(t, chain.genesis, 2, common.Address{1})
+
+	db, _ := ethdb.NewMemDatabase()
+	exporter := NewExporter(chain.db, db, chain.mux)
+	all, logs := new(collector), new(collector)
+	if err := exporter.AddSink(SinkConfig{Name: "all"}, all); err != nil {
+		t.Fatalf("failed to add sink: %v", err)
+	}
+	if err := exporter.AddSink(SinkConfig{Name: "logs", From: 2, Kinds: []Kind{KindLog}, Addresses: []common.Address{testLogger}}, logs); err != nil {
+		t.Fatalf("failed to add sink: %v", err)
+	}
+	if err := exporter.AddSink(SinkConfig{Name: "all"}, all); err == nil {
+		t.Fatalf("duplicate sink added")
+	}
+	if err := exporter.Start(nil); err != nil {
+		t.Fatalf("failed to start exporter: %v", err)
+	}
+	defer exporter.Stop()
+
+	// The existing chain is delivered from the genesis or the requested block
+	want := append([]string{"1:block:0"}, blockSeq(1, 1, false)...)
+	want = append(want, blockSeq(5, 2, false)...)
+	if have := all.wait(t, len(want)); !reflect.DeepEqual(have, want) {
+		t.Fatalf("initial messages mismatch:\nhave %v\nwant %v", have, want)
+	}
+	if have := logs.wait(t, 1); !reflect.DeepEqual(have, []string{"1:log:2"}) {
+		t.Fatalf("initial logs mismatch: have %v", have)
+	}
+	// New blocks are delivered as they are inserted
+	chain.nonce = 2
+	chain.extend(t, main[1], 1, common.Address{1})
+	if have, want := all.wait(t, 4), blockSeq(9, 3, false); !reflect.DeepEqual(have, want) {
+		t.Fatalf("new block messages mismatch:\nhave %v\nwant %v", have, want)
+	}
+	// A reorg retracts the blocks that left the chain before sending the new ones
+	chain.nonce = 1
+	chain.extend(t, main[0], 3, common.Address{2})
+
+	want = append(blockSeq(13, 3, true), blockSeq(17, 2, true)...)
+	for i := uint64(0); i < 3; i++ {
+		want = append(want, blockSeq(21+4*i, 2+i, false)...)
+	}
+	if have := all.wait(t, len(want)); !reflect.DeepEqual(have, want) {
+		t.Fatalf("reorg messages mismatch:\nhave %v\nwant %v", have, want)
+	}
+	want = []string{"2:log:3", "-3:log:3", "-4:log:2", "5:log:2", "6:log:3", "7:log:4"}
+	if have := logs.wait(t, len(want)); !reflect.DeepEqual(have, want) {
+		t.Fatalf("reorg logs mismatch:\nhave %v\nwant %v", have, want)
+	}
+}
+
+func TestExporterRetry(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(t, chain.genesis, 1, common.Address{1})
+
+	db, _ := ethdb.NewMemDatabase()
+	exporter := NewExporter(chain.db, db, chain.mux)
+	sink := &collector{failures: 3}
+	exporter.AddSink(SinkConfig{Name: "flaky", From: 1}, sink)
+	if err := exporter.Start(nil); err != nil {
+		t.Fatalf("failed to start exporter: %v", err)
+	}
+	defer exporter.Stop()
+
+	// Failed deliveries are retried with the same sequence numbers
+	if have, want := sink.wait(t, 4), blockSeq(0, 1, false); !reflect.DeepEqual(have, want) {
+		t.Fatalf("messages mismatch:\nhave %v\nwant %v", have, want)
+	}
+	var status []SinkStatus
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(5 * time.Millisecond) {
+		if status = exporter.Status(); status[0].Cursor.Seq == 4 {
+			break
+		}
+	}
+	if len(status) != 1 || status[0].Cursor.Next != 2 || status[0].Cursor.Seq != 4 || status[0].Error != "" {
+		t.Fatalf("status mismatch: %+v", status)
+	}
+}
+
+func TestExporterCursor(t *testing.T) {
+	chain := newTestChain(t)
+	main := chain.extend(t, chain.genesis, 2, common.Address{1})
+
+	db, _ := ethdb.NewMemDatabase()
+	exporter := NewExporter(chain.db, db, chain.mux)
+	sink := new(collector)
+	exporter.AddSink(SinkConfig{Name: "sink", From: 1}, sink)
+	if err := exporter.Start(nil); err != nil {
+		t.Fatalf("failed to start exporter: %v", err)
+	}
+	sink.wait(t, 8)
+	exporter.Stop()
+
+	// A restarted exporter continues from the stored cursor, retracting blocks
+	// reorged out while it was down
+	chain.nonce = 1
+	chain.extend(t, main[0], 2, common.Address{2})
+
+	exporter = NewExporter(chain.db, db, chain.mux)
+	exporter.AddSink(SinkConfig{Name: "sink", From: 1}, sink)
+	if err := exporter.Start(nil); err != nil {
+		t.Fatalf("failed to restart exporter: %v", err)
+	}
+	defer exporter.Stop()
+
+	want := append(blockSeq(8, 2, true), blockSeq(12, 2, false)...)
+	want = append(want, blockSeq(16, 3, false)...)
+	if have := sink.wait(t, len(want)); !reflect.DeepEqual(have, want) {
+		t.Fatalf("resumed messages mismatch:\nhave %v\nwant %v", have, want)
+	}
+	// A replay sends the chain again from the requested block
+	if err := exporter.Replay("missing", 0); err == nil {
+		t.Fatalf("replay of unknown sink succeeded")
+	}
+	if err := exporter.Replay("sink", 2); err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	want = append(blockSeq(20, 2, false), blockSeq(24, 3, false)...)
+	if have := sink.wait(t, len(want)); !reflect.DeepEqual(have, want) {
+		t.Fatalf("replayed messages mismatch:\nhave %v\nwant %v", have, want)
+	}
+}
+
+// decodeNDJSON decodes newline delimited JSON messages into maps.
+func decodeNDJSON(t *testing.T, r *bufio.Scanner) []map[string]interface{} {
+	var msgs []map[string]interface{}
+	for r.Scan() {
+		msg := make(map[string]interface{})
+		if err := json.Unmarshal(r.Bytes(), &msg); err != nil {
+			t.Fatalf("invalid message %s: %v", r.Text(), err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestFileSink(t *testing.T) {
+	chain := newTestChain(t)
+	blocks := chain.extend(t, chain.genesis, 1, common.Address{1})
+	msgs := blockMessages(chain.db, blocks[0], false, map[Kind]bool{KindBlock: true, KindLog: true}, func(*vm.Log) bool { return true })
+	for i, msg := range msgs {
+		msg.Seq = uint64(i + 1)
+	}
+	dir, err := ioutil.TempDir("", "exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Deliveries are appended to the file
+	path := filepath.Join(dir, "events.ndjson")
+	sink := NewFileSink(path)
+	if err := sink.Deliver(msgs[:1]); err != nil {
+		t.Fatalf("failed to deliver: %v", err)
+	}
+	if err := sink.Deliver(msgs[1:]); err != nil {
+		t.Fatalf("failed to deliver: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	decoded := decodeNDJSON(t, bufio.NewScanner(file))
+	if len(decoded) != 2 {
+		t.Fatalf("message count mismatch: have %d, want 2", len(decoded))
+	}
+	if decoded[0]["kind"] != "block" || decoded[0]["seq"] != float64(1) || decoded[0]["blockHash"] != blocks[0].Hash().Hex() {
+		t.Errorf("block message mismatch: %v", decoded[0])
+	}
+	if data, _ := decoded[0]["data"].(map[string]interface{}); data["number"] != "0x1" {
+		t.Errorf("block data mismatch: %v", decoded[0]["data"])
+	}
+	if data, _ := decoded[1]["data"].(map[string]interface{}); decoded[1]["kind"] != "log" || data["address"] != testLogger.Hex() {
+		t.Errorf("log message mismatch: %v", decoded[1])
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	chain := newTestChain(t)
+	blocks := chain.extend(t, chain.genesis, 1, common.Address{1})
+	msgs := blockMessages(chain.db, blocks[0], true, map[Kind]bool{KindTransaction: true, KindReceipt: true}, func(*vm.Log) bool { return true })
+
+	var (
+		received []map[string]interface{}
+		status   = http.StatusInternalServerError
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected request %s with content type %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		received = decodeNDJSON(t, bufio.NewScanner(r.Body))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	// Messages are only delivered once the webhook accepts them
+	sink := NewWebhookSink(server.URL)
+	if err := sink.Deliver(msgs); err == nil {
+		t.Fatalf("delivery to failing webhook succeeded")
+	}
+	status = http.StatusAccepted
+	if err := sink.Deliver(msgs); err != nil {
+		t.Fatalf("failed to deliver: %v", err)
+	}
+	if len(received) != 2 || received[0]["kind"] != "receipt" || received[1]["kind"] != "transaction" || received[0]["removed"] != true {
+		t.Fatalf("received messages mismatch: %v", received)
+	}
+	if data, _ := received[1]["data"].(map[string]interface{}); data["hash"] != blocks[0].Transactions()[0].Hash().Hex() {
+		t.Errorf("transaction data mismatch: %v", received[1]["data"])
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Kind is the type of chain data a message carries.
+type Kind string
+
+const (
+	KindBlock       Kind = "block"
+	KindTransaction Kind = "transaction"
+	KindReceipt     Kind = "receipt"
+	KindLog         Kind = "log"
+)
+
+// Message is the unit of data delivered to sinks. The messages of a block
+// are its block message, then for every transaction its transaction, receipt
+// and log messages. When a block leaves the canonical chain its messages are
+// sent again in reverse order with Removed set.
+type Message struct {
+	Seq     uint64 // position in the stream of the sink, counting from 1
+	Kind    Kind
+	Removed bool // retraction of a message of a block no longer canonical
+
+	Block   *types.Block       // block the message belongs to
+	TxIndex int                // index of the transaction in the block, except for block messages
+	Tx      *types.Transaction // transaction of transaction, receipt and log messages
+	Receipt *types.Receipt     // receipt of receipt messages
+	Log     *vm.Log            // log of log messages
+}
+
+// MarshalJSON implements json.Marshaler, encoding the data of the message in
+// the format of the JSON-RPC API.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	var data interface{}
+	switch m.Kind {
+	case KindBlock:
+		header := m.Block.Header()
+		data = map[string]interface{}{
+			"number":           rpc.NewHexNumber(header.Number),
+			"hash":             m.Block.Hash(),
+			"parentHash":       header.ParentHash,
+			"miner":            header.Coinbase,
+			"timestamp":        rpc.NewHexNumber(header.Time),
+			"difficulty":       rpc.NewHexNumber(header.Difficulty),
+			"gasLimit":         rpc.NewHexNumber(header.GasLimit),
+			"gasUsed":          rpc.NewHexNumber(header.GasUsed),
+			"extraData":        fmt.Sprintf("%#x", header.Extra),
+			"stateRoot":        header.Root,
+			"transactionsRoot": header.TxHash,
+			"receiptsRoot":     header.ReceiptHash,
+			"transactionCount": rpc.NewHexNumber(len(m.Block.Transactions())),
+		}
+	case KindTransaction:
+		from, _ := m.Tx.From()
+		data = map[string]interface{}{
+			"hash":             m.Tx.Hash(),
+			"transactionIndex": rpc.NewHexNumber(m.TxIndex),
+			"from":             from,
+			"to":               m.Tx.To(),
+			"value":            rpc.NewHexNumber(m.Tx.Value()),
+			"gas":              rpc.NewHexNumber(m.Tx.Gas()),
+			"gasPrice":         rpc.NewHexNumber(m.Tx.GasPrice()),
+			"nonce":            rpc.NewHexNumber(m.Tx.Nonce()),
+			"input":            fmt.Sprintf("%#x", m.Tx.Data()),
+		}
+	case KindReceipt:
+		fields := map[string]interface{}{
+			"transactionHash":   m.Tx.Hash(),
+			"transactionIndex":  rpc.NewHexNumber(m.TxIndex),
+			"root":              common.BytesToHash(m.Receipt.PostState),
+			"gasUsed":           rpc.NewHexNumber(m.Receipt.GasUsed),
+			"cumulativeGasUsed": rpc.NewHexNumber(m.Receipt.CumulativeGasUsed),
+			"contractAddress":   nil,
+			"logCount":          rpc.NewHexNumber(len(m.Receipt.Logs)),
+		}
+		if m.Receipt.ContractAddress != (common.Address{}) {
+			fields["contractAddress"] = m.Receipt.ContractAddress
+		}
+		data = fields
+	case KindLog:
+		data = m.Log
+	default:
+		return nil, fmt.Errorf("unknown message kind %q", m.Kind)
+	}
+	return json.Marshal(map[string]interface{}{
+		"seq":         m.Seq,
+		"kind":        m.Kind,
+		"removed":     m.Removed,
+		"blockNumber": rpc.NewHexNumber(m.Block.Number()),
+		"blockHash":   m.Block.Hash(),
+		"data":        data,
+	})
+}
+
+// blockMessages returns the messages of a block, unnumbered, in the order
+// they are sent or in reverse order with Removed set if removed is set.
+// Messages of kinds not in kinds are left out, logs are left out unless the
+// filter selects them.
+func blockMessages(db ethdb.Database, block *types.Block, removed bool, kinds map[Kind]bool, selects func(*vm.Log) bool) []*Message {
+	var msgs []*Message
+	add := func(msg *Message) {
+		if kinds[msg.Kind] {
+			msg.Block, msg.Removed = block, removed
+			msgs = append(msgs, msg)
+		}
+	}
+	add(&Message{Kind: KindBlock})
+
+	receipts := core.GetBlockReceipts(db, block.Hash())
+	for i, tx := range block.Transactions() {
+		add(&Message{Kind: KindTransaction, TxIndex: i, Tx: tx})
+		if i >= len(receipts) {
+			continue
+		}
+		add(&Message{Kind: KindReceipt, TxIndex: i, Tx: tx, Receipt: receipts[i]})
+		for _, log := range receipts[i].Logs {
+			if selects(log) {
+				add(&Message{Kind: KindLog, TxIndex: i, Tx: tx, Log: log})
+			}
+		}
+	}
+	if removed {
+		for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+			msgs[i], msgs[j] = msgs[j], msgs[i]
+		}
+	}
+	return msgs
+}
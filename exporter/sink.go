@@ -0,0 +1,115 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sink receives the messages of the exporter. Deliver returns nil once the
+// messages are stored, otherwise they are delivered again later, along with
+// the messages following them. Sinks thus see messages at least once and in
+// order, and recognize repeated messages by their sequence number.
+type Sink interface {
+	Deliver(msgs []*Message) error
+}
+
+// SinkFunc adapts a function to the Sink interface, to consume messages in
+// the process of the node.
+type SinkFunc func(msgs []*Message) error
+
+// Deliver implements Sink.
+func (f SinkFunc) Deliver(msgs []*Message) error {
+	return f(msgs)
+}
+
+// writeNDJSON writes the messages as JSON, one message per line.
+func writeNDJSON(w io.Writer, msgs []*Message) error {
+	enc := json.NewEncoder(w)
+	for _, msg := range msgs {
+		if err := enc.Encode(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhookTimeout limits the time a webhook takes to accept messages.
+const webhookTimeout = 30 * time.Second
+
+// WebhookSink posts messages to a URL as newline delimited JSON. Messages are
+// delivered once the URL answers with a 2xx status.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink returns a sink posting messages to url.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// Deliver implements Sink.
+func (s *WebhookSink) Deliver(msgs []*Message) error {
+	body := new(bytes.Buffer)
+	if err := writeNDJSON(body, msgs); err != nil {
+		return err
+	}
+	res, err := s.client.Post(s.url, "application/x-ndjson", body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", s.url, res.Status)
+	}
+	return nil
+}
+
+// FileSink appends messages to a file as newline delimited JSON. Messages are
+// delivered once synced to disk.
+type FileSink struct {
+	path string
+	lock sync.Mutex
+}
+
+// NewFileSink returns a sink appending messages to the file at path, which is
+// created if missing.
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Deliver implements Sink.
+func (s *FileSink) Deliver(msgs []*Message) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if err := writeNDJSON(file, msgs); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
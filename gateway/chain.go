@@ -0,0 +1,500 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Block is the REST representation of a block.
+type Block struct {
+	Number           uint64         `json:"number"`
+	Hash             *common.Hash   `json:"hash"` // nil for the pending block
+	ParentHash       common.Hash    `json:"parentHash"`
+	Miner            common.Address `json:"miner"`
+	Timestamp        uint64         `json:"timestamp"`
+	Difficulty       *Amount        `json:"difficulty"`
+	TotalDifficulty  *Amount        `json:"totalDifficulty"`
+	GasLimit         uint64         `json:"gasLimit"`
+	GasUsed          uint64         `json:"gasUsed"`
+	Size             uint64         `json:"size"`
+	ExtraData        string         `json:"extraData"`
+	StateRoot        common.Hash    `json:"stateRoot"`
+	TransactionsRoot common.Hash    `json:"transactionsRoot"`
+	ReceiptsRoot     common.Hash    `json:"receiptsRoot"`
+	Transactions     []common.Hash  `json:"transactions"`
+}
+
+// Transaction is the REST representation of a transaction.
+type Transaction struct {
+	Hash             common.Hash     `json:"hash"`
+	BlockHash        *common.Hash    `json:"blockHash"`        // nil while pending
+	BlockNumber      *uint64         `json:"blockNumber"`      // nil while pending
+	TransactionIndex *uint64         `json:"transactionIndex"` // nil while pending
+	From             common.Address  `json:"from"`
+	To               *common.Address `json:"to"` // nil for contract creations
+	Value            *Amount         `json:"value"`
+	Gas              uint64          `json:"gas"`
+	GasPrice         *Amount         `json:"gasPrice"`
+	Nonce            uint64          `json:"nonce"`
+	Input            string          `json:"input"`
+}
+
+// Receipt is the REST representation of the receipt of a transaction.
+type Receipt struct {
+	TransactionHash   common.Hash     `json:"transactionHash"`
+	TransactionIndex  uint64          `json:"transactionIndex"`
+	BlockHash         common.Hash     `json:"blockHash"`
+	BlockNumber       uint64          `json:"blockNumber"`
+	From              common.Address  `json:"from"`
+	To                *common.Address `json:"to"`
+	ContractAddress   *common.Address `json:"contractAddress"` // set for contract creations
+	GasUsed           uint64          `json:"gasUsed"`
+	CumulativeGasUsed uint64          `json:"cumulativeGasUsed"`
+	Logs              []Log           `json:"logs"`
+}
+
+// Log is the REST representation of a log of a receipt.
+type Log struct {
+	Address  common.Address `json:"address"`
+	Topics   []common.Hash  `json:"topics"`
+	Data     string         `json:"data"`
+	LogIndex uint64         `json:"logIndex"`
+	Contract string         `json:"contract,omitempty"` // name of the emitting contract if registered
+	Event    string         `json:"event,omitempty"`    // name of the event if its contract is registered
+}
+
+// Account is the REST representation of an account.
+type Account struct {
+	Address    common.Address `json:"address"`
+	Balance    *Amount        `json:"balance"`
+	Nonce      uint64         `json:"nonce"`
+	Contract   bool           `json:"contract"`   // whether code is deployed at the address
+	Permission string         `json:"permission"` // permission level of the account
+}
+
+// TransactionRequest is the body submitting a transaction. Raw holds a signed
+// RLP encoded transaction, otherwise the node signs a transaction from the
+// other fields with an unlocked account.
+type TransactionRequest struct {
+	Raw        string          `json:"raw,omitempty"`
+	From       common.Address  `json:"from"`
+	To         *common.Address `json:"to"`
+	Value      *Amount         `json:"value"`
+	Gas        *Amount         `json:"gas"`
+	GasPrice   *Amount         `json:"gasPrice"`
+	Nonce      *Amount         `json:"nonce"`
+	Data       string          `json:"data"`
+	PrivateFor []string        `json:"privateFor,omitempty"`
+}
+
+// Submission is the response to a transaction submission.
+type Submission struct {
+	Hash common.Hash `json:"hash"`
+}
+
+type rpcBlock struct {
+	Number           *rpc.HexNumber  `json:"number"`
+	Hash             *common.Hash    `json:"hash"`
+	ParentHash       common.Hash     `json:"parentHash"`
+	Miner            common.Address  `json:"miner"`
+	Timestamp        *rpc.HexNumber  `json:"timestamp"`
+	Difficulty       *rpc.HexNumber  `json:"difficulty"`
+	TotalDifficulty  *rpc.HexNumber  `json:"totalDifficulty"`
+	GasLimit         *rpc.HexNumber  `json:"gasLimit"`
+	GasUsed          *rpc.HexNumber  `json:"gasUsed"`
+	Size             *rpc.HexNumber  `json:"size"`
+	ExtraData        string          `json:"extraData"`
+	StateRoot        common.Hash     `json:"stateRoot"`
+	TransactionsRoot common.Hash     `json:"transactionsRoot"`
+	ReceiptRoot      common.Hash     `json:"receiptRoot"`
+	Transactions     json.RawMessage `json:"transactions"`
+}
+
+type rpcTransaction struct {
+	Hash             common.Hash     `json:"hash"`
+	BlockHash        common.Hash     `json:"blockHash"`
+	BlockNumber      *rpc.HexNumber  `json:"blockNumber"`
+	TransactionIndex *rpc.HexNumber  `json:"transactionIndex"`
+	From             common.Address  `json:"from"`
+	To               *common.Address `json:"to"`
+	Value            *rpc.HexNumber  `json:"value"`
+	Gas              *rpc.HexNumber  `json:"gas"`
+	GasPrice         *rpc.HexNumber  `json:"gasPrice"`
+	Nonce            *rpc.HexNumber  `json:"nonce"`
+	Input            string          `json:"input"`
+}
+
+type rpcReceipt struct {
+	TransactionHash   common.Hash     `json:"transactionHash"`
+	TransactionIndex  *rpc.HexNumber  `json:"transactionIndex"`
+	BlockHash         common.Hash     `json:"blockHash"`
+	BlockNumber       *rpc.HexNumber  `json:"blockNumber"`
+	From              common.Address  `json:"from"`
+	To                *common.Address `json:"to"`
+	ContractAddress   *common.Address `json:"contractAddress"`
+	GasUsed           *rpc.HexNumber  `json:"gasUsed"`
+	CumulativeGasUsed *rpc.HexNumber  `json:"cumulativeGasUsed"`
+	Logs              []struct {
+		Address  common.Address `json:"address"`
+		Topics   []common.Hash  `json:"topics"`
+		Data     string         `json:"data"`
+		LogIndex *rpc.HexNumber `json:"logIndex"`
+	} `json:"logs"`
+}
+
+func (tx *rpcTransaction) export() *Transaction {
+	out := &Transaction{
+		Hash:     tx.Hash,
+		From:     tx.From,
+		To:       tx.To,
+		Value:    hexAmount(tx.Value),
+		Gas:      hexUint64(tx.Gas),
+		GasPrice: hexAmount(tx.GasPrice),
+		Nonce:    hexUint64(tx.Nonce),
+		Input:    tx.Input,
+	}
+	if tx.BlockNumber != nil {
+		hash, number, index := tx.BlockHash, hexUint64(tx.BlockNumber), hexUint64(tx.TransactionIndex)
+		out.BlockHash, out.BlockNumber, out.TransactionIndex = &hash, &number, &index
+	}
+	return out
+}
+
+// blockParam converts a block given by number or tag in a path or query into
+// the block parameter of the node.
+func blockParam(id string) (string, error) {
+	switch id {
+	case "", "latest":
+		return "latest", nil
+	case "pending", "earliest":
+		return id, nil
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return "", errorf(http.StatusBadRequest, "invalid block %q", id)
+	}
+	return "0x" + strconv.FormatUint(n, 16), nil
+}
+
+// block retrieves a block by number, hash or tag.
+func (g *Gateway) block(id string, full bool) (*rpcBlock, error) {
+	var (
+		block *rpcBlock
+		err   error
+	)
+	if strings.HasPrefix(id, "0x") {
+		if len(id) != 2+2*common.HashLength {
+			return nil, errorf(http.StatusBadRequest, "invalid block hash %q", id)
+		}
+		err = g.call(&block, "eth_getBlockByHash", id, full)
+	} else {
+		var param string
+		if param, err = blockParam(id); err != nil {
+			return nil, err
+		}
+		err = g.call(&block, "eth_getBlockByNumber", param, full)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errorf(http.StatusNotFound, "block %s not found", id)
+	}
+	return block, nil
+}
+
+// summary converts a block retrieved without transaction details.
+func (b *rpcBlock) summary() (*Block, error) {
+	out := &Block{
+		Number:           hexUint64(b.Number),
+		Hash:             b.Hash,
+		ParentHash:       b.ParentHash,
+		Miner:            b.Miner,
+		Timestamp:        hexUint64(b.Timestamp),
+		Difficulty:       hexAmount(b.Difficulty),
+		TotalDifficulty:  hexAmount(b.TotalDifficulty),
+		GasLimit:         hexUint64(b.GasLimit),
+		GasUsed:          hexUint64(b.GasUsed),
+		Size:             hexUint64(b.Size),
+		ExtraData:        b.ExtraData,
+		StateRoot:        b.StateRoot,
+		TransactionsRoot: b.TransactionsRoot,
+		ReceiptsRoot:     b.ReceiptRoot,
+		Transactions:     []common.Hash{},
+	}
+	if len(b.Transactions) > 0 {
+		if err := json.Unmarshal(b.Transactions, &out.Transactions); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (g *Gateway) getBlock(r *http.Request, params []string, body []byte) (interface{}, error) {
+	block, err := g.block(params[0], false)
+	if err != nil {
+		return nil, err
+	}
+	return block.summary()
+}
+
+// listBlocks serves the blocks from the cursor, or the head, back to genesis.
+func (g *Gateway) listBlocks(r *http.Request, params []string, body []byte) (interface{}, error) {
+	count, err := limit(r)
+	if err != nil {
+		return nil, err
+	}
+	var head rpc.HexNumber
+	if err := g.call(&head, "eth_blockNumber"); err != nil {
+		return nil, err
+	}
+	from := head.Uint64()
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		n, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "invalid cursor %q", cursor)
+		}
+		if n < from {
+			from = n
+		}
+	}
+	page := &Page{}
+	blocks := []*Block{}
+	for number := from; len(blocks) < count; number-- {
+		block, err := g.block(strconv.FormatUint(number, 10), false)
+		if err != nil {
+			return nil, err
+		}
+		summary, err := block.summary()
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, summary)
+		if number == 0 {
+			break
+		}
+		if len(blocks) == count {
+			page.Next = strconv.FormatUint(number-1, 10)
+		}
+	}
+	page.Items = blocks
+	return page, nil
+}
+
+// listBlockTransactions serves the transactions of a block from the index in
+// the cursor on.
+func (g *Gateway) listBlockTransactions(r *http.Request, params []string, body []byte) (interface{}, error) {
+	count, err := limit(r)
+	if err != nil {
+		return nil, err
+	}
+	var from int
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		if from, err = strconv.Atoi(cursor); err != nil || from < 0 {
+			return nil, errorf(http.StatusBadRequest, "invalid cursor %q", cursor)
+		}
+	}
+	block, err := g.block(params[0], true)
+	if err != nil {
+		return nil, err
+	}
+	var txs []*rpcTransaction
+	if err := json.Unmarshal(block.Transactions, &txs); err != nil {
+		return nil, err
+	}
+	page := &Page{}
+	items := []*Transaction{}
+	for i := from; i < len(txs) && len(items) < count; i++ {
+		items = append(items, txs[i].export())
+	}
+	if from+count < len(txs) {
+		page.Next = strconv.Itoa(from + count)
+	}
+	page.Items = items
+	return page, nil
+}
+
+// hashParam parses a hash in a path.
+func hashParam(value string) (common.Hash, error) {
+	if !strings.HasPrefix(value, "0x") || len(value) != 2+2*common.HashLength {
+		return common.Hash{}, errorf(http.StatusBadRequest, "invalid hash %q", value)
+	}
+	return common.HexToHash(value), nil
+}
+
+// addressParam parses an address in a path or body.
+func addressParam(value string) (common.Address, error) {
+	if !common.IsHexAddress(value) {
+		return common.Address{}, errorf(http.StatusBadRequest, "invalid address %q", value)
+	}
+	return common.HexToAddress(value), nil
+}
+
+func (g *Gateway) transaction(hash common.Hash) (*rpcTransaction, error) {
+	var tx *rpcTransaction
+	if err := g.call(&tx, "eth_getTransactionByHash", hash); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (g *Gateway) getTransaction(r *http.Request, params []string, body []byte) (interface{}, error) {
+	hash, err := hashParam(params[0])
+	if err != nil {
+		return nil, err
+	}
+	tx, err := g.transaction(hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, errorf(http.StatusNotFound, "transaction %s not found", params[0])
+	}
+	return tx.export(), nil
+}
+
+func (g *Gateway) getReceipt(r *http.Request, params []string, body []byte) (interface{}, error) {
+	hash, err := hashParam(params[0])
+	if err != nil {
+		return nil, err
+	}
+	var receipt *rpcReceipt
+	if err := g.call(&receipt, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, errorf(http.StatusNotFound, "receipt of %s not found", params[0])
+	}
+	out := &Receipt{
+		TransactionHash:   receipt.TransactionHash,
+		TransactionIndex:  hexUint64(receipt.TransactionIndex),
+		BlockHash:         receipt.BlockHash,
+		BlockNumber:       hexUint64(receipt.BlockNumber),
+		From:              receipt.From,
+		To:                receipt.To,
+		ContractAddress:   receipt.ContractAddress,
+		GasUsed:           hexUint64(receipt.GasUsed),
+		CumulativeGasUsed: hexUint64(receipt.CumulativeGasUsed),
+		Logs:              []Log{},
+	}
+	for _, log := range receipt.Logs {
+		entry := Log{Address: log.Address, Topics: log.Topics, Data: log.Data, LogIndex: hexUint64(log.LogIndex)}
+		if entry.Topics == nil {
+			entry.Topics = []common.Hash{}
+		}
+		entry.Contract, entry.Event = g.event(log.Address, log.Topics)
+		out.Logs = append(out.Logs, entry)
+	}
+	return out, nil
+}
+
+func (g *Gateway) getAccount(r *http.Request, params []string, body []byte) (interface{}, error) {
+	address, err := addressParam(params[0])
+	if err != nil {
+		return nil, err
+	}
+	block, err := blockParam(r.URL.Query().Get("block"))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		balance, nonce rpc.HexNumber
+		code           string
+		account        = &Account{Address: address}
+	)
+	if err := g.call(&balance, "eth_getBalance", address, block); err != nil {
+		return nil, err
+	}
+	if err := g.call(&nonce, "eth_getTransactionCount", address, block); err != nil {
+		return nil, err
+	}
+	if err := g.call(&code, "eth_getCode", address, block); err != nil {
+		return nil, err
+	}
+	if err := g.call(&account.Permission, "eth_getPermission", address, block); err != nil {
+		return nil, err
+	}
+	account.Balance = hexAmount(&balance)
+	account.Nonce = nonce.Uint64()
+	account.Contract = len(common.FromHex(code)) > 0
+	return account, nil
+}
+
+// sendTransaction submits a signed transaction, or one the node signs.
+func (g *Gateway) sendTransaction(r *http.Request, params []string, body []byte) (interface{}, error) {
+	var req TransactionRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.Raw != "" {
+		return g.sendRawTransaction(common.FromHex(req.Raw))
+	}
+	return g.submit(&req, common.FromHex(req.Data))
+}
+
+// sendRawTransaction submits a signed transaction. Transactions the node
+// already knows are accepted again, so that submissions can be retried.
+func (g *Gateway) sendRawTransaction(raw []byte) (*Submission, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, tx); err != nil {
+		return nil, errorf(http.StatusBadRequest, "invalid raw transaction: %v", err)
+	}
+	err := g.call(nil, "eth_sendRawTransaction", common.ToHex(raw))
+	if _, refused := err.(*remoteError); refused {
+		if known, _ := g.transaction(tx.Hash()); known != nil {
+			return &Submission{tx.Hash()}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Submission{tx.Hash()}, nil
+}
+
+// submit sends a transaction signed by the node with the given data.
+func (g *Gateway) submit(req *TransactionRequest, data []byte) (*Submission, error) {
+	if req.From == (common.Address{}) {
+		return nil, errorf(http.StatusBadRequest, "missing sender")
+	}
+	args := map[string]interface{}{
+		"from": req.From,
+		"data": "0x" + common.Bytes2Hex(data),
+	}
+	if req.To != nil {
+		args["to"] = req.To
+	}
+	if req.Value != nil {
+		args["value"] = hexQuantity(req.Value)
+	}
+	if req.Gas != nil {
+		args["gas"] = hexQuantity(req.Gas)
+	}
+	if req.GasPrice != nil {
+		args["gasPrice"] = hexQuantity(req.GasPrice)
+	}
+	if req.Nonce != nil {
+		args["nonce"] = hexQuantity(req.Nonce)
+	}
+	if len(req.PrivateFor) > 0 {
+		args["privateFor"] = req.PrivateFor
+	}
+	var hash common.Hash
+	if err := g.call(&hash, "eth_sendTransaction", args); err != nil {
+		return nil, err
+	}
+	return &Submission{hash}, nil
+}
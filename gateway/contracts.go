@@ -0,0 +1,481 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package gateway
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Contract is a contract registered with the gateway.
+type Contract struct {
+	Name    string          `json:"name"`
+	Address common.Address  `json:"address"`
+	ABI     json.RawMessage `json:"abi"`     // JSON ABI definition
+	Methods []string        `json:"methods"` // Solidity signatures of the methods
+
+	abi abi.ABI
+}
+
+// ContractRequest is the body registering a contract.
+type ContractRequest struct {
+	Address common.Address  `json:"address"`
+	ABI     json.RawMessage `json:"abi"`
+}
+
+// MethodRequest is the body calling or transacting with a contract method.
+// Args holds the arguments either as a list or as an object keyed by their
+// names. Integers are given as numbers or decimal or hex strings, bytes as
+// hex strings.
+type MethodRequest struct {
+	Args       json.RawMessage `json:"args"`
+	From       common.Address  `json:"from"`
+	Value      *Amount         `json:"value"`
+	Gas        *Amount         `json:"gas"`
+	GasPrice   *Amount         `json:"gasPrice"`
+	Nonce      *Amount         `json:"nonce"`      // Transactions only
+	PrivateFor []string        `json:"privateFor"` // Transactions only
+	Block      string          `json:"block"`      // Calls only: block number, latest or pending
+}
+
+// Register adds a contract at address with the given JSON ABI definition,
+// replacing any contract registered under the same name.
+func (g *Gateway) Register(name string, address common.Address, definition []byte) (*Contract, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid contract name %q", name)
+	}
+	parsed, err := abi.JSON(bytes.NewReader(definition))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ABI: %v", err)
+	}
+	contract := &Contract{
+		Name:    name,
+		Address: address,
+		ABI:     json.RawMessage(definition),
+		Methods: []string{},
+		abi:     parsed,
+	}
+	for _, method := range parsed.Methods {
+		contract.Methods = append(contract.Methods, method.String())
+	}
+	sort.Strings(contract.Methods)
+
+	g.contractsLock.Lock()
+	g.contracts[name] = contract
+	g.contractsLock.Unlock()
+	return contract, nil
+}
+
+// Unregister removes the contract registered under name, returning whether
+// it was registered.
+func (g *Gateway) Unregister(name string) bool {
+	g.contractsLock.Lock()
+	defer g.contractsLock.Unlock()
+
+	_, ok := g.contracts[name]
+	delete(g.contracts, name)
+	return ok
+}
+
+// contract returns the contract registered under name.
+func (g *Gateway) contract(name string) (*Contract, error) {
+	g.contractsLock.RLock()
+	defer g.contractsLock.RUnlock()
+
+	if contract, ok := g.contracts[name]; ok {
+		return contract, nil
+	}
+	return nil, errorf(http.StatusNotFound, "contract %s not registered", name)
+}
+
+// event returns the names of the registered contract at address and of its
+// event matching topics, if any.
+func (g *Gateway) event(address common.Address, topics []common.Hash) (string, string) {
+	g.contractsLock.RLock()
+	defer g.contractsLock.RUnlock()
+
+	for _, contract := range g.contracts {
+		if contract.Address != address {
+			continue
+		}
+		if len(topics) > 0 {
+			for _, event := range contract.abi.Events {
+				if event.Id() == topics[0] {
+					return contract.Name, event.Name
+				}
+			}
+		}
+		return contract.Name, ""
+	}
+	return "", ""
+}
+
+// listContracts serves the registered contracts by name from the one in the
+// cursor on.
+func (g *Gateway) listContracts(r *http.Request, params []string, body []byte) (interface{}, error) {
+	count, err := limit(r)
+	if err != nil {
+		return nil, err
+	}
+	cursor := r.URL.Query().Get("cursor")
+
+	g.contractsLock.RLock()
+	var names []string
+	for name := range g.contracts {
+		if name >= cursor {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	page := &Page{}
+	if len(names) > count {
+		page.Next, names = names[count], names[:count]
+	}
+	contracts := []*Contract{}
+	for _, name := range names {
+		contracts = append(contracts, g.contracts[name])
+	}
+	g.contractsLock.RUnlock()
+
+	page.Items = contracts
+	return page, nil
+}
+
+func (g *Gateway) putContract(r *http.Request, params []string, body []byte) (interface{}, error) {
+	var req ContractRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.Address == (common.Address{}) || len(req.ABI) == 0 {
+		return nil, errorf(http.StatusBadRequest, "address and abi required")
+	}
+	contract, err := g.Register(params[0], req.Address, req.ABI)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "%v", err)
+	}
+	return contract, nil
+}
+
+func (g *Gateway) getContract(r *http.Request, params []string, body []byte) (interface{}, error) {
+	return g.contract(params[0])
+}
+
+func (g *Gateway) deleteContract(r *http.Request, params []string, body []byte) (interface{}, error) {
+	contract, err := g.contract(params[0])
+	if err != nil {
+		return nil, err
+	}
+	g.Unregister(params[0])
+	return contract, nil
+}
+
+// method returns the contract, method and call data of a method request.
+func (g *Gateway) method(params []string, body []byte) (*Contract, abi.Method, *MethodRequest, []byte, error) {
+	contract, err := g.contract(params[0])
+	if err != nil {
+		return nil, abi.Method{}, nil, nil, err
+	}
+	method, ok := contract.abi.Methods[params[1]]
+	if !ok {
+		return nil, abi.Method{}, nil, nil, errorf(http.StatusNotFound, "contract %s has no method %s", params[0], params[1])
+	}
+	req := new(MethodRequest)
+	if len(body) > 0 {
+		if err := decode(body, req); err != nil {
+			return nil, abi.Method{}, nil, nil, err
+		}
+	}
+	args, err := packArguments(method, req.Args)
+	if err != nil {
+		return nil, abi.Method{}, nil, nil, errorf(http.StatusBadRequest, "%s: %v", method.Name, err)
+	}
+	data, err := contract.abi.Pack(method.Name, args...)
+	if err != nil {
+		return nil, abi.Method{}, nil, nil, errorf(http.StatusBadRequest, "%v", err)
+	}
+	return contract, method, req, data, nil
+}
+
+// callContract runs a method call on the node without a transaction and
+// serves its outputs keyed by name, or by position if unnamed.
+func (g *Gateway) callContract(r *http.Request, params []string, body []byte) (interface{}, error) {
+	contract, method, req, data, err := g.method(params, body)
+	if err != nil {
+		return nil, err
+	}
+	block, err := blockParam(req.Block)
+	if err != nil {
+		return nil, err
+	}
+	args := map[string]interface{}{
+		"from": req.From,
+		"to":   contract.Address,
+		"data": common.ToHex(data),
+	}
+	if req.Value != nil {
+		args["value"] = hexQuantity(req.Value)
+	}
+	if req.Gas != nil {
+		args["gas"] = hexQuantity(req.Gas)
+	}
+	if req.GasPrice != nil {
+		args["gasPrice"] = hexQuantity(req.GasPrice)
+	}
+	var result string
+	if err := g.call(&result, "eth_call", args, block); err != nil {
+		return nil, err
+	}
+	outputs := make(map[string]interface{})
+	if len(method.Outputs) == 0 {
+		return outputs, nil
+	}
+	output := common.FromHex(result)
+	if len(output) == 0 {
+		return nil, &remoteError{Message: fmt.Sprintf("%s returned no data", method.Name)}
+	}
+	values := make([]interface{}, 0, len(method.Outputs))
+	if len(method.Outputs) == 1 {
+		var value interface{}
+		err = contract.abi.Unpack(&value, method.Name, output)
+		values = append(values, value)
+	} else {
+		err = contract.abi.Unpack(&values, method.Name, output)
+	}
+	if err != nil {
+		return nil, &remoteError{Message: fmt.Sprintf("%s: %v", method.Name, err)}
+	}
+	for i, output := range method.Outputs {
+		name := output.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		outputs[name] = jsonValue(output.Type, values[i])
+	}
+	return outputs, nil
+}
+
+// transactContract sends a transaction signed by the node calling a method.
+func (g *Gateway) transactContract(r *http.Request, params []string, body []byte) (interface{}, error) {
+	contract, _, req, data, err := g.method(params, body)
+	if err != nil {
+		return nil, err
+	}
+	return g.submit(&TransactionRequest{
+		From:       req.From,
+		To:         &contract.Address,
+		Value:      req.Value,
+		Gas:        req.Gas,
+		GasPrice:   req.GasPrice,
+		Nonce:      req.Nonce,
+		PrivateFor: req.PrivateFor,
+	}, data)
+}
+
+// packArguments converts the JSON arguments of a method into the values
+// packed by the abi package.
+func packArguments(method abi.Method, raw json.RawMessage) ([]interface{}, error) {
+	var list []json.RawMessage
+	if len(raw) > 0 && raw[0] == '{' {
+		var named map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &named); err != nil {
+			return nil, err
+		}
+		for _, input := range method.Inputs {
+			value, ok := named[input.Name]
+			if !ok {
+				return nil, fmt.Errorf("missing argument %s", input.Name)
+			}
+			list = append(list, value)
+		}
+	} else if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+	}
+	if len(list) != len(method.Inputs) {
+		return nil, fmt.Errorf("argument count mismatch: %d for %d", len(list), len(method.Inputs))
+	}
+	args := make([]interface{}, len(list))
+	for i, input := range method.Inputs {
+		value, err := abiValue(input.Type, list[i])
+		if err != nil {
+			return nil, fmt.Errorf("argument %d (%s): %v", i, input.Type, err)
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+
+var (
+	bigType     = reflect.TypeOf((*big.Int)(nil))
+	bytesType   = reflect.TypeOf([]byte(nil))
+	addressType = reflect.TypeOf(common.Address{})
+)
+
+// goType returns the Go type the abi package packs for t.
+func goType(t abi.Type) reflect.Type {
+	switch {
+	case t.T == abi.BytesTy || t.T == abi.FixedBytesTy:
+		return bytesType
+	case t.IsSlice || t.IsArray:
+		return reflect.SliceOf(goType(*t.Elem))
+	}
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		switch t.Kind {
+		case reflect.Uint8:
+			return reflect.TypeOf(uint8(0))
+		case reflect.Uint16:
+			return reflect.TypeOf(uint16(0))
+		case reflect.Uint32:
+			return reflect.TypeOf(uint32(0))
+		case reflect.Uint64:
+			return reflect.TypeOf(uint64(0))
+		case reflect.Int8:
+			return reflect.TypeOf(int8(0))
+		case reflect.Int16:
+			return reflect.TypeOf(int16(0))
+		case reflect.Int32:
+			return reflect.TypeOf(int32(0))
+		case reflect.Int64:
+			return reflect.TypeOf(int64(0))
+		}
+		return bigType
+	case abi.BoolTy:
+		return reflect.TypeOf(false)
+	case abi.AddressTy:
+		return addressType
+	}
+	return reflect.TypeOf("")
+}
+
+// abiValue converts the JSON value of an argument into the value of type
+// goType(t).
+func abiValue(t abi.Type, raw json.RawMessage) (interface{}, error) {
+	switch {
+	case t.T == abi.BytesTy || t.T == abi.FixedBytesTy:
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, fmt.Errorf("bytes must be a hex string")
+		}
+		data, err := hex.DecodeString(strings.TrimPrefix(text, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex string %q", text)
+		}
+		if t.T == abi.FixedBytesTy {
+			if len(data) > t.SliceSize {
+				return nil, fmt.Errorf("%d bytes exceed %d", len(data), t.SliceSize)
+			}
+			data = common.RightPadBytes(data, t.SliceSize)
+		}
+		return data, nil
+
+	case t.IsSlice || t.IsArray:
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("expected a list")
+		}
+		if t.IsArray && len(items) != t.SliceSize {
+			return nil, fmt.Errorf("expected %d items, have %d", t.SliceSize, len(items))
+		}
+		slice := reflect.MakeSlice(goType(t), 0, len(items))
+		for _, item := range items {
+			value, err := abiValue(*t.Elem, item)
+			if err != nil {
+				return nil, err
+			}
+			slice = reflect.Append(slice, reflect.ValueOf(value))
+		}
+		return slice.Interface(), nil
+	}
+
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		n, err := parseInteger(raw)
+		if err != nil {
+			return nil, err
+		}
+		if t.T == abi.UintTy && (n.Sign() < 0 || n.BitLen() > t.Size) {
+			return nil, fmt.Errorf("%v out of range", n)
+		}
+		if t.T == abi.IntTy {
+			if bound := new(big.Int).Lsh(common.Big1, uint(t.Size-1)); n.Cmp(bound) >= 0 || n.Cmp(new(big.Int).Neg(bound)) < 0 {
+				return nil, fmt.Errorf("%v out of range", n)
+			}
+		}
+		value := reflect.New(goType(t)).Elem()
+		switch t.Kind {
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value.SetUint(n.Uint64())
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			value.SetInt(n.Int64())
+		default:
+			return n, nil
+		}
+		return value.Interface(), nil
+
+	case abi.BoolTy:
+		var value bool
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("expected a boolean")
+		}
+		return value, nil
+
+	case abi.AddressTy:
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil || !common.IsHexAddress(text) {
+			return nil, fmt.Errorf("invalid address %s", raw)
+		}
+		return common.HexToAddress(text), nil
+
+	case abi.StringTy:
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, fmt.Errorf("expected a string")
+		}
+		return text, nil
+	}
+	return nil, fmt.Errorf("unsupported type")
+}
+
+// jsonValue converts an output unpacked by the abi package for its JSON
+// representation: integers wider than 32 bits become decimal strings and
+// bytes hex strings.
+func jsonValue(t abi.Type, value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []byte:
+		if t.T == abi.FixedBytesTy && len(v) > t.SliceSize {
+			v = v[:t.SliceSize]
+		}
+		return common.ToHex(v)
+	case []*big.Int:
+		items := make([]string, len(v))
+		for i, n := range v {
+			items[i] = n.String()
+		}
+		return items
+	}
+	return value
+}
@@ -0,0 +1,263 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// Package gateway serves the chain of a node as REST resources.
+//
+// The gateway is an http.Handler on top of an RPC client connected to a node.
+// It exposes blocks, transactions, receipts and accounts as JSON documents
+// with decimal amounts instead of hex quantities, calls and transacts with
+// contracts whose ABI is registered at runtime, and lets clients retry
+// transaction submissions safely with an Idempotency-Key header:
+//
+//	GET    /blocks                          newest blocks first, paginated
+//	GET    /blocks/{number|hash|latest}     a block
+//	GET    /blocks/{id}/transactions        its transactions, paginated
+//	GET    /transactions/{hash}             a transaction
+//	GET    /transactions/{hash}/receipt     the receipt of a transaction
+//	POST   /transactions                    submit a signed or node-signed transaction
+//	GET    /accounts/{address}              balance, nonce and permission level
+//	GET    /contracts                       registered contracts, paginated
+//	PUT    /contracts/{name}                register a contract address and ABI
+//	GET    /contracts/{name}                a registered contract
+//	DELETE /contracts/{name}                unregister a contract
+//	POST   /contracts/{name}/call/{method}     call a method without a transaction
+//	POST   /contracts/{name}/transact/{method} send a transaction calling a method
+//
+// Lists take the limit and cursor query parameters and return the cursor of
+// the following page as next.
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	defaultLimit = 20                // Items per page if the request doesn't set a limit
+	maxLimit     = 100               // Most items served in a page
+	maxBody      = 1024 * 128        // Largest request body accepted, matching the HTTP-RPC server
+	idempotency  = 24 * time.Hour    // Time submission responses are kept for retries
+	keyHeader    = "Idempotency-Key" // Request header identifying a submission
+)
+
+// Gateway serves the REST resources of the node reached by an RPC client.
+type Gateway struct {
+	client rpc.Client // RPC client connection to the node
+	autoid uint32     // ID number to use for the next API request
+	lock   sync.Mutex // Singleton access to the client, which isn't concurrent
+
+	contracts     map[string]*Contract // Registered contracts by name
+	contractsLock sync.RWMutex
+
+	submissions *submissions
+	routes      []route
+}
+
+// New creates a gateway to the node client is connected to.
+func New(client rpc.Client) *Gateway {
+	g := &Gateway{
+		client:      client,
+		contracts:   make(map[string]*Contract),
+		submissions: newSubmissions(idempotency),
+	}
+	g.routes = []route{
+		{"GET", "blocks", g.listBlocks, false},
+		{"GET", "blocks/*", g.getBlock, false},
+		{"GET", "blocks/*/transactions", g.listBlockTransactions, false},
+		{"GET", "transactions/*", g.getTransaction, false},
+		{"GET", "transactions/*/receipt", g.getReceipt, false},
+		{"POST", "transactions", g.sendTransaction, true},
+		{"GET", "accounts/*", g.getAccount, false},
+		{"GET", "contracts", g.listContracts, false},
+		{"PUT", "contracts/*", g.putContract, false},
+		{"GET", "contracts/*", g.getContract, false},
+		{"DELETE", "contracts/*", g.deleteContract, false},
+		{"POST", "contracts/*/call/*", g.callContract, false},
+		{"POST", "contracts/*/transact/*", g.transactContract, true},
+	}
+	return g
+}
+
+// handler serves a request given the wildcard segments of its path and its
+// body, returning the document to respond with.
+type handler func(r *http.Request, params []string, body []byte) (interface{}, error)
+
+type route struct {
+	method     string
+	path       string // Path segments, * matching any single segment
+	handle     handler
+	idempotent bool // Whether retries carrying the same Idempotency-Key are answered from the first response
+}
+
+// match returns the wildcard segments of path if it matches the route.
+func (r route) match(path []string) ([]string, bool) {
+	pattern := strings.Split(r.path, "/")
+	if len(pattern) != len(path) {
+		return nil, false
+	}
+	var params []string
+	for i, segment := range pattern {
+		switch {
+		case segment == "*":
+			params = append(params, path[i])
+		case segment != path[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// Error is an error served with an HTTP status.
+type Error struct {
+	Status  int    `json:"-"`
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func errorf(status int, format string, args ...interface{}) *Error {
+	return &Error{status, fmt.Sprintf(format, args...)}
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	var allowed []string
+	for _, route := range g.routes {
+		params, ok := route.match(path)
+		if !ok {
+			continue
+		}
+		if route.method != r.Method {
+			allowed = append(allowed, route.method)
+			continue
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if err != nil {
+			writeJSON(w, http.StatusRequestEntityTooLarge, &Error{Message: err.Error()})
+			return
+		}
+		if key := r.Header.Get(keyHeader); route.idempotent && key != "" {
+			g.serveIdempotent(w, r, key, route.handle, params, body)
+			return
+		}
+		status, doc := g.serve(route.handle, r, params, body)
+		writeJSON(w, status, doc)
+		return
+	}
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeJSON(w, http.StatusMethodNotAllowed, errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusNotFound, errorf(http.StatusNotFound, "no resource at %s", r.URL.Path))
+}
+
+// serve runs a handler and returns the status and document of its response.
+func (g *Gateway) serve(handle handler, r *http.Request, params []string, body []byte) (int, interface{}) {
+	doc, err := handle(r, params, body)
+	switch err := err.(type) {
+	case nil:
+		return http.StatusOK, doc
+	case *Error:
+		return err.Status, err
+	case *remoteError:
+		// The node refused a well formed request
+		return http.StatusUnprocessableEntity, &Error{Message: err.Error()}
+	default:
+		glog.V(logger.Debug).Infof("Gateway %s %s failed: %v", r.Method, r.URL.Path, err)
+		return http.StatusBadGateway, &Error{Message: err.Error()}
+	}
+}
+
+// serveIdempotent serves a submission once per idempotency key. Retries get
+// the response of the first successful submission; failed ones may be retried.
+func (g *Gateway) serveIdempotent(w http.ResponseWriter, r *http.Request, key string, handle handler, params []string, body []byte) {
+	fingerprint := crypto.Keccak256Hash([]byte(r.Method), []byte(r.URL.Path), body)
+	for {
+		sub, owner, err := g.submissions.begin(key, fingerprint)
+		if err != nil {
+			writeJSON(w, http.StatusConflict, errorf(http.StatusConflict, "%v", err))
+			return
+		}
+		if owner {
+			status, doc := g.serve(handle, r, params, body)
+			blob, _ := json.Marshal(doc)
+			g.submissions.finish(key, sub, status, blob)
+			writeBlob(w, status, blob)
+			return
+		}
+		// Another request carries the key, wait for its response
+		<-sub.done
+		if sub.body != nil {
+			w.Header().Set("Idempotent-Replayed", "true")
+			writeBlob(w, sub.status, sub.body)
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, doc interface{}) {
+	blob, err := json.Marshal(doc)
+	if err != nil {
+		status, blob = http.StatusInternalServerError, []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	writeBlob(w, status, blob)
+}
+
+func writeBlob(w http.ResponseWriter, status int, blob []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(blob)
+	w.Write([]byte("\n"))
+}
+
+// Page is a part of a listed collection. Next is the cursor of the following
+// page, empty on the last one.
+type Page struct {
+	Items interface{} `json:"items"`
+	Next  string      `json:"next,omitempty"`
+}
+
+// limit returns the page size requested by r.
+func limit(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultLimit, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errorf(http.StatusBadRequest, "invalid limit %q", value)
+	}
+	if n > maxLimit {
+		n = maxLimit
+	}
+	return n, nil
+}
+
+// decode parses the JSON body of a request into v.
+func decode(body []byte, v interface{}) error {
+	if len(body) == 0 {
+		return errorf(http.StatusBadRequest, "missing request body")
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return errorf(http.StatusBadRequest, "invalid request body: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,534 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const tokenABI = `[
+	{"type": "function", "name": "balanceOf", "constant": true,
+	 "inputs": [{"name": "owner", "type": "address"}], "outputs": [{"name": "balance", "type": "uint256"}]},
+	{"type": "function", "name": "info", "constant": true,
+	 "inputs": [], "outputs": [{"name": "name", "type": "string"}, {"name": "decimals", "type": "uint8"}, {"name": "", "type": "bytes32"}]},
+	{"type": "function", "name": "transfer", "constant": false,
+	 "inputs": [{"name": "to", "type": "address"}, {"name": "amount", "type": "uint256"}, {"name": "memo", "type": "bytes"}],
+	 "outputs": [{"name": "ok", "type": "bool"}]},
+	{"type": "event", "name": "Transfer",
+	 "inputs": [{"name": "from", "type": "address"}, {"name": "to", "type": "address"}, {"name": "amount", "type": "uint256"}]}
+]`
+
+var (
+	testToken   = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	testAccount = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+)
+
+// FakeSendArgs are the arguments of transactions the test node signs.
+type FakeSendArgs struct {
+	From       common.Address  `json:"from"`
+	To         *common.Address `json:"to"`
+	Value      *rpc.HexNumber  `json:"value"`
+	Data       string          `json:"data"`
+	PrivateFor []string        `json:"privateFor"`
+}
+
+// FakeNode serves the eth API the gateway uses from a fixed chain.
+type FakeNode struct {
+	blocks   []map[string]interface{}
+	txs      map[common.Hash]map[string]interface{}
+	receipts map[common.Hash]map[string]interface{}
+	call     func(data []byte) []byte
+
+	sent []FakeSendArgs
+	lock sync.Mutex
+}
+
+func newTestNode(blocks int) *FakeNode {
+	node := &FakeNode{
+		txs:      make(map[common.Hash]map[string]interface{}),
+		receipts: make(map[common.Hash]map[string]interface{}),
+	}
+	parent := common.Hash{}
+	for i := 0; i < blocks; i++ {
+		hash := common.BytesToHash([]byte{0xb0, byte(i)})
+		var txs []map[string]interface{}
+		for j := 0; j < i; j++ {
+			tx := map[string]interface{}{
+				"hash":             common.BytesToHash([]byte{0x70, byte(i), byte(j)}),
+				"blockHash":        hash,
+				"blockNumber":      rpc.NewHexNumber(i),
+				"transactionIndex": rpc.NewHexNumber(j),
+				"from":             testAccount,
+				"to":               testToken,
+				"value":            rpc.NewHexNumber(new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)),
+				"gas":              rpc.NewHexNumber(90000),
+				"gasPrice":         rpc.NewHexNumber(50),
+				"nonce":            rpc.NewHexNumber(j),
+				"input":            "0x",
+			}
+			txs = append(txs, tx)
+			node.txs[tx["hash"].(common.Hash)] = tx
+		}
+		node.blocks = append(node.blocks, map[string]interface{}{
+			"number":           rpc.NewHexNumber(i),
+			"hash":             hash,
+			"parentHash":       parent,
+			"miner":            testAccount,
+			"timestamp":        rpc.NewHexNumber(1500000000 + i),
+			"difficulty":       rpc.NewHexNumber(131072),
+			"totalDifficulty":  rpc.NewHexNumber(131072 * (i + 1)),
+			"gasLimit":         rpc.NewHexNumber(4712388),
+			"gasUsed":          rpc.NewHexNumber(21000 * i),
+			"size":             rpc.NewHexNumber(540),
+			"extraData":        "0x",
+			"stateRoot":        common.Hash{},
+			"transactionsRoot": common.Hash{},
+			"receiptRoot":      common.Hash{},
+			"transactions":     txs,
+			"uncles":           []common.Hash{},
+		})
+		parent = hash
+	}
+	return node
+}
+
+func (n *FakeNode) BlockNumber() *big.Int {
+	return big.NewInt(int64(len(n.blocks) - 1))
+}
+
+func (n *FakeNode) output(block map[string]interface{}, full bool) map[string]interface{} {
+	out := make(map[string]interface{})
+	for key, value := range block {
+		out[key] = value
+	}
+	if !full {
+		hashes := []common.Hash{}
+		for _, tx := range block["transactions"].([]map[string]interface{}) {
+			hashes = append(hashes, tx["hash"].(common.Hash))
+		}
+		out["transactions"] = hashes
+	}
+	return out
+}
+
+func (n *FakeNode) GetBlockByNumber(number rpc.BlockNumber, full bool) map[string]interface{} {
+	if number < 0 {
+		number = rpc.BlockNumber(len(n.blocks) - 1)
+	}
+	if int(number) >= len(n.blocks) {
+		return nil
+	}
+	return n.output(n.blocks[number], full)
+}
+
+func (n *FakeNode) GetBlockByHash(hash common.Hash, full bool) map[string]interface{} {
+	for _, block := range n.blocks {
+		if block["hash"] == hash {
+			return n.output(block, full)
+		}
+	}
+	return nil
+}
+
+func (n *FakeNode) GetTransactionByHash(hash common.Hash) map[string]interface{} {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.txs[hash]
+}
+
+func (n *FakeNode) GetTransactionReceipt(hash common.Hash) map[string]interface{} {
+	return n.receipts[hash]
+}
+
+func (n *FakeNode) GetBalance(address common.Address, block rpc.BlockNumber) *big.Int {
+	balance, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	return balance
+}
+
+func (n *FakeNode) GetTransactionCount(address common.Address, block rpc.BlockNumber) *rpc.HexNumber {
+	return rpc.NewHexNumber(7)
+}
+
+func (n *FakeNode) GetCode(address common.Address, block rpc.BlockNumber) string {
+	if address == testToken {
+		return "0x6060"
+	}
+	return "0x"
+}
+
+func (n *FakeNode) GetPermission(address common.Address, block rpc.BlockNumber) string {
+	return "transact"
+}
+
+func (n *FakeNode) Call(args FakeSendArgs, block rpc.BlockNumber) (string, error) {
+	if args.To == nil || *args.To != testToken {
+		return "0x", nil
+	}
+	return common.ToHex(n.call(common.FromHex(args.Data))), nil
+}
+
+func (n *FakeNode) SendTransaction(args FakeSendArgs) (common.Hash, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.sent = append(n.sent, args)
+	return common.BytesToHash([]byte{0x5e, byte(len(n.sent))}), nil
+}
+
+func (n *FakeNode) SendRawTransaction(encoded string) (string, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(encoded), tx); err != nil {
+		return "", err
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if _, known := n.txs[tx.Hash()]; known {
+		return "", errors.New("Known transaction")
+	}
+	n.txs[tx.Hash()] = map[string]interface{}{"hash": tx.Hash(), "nonce": rpc.NewHexNumber(tx.Nonce()), "input": "0x"}
+	return tx.Hash().Hex(), nil
+}
+
+func newTestGateway(t *testing.T, node *FakeNode) *Gateway {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatalf("failed to register test node: %v", err)
+	}
+	return New(rpc.NewInProcRPCClient(server))
+}
+
+// do serves a request by the gateway and decodes its JSON response.
+func do(t *testing.T, g *Gateway, method, path, body string, header http.Header, v interface{}) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: invalid response %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+func TestBlocks(t *testing.T) {
+	g := newTestGateway(t, newTestNode(5))
+
+	var block map[string]interface{}
+	if rec := do(t, g, "GET", "/blocks/latest", "", nil, &block); rec.Code != http.StatusOK {
+		t.Fatalf("status mismatch: have %d, want %d", rec.Code, http.StatusOK)
+	}
+	if block["number"] != 4.0 || block["totalDifficulty"] != "655360" || block["timestamp"] != 1500000004.0 {
+		t.Errorf("latest block mismatch: %v", block)
+	}
+	if txs := block["transactions"].([]interface{}); len(txs) != 4 {
+		t.Errorf("transaction hash count mismatch: have %d, want 4", len(txs))
+	}
+	hash := common.BytesToHash([]byte{0xb0, 2}).Hex()
+	if do(t, g, "GET", "/blocks/"+hash, "", nil, &block); block["number"] != 2.0 {
+		t.Errorf("block by hash mismatch: %v", block)
+	}
+	if rec := do(t, g, "GET", "/blocks/9", "", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing block status mismatch: have %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := do(t, g, "GET", "/blocks/abc", "", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid block status mismatch: have %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// Blocks are listed newest first until genesis
+	var numbers []float64
+	for cursor, pages := "", 0; ; pages++ {
+		var page struct {
+			Items []map[string]interface{}
+			Next  string
+		}
+		do(t, g, "GET", "/blocks?limit=2&cursor="+cursor, "", nil, &page)
+		for _, block := range page.Items {
+			numbers = append(numbers, block["number"].(float64))
+		}
+		if cursor = page.Next; cursor == "" || pages > 5 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(numbers, []float64{4, 3, 2, 1, 0}) {
+		t.Errorf("listed blocks mismatch: %v", numbers)
+	}
+	var page struct{ Items []Block }
+	if do(t, g, "GET", "/blocks?cursor=100&limit=1", "", nil, &page); len(page.Items) != 1 || page.Items[0].Number != 4 {
+		t.Errorf("cursor past the head not clamped: %+v", page.Items)
+	}
+
+	// Transactions of a block are paginated by index
+	var txs struct {
+		Items []Transaction
+		Next  string
+	}
+	do(t, g, "GET", "/blocks/3/transactions?limit=2", "", nil, &txs)
+	if len(txs.Items) != 2 || txs.Next != "2" || *txs.Items[1].TransactionIndex != 1 {
+		t.Fatalf("first transaction page mismatch: %+v", txs)
+	}
+	if value := txs.Items[0].Value.BigInt().String(); value != "100000000000000000000" {
+		t.Errorf("value mismatch: have %s", value)
+	}
+	txs.Next = ""
+	do(t, g, "GET", "/blocks/3/transactions?limit=2&cursor=2", "", nil, &txs)
+	if len(txs.Items) != 1 || txs.Next != "" {
+		t.Errorf("last transaction page mismatch: %+v", txs)
+	}
+}
+
+func TestTransactionsAndAccounts(t *testing.T) {
+	node := newTestNode(3)
+	g := newTestGateway(t, node)
+	if _, err := g.Register("token", testToken, []byte(tokenABI)); err != nil {
+		t.Fatalf("failed to register contract: %v", err)
+	}
+	parsed, _ := abi.JSON(strings.NewReader(tokenABI))
+
+	hash := common.BytesToHash([]byte{0x70, 2, 1})
+	node.receipts[hash] = map[string]interface{}{
+		"transactionHash":   hash,
+		"transactionIndex":  rpc.NewHexNumber(1),
+		"blockHash":         common.BytesToHash([]byte{0xb0, 2}),
+		"blockNumber":       rpc.NewHexNumber(2),
+		"from":              testAccount,
+		"to":                testToken,
+		"contractAddress":   nil,
+		"gasUsed":           rpc.NewHexNumber(30000),
+		"cumulativeGasUsed": rpc.NewHexNumber(51000),
+		"logs": []map[string]interface{}{{
+			"address":  testToken,
+			"topics":   []common.Hash{parsed.Events["Transfer"].Id()},
+			"data":     "0x",
+			"logIndex": "0x0",
+		}},
+	}
+	var tx Transaction
+	if do(t, g, "GET", "/transactions/"+hash.Hex(), "", nil, &tx); tx.BlockNumber == nil || *tx.BlockNumber != 2 || tx.Nonce != 1 {
+		t.Errorf("transaction mismatch: %+v", tx)
+	}
+	var receipt Receipt
+	do(t, g, "GET", "/transactions/"+hash.Hex()+"/receipt", "", nil, &receipt)
+	if receipt.GasUsed != 30000 || len(receipt.Logs) != 1 {
+		t.Fatalf("receipt mismatch: %+v", receipt)
+	}
+	if log := receipt.Logs[0]; log.Contract != "token" || log.Event != "Transfer" {
+		t.Errorf("log not named after the registered contract: %+v", log)
+	}
+	missing := common.BytesToHash([]byte{0x01}).Hex()
+	if rec := do(t, g, "GET", "/transactions/"+missing+"/receipt", "", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing receipt status mismatch: have %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	var account map[string]interface{}
+	do(t, g, "GET", "/accounts/"+testToken.Hex(), "", nil, &account)
+	want := map[string]interface{}{
+		"address":    strings.ToLower(testToken.Hex()),
+		"balance":    "1000000000000000000000",
+		"nonce":      7.0,
+		"contract":   true,
+		"permission": "transact",
+	}
+	if !reflect.DeepEqual(account, want) {
+		t.Errorf("account mismatch: have %v, want %v", account, want)
+	}
+	if rec := do(t, g, "GET", "/accounts/0x12", "", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid address status mismatch: have %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := do(t, g, "DELETE", "/accounts/"+testToken.Hex(), "", nil, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("unsupported method status mismatch: have %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestContracts(t *testing.T) {
+	node := newTestNode(1)
+	g := newTestGateway(t, node)
+	parsed, _ := abi.JSON(strings.NewReader(tokenABI))
+
+	body, _ := json.Marshal(map[string]interface{}{"address": testToken, "abi": json.RawMessage(tokenABI)})
+	if rec := do(t, g, "PUT", "/contracts/token", string(body), nil, nil); rec.Code != http.StatusOK {
+		t.Fatalf("registration failed: %s", rec.Body.String())
+	}
+	if rec := do(t, g, "PUT", "/contracts/broken", `{"address": "0x01", "abi": [{"type": "function", "inputs": [{"type": "wat"}]}]}`, nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid ABI status mismatch: have %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	var contract Contract
+	if do(t, g, "GET", "/contracts/token", "", nil, &contract); contract.Address != testToken || len(contract.Methods) != 3 {
+		t.Errorf("contract mismatch: %+v", contract)
+	}
+
+	// Calls are encoded from JSON arguments and decoded into JSON outputs
+	node.call = func(data []byte) []byte {
+		if want, _ := parsed.Pack("balanceOf", testAccount); bytes.Equal(data, want) {
+			return common.LeftPadBytes(big.NewInt(1234567).Bytes(), 32)
+		}
+		if want, _ := parsed.Pack("info"); bytes.Equal(data, want) {
+			out := common.LeftPadBytes([]byte{0x60}, 32)
+			out = append(out, common.LeftPadBytes([]byte{18}, 32)...)
+			out = append(out, common.RightPadBytes([]byte("TKN"), 32)...)
+			out = append(out, common.LeftPadBytes([]byte{5}, 32)...)
+			return append(out, common.RightPadBytes([]byte("Token"), 32)...)
+		}
+		return nil
+	}
+	var outputs map[string]interface{}
+	do(t, g, "POST", "/contracts/token/call/balanceOf", `{"args": ["`+testAccount.Hex()+`"]}`, nil, &outputs)
+	if outputs["balance"] != "1234567" {
+		t.Errorf("balanceOf outputs mismatch: %v", outputs)
+	}
+	do(t, g, "POST", "/contracts/token/call/balanceOf", `{"args": {"owner": "`+testAccount.Hex()+`"}}`, nil, &outputs)
+	if outputs["balance"] != "1234567" {
+		t.Errorf("balanceOf outputs with named arguments mismatch: %v", outputs)
+	}
+	outputs = nil
+	do(t, g, "POST", "/contracts/token/call/info", "", nil, &outputs)
+	want := map[string]interface{}{"name": "Token", "decimals": 18.0, "2": common.ToHex(common.RightPadBytes([]byte("TKN"), 32))}
+	if !reflect.DeepEqual(outputs, want) {
+		t.Errorf("info outputs mismatch: have %v, want %v", outputs, want)
+	}
+	for _, args := range []string{`[]`, `["0x12"]`, `{"holder": "0x01"}`, `[1]`} {
+		if rec := do(t, g, "POST", "/contracts/token/call/balanceOf", `{"args": `+args+`}`, nil, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("arguments %s: status mismatch: have %d, want %d", args, rec.Code, http.StatusBadRequest)
+		}
+	}
+	if rec := do(t, g, "POST", "/contracts/token/call/mint", "", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown method status mismatch: have %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// Transactions carry the encoded method call
+	var sub Submission
+	do(t, g, "POST", "/contracts/token/transact/transfer",
+		`{"from": "`+testAccount.Hex()+`", "args": ["`+testAccount.Hex()+`", "1000000000000000000", "0xcafe"]}`, nil, &sub)
+	wantData, _ := parsed.Pack("transfer", testAccount, big.NewInt(1000000000000000000), []byte{0xca, 0xfe})
+	if len(node.sent) != 1 || *node.sent[0].To != testToken || node.sent[0].Data != common.ToHex(wantData) {
+		t.Errorf("transfer transaction mismatch: %+v", node.sent)
+	}
+	if sub.Hash == (common.Hash{}) {
+		t.Error("transaction hash missing")
+	}
+
+	// Contracts are listed by name and can be removed
+	for _, name := range []string{"a", "b", "c"} {
+		g.Register(name, testToken, []byte(tokenABI))
+	}
+	var page struct {
+		Items []Contract
+		Next  string
+	}
+	if do(t, g, "GET", "/contracts?limit=3", "", nil, &page); len(page.Items) != 3 || page.Next != "token" {
+		t.Errorf("contract page mismatch: %d items, next %q", len(page.Items), page.Next)
+	}
+	do(t, g, "DELETE", "/contracts/token", "", nil, nil)
+	if rec := do(t, g, "GET", "/contracts/token", "", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("removed contract status mismatch: have %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestIdempotentSubmission(t *testing.T) {
+	node := newTestNode(1)
+	g := newTestGateway(t, node)
+
+	body := `{"from": "` + testAccount.Hex() + `", "to": "` + testToken.Hex() + `", "value": "1000"}`
+	header := http.Header{keyHeader: {"order-42"}}
+
+	var first, second Submission
+	do(t, g, "POST", "/transactions", body, header, &first)
+	rec := do(t, g, "POST", "/transactions", body, header, &second)
+	if len(node.sent) != 1 {
+		t.Fatalf("retry submitted again: %d transactions", len(node.sent))
+	}
+	if first != second || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry response mismatch: %x, %x", first.Hash, second.Hash)
+	}
+	if value := node.sent[0].Value.BigInt(); value.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("value mismatch: have %v, want 1000", value)
+	}
+	if rec := do(t, g, "POST", "/transactions", `{"from": "`+testAccount.Hex()+`"}`, header, nil); rec.Code != http.StatusConflict {
+		t.Errorf("reused key status mismatch: have %d, want %d", rec.Code, http.StatusConflict)
+	}
+	// Failed submissions may be retried under the same key
+	failing := http.Header{keyHeader: {"order-43"}}
+	if rec := do(t, g, "POST", "/transactions", `{"to": "`+testToken.Hex()+`"}`, failing, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid submission status mismatch: have %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := do(t, g, "POST", "/transactions", body, failing, nil); rec.Code != http.StatusOK || len(node.sent) != 2 {
+		t.Errorf("retry of failed submission mismatch: %d, %d transactions", rec.Code, len(node.sent))
+	}
+
+	// Signed transactions are accepted again once known to the node
+	key, _ := crypto.GenerateKey()
+	tx, _ := types.NewTransaction(0, testToken, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil).SignECDSA(key)
+	raw, _ := rlp.EncodeToBytes(tx)
+	for i := 0; i < 2; i++ {
+		var sub Submission
+		rec := do(t, g, "POST", "/transactions", `{"raw": "`+common.ToHex(raw)+`"}`, nil, &sub)
+		if rec.Code != http.StatusOK || sub.Hash != tx.Hash() {
+			t.Errorf("raw submission %d mismatch: %d %s", i, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := do(t, g, "POST", "/transactions", `{"raw": "0x1234"}`, nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid raw transaction status mismatch: have %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestABIValues(t *testing.T) {
+	for i, tt := range []struct {
+		typ   string
+		json  string
+		value interface{}
+		fails bool
+	}{
+		{"uint256", `"0x10"`, big.NewInt(16), false},
+		{"uint256", `-1`, nil, true},
+		{"uint8", `255`, uint8(255), false},
+		{"uint8", `256`, nil, true},
+		{"int8", `-128`, int8(-128), false},
+		{"int8", `128`, nil, true},
+		{"int64", `"-9000000000000000000"`, int64(-9000000000000000000), false},
+		{"bool", `true`, true, false},
+		{"string", `"dchain"`, "dchain", false},
+		{"bytes", `"0x0102"`, []byte{1, 2}, false},
+		{"bytes4", `"0x0102"`, []byte{1, 2, 0, 0}, false},
+		{"bytes2", `"0x010203"`, nil, true},
+		{"address[]", `["0x00000000000000000000000000000000000000bb"]`, []common.Address{testAccount}, false},
+		{"uint256[2]", `[1, "2"]`, []*big.Int{big.NewInt(1), big.NewInt(2)}, false},
+		{"uint256[2]", `[1]`, nil, true},
+	} {
+		typ, err := abi.NewType(tt.typ)
+		if err != nil {
+			t.Fatalf("test %d: invalid type %s: %v", i, tt.typ, err)
+		}
+		value, err := abiValue(typ, json.RawMessage(tt.json))
+		if (err != nil) != tt.fails {
+			t.Errorf("test %d: %s %s: error mismatch: %v", i, tt.typ, tt.json, err)
+			continue
+		}
+		if !tt.fails && !reflect.DeepEqual(value, tt.value) {
+			t.Errorf("test %d: %s %s: value mismatch: have %#v, want %#v", i, tt.typ, tt.json, value, tt.value)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package gateway
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var errKeyReused = errors.New("idempotency key used for a different request")
+
+// submission is a request served under an idempotency key. Its status and
+// body are set once done is closed, the body being nil if it failed.
+type submission struct {
+	fingerprint common.Hash // Hash of the method, path and body of the request
+	done        chan struct{}
+	status      int
+	body        []byte
+	expires     time.Time
+}
+
+// submissions remembers the responses of successful submissions by their
+// idempotency key for a while, so that clients can retry them safely.
+type submissions struct {
+	entries map[string]*submission
+	ttl     time.Duration
+	lock    sync.Mutex
+}
+
+func newSubmissions(ttl time.Duration) *submissions {
+	return &submissions{entries: make(map[string]*submission), ttl: ttl}
+}
+
+// begin returns the submission of key, and whether the caller created it and
+// must finish it. Keys can't be reused for requests with another fingerprint.
+func (s *submissions) begin(key string, fingerprint common.Hash) (*submission, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	for k, sub := range s.entries {
+		if !sub.expires.IsZero() && now.After(sub.expires) {
+			delete(s.entries, k)
+		}
+	}
+	if sub, ok := s.entries[key]; ok {
+		if sub.fingerprint != fingerprint {
+			return nil, false, errKeyReused
+		}
+		return sub, false, nil
+	}
+	sub := &submission{fingerprint: fingerprint, done: make(chan struct{})}
+	s.entries[key] = sub
+	return sub, true, nil
+}
+
+// finish records the response of a submission. Failed submissions are
+// forgotten so that they can be retried.
+func (s *submissions) finish(key string, sub *submission, status int, body []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if status >= 200 && status < 300 {
+		sub.status, sub.body, sub.expires = status, body, time.Now().Add(s.ttl)
+	} else {
+		delete(s.entries, key)
+	}
+	close(sub.done)
+}
@@ -0,0 +1,147 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// request is a JSON RPC request sent to the node.
+type request struct {
+	JSONRPC string        `json:"jsonrpc"` // Version of the JSON RPC protocol, always set to 2.0
+	ID      int           `json:"id"`      // Auto incrementing ID number for this request
+	Method  string        `json:"method"`  // Remote procedure name to invoke on the server
+	Params  []interface{} `json:"params"`  // List of parameters to pass through
+}
+
+// response is a JSON RPC response sent back by the node.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"` // Version of the JSON RPC protocol, always set to 2.0
+	ID      int             `json:"id"`      // Auto incrementing ID number for this request
+	Error   *remoteError    `json:"error"`   // Any error returned by the remote side
+	Result  json.RawMessage `json:"result"`  // Whatever the remote side sends us in reply
+}
+
+// remoteError is an error returned by the node for a request it received, as
+// opposed to failures reaching it.
+type remoteError struct {
+	Code    int    `json:"code"`    // JSON RPC error code associated with the failure
+	Message string `json:"message"` // Specific error message of the failure
+}
+
+func (e *remoteError) Error() string { return e.Message }
+
+// call invokes method on the node and decodes its result into result, which
+// is left untouched if the node returns null.
+func (g *Gateway) call(result interface{}, method string, params ...interface{}) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if params == nil {
+		params = []interface{}{}
+	}
+	req := &request{
+		JSONRPC: "2.0",
+		ID:      int(atomic.AddUint32(&g.autoid, 1)),
+		Method:  method,
+		Params:  params,
+	}
+	if err := g.client.Send(req); err != nil {
+		return err
+	}
+	res := new(response)
+	if err := g.client.Recv(res); err != nil {
+		return err
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+	if result == nil || len(res.Result) == 0 || string(res.Result) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(res.Result, result); err != nil {
+		return fmt.Errorf("invalid %s result: %v", method, err)
+	}
+	return nil
+}
+
+// Amount is an integer serialized as a decimal string, as values and balances
+// exceed the numbers JSON decoders represent exactly. Numbers and hex strings
+// are accepted as input too.
+type Amount big.Int
+
+// NewAmount returns the amount of n, nil if n is nil.
+func NewAmount(n *big.Int) *Amount {
+	if n == nil {
+		return nil
+	}
+	return (*Amount)(new(big.Int).Set(n))
+}
+
+// BigInt returns the amount as a big integer.
+func (a *Amount) BigInt() *big.Int {
+	return (*big.Int)(a)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a *Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*big.Int)(a).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Amount) UnmarshalJSON(input []byte) error {
+	n, err := parseInteger(input)
+	if err != nil {
+		return err
+	}
+	(*big.Int)(a).Set(n)
+	return nil
+}
+
+// parseInteger parses a JSON number, or a string holding a decimal or 0x
+// prefixed hex integer.
+func parseInteger(input []byte) (*big.Int, error) {
+	text := string(input)
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		text = text[1 : len(text)-1]
+	}
+	n, ok := new(big.Int).SetString(text, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %s", input)
+	}
+	return n, nil
+}
+
+// hexAmount converts a quantity of the node into an amount.
+func hexAmount(h *rpc.HexNumber) *Amount {
+	if h == nil {
+		return nil
+	}
+	return NewAmount(h.BigInt())
+}
+
+// hexUint64 converts a quantity of the node into an integer, 0 if missing.
+func hexUint64(h *rpc.HexNumber) uint64 {
+	if h == nil {
+		return 0
+	}
+	return h.Uint64()
+}
+
+// hexQuantity converts an amount into a quantity of the node.
+func hexQuantity(a *Amount) *rpc.HexNumber {
+	if a == nil {
+		return nil
+	}
+	return rpc.NewHexNumber(a.BigInt())
+}
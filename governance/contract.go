@@ -0,0 +1,324 @@
+// This file is an automatically generated Go binding. Do not modify as any
+// change will likely be lost upon the next re-generation!
+
+package governance
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// GovernanceABI is the input ABI used to generate the binding from.
+const GovernanceABI = `[{"constant":true,"inputs":[],"name":"threshold","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":true,"inputs":[],"name":"proposalCount","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":true,"inputs":[{"name":"id","type":"uint256"}],"name":"getProposal","outputs":[{"name":"kind","type":"uint8"},{"name":"account","type":"address"},{"name":"node","type":"bytes32"},{"name":"value","type":"uint8"},{"name":"proposer","type":"address"},{"name":"approvals","type":"uint256"},{"name":"rejections","type":"uint256"},{"name":"state","type":"uint8"}],"type":"function"},{"constant":true,"inputs":[{"name":"id","type":"uint256"},{"name":"voter","type":"address"}],"name":"getVote","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":false,"inputs":[{"name":"kind","type":"uint8"},{"name":"account","type":"address"},{"name":"node","type":"bytes32"},{"name":"value","type":"uint8"}],"name":"propose","outputs":[{"name":"id","type":"uint256"}],"type":"function"},{"constant":false,"inputs":[{"name":"id","type":"uint256"},{"name":"approve","type":"bool"}],"name":"vote","outputs":[],"type":"function"},{"anonymous":false,"inputs":[{"indexed":true,"name":"id","type":"uint256"},{"indexed":true,"name":"proposer","type":"address"},{"indexed":false,"name":"kind","type":"uint8"},{"indexed":false,"name":"account","type":"address"},{"indexed":false,"name":"node","type":"bytes32"},{"indexed":false,"name":"value","type":"uint8"}],"name":"Proposed","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"name":"id","type":"uint256"},{"indexed":true,"name":"voter","type":"address"},{"indexed":false,"name":"approve","type":"bool"}],"name":"Voted","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"name":"id","type":"uint256"},{"indexed":false,"name":"kind","type":"uint8"},{"indexed":false,"name":"account","type":"address"},{"indexed":false,"name":"node","type":"bytes32"},{"indexed":false,"name":"value","type":"uint8"}],"name":"Approved","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"name":"id","type":"uint256"}],"name":"Rejected","type":"event"}]`
+
+// Governance is an auto generated Go binding around an Ethereum contract.
+type Governance struct {
+	GovernanceCaller     // Read-only binding to the contract
+	GovernanceTransactor // Write-only binding to the contract
+}
+
+// GovernanceCaller is an auto generated read-only Go binding around an Ethereum contract.
+type GovernanceCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// GovernanceTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type GovernanceTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// GovernanceSession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type GovernanceSession struct {
+	Contract     *Governance       // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// GovernanceCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type GovernanceCallerSession struct {
+	Contract *GovernanceCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts     // Call options to use throughout this session
+}
+
+// GovernanceTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type GovernanceTransactorSession struct {
+	Contract     *GovernanceTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts     // Transaction auth options to use throughout this session
+}
+
+// GovernanceRaw is an auto generated low-level Go binding around an Ethereum contract.
+type GovernanceRaw struct {
+	Contract *Governance // Generic contract binding to access the raw methods on
+}
+
+// GovernanceCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type GovernanceCallerRaw struct {
+	Contract *GovernanceCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// GovernanceTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type GovernanceTransactorRaw struct {
+	Contract *GovernanceTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewGovernance creates a new instance of Governance, bound to a specific deployed contract.
+func NewGovernance(address common.Address, backend bind.ContractBackend) (*Governance, error) {
+	contract, err := bindGovernance(address, backend.(bind.ContractCaller), backend.(bind.ContractTransactor))
+	if err != nil {
+		return nil, err
+	}
+	return &Governance{GovernanceCaller: GovernanceCaller{contract: contract}, GovernanceTransactor: GovernanceTransactor{contract: contract}}, nil
+}
+
+// NewGovernanceCaller creates a new read-only instance of Governance, bound to a specific deployed contract.
+func NewGovernanceCaller(address common.Address, caller bind.ContractCaller) (*GovernanceCaller, error) {
+	contract, err := bindGovernance(address, caller, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &GovernanceCaller{contract: contract}, nil
+}
+
+// NewGovernanceTransactor creates a new write-only instance of Governance, bound to a specific deployed contract.
+func NewGovernanceTransactor(address common.Address, transactor bind.ContractTransactor) (*GovernanceTransactor, error) {
+	contract, err := bindGovernance(address, nil, transactor)
+	if err != nil {
+		return nil, err
+	}
+	return &GovernanceTransactor{contract: contract}, nil
+}
+
+// bindGovernance binds a generic wrapper to an already deployed contract.
+func bindGovernance(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(GovernanceABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_Governance *GovernanceRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _Governance.Contract.GovernanceCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_Governance *GovernanceRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _Governance.Contract.GovernanceTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_Governance *GovernanceRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _Governance.Contract.GovernanceTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_Governance *GovernanceCallerRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _Governance.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_Governance *GovernanceTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _Governance.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_Governance *GovernanceTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _Governance.Contract.contract.Transact(opts, method, params...)
+}
+
+// GetProposal is a free data retrieval call binding the contract method 0xc7f758a8.
+//
+// Solidity: function getProposal(id uint256) constant returns(kind uint8, account address, node bytes32, value uint8, proposer address, approvals uint256, rejections uint256, state uint8)
+func (_Governance *GovernanceCaller) GetProposal(opts *bind.CallOpts, id *big.Int) (struct {
+	Kind       uint8
+	Account    common.Address
+	Node       [32]byte
+	Value      uint8
+	Proposer   common.Address
+	Approvals  *big.Int
+	Rejections *big.Int
+	State      uint8
+}, error) {
+	ret := new(struct {
+		Kind       uint8
+		Account    common.Address
+		Node       [32]byte
+		Value      uint8
+		Proposer   common.Address
+		Approvals  *big.Int
+		Rejections *big.Int
+		State      uint8
+	})
+	out := ret
+	err := _Governance.contract.Call(opts, out, "getProposal", id)
+	return *ret, err
+}
+
+// GetProposal is a free data retrieval call binding the contract method 0xc7f758a8.
+//
+// Solidity: function getProposal(id uint256) constant returns(kind uint8, account address, node bytes32, value uint8, proposer address, approvals uint256, rejections uint256, state uint8)
+func (_Governance *GovernanceSession) GetProposal(id *big.Int) (struct {
+	Kind       uint8
+	Account    common.Address
+	Node       [32]byte
+	Value      uint8
+	Proposer   common.Address
+	Approvals  *big.Int
+	Rejections *big.Int
+	State      uint8
+}, error) {
+	return _Governance.Contract.GetProposal(&_Governance.CallOpts, id)
+}
+
+// GetProposal is a free data retrieval call binding the contract method 0xc7f758a8.
+//
+// Solidity: function getProposal(id uint256) constant returns(kind uint8, account address, node bytes32, value uint8, proposer address, approvals uint256, rejections uint256, state uint8)
+func (_Governance *GovernanceCallerSession) GetProposal(id *big.Int) (struct {
+	Kind       uint8
+	Account    common.Address
+	Node       [32]byte
+	Value      uint8
+	Proposer   common.Address
+	Approvals  *big.Int
+	Rejections *big.Int
+	State      uint8
+}, error) {
+	return _Governance.Contract.GetProposal(&_Governance.CallOpts, id)
+}
+
+// GetVote is a free data retrieval call binding the contract method 0xbc3f931f.
+//
+// Solidity: function getVote(id uint256, voter address) constant returns(uint8)
+func (_Governance *GovernanceCaller) GetVote(opts *bind.CallOpts, id *big.Int, voter common.Address) (uint8, error) {
+	var (
+		ret0 = new(uint8)
+	)
+	out := ret0
+	err := _Governance.contract.Call(opts, out, "getVote", id, voter)
+	return *ret0, err
+}
+
+// GetVote is a free data retrieval call binding the contract method 0xbc3f931f.
+//
+// Solidity: function getVote(id uint256, voter address) constant returns(uint8)
+func (_Governance *GovernanceSession) GetVote(id *big.Int, voter common.Address) (uint8, error) {
+	return _Governance.Contract.GetVote(&_Governance.CallOpts, id, voter)
+}
+
+// GetVote is a free data retrieval call binding the contract method 0xbc3f931f.
+//
+// Solidity: function getVote(id uint256, voter address) constant returns(uint8)
+func (_Governance *GovernanceCallerSession) GetVote(id *big.Int, voter common.Address) (uint8, error) {
+	return _Governance.Contract.GetVote(&_Governance.CallOpts, id, voter)
+}
+
+// ProposalCount is a free data retrieval call binding the contract method 0xda35c664.
+//
+// Solidity: function proposalCount() constant returns(uint256)
+func (_Governance *GovernanceCaller) ProposalCount(opts *bind.CallOpts) (*big.Int, error) {
+	var (
+		ret0 = new(*big.Int)
+	)
+	out := ret0
+	err := _Governance.contract.Call(opts, out, "proposalCount")
+	return *ret0, err
+}
+
+// ProposalCount is a free data retrieval call binding the contract method 0xda35c664.
+//
+// Solidity: function proposalCount() constant returns(uint256)
+func (_Governance *GovernanceSession) ProposalCount() (*big.Int, error) {
+	return _Governance.Contract.ProposalCount(&_Governance.CallOpts)
+}
+
+// ProposalCount is a free data retrieval call binding the contract method 0xda35c664.
+//
+// Solidity: function proposalCount() constant returns(uint256)
+func (_Governance *GovernanceCallerSession) ProposalCount() (*big.Int, error) {
+	return _Governance.Contract.ProposalCount(&_Governance.CallOpts)
+}
+
+// Threshold is a free data retrieval call binding the contract method 0x42cde4e8.
+//
+// Solidity: function threshold() constant returns(uint256)
+func (_Governance *GovernanceCaller) Threshold(opts *bind.CallOpts) (*big.Int, error) {
+	var (
+		ret0 = new(*big.Int)
+	)
+	out := ret0
+	err := _Governance.contract.Call(opts, out, "threshold")
+	return *ret0, err
+}
+
+// Threshold is a free data retrieval call binding the contract method 0x42cde4e8.
+//
+// Solidity: function threshold() constant returns(uint256)
+func (_Governance *GovernanceSession) Threshold() (*big.Int, error) {
+	return _Governance.Contract.Threshold(&_Governance.CallOpts)
+}
+
+// Threshold is a free data retrieval call binding the contract method 0x42cde4e8.
+//
+// Solidity: function threshold() constant returns(uint256)
+func (_Governance *GovernanceCallerSession) Threshold() (*big.Int, error) {
+	return _Governance.Contract.Threshold(&_Governance.CallOpts)
+}
+
+// Propose is a paid mutator transaction binding the contract method 0x55fd107f.
+//
+// Solidity: function propose(kind uint8, account address, node bytes32, value uint8) returns(id uint256)
+func (_Governance *GovernanceTransactor) Propose(opts *bind.TransactOpts, kind uint8, account common.Address, node [32]byte, value uint8) (*types.Transaction, error) {
+	return _Governance.contract.Transact(opts, "propose", kind, account, node, value)
+}
+
+// Propose is a paid mutator transaction binding the contract method 0x55fd107f.
+//
+// Solidity: function propose(kind uint8, account address, node bytes32, value uint8) returns(id uint256)
+func (_Governance *GovernanceSession) Propose(kind uint8, account common.Address, node [32]byte, value uint8) (*types.Transaction, error) {
+	return _Governance.Contract.Propose(&_Governance.TransactOpts, kind, account, node, value)
+}
+
+// Propose is a paid mutator transaction binding the contract method 0x55fd107f.
+//
+// Solidity: function propose(kind uint8, account address, node bytes32, value uint8) returns(id uint256)
+func (_Governance *GovernanceTransactorSession) Propose(kind uint8, account common.Address, node [32]byte, value uint8) (*types.Transaction, error) {
+	return _Governance.Contract.Propose(&_Governance.TransactOpts, kind, account, node, value)
+}
+
+// Vote is a paid mutator transaction binding the contract method 0xc9d27afe.
+//
+// Solidity: function vote(id uint256, approve bool) returns()
+func (_Governance *GovernanceTransactor) Vote(opts *bind.TransactOpts, id *big.Int, approve bool) (*types.Transaction, error) {
+	return _Governance.contract.Transact(opts, "vote", id, approve)
+}
+
+// Vote is a paid mutator transaction binding the contract method 0xc9d27afe.
+//
+// Solidity: function vote(id uint256, approve bool) returns()
+func (_Governance *GovernanceSession) Vote(id *big.Int, approve bool) (*types.Transaction, error) {
+	return _Governance.Contract.Vote(&_Governance.TransactOpts, id, approve)
+}
+
+// Vote is a paid mutator transaction binding the contract method 0xc9d27afe.
+//
+// Solidity: function vote(id uint256, approve bool) returns()
+func (_Governance *GovernanceTransactorSession) Vote(id *big.Int, approve bool) (*types.Transaction, error) {
+	return _Governance.Contract.Vote(&_Governance.TransactOpts, id, approve)
+}
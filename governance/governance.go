@@ -0,0 +1,63 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// Package governance contains the Go bindings of the consortium governance
+// system contract. The contract is deployed by the genesis block at
+// core.GovernanceAddress and executed natively by the nodes as a precompiled
+// contract, which transactions and other contracts may call; bind to it with
+//
+//	gov, err := governance.NewGovernance(core.GovernanceAddress, backend)
+//
+// Admin accounts propose membership and permission changes and vote on them.
+// A proposal is approved once the number of approvals reaches the threshold
+// and rejected once the rejections do. The proposer approves implicitly.
+package governance
+
+import "bytes"
+
+// Kinds of proposals.
+const (
+	// KindPermission sets the permission level of the account to the value.
+	KindPermission uint8 = 1
+
+	// KindDeployer adds the account to the deployment allow-list if the
+	// value is 1 and removes it if it is 0.
+	KindDeployer uint8 = 2
+
+	// KindValidator makes the node a validator if the value is 1 and a peer
+	// if it is 0. The node is the name of its certificate at the CA.
+	KindValidator uint8 = 3
+
+	// KindThreshold sets the number of votes needed to decide proposals.
+	KindThreshold uint8 = 4
+)
+
+// States of proposals.
+const (
+	StatePending  uint8 = 1
+	StateApproved uint8 = 2
+	StateRejected uint8 = 3
+)
+
+// Votes as returned by GetVote.
+const (
+	VoteNone    uint8 = 0
+	VoteApprove uint8 = 1
+	VoteReject  uint8 = 2
+)
+
+// NodeName converts a certificate name to the node argument of proposals.
+// Names longer than 32 bytes are truncated.
+func NodeName(name string) (node [32]byte) {
+	copy(node[:], name)
+	return node
+}
+
+// NodeString returns the certificate name held by a node argument.
+func NodeString(node [32]byte) string {
+	return string(bytes.TrimRight(node[:], "\x00"))
+}
@@ -80,6 +80,21 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'auditLog',
+			call: 'admin_auditLog',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'exportStatus',
+			call: 'admin_exportStatus'
+		}),
+		new web3._extend.Method({
+			name: 'exportReplay',
+			call: 'admin_exportReplay',
+			params: 2
+		}),
 		new web3._extend.Method({
 			name: 'setGlobalRegistrar',
 			call: 'admin_setGlobalRegistrar',
@@ -335,6 +350,42 @@ web3._extend({
 			call: 'eth_submitTransaction',
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getPermission',
+			call: 'eth_getPermission',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getPrivateStorageAt',
+			call: 'eth_getPrivateStorageAt',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.utils.toHex, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getPrivateCode',
+			call: 'eth_getPrivateCode',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionsByAddress',
+			call: 'eth_getTransactionsByAddress',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransfers',
+			call: 'eth_getInternalTransfers',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getLogsByAddress',
+			call: 'eth_getLogsByAddress',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
 		})
 	],
 	properties:
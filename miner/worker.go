@@ -289,12 +289,6 @@ func (self *worker) wait() {
 					continue
 				}
 
-				stat, err := self.chain.WriteBlock(block)
-				if err != nil {
-					glog.V(logger.Error).Infoln("error writing block to chain", err)
-					continue
-				}
-
 				// update block hash since it is now available and not when the receipt/log of individual transactions were created
 				for _, r := range work.receipts {
 					for _, l := range r.Logs {
@@ -304,6 +298,19 @@ func (self *worker) wait() {
 				for _, log := range work.state.Logs() {
 					log.BlockHash = block.Hash()
 				}
+				// store the receipts and internal transfers before the block may become the head, the activity index reads them
+				if err := core.WriteBlockReceipts(self.chainDb, block.Hash(), work.receipts); err != nil {
+					glog.V(logger.Warn).Infoln("error writing block receipts:", err)
+				}
+				if err := core.WriteBlockTransfers(self.chainDb, block.Hash(), core.BlockTransfers(block, work.state)); err != nil {
+					glog.V(logger.Warn).Infoln("error writing block transfers:", err)
+				}
+
+				stat, err := self.chain.WriteBlock(block)
+				if err != nil {
+					glog.V(logger.Error).Infoln("error writing block to chain", err)
+					continue
+				}
 
 				// check if canon block and write transactions
 				if stat == core.CanonStatTy {
@@ -316,7 +323,7 @@ func (self *worker) wait() {
 				}
 
 				// broadcast before waiting for validation
-				go func(block *types.Block, logs vm.Logs, receipts []*types.Receipt) {
+				go func(block *types.Block, logs vm.Logs) {
 					self.mux.Post(core.NewMinedBlockEvent{Block: block})
 					self.mux.Post(core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
 
@@ -324,10 +331,7 @@ func (self *worker) wait() {
 						self.mux.Post(core.ChainHeadEvent{Block: block})
 						self.mux.Post(logs)
 					}
-					if err := core.WriteBlockReceipts(self.chainDb, block.Hash(), receipts); err != nil {
-						glog.V(logger.Warn).Infoln("error writing block receipts:", err)
-					}
-				}(block, work.state.Logs(), work.receipts)
+				}(block, work.state.Logs())
 			}
 
 			// check staleness and display confirmation
@@ -527,7 +531,7 @@ func (self *worker) commitNewWork() {
 
 	if atomic.LoadInt32(&self.mining) == 1 {
 		// commit state root after all state transitions.
-		core.AccumulateRewards(work.state, header, uncles)
+		core.AccumulateRewards(self.config, work.state, header, uncles)
 		header.Root = work.state.IntermediateRoot()
 	}
 
@@ -572,7 +576,7 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 		from, _ := tx.From()
 
 		// Ignore any transactions (and accounts subsequently) with low gas limits
-		if tx.GasPrice().Cmp(gasPrice) < 0 && !env.ownedAccounts.Has(from) {
+		if tx.GasPrice().Cmp(gasPrice) < 0 && !env.ownedAccounts.Has(from) && !env.config.FreeGas {
 			// Pop the current low-priced transaction without shifting in the next from the account
 			glog.V(logger.Info).Infof("Transaction (%x) below gas price (tx=%v ask=%v). All sequential txs from this address(%x) will be ignored\n", tx.Hash().Bytes()[:4], common.CurrencyToString(tx.GasPrice()), common.CurrencyToString(gasPrice), from[:4])
 
@@ -21,6 +21,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/audit"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -156,6 +157,27 @@ func (api *PrivateAdminAPI) StopWS() (bool, error) {
 	return true, nil
 }
 
+// maxAuditEntries limits the number of entries returned by AuditLog.
+const maxAuditEntries = 1000
+
+// AuditLog returns the permission decisions recorded by the node, oldest
+// first, selected by an optional filter on time range and kinds. At most
+// maxAuditEntries are returned, use the export command for more.
+func (api *PrivateAdminAPI) AuditLog(filter *audit.Filter) ([]*audit.Entry, error) {
+	log := api.node.AuditLog()
+	if log == nil {
+		return nil, ErrNodeStopped
+	}
+	var f audit.Filter
+	if filter != nil {
+		f = *filter
+	}
+	if f.Limit <= 0 || f.Limit > maxAuditEntries {
+		f.Limit = maxAuditEntries
+	}
+	return log.Query(f)
+}
+
 // PublicAdminAPI is the collection of administrative API methods exposed over
 // both secure and unsecure RPC channels.
 type PublicAdminAPI struct {
@@ -0,0 +1,69 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package node
+
+import (
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/audit"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// OpenAuditLog opens the permission audit log kept in the data directory, or
+// an in-memory one if datadir is empty. The database must be closed by the
+// caller once the log is no longer used.
+func OpenAuditLog(datadir string) (*audit.Log, ethdb.Database, error) {
+	var (
+		db  ethdb.Database
+		err error
+	)
+	if datadir == "" {
+		db, err = ethdb.NewMemDatabase()
+	} else {
+		db, err = ethdb.NewLDBDatabase(filepath.Join(datadir, datadirAuditLog), 0, 0)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	log, err := audit.NewLog(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return log, db, nil
+}
+
+// startAudit opens the audit log and makes it the destination of the
+// permission decisions recorded by the node and its services.
+func (n *Node) startAudit() error {
+	log, db, err := OpenAuditLog(n.datadir)
+	if err != nil {
+		return err
+	}
+	n.auditLog, n.auditDB = log, db
+	audit.SetDefault(log)
+	return nil
+}
+
+// stopAudit stops recording and closes the audit log.
+func (n *Node) stopAudit() {
+	if n.auditDB == nil {
+		return
+	}
+	audit.SetDefault(nil)
+	n.auditDB.Close()
+	n.auditLog, n.auditDB = nil, nil
+}
+
+// AuditLog returns the audit log of the running node, nil if it is stopped.
+func (n *Node) AuditLog() *audit.Log {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.auditLog
+}
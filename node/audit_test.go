@@ -0,0 +1,118 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package node
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/audit"
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestRPCAuditLog(t *testing.T) {
+	if err := ca.Init(nil); err != nil {
+		t.Fatalf("failed to init crypto: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	log, db, err := OpenAuditLog(dir)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	audit.SetDefault(log)
+	defer audit.SetDefault(nil)
+
+	root, rootKey := newRPCTestCertificate(t, "root", ca.Admin, nil, nil)
+	client, _ := newRPCTestCertificate(t, "client", ca.Client, root, rootKey)
+	auth := newRPCAuthenticator(DefaultRPCPolicy, root, nil)
+
+	if err := auth.Authorize(client, "admin", "addPeer"); err == nil {
+		t.Fatal("client allowed to call admin")
+	}
+	r, _ := http.NewRequest("POST", "http://localhost", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("Authorization", "Basic x")
+	if _, err := auth.Authenticate(r); err == nil {
+		t.Fatal("unsupported authorization accepted")
+	}
+	db.Close()
+
+	// Entries are kept in the data directory
+	log, db, err = OpenAuditLog(dir)
+	if err != nil {
+		t.Fatalf("failed to reopen audit log: %v", err)
+	}
+	defer db.Close()
+	entries, err := log.Query(audit.Filter{Kinds: []audit.Kind{audit.RPCDenied}})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Subject != "client" || entries[1].Subject != "10.0.0.1" {
+		t.Fatalf("audit entries mismatch: %+v", entries)
+	}
+}
+
+func TestRPCAuditAggregation(t *testing.T) {
+	if err := ca.Init(nil); err != nil {
+		t.Fatalf("failed to init crypto: %v", err)
+	}
+	db, _ := ethdb.NewMemDatabase()
+	log, _ := audit.NewLog(db)
+	audit.SetDefault(log)
+	defer audit.SetDefault(nil)
+
+	root, _ := newRPCTestCertificate(t, "root", ca.Admin, nil, nil)
+	auth := newRPCAuthenticator(DefaultRPCPolicy, root, nil)
+	now := time.Unix(1500000000, 0)
+	auth.now = func() time.Time { return now }
+
+	// Only the first denial of a caller is recorded within the window
+	deny := func(addr string) {
+		r, _ := http.NewRequest("POST", "http://localhost", nil)
+		r.RemoteAddr = addr
+		r.Header.Set("Authorization", "Basic x")
+		auth.Authenticate(r)
+	}
+	for i := 0; i < 10; i++ {
+		deny(fmt.Sprintf("10.0.0.1:%d", 1000+i))
+		auth.Authorize(nil, "personal", "unlockAccount")
+	}
+	deny("10.0.0.2:1000")
+	if n := log.Len(); n != 3 {
+		t.Fatalf("entries within the window mismatch: have %d, want 3", n)
+	}
+	// The rest is counted in one entry per caller once the window passed
+	now = now.Add(rpcDenialWindow)
+	deny("10.0.0.2:1000")
+	if n := log.Len(); n != 6 {
+		t.Fatalf("entries after the window mismatch: have %d, want 6", n)
+	}
+	entries, _ := log.Query(audit.Filter{})
+	for _, e := range entries[3:5] {
+		if !strings.HasPrefix(e.Detail, "9 more denied") {
+			t.Errorf("aggregated entry mismatch: %+v", e)
+		}
+	}
+	// Counts still pending are recorded when the node stops
+	deny("10.0.0.2:1000")
+	auth.flush()
+	if n := log.Len(); n != 7 {
+		t.Fatalf("entries after flush mismatch: have %d, want 7", n)
+	}
+}
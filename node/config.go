@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/settings"
 )
 
 var (
@@ -41,6 +42,7 @@ var (
 	datadirStaticNodes  = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase = "nodes"              // Path within the datadir to store the node infos
+	datadirAuditLog     = "auditlog"           // Path within the datadir to store the permission audit log
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -74,6 +76,11 @@ type Config struct {
 	// or not. Disabling is usually useful for protocol debugging (manual topology).
 	NoDiscovery bool
 
+	// AllowAllPeers disables the whitelist managed by the CA, so that every node
+	// holding a valid enrollment certificate may connect. By default the node
+	// refuses all peers until the whitelist has been loaded from the CA.
+	AllowAllPeers bool
+
 	// Bootstrap nodes used to establish connectivity with the rest of the network.
 	BootstrapNodes []*discover.Node
 
@@ -137,6 +144,20 @@ type Config struct {
 	// If the module list is empty, all RPC API endpoints designated public will be
 	// exposed.
 	WSModules []string
+
+	// RPCACL restricts the methods HTTP and websocket callers may call to the
+	// ones their role is granted by the RPC policy. Callers identify with a
+	// token signed by the key of their enrollment certificate, the others get
+	// the anonymous role. The IPC and in-process endpoints are not restricted.
+	RPCACL bool
+
+	// RPCPolicy is the JSON file mapping roles to the methods they may call.
+	// If empty, DefaultRPCPolicy is used.
+	RPCPolicy string
+
+	// DChain holds the validated settings of properties.yaml, passed to the CA
+	// client and to the services. If nil, settings.Default is used.
+	DChain *settings.Config
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -25,16 +25,19 @@ import (
 	"reflect"
 	"sync"
 	"syscall"
+	"time"
 
 	"fmt"
+	"github.com/ethereum/go-ethereum/audit"
 	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/logger"
 	"github.com/ethereum/go-ethereum/logger/glog"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
-	"encoding/binary"
+	"github.com/ethereum/go-ethereum/settings"
 )
 
 var (
@@ -50,6 +53,7 @@ var (
 // be registered.
 type Node struct {
 	datadir  string         // Path to the currently used data directory
+	dchain   *settings.Config // DChain settings handed to the CA client and the services
 	eventmux *event.TypeMux // Event multiplexer used between the services of a stack
 
 	serverConfig p2p.Config
@@ -81,6 +85,19 @@ type Node struct {
 	wsListener  net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler   *rpc.Server  // Websocket RPC request handler to process the API requests
 
+	allowAllPeers bool // Whether every enrolled node may connect, ignoring the CA whitelist
+
+	rpcACL    bool              // Whether HTTP and websocket calls are authorized
+	rpcPolicy string            // File of the RPC policy (empty = DefaultRPCPolicy)
+	rpcAuth   *rpcAuthenticator // Authorizes HTTP and websocket calls (nil = unrestricted)
+
+	auditLog *audit.Log     // Log of the permission decisions of the running node
+	auditDB  ethdb.Database // Database holding the audit log
+
+	renewer     *certRenewer    // Background renewal of the enrollment certificate
+	whitelist   *whitelistSync  // Background sync of the CA managed whitelist
+	revocations *revocationSync // Background sync of the CA revocation list
+
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 }
@@ -93,6 +110,14 @@ func New(conf *Config) (*Node, error) {
 			return nil, err
 		}
 	}
+	// The CA client and the enrollment key follow the DChain settings
+	dchain := conf.DChain
+	if dchain == nil {
+		dchain = settings.Default()
+	}
+	if err := ca.Init(dchain); err != nil {
+		return nil, err
+	}
 	// Assemble the networking layer and the node itself
 	nodeDbPath := ""
 	if conf.DataDir != "" {
@@ -100,6 +125,7 @@ func New(conf *Config) (*Node, error) {
 	}
 	return &Node{
 		datadir: conf.DataDir,
+		dchain:  dchain,
 		serverConfig: p2p.Config{
 			PrivateKey:           conf.NodeKey(),
 			EnrollmentPrivateKey: conf.CreateCAKeyPair(),
@@ -128,6 +154,9 @@ func New(conf *Config) (*Node, error) {
 		wsEndpoint:    conf.WSEndpoint(),
 		wsWhitelist:   conf.WSModules,
 		wsOrigins:     conf.WSOrigins,
+		allowAllPeers: conf.AllowAllPeers,
+		rpcACL:        conf.RPCACL,
+		rpcPolicy:     conf.RPCPolicy,
 		eventmux:      new(event.TypeMux),
 	}, nil
 }
@@ -159,6 +188,14 @@ func (n *Node) Start() error {
 	running := &p2p.Server{Config: n.serverConfig}
 
 	var err error
+	// The enrollment key must be on the curve of the configured security level
+	if running.EnrollmentPrivateKey != nil {
+		if err := ca.CheckPublicKey(&running.EnrollmentPrivateKey.PublicKey); err != nil {
+			return fmt.Errorf("Server.EnrollmentPrivateKey unusable, remove it from %s to create a new one: %v", filepath.Join(n.datadir, "cakeystore"), err)
+		}
+	}
+	// The pinned CA root is kept in the cakeystore of the data directory
+	ca.SetClientDataDir(n.datadir)
 	// apply the certificate will be done off-line
 	running.EnrollmentCertificate, err = ca.IssueCertificate(&(running.EnrollmentPrivateKey.PublicKey), running.Name, n.datadir)
 
@@ -168,31 +205,74 @@ func (n *Node) Start() error {
 		glog.V(logger.Debug).Infof("running.EnrollmentCertificate: %v", running.EnrollmentCertificate)
 		return fmt.Errorf("Server.EnrollmentCertificate build failed %v", err)
 	}
+	ca.SetClientCertificate(running.EnrollmentPrivateKey, running.EnrollmentCertificate)
 
-	running.NodeType = ca.Validator
-	for _, ext := range running.EnrollmentCertificate.Extensions {
-		if ext.Critical && ext.Id.Equal([]int{1, 33, 80}) {
-			val := int32(uint8(ext.Value[0]))
-			if err == nil && val >= 0 && val <= 3 {
-				running.NodeType = ca.NodeType(val)
+	// The server refuses to start with a lapsed certificate, renew it first
+	if needsRenewal(running.EnrollmentCertificate, time.Now()) {
+		renewed, err := ca.RenewCertificate(running.EnrollmentPrivateKey, running.EnrollmentCertificate, running.Name, n.datadir)
+		if err != nil {
+			if ca.VerifyValidity(running.EnrollmentCertificate, time.Now()) != nil {
+				return fmt.Errorf("Server.EnrollmentCertificate renewal failed %v", err)
 			}
-		} else if ext.Critical && ext.Id.Equal([]int{1, 33, 81}) {
-			val := binary.LittleEndian.Uint32(ext.Value)
-			running.PeerId = val
-			fmt.Println("Peer Id is : ", val)
+			glog.V(logger.Warn).Infof("Enrollment certificate renewal failed, will retry: %v", err)
+		} else {
+			running.EnrollmentCertificate = renewed
+			ca.SetClientCertificate(running.EnrollmentPrivateKey, renewed)
 		}
 	}
 
-	glog.V(logger.Debug).Infof("running.NodeType: %v", running.NodeType)
-	fmt.Println("running.NodeType: ", running.NodeType)
+	// Remote nodes verify the certificate up to the shared root, present the
+	// intermediate CAs that issued it along with it
+	chain, err := ca.GetCertificateChain()
+	if err != nil {
+		return fmt.Errorf("Server.EnrollmentChain build failed %v", err)
+	}
+	running.EnrollmentChain = chain[:len(chain)-1]
+	if err := ca.VerifyChain(running.EnrollmentCertificate, running.EnrollmentChain, chain[len(chain)-1]); err != nil {
+		glog.V(logger.Warn).Infof("Enrollment certificate does not verify against the CA chain: %v", err)
+	}
+
+	running.NodeType, running.PeerId = ca.GetNodeInfo(running.EnrollmentCertificate)
+	glog.V(logger.Debug).Infof("running.NodeType: %v, running.PeerId: %v", running.NodeType, running.PeerId)
 
 	running.ReplicaCount, err = ca.GetReplicaCount()
 	if err != nil {
 		glog.V(logger.Debug).Infof("running.ReplicaCount: %v", running.ReplicaCount)
 		return fmt.Errorf("Server.ReplicaCount failed %v", err)
 	}
-	fmt.Println("Server.ReplicaCount is : ", running.ReplicaCount)
+	glog.V(logger.Debug).Infof("running.ReplicaCount: %v", running.ReplicaCount)
+
+	// Restrict connections to the whitelist from the start, later changes
+	// are synced while the node is running. Until the whitelist is loaded
+	// no node is allowed.
+	var whitelist *whitelistSync
+	if n.allowAllPeers {
+		glog.V(logger.Warn).Infof("Whitelist disabled, accepting every enrolled node")
+	} else {
+		running.Whitelist = p2p.NewWhitelist()
+		whitelist = newWhitelistSync(running.Whitelist)
+		if err := whitelist.pull(); err != nil {
+			glog.V(logger.Warn).Infof("Could not load the whitelist, refusing all nodes until it is: %v", err)
+		}
+	}
+	running.Revocations = p2p.NewRevocations()
+	revocations := newRevocationSync(running.Revocations)
+	if err := revocations.pull(); err != nil {
+		glog.V(logger.Warn).Infof("Could not load the revocation list, will retry: %v", err)
+	}
 
+	// HTTP and websocket callers are identified by certificates of the same CA and
+	// refused once it revoked them
+	n.rpcAuth = nil
+	if n.rpcACL {
+		policy := DefaultRPCPolicy
+		if n.rpcPolicy != "" {
+			if policy, err = LoadRPCPolicy(n.rpcPolicy); err != nil {
+				return err
+			}
+		}
+		n.rpcAuth = newRPCAuthenticator(policy, chain[len(chain)-1], running.Revocations)
+	}
 
 	services := make(map[reflect.Type]Service)
 	for _, constructor := range n.serviceFuncs {
@@ -204,6 +284,9 @@ func (n *Node) Start() error {
 			NodeType: running.NodeType,
 			PeerId:   running.PeerId,
 			PeerCount: running.ReplicaCount,
+			Organization: ca.GetOrganization(running.EnrollmentCertificate),
+			DChain:       n.dchain,
+			CARoot:       chain[len(chain)-1],
 		}
 		for kind, s := range services { // copy needed for threaded access
 			ctx.services[kind] = s
@@ -223,7 +306,12 @@ func (n *Node) Start() error {
 	for _, service := range services {
 		running.Protocols = append(running.Protocols, service.Protocols()...)
 	}
+	// Permission decisions are recorded from the first connection on
+	if err := n.startAudit(); err != nil {
+		return err
+	}
 	if err := running.Start(); err != nil {
+		n.stopAudit()
 		if errno, ok := err.(syscall.Errno); ok && datadirInUseErrnos[uint(errno)] {
 			return ErrDatadirUsed
 		}
@@ -238,6 +326,7 @@ func (n *Node) Start() error {
 				services[kind].Stop()
 			}
 			running.Stop()
+			n.stopAudit()
 
 			return err
 		}
@@ -250,8 +339,19 @@ func (n *Node) Start() error {
 			service.Stop()
 		}
 		running.Stop()
+		n.stopAudit()
 		return err
 	}
+	// Keep the enrollment certificate fresh while the node is running
+	n.renewer = newCertRenewer(running, n.datadir)
+	n.renewer.start()
+	n.whitelist = whitelist
+	if n.whitelist != nil {
+		n.whitelist.start()
+	}
+	n.revocations = revocations
+	n.revocations.start()
+
 	// Finish initializing the startup
 	n.services = services
 	n.server = running
@@ -399,6 +499,9 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 			glog.V(logger.Debug).Infof("HTTP registered %T under '%s'", api.Service, api.Namespace)
 		}
 	}
+	if n.rpcAuth != nil {
+		handler.SetAuthenticator(n.rpcAuth)
+	}
 	// All APIs registered, start the HTTP listener
 	var (
 		listener net.Listener
@@ -454,6 +557,9 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 			glog.V(logger.Debug).Infof("WebSocket registered %T under '%s'", api.Service, api.Namespace)
 		}
 	}
+	if n.rpcAuth != nil {
+		handler.SetAuthenticator(n.rpcAuth)
+	}
 	// All APIs registered, start the HTTP listener
 	var (
 		listener net.Listener
@@ -499,10 +605,22 @@ func (n *Node) Stop() error {
 		return ErrNodeStopped
 	}
 	// Otherwise terminate the API, all services and the P2P server too
+	n.renewer.stop()
+	n.renewer = nil
+	if n.whitelist != nil {
+		n.whitelist.stop()
+		n.whitelist = nil
+	}
+	n.revocations.stop()
+	n.revocations = nil
+
 	n.stopWS()
 	n.stopHTTP()
 	n.stopIPC()
 	n.rpcAPIs = nil
+	if n.rpcAuth != nil {
+		n.rpcAuth.flush()
+	}
 
 	failure := &StopError{
 		Services: make(map[reflect.Type]error),
@@ -513,6 +631,7 @@ func (n *Node) Stop() error {
 		}
 	}
 	n.server.Stop()
+	n.stopAudit()
 
 	n.services = nil
 	n.server = nil
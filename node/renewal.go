@@ -0,0 +1,108 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package node
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+const (
+	certCheckInterval = time.Hour          // Time between two expiry checks of the enrollment certificate
+	certRenewBefore   = 7 * 24 * time.Hour // Renew the certificate at the latest this long before it expires
+)
+
+// renewFunc requests a fresh certificate from the CA.
+type renewFunc func(priv *ecdsa.PrivateKey, cert *x509.Certificate, name, path string) (*x509.Certificate, error)
+
+// certRenewer periodically checks the enrollment certificate of a running p2p
+// server and asks the CA for a fresh one before it expires. The renewed
+// certificate is swapped into the server without dropping connected peers.
+type certRenewer struct {
+	server  *p2p.Server
+	datadir string
+	renew   renewFunc
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newCertRenewer(server *p2p.Server, datadir string) *certRenewer {
+	return &certRenewer{
+		server:  server,
+		datadir: datadir,
+		renew:   ca.RenewCertificate,
+		quit:    make(chan struct{}),
+	}
+}
+
+func (r *certRenewer) start() {
+	r.wg.Add(1)
+	go r.loop()
+}
+
+func (r *certRenewer) stop() {
+	close(r.quit)
+	r.wg.Wait()
+}
+
+func (r *certRenewer) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(certCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.check(time.Now()); err != nil {
+			glog.V(logger.Warn).Infof("Enrollment certificate renewal failed, retrying in %v: %v", certCheckInterval, err)
+		}
+		select {
+		case <-ticker.C:
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// check renews the certificate if it is within its renewal window.
+func (r *certRenewer) check(now time.Time) error {
+	cert := r.server.Certificate()
+	if cert == nil || !needsRenewal(cert, now) {
+		return nil
+	}
+	glog.V(logger.Info).Infof("Enrollment certificate expires at %v, requesting a new one", cert.NotAfter)
+
+	renewed, err := r.renew(r.server.EnrollmentPrivateKey, cert, r.server.Name, r.datadir)
+	if err != nil {
+		return err
+	}
+	if err := r.server.SetCertificate(renewed); err != nil {
+		return err
+	}
+	ca.SetClientCertificate(r.server.EnrollmentPrivateKey, renewed)
+	glog.V(logger.Info).Infof("Enrollment certificate renewed, valid until %v", renewed.NotAfter)
+	return nil
+}
+
+// needsRenewal reports whether the certificate is close enough to its expiry
+// to be renewed. Short lived certificates are renewed once two thirds of
+// their lifetime have passed.
+func needsRenewal(cert *x509.Certificate, now time.Time) bool {
+	window := certRenewBefore
+	if lifetime := cert.NotAfter.Sub(cert.NotBefore); lifetime/3 < window {
+		window = lifetime / 3
+	}
+	return !now.Before(cert.NotAfter.Add(-window))
+}
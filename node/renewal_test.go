@@ -0,0 +1,98 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package node
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		notBefore, notAfter time.Time
+		renew               bool
+	}{
+		{now.Add(-time.Hour), now.Add(90 * 24 * time.Hour), false},
+		{now.Add(-80 * 24 * time.Hour), now.Add(6 * 24 * time.Hour), true},
+		{now.Add(-time.Hour), now.Add(-time.Minute), true},
+		{now.Add(-time.Hour), now.Add(2 * time.Hour), false},
+		{now.Add(-2 * time.Hour), now.Add(time.Hour - time.Second), true},
+	}
+	for i, tt := range tests {
+		cert := &x509.Certificate{NotBefore: tt.notBefore, NotAfter: tt.notAfter}
+		if have := needsRenewal(cert, now); have != tt.renew {
+			t.Errorf("test %d: renewal mismatch: have %v, want %v", i, have, tt.renew)
+		}
+	}
+}
+
+func TestCertRenewerSwapsCertificate(t *testing.T) {
+	now := time.Now()
+	current, _, err := ca.NewTestCertificate("renewal", nil, nil, now.Add(-89*24*time.Hour), now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	fresh, _, err := ca.NewTestCertificate("renewal", nil, nil, now.Add(-time.Minute), now.Add(90*24*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	key, _ := crypto.GenerateKey()
+	server := &p2p.Server{Config: p2p.Config{
+		PrivateKey:            key,
+		EnrollmentPrivateKey:  key,
+		EnrollmentCertificate: current,
+		MaxPeers:              10,
+		NoDial:                true,
+	}}
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	renewer := newCertRenewer(server, "")
+
+	// A failing CA must leave the current certificate in place
+	renewer.renew = func(*ecdsa.PrivateKey, *x509.Certificate, string, string) (*x509.Certificate, error) {
+		return nil, errors.New("ca unreachable")
+	}
+	if err := renewer.check(now); err == nil {
+		t.Fatalf("renewal error not reported")
+	}
+	if server.Certificate() != current {
+		t.Fatalf("certificate replaced after failed renewal")
+	}
+	// A successful renewal swaps the certificate, later checks are no-ops
+	calls := 0
+	renewer.renew = func(priv *ecdsa.PrivateKey, cert *x509.Certificate, name, path string) (*x509.Certificate, error) {
+		calls++
+		if cert != current {
+			t.Errorf("renewal requested for the wrong certificate")
+		}
+		return fresh, nil
+	}
+	for i := 0; i < 2; i++ {
+		if err := renewer.check(now); err != nil {
+			t.Fatalf("failed to renew certificate: %v", err)
+		}
+	}
+	if server.Certificate() != fresh {
+		t.Errorf("renewed certificate not installed")
+	}
+	if calls != 1 {
+		t.Errorf("renewal call count mismatch: have %d, want 1", calls)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package node
+
+import (
+	"crypto/x509"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// revocationPollInterval is the time between two pulls of the revocation list.
+const revocationPollInterval = time.Minute
+
+// revocationSync keeps the revocations of a p2p server in line with the
+// revocation list published by the CA.
+type revocationSync struct {
+	revocations *p2p.Revocations
+	fetch       func() (*x509.Certificate, []*big.Int, error)
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newRevocationSync(revocations *p2p.Revocations) *revocationSync {
+	return &revocationSync{
+		revocations: revocations,
+		fetch:       ca.GetRevocationList,
+		quit:        make(chan struct{}),
+	}
+}
+
+func (s *revocationSync) start() {
+	s.wg.Add(1)
+	go s.pollLoop()
+}
+
+func (s *revocationSync) stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// pull fetches the revocation list from the CA and applies it.
+func (s *revocationSync) pull() error {
+	issuer, serials, err := s.fetch()
+	if err != nil {
+		return err
+	}
+	s.revocations.Update(issuer, serials)
+	glog.V(logger.Debug).Infof("Revocations updated: %d certificates of %q", len(serials), issuer.Subject.CommonName)
+	return nil
+}
+
+func (s *revocationSync) pollLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(revocationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.pull(); err != nil {
+				glog.V(logger.Warn).Infof("Revocation list pull failed: %v", err)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package node
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/audit"
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// rpcTokenScheme is the authorization scheme of the RPC tokens made by
+// ca.SignRPCToken, sent as "Authorization: DChain <token>".
+const rpcTokenScheme = "DChain"
+
+// Roles of RPC callers. Callers with a certificate get the role of its node
+// type, the others the anonymous role.
+const (
+	RoleAnonymous = "anonymous"
+	RoleClient    = "client"
+	RolePeer      = "peer"
+	RoleValidator = "validator"
+	RoleAdmin     = "admin"
+)
+
+// RPCPolicy maps roles to the RPC methods they may call. A method is given
+// as "namespace_method", "namespace_*" for the whole namespace or "*" for
+// every method. Roles missing from the policy may not call anything.
+type RPCPolicy struct {
+	Roles map[string][]string `json:"roles"`
+}
+
+// DefaultRPCPolicy keeps the administrative and account namespaces for the
+// admin role. Clients and peers may use the chain, validators may also run
+// the miner and debug the node.
+var DefaultRPCPolicy = &RPCPolicy{
+	Roles: map[string][]string{
+		RoleAnonymous: {"eth_*", "net_*", "web3_*", "rpc_*"},
+		RoleClient:    {"eth_*", "net_*", "web3_*", "rpc_*", "shh_*", "txpool_*"},
+		RolePeer:      {"eth_*", "net_*", "web3_*", "rpc_*", "shh_*", "txpool_*"},
+		RoleValidator: {"eth_*", "net_*", "web3_*", "rpc_*", "shh_*", "txpool_*", "miner_*", "debug_*"},
+		RoleAdmin:     {"*"},
+	},
+}
+
+// LoadRPCPolicy reads a policy from a JSON file.
+func LoadRPCPolicy(file string) (*RPCPolicy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	policy := new(RPCPolicy)
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("invalid RPC policy %s: %v", file, err)
+	}
+	return policy, nil
+}
+
+// Allowed reports whether role may call the given method of the given
+// namespace.
+func (p *RPCPolicy) Allowed(role, namespace, method string) bool {
+	for _, pattern := range p.Roles[role] {
+		switch {
+		case pattern == "*":
+			return true
+		case strings.HasSuffix(pattern, "_*") && pattern[:len(pattern)-2] == namespace:
+			return true
+		case pattern == namespace+"_"+method:
+			return true
+		}
+	}
+	return false
+}
+
+// roleOf returns the role of a caller certificate, nil meaning anonymous.
+func roleOf(cert *x509.Certificate) string {
+	if cert == nil {
+		return RoleAnonymous
+	}
+	switch nodetype, _ := ca.GetNodeInfo(cert); nodetype {
+	case ca.Client:
+		return RoleClient
+	case ca.Peer:
+		return RolePeer
+	case ca.Validator:
+		return RoleValidator
+	case ca.Admin:
+		return RoleAdmin
+	}
+	return ""
+}
+
+// rpcAuthenticator identifies RPC callers by the certificates issued below
+// the CA root and authorizes their calls by the role of the certificate.
+// Certificates revoked by their CA are refused like invalid ones.
+type rpcAuthenticator struct {
+	policy      *RPCPolicy
+	root        *x509.Certificate
+	revocations *p2p.Revocations
+	denials     *rpcDenials
+	now         func() time.Time
+}
+
+func newRPCAuthenticator(policy *RPCPolicy, root *x509.Certificate, revocations *p2p.Revocations) *rpcAuthenticator {
+	return &rpcAuthenticator{
+		policy:      policy,
+		root:        root,
+		revocations: revocations,
+		denials:     &rpcDenials{windows: make(map[string]*rpcDenialCount)},
+		now:         time.Now,
+	}
+}
+
+// Authenticate returns the certificate of the caller, taken from an RPC token
+// or from the client certificate of a TLS connection. Callers presenting
+// neither are anonymous, invalid credentials are refused.
+func (a *rpcAuthenticator) Authenticate(r *http.Request) (interface{}, error) {
+	cert, err := a.authenticate(r)
+	if err != nil {
+		a.denials.record(remoteHost(r), fmt.Sprintf("authentication failed: %v", err), a.now())
+		return nil, err
+	}
+	if cert == nil {
+		return nil, nil
+	}
+	return cert, nil
+}
+
+func (a *rpcAuthenticator) authenticate(r *http.Request) (*x509.Certificate, error) {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if !strings.HasPrefix(auth, rpcTokenScheme+" ") {
+			return nil, fmt.Errorf("unsupported authorization scheme")
+		}
+		cert, err := ca.VerifyRPCToken(strings.TrimSpace(auth[len(rpcTokenScheme)+1:]), a.root, a.now())
+		if err != nil {
+			return nil, err
+		}
+		if a.revocations.Revoked(cert) {
+			return nil, fmt.Errorf("certificate %v has been revoked", cert.SerialNumber)
+		}
+		return cert, nil
+	}
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		certs := r.TLS.PeerCertificates
+		if ca.IsTCert(certs[0]) {
+			return nil, fmt.Errorf("transaction certificates cannot identify rpc callers")
+		}
+		if err := ca.VerifyChainAt(certs[0], certs[1:], a.root, a.now()); err != nil {
+			return nil, err
+		}
+		if a.revocations.Revoked(certs[0]) {
+			return nil, fmt.Errorf("certificate %v has been revoked", certs[0].SerialNumber)
+		}
+		return certs[0], nil
+	}
+	return nil, nil
+}
+
+// Authorize returns an error if the role of caller may not call the method.
+func (a *rpcAuthenticator) Authorize(caller interface{}, namespace, method string) error {
+	cert, _ := caller.(*x509.Certificate)
+	role := roleOf(cert)
+	if !a.policy.Allowed(role, namespace, method) {
+		caller := role
+		if cert != nil {
+			caller = cert.Subject.CommonName
+		}
+		a.denials.record(caller, fmt.Sprintf("%s_%s denied to role %s", namespace, method, role), a.now())
+		return fmt.Errorf("role %q is not allowed to call it", role)
+	}
+	return nil
+}
+
+// flush records the denials still being counted.
+func (a *rpcAuthenticator) flush() {
+	a.denials.flush(time.Time{})
+}
+
+// remoteHost returns the address of the caller that sent r, without the port
+// that changes with every connection.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rpcDenialWindow is the period over which the denied requests of a caller
+// are aggregated into a single audit entry.
+const rpcDenialWindow = time.Minute
+
+// rpcDenials keeps callers retrying denied requests from filling the audit
+// log. The first denial of a caller is recorded right away, the further ones
+// within rpcDenialWindow are only counted and recorded as one entry once the
+// window has passed.
+type rpcDenials struct {
+	mu      sync.Mutex
+	windows map[string]*rpcDenialCount
+}
+
+type rpcDenialCount struct {
+	start  time.Time
+	count  int    // denials after the first one
+	detail string // detail of the last denial
+}
+
+func (d *rpcDenials) record(subject, detail string, now time.Time) {
+	d.flush(now)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if w := d.windows[subject]; w != nil {
+		w.count++
+		w.detail = detail
+		return
+	}
+	d.windows[subject] = &rpcDenialCount{start: now}
+	audit.Record(audit.RPCDenied, subject, detail)
+}
+
+// flush records the counts of the windows passed at now, the zero time
+// meaning all of them.
+func (d *rpcDenials) flush(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for subject, w := range d.windows {
+		if !now.IsZero() && now.Sub(w.start) < rpcDenialWindow {
+			continue
+		}
+		delete(d.windows, subject)
+		if w.count > 0 {
+			audit.Record(audit.RPCDenied, subject, fmt.Sprintf("%d more denied since %s, last: %s", w.count, w.start.Format(time.RFC3339), w.detail))
+		}
+	}
+}
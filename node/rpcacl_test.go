@@ -0,0 +1,176 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package node
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+func newRPCTestCertificate(t *testing.T, name string, nodetype ca.NodeType, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			{Id: ca.NodeTypeOID, Critical: true, Value: []byte{byte(nodetype)}},
+		},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+func TestRPCPolicy(t *testing.T) {
+	tests := []struct {
+		role, namespace, method string
+		allowed                 bool
+	}{
+		{RoleAnonymous, "eth", "blockNumber", true},
+		{RoleAnonymous, "personal", "unlockAccount", false},
+		{RoleClient, "txpool", "status", true},
+		{RoleClient, "admin", "addPeer", false},
+		{RoleClient, "debug", "traceTransaction", false},
+		{RoleClient, "personal", "newAccount", false},
+		{RoleValidator, "debug", "traceTransaction", true},
+		{RoleValidator, "admin", "nodeInfo", false},
+		{RoleAdmin, "admin", "addPeer", true},
+		{"", "eth", "blockNumber", false},
+	}
+	for _, tt := range tests {
+		if allowed := DefaultRPCPolicy.Allowed(tt.role, tt.namespace, tt.method); allowed != tt.allowed {
+			t.Errorf("%s: %s_%s allowed = %v, want %v", tt.role, tt.namespace, tt.method, allowed, tt.allowed)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "dchain-rpcacl-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "policy.json")
+	if err := ioutil.WriteFile(file, []byte(`{"roles": {"client": ["eth_*", "admin_nodeInfo"]}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadRPCPolicy(file)
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	if !policy.Allowed(RoleClient, "admin", "nodeInfo") || policy.Allowed(RoleClient, "admin", "peers") {
+		t.Error("method patterns not applied")
+	}
+	if policy.Allowed(RoleAnonymous, "eth", "blockNumber") {
+		t.Error("role missing from the policy allowed")
+	}
+}
+
+func TestRPCAuthenticator(t *testing.T) {
+	if err := ca.Init(nil); err != nil {
+		t.Fatalf("failed to init crypto: %v", err)
+	}
+	root, rootKey := newRPCTestCertificate(t, "root", ca.Admin, nil, nil)
+	client, clientKey := newRPCTestCertificate(t, "client", ca.Client, root, rootKey)
+	admin, adminKey := newRPCTestCertificate(t, "admin", ca.Admin, root, rootKey)
+	revocations := p2p.NewRevocations()
+	auth := newRPCAuthenticator(DefaultRPCPolicy, root, revocations)
+
+	request := func(key *ecdsa.PrivateKey, cert *x509.Certificate) *http.Request {
+		r, _ := http.NewRequest("POST", "http://localhost", nil)
+		if cert != nil {
+			token, err := ca.SignRPCToken(key, time.Now(), cert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Authorization", rpcTokenScheme+" "+token)
+		}
+		return r
+	}
+
+	caller, err := auth.Authenticate(request(clientKey, client))
+	if err != nil {
+		t.Fatalf("client refused: %v", err)
+	}
+	if err := auth.Authorize(caller, "eth", "sendTransaction"); err != nil {
+		t.Errorf("client refused eth: %v", err)
+	}
+	for _, namespace := range []string{"admin", "debug", "personal"} {
+		if err := auth.Authorize(caller, namespace, "anything"); err == nil {
+			t.Errorf("client allowed to call %s", namespace)
+		}
+	}
+
+	caller, err = auth.Authenticate(request(adminKey, admin))
+	if err != nil {
+		t.Fatalf("admin refused: %v", err)
+	}
+	if err := auth.Authorize(caller, "admin", "addPeer"); err != nil {
+		t.Errorf("admin refused admin: %v", err)
+	}
+
+	caller, err = auth.Authenticate(request(nil, nil))
+	if err != nil || caller != nil {
+		t.Fatalf("anonymous caller mismatch: %v, %v", caller, err)
+	}
+	if err := auth.Authorize(caller, "personal", "unlockAccount"); err == nil {
+		t.Error("anonymous caller allowed to call personal")
+	}
+
+	// Tokens for certificates of another CA or signed by another key fail
+	if _, err := auth.Authenticate(request(clientKey, admin)); err == nil {
+		t.Error("token signed by a foreign key accepted")
+	}
+	other, otherKey := newRPCTestCertificate(t, "other", ca.Admin, nil, nil)
+	if _, err := auth.Authenticate(request(otherKey, other)); err == nil {
+		t.Error("token of a foreign CA accepted")
+	}
+
+	// Revoked certificates are refused, by token and over TLS
+	revocations.Update(root, []*big.Int{client.SerialNumber})
+	if _, err := auth.Authenticate(request(clientKey, client)); err == nil {
+		t.Error("token of a revoked certificate accepted")
+	}
+	r := request(nil, nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{client}}
+	if _, err := auth.Authenticate(r); err == nil {
+		t.Error("revoked TLS client certificate accepted")
+	}
+	r.TLS.PeerCertificates = []*x509.Certificate{admin}
+	if _, err := auth.Authenticate(r); err != nil {
+		t.Errorf("TLS admin refused: %v", err)
+	}
+}
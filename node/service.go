@@ -17,6 +17,7 @@
 package node
 
 import (
+	"crypto/x509"
 	"path/filepath"
 	"reflect"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/settings"
 )
 
 // ServiceContext is a collection of service independent options inherited from
@@ -37,6 +39,9 @@ type ServiceContext struct {
 	NodeType ca.NodeType
 	PeerId   uint32
 	PeerCount uint32
+	Organization string // Organization the enrollment certificate is issued to
+	CARoot       *x509.Certificate // Root of the CA all members are enrolled under
+	DChain       *settings.Config    // DChain settings of the node
 }
 
 // OpenDatabase opens an existing database with the given name (or creates one
@@ -0,0 +1,114 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+const (
+	whitelistPollInterval = time.Minute      // Time between two full pulls of the whitelist
+	whitelistRetryDelay   = 5 * time.Second // Time to wait before reopening a failed update stream
+)
+
+// whitelistSync keeps the whitelist of a p2p server in line with the one
+// managed by the CA. Changes are streamed from the CA as they happen; the
+// whole list is pulled periodically in case the stream is down.
+type whitelistSync struct {
+	whitelist *p2p.Whitelist
+	fetch     func() ([]string, []string, error)
+	watch     func(quit <-chan struct{}, update func(cidrs, ids []string)) error
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newWhitelistSync(whitelist *p2p.Whitelist) *whitelistSync {
+	return &whitelistSync{
+		whitelist: whitelist,
+		fetch:     ca.GetWhitelist,
+		watch:     ca.WatchWhitelist,
+		quit:      make(chan struct{}),
+	}
+}
+
+func (s *whitelistSync) start() {
+	s.wg.Add(2)
+	go s.pollLoop()
+	go s.watchLoop()
+}
+
+func (s *whitelistSync) stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// pull fetches the whole whitelist from the CA and applies it.
+func (s *whitelistSync) pull() error {
+	cidrs, ids, err := s.fetch()
+	if err != nil {
+		return err
+	}
+	return s.apply(cidrs, ids)
+}
+
+func (s *whitelistSync) apply(cidrs, ids []string) error {
+	if err := s.whitelist.Update(cidrs, ids); err != nil {
+		return err
+	}
+	glog.V(logger.Debug).Infof("Whitelist updated: %d networks, %d nodes", len(cidrs), len(ids))
+	return nil
+}
+
+func (s *whitelistSync) pollLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(whitelistPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.pull(); err != nil {
+				glog.V(logger.Warn).Infof("Whitelist pull failed: %v", err)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *whitelistSync) watchLoop() {
+	defer s.wg.Done()
+
+	for {
+		err := s.watch(s.quit, func(cidrs, ids []string) {
+			if err := s.apply(cidrs, ids); err != nil {
+				glog.V(logger.Warn).Infof("Invalid whitelist update: %v", err)
+			}
+		})
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+		glog.V(logger.Debug).Infof("Whitelist stream closed, reopening in %v: %v", whitelistRetryDelay, err)
+
+		select {
+		case <-time.After(whitelistRetryDelay):
+		case <-s.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package node
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+func TestWhitelistSync(t *testing.T) {
+	whitelist := p2p.NewWhitelist()
+	sync := newWhitelistSync(whitelist)
+
+	sync.fetch = func() ([]string, []string, error) {
+		return []string{"10.0.0.0/8"}, nil, nil
+	}
+	streamed := make(chan []string)
+	sync.watch = func(quit <-chan struct{}, update func(cidrs, ids []string)) error {
+		for {
+			select {
+			case cidrs := <-streamed:
+				update(cidrs, nil)
+			case <-quit:
+				return errors.New("closed")
+			}
+		}
+	}
+
+	// A whitelist that could not be loaded refuses every node
+	fetch := sync.fetch
+	sync.fetch = func() ([]string, []string, error) {
+		return nil, nil, errors.New("unreachable")
+	}
+	var id discover.NodeID
+	if err := sync.pull(); err == nil || whitelist.Allowed(net.ParseIP("10.1.1.1"), id) {
+		t.Fatalf("failed pull allowed nodes: %v", err)
+	}
+	sync.fetch = fetch
+
+	if err := sync.pull(); err != nil {
+		t.Fatalf("pull failed: %v", err)
+	}
+	if !whitelist.Allowed(net.ParseIP("10.1.1.1"), id) || whitelist.Allowed(net.ParseIP("172.16.0.1"), id) {
+		t.Fatal("pulled whitelist not applied")
+	}
+
+	sync.start()
+	defer sync.stop()
+
+	streamed <- []string{"172.16.0.0/12"}
+	for deadline := time.Now().Add(time.Second); !whitelist.Allowed(net.ParseIP("172.16.0.1"), id); {
+		if time.Now().After(deadline) {
+			t.Fatal("streamed whitelist not applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if whitelist.Allowed(net.ParseIP("10.1.1.1"), id) {
+		t.Fatal("streamed whitelist did not replace the old one")
+	}
+}
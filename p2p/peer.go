@@ -17,6 +17,7 @@
 package p2p
 
 import (
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
@@ -25,6 +26,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
 	"github.com/ethereum/go-ethereum/logger"
 	"github.com/ethereum/go-ethereum/logger/glog"
 	"github.com/ethereum/go-ethereum/p2p/discover"
@@ -47,6 +49,10 @@ const (
 	pongMsg      = 0x03
 	getPeersMsg  = 0x04
 	peersMsg     = 0x05
+
+	// enrollmentProofMsg carries the signature proving possession of the
+	// enrollment key, exchanged right after the encryption handshake.
+	enrollmentProofMsg = 0x06
 )
 
 // protoHandshake is the RLP structure of the protocol handshake.
@@ -91,6 +97,23 @@ func (p *Peer) Name() string {
 	return p.rw.name
 }
 
+// Certificate returns the enrollment certificate of the remote node, or nil
+// if the connection was not set up by the enrollment handshake. The remote
+// node proved to hold the key of the certificate for its node ID, so the
+// certificate identifies the node.
+func (p *Peer) Certificate() *x509.Certificate {
+	return p.rw.cert
+}
+
+// Organization returns the organization the enrollment certificate of the
+// remote node is issued to, or the empty string if it is not known.
+func (p *Peer) Organization() string {
+	if p.rw.cert == nil {
+		return ""
+	}
+	return ca.GetOrganization(p.rw.cert)
+}
+
 // Caps returns the capabilities (supported subprotocols) of the remote peer.
 func (p *Peer) Caps() []Cap {
 	// TODO: maybe return copy
@@ -367,9 +390,10 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 // peer. Sub-protocol independent fields are contained and initialized here, with
 // protocol specifics delegated to all connected sub-protocols.
 type PeerInfo struct {
-	ID      string   `json:"id"`   // Unique node identifier (also the encryption key)
-	Name    string   `json:"name"` // Name of the node, including client type, version, OS, custom data
-	Caps    []string `json:"caps"` // Sum-protocols advertised by this particular peer
+	ID           string   `json:"id"`                     // Unique node identifier (also the encryption key)
+	Name         string   `json:"name"`                   // Name of the node, including client type, version, OS, custom data
+	Caps         []string `json:"caps"`                   // Sum-protocols advertised by this particular peer
+	Organization string   `json:"organization,omitempty"` // Organization of the enrollment certificate
 	Network struct {
 		LocalAddress  string `json:"localAddress"`  // Local endpoint of the TCP data connection
 		RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
@@ -386,10 +410,11 @@ func (p *Peer) Info() *PeerInfo {
 	}
 	// Assemble the generic peer metadata
 	info := &PeerInfo{
-		ID:        p.ID().String(),
-		Name:      p.Name(),
-		Caps:      caps,
-		Protocols: make(map[string]interface{}),
+		ID:           p.ID().String(),
+		Name:         p.Name(),
+		Caps:         caps,
+		Organization: p.Organization(),
+		Protocols:    make(map[string]interface{}),
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
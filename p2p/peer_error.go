@@ -66,6 +66,9 @@ const (
 	DiscUnexpectedIdentity
 	DiscSelf
 	DiscReadTimeout
+	DiscNotWhitelisted
+	DiscRoleViolation
+	DiscCertificateRevoked
 	DiscSubprotocolError = 0x10
 )
 
@@ -82,6 +85,9 @@ var discReasonToString = [...]string{
 	DiscUnexpectedIdentity:  "Unexpected identity",
 	DiscSelf:                "Connected to self",
 	DiscReadTimeout:         "Read timeout",
+	DiscNotWhitelisted:      "Not whitelisted",
+	DiscRoleViolation:       "Message not permitted for node type",
+	DiscCertificateRevoked:  "Certificate revoked",
 	DiscSubprotocolError:    "Subprotocol error",
 }
 
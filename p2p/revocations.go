@@ -0,0 +1,72 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package p2p
+
+import (
+	"crypto/x509"
+	"math/big"
+	"sync"
+)
+
+// Revocations holds the enrollment certificates revoked by the CAs of the
+// network. Nodes presenting a revoked certificate are refused.
+//
+// Revocations are safe for concurrent use and may be updated while the server
+// is running, connected peers whose certificate is revoked are disconnected.
+type Revocations struct {
+	lock    sync.RWMutex
+	serials map[string]map[string]bool // issuer subject -> revoked serial numbers
+
+	changed chan struct{} // signals the server to recheck its peers
+}
+
+// NewRevocations creates an empty set of revocations.
+func NewRevocations() *Revocations {
+	return &Revocations{
+		serials: make(map[string]map[string]bool),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// Update replaces the certificates revoked by issuer with the ones having the
+// given serial numbers.
+func (r *Revocations) Update(issuer *x509.Certificate, serials []*big.Int) {
+	revoked := make(map[string]bool, len(serials))
+	for _, serial := range serials {
+		revoked[serial.String()] = true
+	}
+
+	r.lock.Lock()
+	r.serials[string(issuer.RawSubject)] = revoked
+	r.lock.Unlock()
+
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Revoked reports whether cert has been revoked by its issuer.
+func (r *Revocations) Revoked(cert *x509.Certificate) bool {
+	if r == nil || cert == nil {
+		return false
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.serials[string(cert.RawIssuer)][cert.SerialNumber.String()]
+}
+
+// updates returns the channel signalled on every update, or nil for a nil set
+// so that it never fires.
+func (r *Revocations) updates() <-chan struct{} {
+	if r == nil {
+		return nil
+	}
+	return r.changed
+}
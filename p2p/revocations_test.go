@@ -0,0 +1,66 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package p2p
+
+import (
+	"crypto/x509"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
+)
+
+func TestServerRevocations(t *testing.T) {
+	now := time.Now()
+	root, rootKey, err := ca.NewTestCertificate("root", nil, nil, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	first, _, _ := ca.NewTestCertificate("first", root, rootKey, now.Add(-time.Hour), now.Add(time.Hour))
+	second, _, _ := ca.NewTestCertificate("second", root, rootKey, now.Add(-time.Hour), now.Add(time.Hour))
+
+	revocations := NewRevocations()
+	revocations.Update(root, []*big.Int{first.SerialNumber})
+	srv := &Server{
+		Config: Config{
+			PrivateKey:           newkey(),
+			MaxPeers:             10,
+			NoDial:               true,
+			EnrollmentPrivateKey: newkey(),
+			Revocations:          revocations,
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(cert *x509.Certificate) *conn {
+		id := randomID()
+		fd, _ := net.Pipe()
+		tx := newTestTransport(id, fd)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, id: id, cert: cert, cont: make(chan error)}
+	}
+	if err := srv.checkpoint(newconn(first), srv.posthandshake); err != DiscCertificateRevoked {
+		t.Fatalf("wrong error for revoked certificate: %v", err)
+	}
+	if err := srv.checkpoint(newconn(second), srv.addpeer); err != nil {
+		t.Fatalf("could not add node: %v", err)
+	}
+
+	// Revoking the certificate of the connected node drops it
+	revocations.Update(root, []*big.Int{first.SerialNumber, second.SerialNumber})
+	for deadline := time.Now().Add(time.Second); srv.PeerCount() > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("revoked peer not dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -73,8 +73,28 @@ const (
 	// This is shorter than the usual timeout because we don't want
 	// to wait if the connection is known to be bad anyway.
 	discWriteTimeout = 1 * time.Second
+
+	// enrollmentProofLabel separates the session identifier signed for the
+	// enrollment proof from other uses of the handshake secrets.
+	enrollmentProofLabel = "dchain enrollment proof"
+
+	// maxCertificateSize limits a PEM encoded certificate read during the
+	// enrollment handshake.
+	maxCertificateSize = 8 * 1024
 )
 
+var errEnrollmentRefused = errors.New("enrollment certificate refused by remote node")
+
+// enrollmentRejectedError is returned if the certificate chain presented by
+// the remote node does not verify.
+type enrollmentRejectedError struct {
+	err error
+}
+
+func (e *enrollmentRejectedError) Error() string {
+	return "enrollment certificate rejected: " + e.err.Error()
+}
+
 // rlpx is the transport protocol used by actual (non-test) connections.
 // It wraps the frame encoder with locks and read/write deadlines.
 type rlpx struct {
@@ -82,6 +102,7 @@ type rlpx struct {
 
 	rmu, wmu sync.Mutex
 	rw       *rlpxFrameRW
+	session  []byte // identifies the connection, set by the encryption handshake
 }
 
 func newRLPX(fd net.Conn) transport {
@@ -182,19 +203,85 @@ func (t *rlpx) doEncHandshake(prv *ecdsa.PrivateKey, dial *discover.Node) (disco
 	}
 	t.wmu.Lock()
 	t.rw = newRLPXFrameRW(t.fd, sec)
+	t.session = crypto.Keccak256([]byte(enrollmentProofLabel), sec.MAC)
 	t.wmu.Unlock()
 	return sec.RemoteID, nil
 }
 
-func (t *rlpx) doEnrollmentHandshake(cert *x509.Certificate, dial *discover.Node) (bool, error) {
+// doEnrollmentProof exchanges signatures made with the enrollment keys over
+// the session of the encryption handshake and the node IDs of both ends. The
+// certificates of the enrollment handshake are sent in the clear, the proof
+// shows the remote node holds the key of cert and binds it to its node ID,
+// so that a replayed certificate is of no use.
+func (t *rlpx) doEnrollmentProof(prv *ecdsa.PrivateKey, local, remote discover.NodeID, cert *x509.Certificate) error {
+	if cert == nil {
+		return &enrollmentRejectedError{errors.New("no certificate to verify the enrollment proof")}
+	}
+	pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return &enrollmentRejectedError{errors.New("certificate key is not an ECDSA key")}
+	}
+	sign, err := ca.SignEnrollmentProof(prv, enrollmentProofData(t.session, local, remote))
+	if err != nil {
+		return err
+	}
+	// As for the protocol handshake, writing happens concurrently
+	werr := make(chan error, 1)
+	go func() { werr <- Send(t.rw, enrollmentProofMsg, sign) }()
+	theirs, err := readEnrollmentProof(t.rw)
+	if err != nil {
+		<-werr // make sure the write terminates too
+		return err
+	}
+	if err := <-werr; err != nil {
+		return fmt.Errorf("write error: %v", err)
+	}
+	if err := ca.VerifyEnrollmentProof(pub, enrollmentProofData(t.session, remote, local), theirs); err != nil {
+		return &enrollmentRejectedError{err}
+	}
+	return nil
+}
+
+// enrollmentProofData returns the data signed by the node with ID signer to
+// prove possession of its enrollment key to the node with ID verifier.
+func enrollmentProofData(session []byte, signer, verifier discover.NodeID) []byte {
+	data := append([]byte{}, session...)
+	data = append(data, signer[:]...)
+	return append(data, verifier[:]...)
+}
 
-	if dial == nil {
-		return receiverEnrollmentHandshake(t.fd)
-	} else {
-		return initiatorEnrollmentHandshake(t.fd, cert)
+func readEnrollmentProof(rw MsgReader) ([]byte, error) {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+	if msg.Size > baseProtocolMaxMsgSize {
+		return nil, fmt.Errorf("message too big")
+	}
+	if msg.Code == discMsg {
+		var reason [1]DiscReason
+		rlp.Decode(msg.Payload, &reason)
+		return nil, reason[0]
 	}
+	if msg.Code != enrollmentProofMsg {
+		return nil, fmt.Errorf("expected enrollment proof, got %x", msg.Code)
+	}
+	var sign []byte
+	if err := msg.Decode(&sign); err != nil {
+		return nil, err
+	}
+	return sign, nil
+}
 
-	return true, nil
+func (t *rlpx) doEnrollmentHandshake(certs []*x509.Certificate, dial *discover.Node) (*x509.Certificate, error) {
+	root, err := enrollmentRoot()
+	if err != nil {
+		return nil, err
+	}
+	if dial == nil {
+		return receiverEnrollmentHandshake(t.fd, certs, root)
+	}
+	return initiatorEnrollmentHandshake(t.fd, certs, root)
 }
 
 // encHandshake contains the state of the encryption handshake.
@@ -279,55 +366,108 @@ func (h *encHandshake) staticSharedSecret(prv *ecdsa.PrivateKey) ([]byte, error)
 	return ecies.ImportECDSA(prv).GenerateShared(h.remotePub, sskLen, sskLen)
 }
 
-func initiatorEnrollmentHandshake(conn io.ReadWriter, cert *x509.Certificate) (valid bool, err error) {
-
-	raw := cert.Raw
-	cooked := pem.EncodeToMemory(
-		&pem.Block{
-			Type:  "CERTIFICATE",
-			Bytes: raw,
-		})
+// initiatorEnrollmentHandshake presents the enrollment certificate, followed
+// by the certificates of the intermediate CAs that issued it, to the remote
+// node. Once accepted, the remote node presents its own chain, which is
+// verified against root and its enrollment certificate returned.
+func initiatorEnrollmentHandshake(conn io.ReadWriter, certs []*x509.Certificate, root *x509.Certificate) (*x509.Certificate, error) {
+	cooked := ca.EncodeCertificates(certs...)
 
-	if _, err = conn.Write([]byte(cooked)); err != nil {
+	if _, err := conn.Write([]byte(cooked)); err != nil {
 		glog.V(logger.Debug).Infof("could not send certificate: %v", err)
-		return false, fmt.Errorf("could not send certificate: %v", err)
+		return nil, fmt.Errorf("could not send certificate: %v", err)
 	}
 
-	message, err := bufio.NewReader(conn).ReadString('\n')
+	r := bufio.NewReader(conn)
+	message, err := r.ReadString('\n')
 	if err != nil {
 		glog.V(logger.Debug).Infof("certificate response: msg %v;  err %v", message, err)
-		return false, fmt.Errorf("could not get certificate response: %v", err)
+		return nil, fmt.Errorf("could not get certificate response: %v", err)
 	}
 
 	glog.V(logger.Debug).Infof("certificate response: msg %v", message)
-	valid = strings.Compare(message, "pass\n") == 0
-	return valid, err
+	if strings.Compare(message, "pass\n") != 0 {
+		return nil, errEnrollmentRefused
+	}
+	return readEnrollmentChain(r, root)
 }
 
-func receiverEnrollmentHandshake(conn io.ReadWriter) (valid bool, err error) {
-	message := make([]byte, 1000)
-	n, err := bufio.NewReader(conn).Read([]byte(message))
+// receiverEnrollmentHandshake reads the certificate chain presented by the
+// remote node and verifies it against root. If it is accepted, the local
+// chain certs is presented in turn. The verified enrollment certificate of
+// the remote node is returned.
+func receiverEnrollmentHandshake(conn io.ReadWriter, certs []*x509.Certificate, root *x509.Certificate) (*x509.Certificate, error) {
+	cert, err := readEnrollmentChain(bufio.NewReader(conn), root)
 	if err != nil {
-		return false, fmt.Errorf("could not readfull: %v", err)
+		conn.Write([]byte("failed\n"))
+		return nil, err
 	}
+	if _, err := conn.Write(append([]byte("pass\n"), ca.EncodeCertificates(certs...)...)); err != nil {
+		return nil, fmt.Errorf("could not send certificate: %v", err)
+	}
+	return cert, nil
+}
 
-	receiverCert := message[0:n]
-
-	root, err := ca.GetCACertificate()
+// enrollmentRoot returns the root certificate enrollment chains are verified
+// against.
+func enrollmentRoot() (*x509.Certificate, error) {
+	cooked, err := ca.GetRootCertificate()
 	if err != nil {
-		glog.V(logger.Debug).Infof("could not get ca certificate: root %v;  receiverCert %v; err %v", root, receiverCert, err)
-		return false, fmt.Errorf("could not get ca certificate: %v", err)
+		glog.V(logger.Debug).Infof("could not get ca certificate: root %v; err %v", cooked, err)
+		return nil, fmt.Errorf("could not get ca certificate: %v", err)
+	}
+	root := ca.BuildCertificateFromBytes(cooked)
+	if root == nil {
+		return nil, fmt.Errorf("could not get ca certificate: certificate data error")
 	}
-	err = ca.VerifySignature(receiverCert, root)
-	valid = err == nil
+	return root, nil
+}
 
-	if valid {
-		conn.Write([]byte("pass\n"))
-	}else {
-		conn.Write([]byte("failed\n"))
+// readEnrollmentChain reads a certificate chain up to the first certificate
+// issued by the root and verifies it. The enrollment certificate at the start
+// of the chain is returned.
+func readEnrollmentChain(r *bufio.Reader, root *x509.Certificate) (*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for len(certs) <= ca.MaxChainLength {
+		cert, err := readEnrollmentCertificate(r)
+		if err != nil {
+			return nil, fmt.Errorf("could not read certificate: %v", err)
+		}
+		certs = append(certs, cert)
+		if bytes.Equal(cert.RawIssuer, root.RawSubject) {
+			break
+		}
+	}
+	if err := ca.VerifyChain(certs[0], certs[1:], root); err != nil {
+		return nil, &enrollmentRejectedError{err}
 	}
+	if ca.IsTCert(certs[0]) {
+		return nil, &enrollmentRejectedError{errors.New("transaction certificates cannot enroll nodes")}
+	}
+	return certs[0], nil
+}
 
-	return valid, err
+// readEnrollmentCertificate reads one PEM encoded certificate.
+func readEnrollmentCertificate(r *bufio.Reader) (*x509.Certificate, error) {
+	var cooked []byte
+	for {
+		line, err := r.ReadSlice('\n')
+		cooked = append(cooked, line...)
+		if err != nil {
+			return nil, err
+		}
+		if len(cooked) > maxCertificateSize {
+			return nil, errors.New("certificate too large")
+		}
+		if bytes.HasPrefix(line, []byte("-----END")) {
+			break
+		}
+	}
+	block, _ := pem.Decode(cooked)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("certificate data error")
+	}
+	return x509.ParseCertificate(block.Bytes)
 }
 
 // initiatorEncHandshake negotiates a session token on conn.
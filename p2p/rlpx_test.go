@@ -17,8 +17,11 @@
 package p2p
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/ecdsa"
 	"crypto/rand"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
@@ -32,6 +35,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
 	"github.com/ethereum/go-ethereum/crypto/ecies"
 	"github.com/ethereum/go-ethereum/crypto/sha3"
 	"github.com/ethereum/go-ethereum/p2p/discover"
@@ -58,6 +62,147 @@ func TestSharedSecret(t *testing.T) {
 	}
 }
 
+func TestReadEnrollmentCertificate(t *testing.T) {
+	var certs []*x509.Certificate
+	for _, name := range []string{"node", "intermediate"} {
+		cert, _, err := ca.NewTestCertificate(name, nil, nil, time.Now(), time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		certs = append(certs, cert)
+	}
+
+	// Certificates of a chain are read one at a time
+	r := bufio.NewReader(bytes.NewReader(ca.EncodeCertificates(certs...)))
+	for _, want := range certs {
+		cert, err := readEnrollmentCertificate(r)
+		if err != nil {
+			t.Fatalf("failed to read certificate: %v", err)
+		}
+		if !cert.Equal(want) {
+			t.Fatalf("certificate mismatch: have %q, want %q", cert.Subject.CommonName, want.Subject.CommonName)
+		}
+	}
+	if _, err := readEnrollmentCertificate(r); err != io.EOF {
+		t.Fatalf("unexpected error at end of chain: %v", err)
+	}
+
+	// Oversized input is refused before the end marker
+	oversized := strings.Repeat(strings.Repeat("A", 64)+"\n", maxCertificateSize/64+1)
+	r = bufio.NewReader(strings.NewReader("-----BEGIN CERTIFICATE-----\n" + oversized))
+	if _, err := readEnrollmentCertificate(r); err == nil {
+		t.Fatal("oversized certificate accepted")
+	}
+}
+
+func TestEnrollmentHandshake(t *testing.T) {
+	if err := ca.Init(nil); err != nil {
+		t.Fatalf("failed to init crypto: %v", err)
+	}
+	newCert := func(name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+		cert, key, err := ca.NewTestCertificate(name, parent, parentKey, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert, key
+	}
+	root, rootKey := newCert("root", nil, nil)
+	initiator, _ := newCert("initiator", root, rootKey)
+	receiver, _ := newCert("receiver", root, rootKey)
+
+	// Both ends learn the certificate of the other
+	p0, p1 := net.Pipe()
+	done := make(chan *x509.Certificate)
+	go func() {
+		cert, err := receiverEnrollmentHandshake(p1, []*x509.Certificate{receiver}, root)
+		if err != nil {
+			t.Errorf("receiver failed: %v", err)
+		}
+		done <- cert
+	}()
+	cert, err := initiatorEnrollmentHandshake(p0, []*x509.Certificate{initiator}, root)
+	if err != nil {
+		t.Fatalf("initiator failed: %v", err)
+	}
+	if !cert.Equal(receiver) {
+		t.Errorf("initiator got the wrong certificate: %q", cert.Subject.CommonName)
+	}
+	if cert := <-done; cert == nil || !cert.Equal(initiator) {
+		t.Errorf("receiver got the wrong certificate: %v", cert)
+	}
+	p0.Close()
+	p1.Close()
+
+	// Certificates of another CA with the same name are refused
+	other, otherKey := newCert("root", nil, nil)
+	foreign, _ := newCert("foreign", other, otherKey)
+	p0, p1 = net.Pipe()
+	defer p0.Close()
+	defer p1.Close()
+	go func() {
+		_, err := receiverEnrollmentHandshake(p1, []*x509.Certificate{receiver}, root)
+		if _, ok := err.(*enrollmentRejectedError); !ok {
+			t.Errorf("receiver error mismatch: have %v, want rejection", err)
+		}
+		done <- nil
+	}()
+	if _, err := initiatorEnrollmentHandshake(p0, []*x509.Certificate{foreign}, root); err != errEnrollmentRefused {
+		t.Errorf("initiator error mismatch: have %v, want %v", err, errEnrollmentRefused)
+	}
+	<-done
+}
+
+func TestEnrollmentProof(t *testing.T) {
+	if err := ca.Init(nil); err != nil {
+		t.Fatalf("failed to init crypto: %v", err)
+	}
+	root, rootKey, err := ca.NewTestCertificate("root", nil, nil, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initiator, initiatorKey, _ := ca.NewTestCertificate("initiator", root, rootKey, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	receiver, receiverKey, _ := ca.NewTestCertificate("receiver", root, rootKey, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	_, otherKey, _ := ca.NewTestCertificate("other", root, rootKey, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	// prove runs the encryption handshake and the enrollment proof between
+	// two nodes, the initiator signing with key while presenting initiator.
+	prove := func(key *ecdsa.PrivateKey) (initErr, recvErr error) {
+		prv0, _ := crypto.GenerateKey()
+		prv1, _ := crypto.GenerateKey()
+		id0, id1 := discover.PubkeyID(&prv0.PublicKey), discover.PubkeyID(&prv1.PublicKey)
+		fd0, fd1 := net.Pipe()
+		defer fd0.Close()
+		defer fd1.Close()
+
+		done := make(chan error)
+		go func() {
+			tr := newRLPX(fd1).(*rlpx)
+			if _, err := tr.doEncHandshake(prv1, nil); err != nil {
+				done <- err
+				return
+			}
+			err := tr.doEnrollmentProof(receiverKey, id1, id0, initiator)
+			fd1.Close()
+			done <- err
+		}()
+		tr := newRLPX(fd0).(*rlpx)
+		if _, err := tr.doEncHandshake(prv0, &discover.Node{ID: id1}); err != nil {
+			return err, <-done
+		}
+		initErr = tr.doEnrollmentProof(key, id0, id1, receiver)
+		return initErr, <-done
+	}
+	if initErr, recvErr := prove(initiatorKey); initErr != nil || recvErr != nil {
+		t.Fatalf("proof failed: initiator %v, receiver %v", initErr, recvErr)
+	}
+
+	// A certificate presented without its key is rejected
+	_, recvErr := prove(otherKey)
+	if _, ok := recvErr.(*enrollmentRejectedError); !ok {
+		t.Fatalf("receiver error mismatch: have %v, want rejection", recvErr)
+	}
+}
+
 func TestEncHandshake(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		start := time.Now()
@@ -25,6 +25,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/audit"
 	"github.com/ethereum/go-ethereum/logger"
 	"github.com/ethereum/go-ethereum/logger/glog"
 	"github.com/ethereum/go-ethereum/p2p/discover"
@@ -65,6 +66,11 @@ type Config struct {
 
 	EnrollmentCertificate *x509.Certificate
 
+	// EnrollmentChain holds the certificates of the intermediate CAs that
+	// issued EnrollmentCertificate, ordered towards the root. It is empty if
+	// the certificate is issued by the root directly.
+	EnrollmentChain []*x509.Certificate
+
 	NodeType ca.NodeType
 
 	PeerId   uint32
@@ -128,6 +134,15 @@ type Config struct {
 
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool
+
+	// Whitelist restricts the nodes the server connects to and accepts
+	// connections from. If nil, any node holding a valid enrollment
+	// certificate may connect.
+	Whitelist *Whitelist
+
+	// Revocations lists the enrollment certificates withdrawn by the CAs.
+	// Nodes presenting one of them are refused.
+	Revocations *Revocations
 }
 
 // Server manages all peer connections.
@@ -143,6 +158,9 @@ type Server struct {
 	lock    sync.Mutex // protects running
 	running bool
 
+	certLock       sync.RWMutex      // protects enrollmentCert
+	enrollmentCert *x509.Certificate // certificate presented in new handshakes
+
 	ntab         discoverTable
 	listener     net.Listener
 	ourHandshake *protoHandshake
@@ -177,10 +195,11 @@ type conn struct {
 	fd net.Conn
 	transport
 	flags connFlag
-	cont  chan error      // The run loop uses cont to signal errors to setupConn.
-	id    discover.NodeID // valid after the encryption handshake
-	caps  []Cap           // valid after the protocol handshake
-	name  string          // valid after the protocol handshake
+	cont  chan error        // The run loop uses cont to signal errors to setupConn.
+	id    discover.NodeID   // valid after the encryption handshake
+	caps  []Cap             // valid after the protocol handshake
+	name  string            // valid after the protocol handshake
+	cert  *x509.Certificate // valid after the enrollment handshake
 }
 
 type transport interface {
@@ -188,7 +207,15 @@ type transport interface {
 	doEncHandshake(prv *ecdsa.PrivateKey, dialDest *discover.Node) (discover.NodeID, error)
 	doProtoHandshake(our *protoHandshake) (*protoHandshake, error)
 
-	doEnrollmentHandshake(cert *x509.Certificate, dial *discover.Node) (bool, error)
+	// The enrollment handshake presents certs, the enrollment certificate
+	// and its intermediate CAs, and returns the verified certificate of the
+	// remote node.
+	doEnrollmentHandshake(certs []*x509.Certificate, dial *discover.Node) (*x509.Certificate, error)
+
+	// The enrollment proof follows the encryption handshake. Both ends sign
+	// the session and their node IDs with the enrollment key, the signature
+	// of the remote node is verified against its certificate cert.
+	doEnrollmentProof(prv *ecdsa.PrivateKey, local, remote discover.NodeID, cert *x509.Certificate) error
 
 	// The MsgReadWriter can only be used after the encryption
 	// handshake has completed. The code uses conn.id to track this
@@ -299,6 +326,27 @@ func (srv *Server) Self() *discover.Node {
 	return srv.ntab.Self()
 }
 
+// Certificate returns the enrollment certificate currently presented to
+// remote peers during the enrollment handshake.
+func (srv *Server) Certificate() *x509.Certificate {
+	srv.certLock.RLock()
+	defer srv.certLock.RUnlock()
+	return srv.enrollmentCert
+}
+
+// SetCertificate replaces the enrollment certificate of a running server.
+// Established peer connections are kept, the new certificate is presented
+// starting with the next handshake.
+func (srv *Server) SetCertificate(cert *x509.Certificate) error {
+	if err := ca.VerifyValidity(cert, time.Now()); err != nil {
+		return err
+	}
+	srv.certLock.Lock()
+	defer srv.certLock.Unlock()
+	srv.enrollmentCert = cert
+	return nil
+}
+
 // Stop terminates the server and all active peer connections.
 // It blocks until all active connections have been closed.
 func (srv *Server) Stop() {
@@ -334,6 +382,14 @@ func (srv *Server) Start() (err error) {
 	if srv.EnrollmentPrivateKey == nil {
 		return fmt.Errorf("Server.EnrollmentPrivateKey must be set to a non-nil key")
 	}
+	if srv.EnrollmentCertificate != nil {
+		if err := ca.VerifyValidity(srv.EnrollmentCertificate, time.Now()); err != nil {
+			return fmt.Errorf("Server.EnrollmentCertificate is not usable: %v", err)
+		}
+	}
+	srv.certLock.Lock()
+	srv.enrollmentCert = srv.EnrollmentCertificate
+	srv.certLock.Unlock()
 
 	if srv.newTransport == nil {
 		srv.newTransport = newRLPX
@@ -519,6 +575,24 @@ running:
 			// A peer disconnected.
 			glog.V(logger.Detail).Infoln("<-delpeer:", p)
 			delete(peers, p.ID())
+		case <-srv.Whitelist.updates():
+			// The whitelist changed, drop the peers it no longer allows.
+			for id, p := range peers {
+				if !srv.Whitelist.Allowed(remoteIP(p.rw.fd), id) {
+					glog.V(logger.Debug).Infof("Dropping %v: no longer whitelisted", p)
+					// Disconnect waits for the peer to be running, which
+					// may depend on this loop.
+					go p.Disconnect(DiscNotWhitelisted)
+				}
+			}
+		case <-srv.Revocations.updates():
+			// Certificates were revoked, drop the peers presenting them.
+			for _, p := range peers {
+				if srv.Revocations.Revoked(p.rw.cert) {
+					glog.V(logger.Debug).Infof("Dropping %v: certificate revoked", p)
+					go p.Disconnect(DiscCertificateRevoked)
+				}
+			}
 		}
 	}
 
@@ -559,6 +633,10 @@ func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn)
 		return DiscAlreadyConnected
 	case c.id == srv.Self().ID:
 		return DiscSelf
+	case !srv.Whitelist.Allowed(remoteIP(c.fd), c.id):
+		return DiscNotWhitelisted
+	case srv.Revocations.Revoked(c.cert):
+		return DiscCertificateRevoked
 	default:
 		return nil
 	}
@@ -635,15 +713,34 @@ func (srv *Server) setupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 		return
 	}
 
-	// Run the enrollment handshake.
-	if valid, err := c.doEnrollmentHandshake(srv.EnrollmentCertificate, dialDest); err != nil || !valid {
+	// Don't bother dialing nodes the whitelist rejects anyway.
+	if dialDest != nil && !srv.Whitelist.Allowed(dialDest.IP, dialDest.ID) {
+		glog.V(logger.Debug).Infof("%v not dialing %x: not whitelisted", c, dialDest.ID[:8])
+		c.close(DiscNotWhitelisted)
+		return
+	}
+
+	// Run the enrollment handshake. The certificate of the remote node is
+	// only trusted once it proved to hold its key, after the encryption
+	// handshake.
+	certs := append([]*x509.Certificate{srv.Certificate()}, srv.EnrollmentChain...)
+	cert, err := c.doEnrollmentHandshake(certs, dialDest)
+	if err != nil {
 		glog.V(logger.Debug).Infof("%v faild enrollment handshake: %v", c, err)
+		if _, ok := err.(*enrollmentRejectedError); ok {
+			audit.Record(audit.PeerRejected, fd.RemoteAddr().String(), err.Error())
+		}
 		c.close(err)
 		return
 	}
+	if srv.Revocations.Revoked(cert) {
+		glog.V(logger.Debug).Infof("%v refused revoked certificate %v of %q", c, cert.SerialNumber, cert.Subject.CommonName)
+		audit.Record(audit.PeerRejected, fd.RemoteAddr().String(), "certificate revoked")
+		c.close(DiscCertificateRevoked)
+		return
+	}
 
 	// Run the encryption handshake.
-	var err error
 	if c.id, err = c.doEncHandshake(srv.PrivateKey, dialDest); err != nil {
 		glog.V(logger.Debug).Infof("%v faild enc handshake: %v", c, err)
 		c.close(err)
@@ -655,6 +752,17 @@ func (srv *Server) setupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 		glog.V(logger.Debug).Infof("%v dialed identity mismatch, want %x", c, dialDest.ID[:8])
 		return
 	}
+	// Check the remote node holds the key of the certificate it presented.
+	local := discover.PubkeyID(&srv.PrivateKey.PublicKey)
+	if err := c.doEnrollmentProof(srv.EnrollmentPrivateKey, local, c.id, cert); err != nil {
+		glog.V(logger.Debug).Infof("%v failed enrollment proof: %v", c, err)
+		if _, ok := err.(*enrollmentRejectedError); ok {
+			audit.Record(audit.PeerRejected, fd.RemoteAddr().String(), err.Error())
+		}
+		c.close(err)
+		return
+	}
+	c.cert = cert
 	if err := srv.checkpoint(c, srv.posthandshake); err != nil {
 		glog.V(logger.Debug).Infof("%v failed checkpoint posthandshake: %v", c, err)
 		c.close(err)
@@ -18,6 +18,7 @@ package p2p
 
 import (
 	"crypto/ecdsa"
+	"crypto/x509"
 	"errors"
 	"math/rand"
 	"net"
@@ -26,6 +27,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/caserver/ca"
 	"github.com/ethereum/go-ethereum/crypto/sha3"
 	"github.com/ethereum/go-ethereum/p2p/discover"
 )
@@ -57,6 +59,14 @@ func (c *testTransport) doEncHandshake(prv *ecdsa.PrivateKey, dialDest *discover
 	return c.id, nil
 }
 
+func (c *testTransport) doEnrollmentHandshake(certs []*x509.Certificate, dialDest *discover.Node) (*x509.Certificate, error) {
+	return nil, nil
+}
+
+func (c *testTransport) doEnrollmentProof(prv *ecdsa.PrivateKey, local, remote discover.NodeID, cert *x509.Certificate) error {
+	return nil
+}
+
 func (c *testTransport) doProtoHandshake(our *protoHandshake) (*protoHandshake, error) {
 	return &protoHandshake{ID: c.id, Name: "test"}, nil
 }
@@ -72,6 +82,8 @@ func startTestServer(t *testing.T, id discover.NodeID, pf func(*Peer)) *Server {
 		MaxPeers:   10,
 		ListenAddr: "127.0.0.1:0",
 		PrivateKey: newkey(),
+
+		EnrollmentPrivateKey: newkey(),
 	}
 	server := &Server{
 		Config:       config,
@@ -322,6 +334,8 @@ func TestServerAtCap(t *testing.T) {
 			MaxPeers:     10,
 			NoDial:       true,
 			TrustedNodes: []*discover.Node{{ID: trustedID}},
+
+			EnrollmentPrivateKey: newkey(),
 		},
 	}
 	if err := srv.Start(); err != nil {
@@ -390,30 +404,37 @@ func TestServerSetupConn(t *testing.T) {
 			wantCalls:    "doEncHandshake,close,",
 			wantCloseErr: DiscUnexpectedIdentity,
 		},
+		{
+			tt:           &setupTransport{id: id, enrollmentProofErr: errEnrollmentRefused},
+			dialDest:     &discover.Node{ID: id},
+			flags:        dynDialedConn,
+			wantCalls:    "doEncHandshake,doEnrollmentProof,close,",
+			wantCloseErr: errEnrollmentRefused,
+		},
 		{
 			tt:           &setupTransport{id: id, phs: &protoHandshake{ID: randomID()}},
 			dialDest:     &discover.Node{ID: id},
 			flags:        dynDialedConn,
-			wantCalls:    "doEncHandshake,doProtoHandshake,close,",
+			wantCalls:    "doEncHandshake,doEnrollmentProof,doProtoHandshake,close,",
 			wantCloseErr: DiscUnexpectedIdentity,
 		},
 		{
 			tt:           &setupTransport{id: id, protoHandshakeErr: errors.New("foo")},
 			dialDest:     &discover.Node{ID: id},
 			flags:        dynDialedConn,
-			wantCalls:    "doEncHandshake,doProtoHandshake,close,",
+			wantCalls:    "doEncHandshake,doEnrollmentProof,doProtoHandshake,close,",
 			wantCloseErr: errors.New("foo"),
 		},
 		{
 			tt:           &setupTransport{id: srvid, phs: &protoHandshake{ID: srvid}},
 			flags:        inboundConn,
-			wantCalls:    "doEncHandshake,close,",
+			wantCalls:    "doEncHandshake,doEnrollmentProof,close,",
 			wantCloseErr: DiscSelf,
 		},
 		{
 			tt:           &setupTransport{id: id, phs: &protoHandshake{ID: id}},
 			flags:        inboundConn,
-			wantCalls:    "doEncHandshake,doProtoHandshake,close,",
+			wantCalls:    "doEncHandshake,doEnrollmentProof,doProtoHandshake,close,",
 			wantCloseErr: DiscUselessPeer,
 		},
 	}
@@ -425,6 +446,8 @@ func TestServerSetupConn(t *testing.T) {
 				MaxPeers:   10,
 				NoDial:     true,
 				Protocols:  []Protocol{discard},
+
+				EnrollmentPrivateKey: srvkey,
 			},
 			newTransport: func(fd net.Conn) transport { return test.tt },
 		}
@@ -445,8 +468,9 @@ func TestServerSetupConn(t *testing.T) {
 }
 
 type setupTransport struct {
-	id              discover.NodeID
-	encHandshakeErr error
+	id                 discover.NodeID
+	encHandshakeErr    error
+	enrollmentProofErr error
 
 	phs               *protoHandshake
 	protoHandshakeErr error
@@ -459,6 +483,13 @@ func (c *setupTransport) doEncHandshake(prv *ecdsa.PrivateKey, dialDest *discove
 	c.calls += "doEncHandshake,"
 	return c.id, c.encHandshakeErr
 }
+func (c *setupTransport) doEnrollmentHandshake(certs []*x509.Certificate, dialDest *discover.Node) (*x509.Certificate, error) {
+	return nil, nil
+}
+func (c *setupTransport) doEnrollmentProof(prv *ecdsa.PrivateKey, local, remote discover.NodeID, cert *x509.Certificate) error {
+	c.calls += "doEnrollmentProof,"
+	return c.enrollmentProofErr
+}
 func (c *setupTransport) doProtoHandshake(our *protoHandshake) (*protoHandshake, error) {
 	c.calls += "doProtoHandshake,"
 	if c.protoHandshakeErr != nil {
@@ -493,3 +524,62 @@ func randomID() (id discover.NodeID) {
 	}
 	return id
 }
+
+func TestServerRefusesExpiredCertificate(t *testing.T) {
+	expired, _, err := ca.NewTestCertificate("test", nil, nil, time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	srv := &Server{Config: Config{
+		PrivateKey:            newkey(),
+		EnrollmentPrivateKey:  newkey(),
+		EnrollmentCertificate: expired,
+		MaxPeers:              10,
+		NoDial:                true,
+	}}
+	if err := srv.Start(); err == nil {
+		srv.Stop()
+		t.Fatal("server started with an expired enrollment certificate")
+	}
+}
+
+func TestServerSetCertificate(t *testing.T) {
+	connected := make(chan *Peer)
+	remid := randomID()
+	srv := startTestServer(t, remid, func(p *Peer) { connected <- p })
+	defer close(connected)
+	defer srv.Stop()
+
+	// connect a peer before swapping the certificate
+	conn, err := net.DialTimeout("tcp", srv.ListenAddr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+	select {
+	case <-connected:
+	case <-time.After(1 * time.Second):
+		t.Fatal("server did not accept connection")
+	}
+
+	expired, _, err := ca.NewTestCertificate("test", nil, nil, time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	if err := srv.SetCertificate(expired); err == nil {
+		t.Fatal("server accepted an expired certificate")
+	}
+	fresh, _, err := ca.NewTestCertificate("test", nil, nil, time.Now().Add(-time.Minute), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	if err := srv.SetCertificate(fresh); err != nil {
+		t.Fatalf("could not set certificate: %v", err)
+	}
+	if srv.Certificate() != fresh {
+		t.Error("server does not present the new certificate")
+	}
+	if n := srv.PeerCount(); n != 1 {
+		t.Errorf("peer count mismatch after certificate swap: got %d, want 1", n)
+	}
+}
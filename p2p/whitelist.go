@@ -0,0 +1,129 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package p2p
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// Whitelist restricts the nodes a server connects to and accepts connections
+// from. A node is allowed if its IP address lies within one of the listed
+// networks or if its node ID is listed. An empty whitelist, e.g. one that has
+// not been loaded yet, allows no node at all; a nil whitelist allows every node.
+//
+// Whitelists are safe for concurrent use and may be updated while the server
+// is running, connected peers that are no longer allowed are disconnected.
+type Whitelist struct {
+	lock sync.RWMutex
+	nets []*net.IPNet
+	ids  map[discover.NodeID]bool
+
+	changed chan struct{} // signals the server to recheck its peers
+}
+
+// NewWhitelist creates an empty whitelist.
+func NewWhitelist() *Whitelist {
+	return &Whitelist{
+		ids:     make(map[discover.NodeID]bool),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// Update replaces the contents of the whitelist. Networks are given in CIDR
+// notation, a plain IP address stands for itself only. Node IDs are hex
+// encoded. The whitelist is left unchanged if any entry is malformed.
+func (w *Whitelist) Update(cidrs []string, ids []string) error {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		n, err := ParseCIDR(cidr)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, n)
+	}
+	nodes := make(map[discover.NodeID]bool, len(ids))
+	for _, id := range ids {
+		nid, err := discover.HexID(id)
+		if err != nil {
+			return fmt.Errorf("invalid node id %q: %v", id, err)
+		}
+		nodes[nid] = true
+	}
+
+	w.lock.Lock()
+	w.nets, w.ids = nets, nodes
+	w.lock.Unlock()
+
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Allowed reports whether a node with the given address and ID may connect.
+func (w *Whitelist) Allowed(ip net.IP, id discover.NodeID) bool {
+	if w == nil {
+		return true
+	}
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.ids[id] {
+		return true
+	}
+	for _, n := range w.nets {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// updates returns the channel signalled on every update, or nil for a nil
+// whitelist so that it never fires.
+func (w *Whitelist) updates() <-chan struct{} {
+	if w == nil {
+		return nil
+	}
+	return w.changed
+}
+
+// ParseCIDR parses a network in CIDR notation or a single IP address.
+func ParseCIDR(s string) (*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network %q: %v", s, err)
+	}
+	return n, nil
+}
+
+// remoteIP returns the IP address of the remote end of a connection.
+func remoteIP(fd net.Conn) net.IP {
+	if addr, ok := fd.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+func TestWhitelist(t *testing.T) {
+	var nilList *Whitelist
+	if !nilList.Allowed(net.ParseIP("10.0.0.1"), randomID()) {
+		t.Fatal("nil whitelist rejected a node")
+	}
+
+	w := NewWhitelist()
+	if w.Allowed(net.ParseIP("10.0.0.1"), randomID()) {
+		t.Fatal("empty whitelist allowed a node")
+	}
+
+	listed := randomID()
+	if err := w.Update([]string{"10.1.0.0/16", "192.168.0.7"}, []string{listed.String()}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	tests := []struct {
+		ip      string
+		id      discover.NodeID
+		allowed bool
+	}{
+		{"10.1.2.3", randomID(), true},
+		{"10.2.0.1", randomID(), false},
+		{"192.168.0.7", randomID(), true},
+		{"192.168.0.8", randomID(), false},
+		{"172.16.0.1", listed, true},
+	}
+	for i, tt := range tests {
+		if allowed := w.Allowed(net.ParseIP(tt.ip), tt.id); allowed != tt.allowed {
+			t.Errorf("test %d: %s allowed = %v, want %v", i, tt.ip, allowed, tt.allowed)
+		}
+	}
+
+	// Malformed updates leave the whitelist untouched
+	if err := w.Update([]string{"10.0.0.0/33"}, nil); err == nil {
+		t.Error("invalid network accepted")
+	}
+	if err := w.Update(nil, []string{"abcd"}); err == nil {
+		t.Error("invalid node id accepted")
+	}
+	if !w.Allowed(net.ParseIP("10.1.2.3"), randomID()) || w.Allowed(net.ParseIP("10.2.0.1"), randomID()) {
+		t.Error("whitelist changed by malformed update")
+	}
+}
+
+func TestServerWhitelist(t *testing.T) {
+	first, second := randomID(), randomID()
+	whitelist := NewWhitelist()
+	if err := whitelist.Update(nil, []string{first.String()}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	srv := &Server{
+		Config: Config{
+			PrivateKey:           newkey(),
+			MaxPeers:             10,
+			NoDial:               true,
+			EnrollmentPrivateKey: newkey(),
+			Whitelist:            whitelist,
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id discover.NodeID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(id, fd)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, id: id, cont: make(chan error)}
+	}
+	if err := srv.checkpoint(newconn(second), srv.posthandshake); err != DiscNotWhitelisted {
+		t.Fatalf("wrong error for unlisted node: %v", err)
+	}
+	if err := srv.checkpoint(newconn(first), srv.addpeer); err != nil {
+		t.Fatalf("could not add listed node: %v", err)
+	}
+
+	// Delisting the connected node drops it
+	if err := whitelist.Update(nil, []string{second.String()}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	for deadline := time.Now().Add(time.Second); srv.PeerCount() > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("delisted peer not dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package params
+
+import "math/big"
+
+// Gas prices of the DChain precompiled contracts.
+var (
+	CertificateVerifyGas     = big.NewInt(6000) // Once per certificate verification.
+	CertificateVerifyWordGas = big.NewInt(24)   // Per word of the certificates to verify.
+
+	P256VerifyGas = big.NewInt(3450) // Once per P-256 signature verification.
+	P384VerifyGas = big.NewInt(7000) // Once per P-384 signature verification.
+	Sha384Gas     = big.NewInt(60)   // Once per SHA-384 operation.
+	Sha384WordGas = big.NewInt(12)   // Per word of the SHA-384 input.
+
+	GovernanceGas = big.NewInt(700) // Once per governance call, storage and logs are charged as by the EVM.
+)
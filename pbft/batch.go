@@ -9,12 +9,11 @@ package pbft
 
 import (
 	"time"
-	"fmt"
 	"github.com/ethereum/go-ethereum/logger/glog"
-	"github.com/spf13/viper"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/settings"
 )
 
 type obcBatch struct {
@@ -33,18 +32,13 @@ type obcBatch struct {
 }
 
 
-func newObcBatch(mux *event.TypeMux, peerId uint32, peerCount uint32) *obcBatch {
-	var err error
-
+func newObcBatch(mux *event.TypeMux, peerId uint32, peerCount uint32, conf *settings.Consensus) *obcBatch {
 	op := &obcBatch{}
 	op.mux = mux
-	op.pbft = newPbftCore(peerId, peerCount, mux)
+	op.pbft = newPbftCore(peerId, peerCount, mux, conf)
 
-	op.batchSize = viper.GetInt("consensus.batchsize")
-	op.batchTimeout, err = time.ParseDuration(viper.GetString("consensus.timeout.batch"))
-	if err != nil {
-		panic(fmt.Errorf("Cannot parse batch timeout: %s", err))
-	}
+	op.batchSize = conf.BatchSize
+	op.batchTimeout = conf.BatchTimeout
 	glog.Infof("PBFT Batch size = %d", op.batchSize)
 	glog.Infof("PBFT Batch timeout = %v", op.batchTimeout)
 
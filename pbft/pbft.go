@@ -10,12 +10,11 @@ package pbft
 import (
 	"sync"
 	"time"
-	"fmt"
-	"github.com/spf13/viper"
 	"github.com/ethereum/go-ethereum/logger/glog"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/settings"
 )
 
 type Consenter interface {
@@ -94,12 +93,13 @@ type pbftCore struct {
 	//newViewStore    map[uint64]*NewView      // track last new-view we received or sent
 }
 
-func New(mux *event.TypeMux, peerId uint32, peerCount uint32) Consenter {
-	return newObcBatch(mux, peerId, peerCount)
+// New returns the PBFT consenter of the replica with the given id. The
+// settings must have been validated, see settings.Config.Validate.
+func New(mux *event.TypeMux, peerId uint32, peerCount uint32, conf *settings.Consensus) Consenter {
+	return newObcBatch(mux, peerId, peerCount, conf)
 }
 
-func newPbftCore(peerId uint32, peerCount uint32, mux *event.TypeMux) *pbftCore {
-	var err error
+func newPbftCore(peerId uint32, peerCount uint32, mux *event.TypeMux, conf *settings.Consensus) *pbftCore {
 	instance := &pbftCore{}
 	instance.id = peerId
 	instance.replicaCount = peerCount
@@ -111,43 +111,20 @@ func newPbftCore(peerId uint32, peerCount uint32, mux *event.TypeMux) *pbftCore
 	//instance.vcResendTimer = etf.CreateTimer()
 	//instance.nullRequestTimer = etf.CreateTimer()
 
-	instance.N = uint32(viper.GetInt("consensus.N"))
-	instance.f = uint32(viper.GetInt("consensus.f"))
-	if instance.f*3+1 > instance.N {
-		panic(fmt.Sprintf("need at least %d enough replicas to tolerate %d byzantine faults, but only %d replicas viperured", instance.f*3+1, instance.f, instance.N))
-	}
-
-	instance.K = uint32(viper.GetInt("consensus.K"))
-
-	instance.logMultiplier = uint32(viper.GetInt("consensus.logmultiplier"))
-	if instance.logMultiplier < 2 {
-		panic("Log multiplier must be greater than or equal to 2")
-	}
+	instance.N = uint32(conf.N)
+	instance.f = uint32(conf.F)
+	instance.K = uint32(conf.K)
+	instance.logMultiplier = uint32(conf.LogMultiplier)
 	instance.L = instance.logMultiplier * instance.K // log size
-	instance.viewChangePeriod = uint32(viper.GetInt("consensus.viewchangeperiod"))
+	instance.viewChangePeriod = uint32(conf.ViewChangePeriod)
 
-	instance.byzantine = viper.GetBool("consensus.byzantine")
+	instance.byzantine = conf.Byzantine
 
-	instance.requestTimeout, err = time.ParseDuration(viper.GetString("consensus.timeout.request"))
-	if err != nil {
-		panic(fmt.Errorf("Cannot parse request timeout: %s", err))
-	}
-	instance.vcResendTimeout, err = time.ParseDuration(viper.GetString("consensus.timeout.resendviewchange"))
-	if err != nil {
-		panic(fmt.Errorf("Cannot parse request timeout: %s", err))
-	}
-	instance.newViewTimeout, err = time.ParseDuration(viper.GetString("consensus.timeout.viewchange"))
-	if err != nil {
-		panic(fmt.Errorf("Cannot parse new view timeout: %s", err))
-	}
-	instance.nullRequestTimeout, err = time.ParseDuration(viper.GetString("consensus.timeout.nullrequest"))
-	if err != nil {
-		instance.nullRequestTimeout = 0
-	}
-	instance.broadcastTimeout, err = time.ParseDuration(viper.GetString("consensus.timeout.broadcast"))
-	if err != nil {
-		panic(fmt.Errorf("Cannot parse new broadcast timeout: %s", err))
-	}
+	instance.requestTimeout = conf.RequestTimeout
+	instance.vcResendTimeout = conf.ResendViewChangeTimeout
+	instance.newViewTimeout = conf.ViewChangeTimeout
+	instance.nullRequestTimeout = conf.NullRequestTimeout
+	instance.broadcastTimeout = conf.BroadcastTimeout
 
 	instance.activeView = true
 	instance.replicaCount = instance.N
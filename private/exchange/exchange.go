@@ -0,0 +1,396 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// Package exchange implements the pdx sub-protocol, which moves the payloads
+// of private transactions between their participants only.
+//
+// Peers are identified by the common name of their enrollment certificate,
+// which the p2p layer only hands out once the peer proved to hold its key. A payload is sent to each of its participants
+// directly and kept in an outbox until the participant acknowledges it, it is
+// resent whenever the participant connects and periodically while it is
+// connected. Nodes that miss a payload ask their peers for it; a payload is
+// only handed to peers that participate in it.
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/logger"
+	"github.com/ethereum/go-ethereum/logger/glog"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/private"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	ProtocolName    = "pdx"
+	ProtocolVersion = 1
+	ProtocolLength  = 4
+
+	ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
+)
+
+// pdx protocol message codes
+const (
+	PayloadMsg    = 0x00 // a payload, sent to participants or in reply to GetPayloadMsg
+	AckMsg        = 0x01 // the hash of a payload that was stored
+	GetPayloadMsg = 0x02 // the hash of a payload the peer misses
+	NotFoundMsg   = 0x03 // the hash of a requested payload that can't be handed out
+)
+
+var (
+	retryInterval   = 30 * time.Second // Interval between deliveries of unacknowledged payloads
+	retrieveTimeout = 5 * time.Second  // Time to wait for peers to hand out a missing payload
+)
+
+var errNoCertificate = errors.New("peer without enrollment certificate")
+
+var outboxKey = []byte("private-outbox")
+
+// delivery is a payload that was not acknowledged by a participant yet.
+type delivery struct {
+	Hash common.Hash
+	Name string
+}
+
+// request is a retrieval of a payload waiting for the answers of the peers.
+type request struct {
+	pending map[string]bool // names of the peers that haven't answered yet
+	done    chan struct{}   // closed when the retrieval is over
+	closed  bool
+}
+
+func (r *request) answered(name string) {
+	delete(r.pending, name)
+	if len(r.pending) == 0 {
+		r.finish()
+	}
+}
+
+func (r *request) finish() {
+	if !r.closed {
+		r.closed = true
+		close(r.done)
+	}
+}
+
+// Exchange distributes and retrieves private payloads. It implements the
+// payload source of core.PrivateState.
+type Exchange struct {
+	db    ethdb.Database
+	store *private.Store
+	self  func() string // certificate name of the node
+
+	mu       sync.Mutex
+	arrived  func(common.Hash)            // called with the payloads received from peers
+	peers    map[string]p2p.MsgReadWriter // connected peers by certificate name
+	outbox   []delivery                   // payloads not acknowledged yet
+	requests map[common.Hash][]*request   // running retrievals by payload hash
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns an exchange storing payloads in store and its outbox in db.
+// self returns the certificate name of the node.
+func New(db ethdb.Database, store *private.Store, self func() string) *Exchange {
+	x := &Exchange{
+		db:       db,
+		store:    store,
+		self:     self,
+		peers:    make(map[string]p2p.MsgReadWriter),
+		requests: make(map[common.Hash][]*request),
+		quit:     make(chan struct{}),
+	}
+	if enc, err := db.Get(outboxKey); err == nil {
+		if err := rlp.DecodeBytes(enc, &x.outbox); err != nil {
+			glog.V(logger.Error).Infof("private payload outbox corrupted: %v", err)
+		}
+	}
+	return x
+}
+
+// SetArrivalHandler sets a function called with the hash of each payload
+// received from a peer, e.g. to execute the blocks that missed it.
+func (x *Exchange) SetArrivalHandler(fn func(common.Hash)) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.arrived = fn
+}
+
+// Protocol returns the pdx sub-protocol.
+func (x *Exchange) Protocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    ProtocolName,
+		Version: ProtocolVersion,
+		Length:  ProtocolLength,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			// Certificates presented without proof of possession of
+			// the key are not handed out, see p2p.Peer.Certificate
+			cert := p.Certificate()
+			if cert == nil {
+				return errNoCertificate
+			}
+			return x.handle(cert.Subject.CommonName, rw)
+		},
+	}
+}
+
+// Start starts the periodic delivery of unacknowledged payloads.
+func (x *Exchange) Start() {
+	x.wg.Add(1)
+	go x.loop()
+}
+
+// Stop terminates the exchange.
+func (x *Exchange) Stop() {
+	close(x.quit)
+	x.wg.Wait()
+}
+
+func (x *Exchange) loop() {
+	defer x.wg.Done()
+
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			x.redeliver("")
+		case <-x.quit:
+			return
+		}
+	}
+}
+
+// Send stores env and delivers it to the other participants.
+func (x *Exchange) Send(env *private.Envelope) (common.Hash, error) {
+	hash, err := x.store.Put(env)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	self := x.self()
+
+	x.mu.Lock()
+	for _, name := range env.Participants {
+		if name != self {
+			x.outbox = append(x.outbox, delivery{hash, name})
+		}
+	}
+	err = x.saveOutbox()
+	x.mu.Unlock()
+
+	x.redeliver("")
+	return hash, err
+}
+
+// Get returns the payload with the given hash. Payloads that are not stored
+// locally are requested from the connected peers.
+func (x *Exchange) Get(hash common.Hash) *private.Envelope {
+	return x.Retrieve(hash, retrieveTimeout)
+}
+
+// Retrieve returns the payload with the given hash, asking the connected
+// peers for it if it is not stored locally. It waits until a peer hands out
+// the payload, all peers refused or the timeout expired.
+func (x *Exchange) Retrieve(hash common.Hash, timeout time.Duration) *private.Envelope {
+	if env := x.store.Get(hash); env != nil {
+		return env
+	}
+	x.mu.Lock()
+	if len(x.peers) == 0 {
+		x.mu.Unlock()
+		return nil
+	}
+	req := &request{pending: make(map[string]bool), done: make(chan struct{})}
+	for name, rw := range x.peers {
+		req.pending[name] = true
+		go send(rw, GetPayloadMsg, hash)
+	}
+	x.requests[hash] = append(x.requests[hash], req)
+	x.mu.Unlock()
+
+	select {
+	case <-req.done:
+	case <-time.After(timeout):
+	case <-x.quit:
+	}
+
+	x.mu.Lock()
+	reqs := x.requests[hash]
+	for i, r := range reqs {
+		if r == req {
+			reqs = append(reqs[:i], reqs[i+1:]...)
+			break
+		}
+	}
+	if len(reqs) == 0 {
+		delete(x.requests, hash)
+	} else {
+		x.requests[hash] = reqs
+	}
+	x.mu.Unlock()
+
+	return x.store.Get(hash)
+}
+
+// handle runs the protocol with the peer holding the named certificate.
+func (x *Exchange) handle(name string, rw p2p.MsgReadWriter) error {
+	x.mu.Lock()
+	x.peers[name] = rw
+	x.mu.Unlock()
+
+	defer func() {
+		x.mu.Lock()
+		if x.peers[name] == rw {
+			delete(x.peers, name)
+		}
+		for _, reqs := range x.requests {
+			for _, req := range reqs {
+				req.answered(name)
+			}
+		}
+		x.mu.Unlock()
+	}()
+	x.redeliver(name)
+
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Size > ProtocolMaxMsgSize {
+			return fmt.Errorf("message too large: %v > %v", msg.Size, ProtocolMaxMsgSize)
+		}
+		if err := x.handleMsg(name, rw, msg); err != nil {
+			glog.V(logger.Debug).Infof("pdx peer %s: %v", name, err)
+			return err
+		}
+	}
+}
+
+func (x *Exchange) handleMsg(name string, rw p2p.MsgReadWriter, msg p2p.Msg) error {
+	defer msg.Discard()
+
+	if msg.Code == PayloadMsg {
+		env := new(private.Envelope)
+		if err := msg.Decode(env); err != nil {
+			return err
+		}
+		// Only participants may hand out a payload, and only to participants
+		if !participates(env, name) || !participates(env, x.self()) {
+			glog.V(logger.Debug).Infof("pdx peer %s: dropped payload %x not shared with both", name, env.Hash().Bytes()[:4])
+			return nil
+		}
+		hash, err := x.store.Put(env)
+		if err != nil {
+			return err
+		}
+		x.mu.Lock()
+		for _, req := range x.requests[hash] {
+			req.finish()
+		}
+		arrived := x.arrived
+		x.mu.Unlock()
+
+		if arrived != nil {
+			arrived(hash)
+		}
+		go send(rw, AckMsg, hash)
+		return nil
+	}
+
+	var hash common.Hash
+	if err := msg.Decode(&hash); err != nil {
+		return err
+	}
+	switch msg.Code {
+	case AckMsg:
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		for i, d := range x.outbox {
+			if d.Hash == hash && d.Name == name {
+				x.outbox = append(x.outbox[:i], x.outbox[i+1:]...)
+				return x.saveOutbox()
+			}
+		}
+
+	case GetPayloadMsg:
+		if env := x.store.Get(hash); env != nil && participates(env, name) {
+			go send(rw, PayloadMsg, env)
+		} else {
+			go send(rw, NotFoundMsg, hash)
+		}
+
+	case NotFoundMsg:
+		x.mu.Lock()
+		for _, req := range x.requests[hash] {
+			req.answered(name)
+		}
+		x.mu.Unlock()
+
+	default:
+		return fmt.Errorf("invalid message code %d", msg.Code)
+	}
+	return nil
+}
+
+// redeliver sends the unacknowledged payloads to the connected participants,
+// or only to the named one if name is not empty.
+func (x *Exchange) redeliver(name string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for _, d := range x.outbox {
+		if name != "" && d.Name != name {
+			continue
+		}
+		rw, ok := x.peers[d.Name]
+		if !ok {
+			continue
+		}
+		if env := x.store.Get(d.Hash); env != nil {
+			go send(rw, PayloadMsg, env)
+		}
+	}
+}
+
+// saveOutbox persists the outbox, x.mu must be held.
+func (x *Exchange) saveOutbox() error {
+	enc, err := rlp.EncodeToBytes(x.outbox)
+	if err != nil {
+		return err
+	}
+	return x.db.Put(outboxKey, enc)
+}
+
+// Pending returns the number of deliveries not acknowledged yet.
+func (x *Exchange) Pending() int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return len(x.outbox)
+}
+
+func participates(env *private.Envelope, name string) bool {
+	for _, p := range env.Participants {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+func send(rw p2p.MsgReadWriter, code uint64, data interface{}) {
+	if err := p2p.Send(rw, code, data); err != nil {
+		glog.V(logger.Detail).Infof("pdx send %d failed: %v", code, err)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/private"
+)
+
+type testNode struct {
+	*Exchange
+	db    ethdb.Database
+	store *private.Store
+}
+
+func newTestNode(name string) *testNode {
+	db, _ := ethdb.NewMemDatabase()
+	store := private.NewStore(db)
+	return &testNode{New(db, store, func() string { return name }), db, store}
+}
+
+// connect runs the protocol between two nodes until the returned function is
+// called.
+func connect(a *testNode, aName string, b *testNode, bName string) func() {
+	rwA, rwB := p2p.MsgPipe()
+	go a.handle(bName, rwA)
+	go b.handle(aName, rwB)
+	return func() { rwA.Close() }
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timeout waiting for %s", what)
+}
+
+func TestExchange(t *testing.T) {
+	node1, node2, node3 := newTestNode("node1"), newTestNode("node2"), newTestNode("node3")
+	disconnect := connect(node1, "node1", node2, "node2")
+
+	arrived := make(chan common.Hash, 1)
+	node2.SetArrivalHandler(func(hash common.Hash) { arrived <- hash })
+
+	// Payloads go to the connected participants, which acknowledge them
+	env := &private.Envelope{Participants: []string{"node1", "node2", "node3"}, Ciphertexts: [][]byte{{1}, {2}, {3}}}
+	hash, err := node1.Send(env)
+	if err != nil {
+		t.Fatalf("failed to send payload: %v", err)
+	}
+	waitFor(t, "delivery to node2", func() bool { return node2.store.Get(hash) != nil })
+	select {
+	case got := <-arrived:
+		if got != hash {
+			t.Fatalf("arrived payload mismatch: have %x, want %x", got, hash)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("arrival not reported")
+	}
+	waitFor(t, "acknowledgement of node2", func() bool { return node1.Pending() == 1 })
+
+	// The delivery to the offline participant survives a restart and is
+	// made when it connects
+	disconnect()
+	node1 = &testNode{New(node1.db, node1.store, func() string { return "node1" }), node1.db, node1.store}
+	if node1.Pending() != 1 {
+		t.Fatalf("pending deliveries mismatch after restart: have %d, want 1", node1.Pending())
+	}
+	connect(node1, "node1", node3, "node3")
+	waitFor(t, "delivery to node3", func() bool { return node3.store.Get(hash) != nil })
+	waitFor(t, "acknowledgement of node3", func() bool { return node1.Pending() == 0 })
+
+	// Participants that missed a payload retrieve it, others are refused
+	secret := &private.Envelope{Participants: []string{"node1", "node3"}, Ciphertexts: [][]byte{{1}, {3}}}
+	secretHash, _ := node1.store.Put(secret)
+	if got := node3.Retrieve(secretHash, time.Second); got == nil || got.Hash() != secretHash {
+		t.Fatalf("participant failed to retrieve payload: %v", got)
+	}
+	outsider := newTestNode("node2")
+	connect(node1, "node1", outsider, "node2")
+	waitFor(t, "outsider connection", func() bool {
+		outsider.mu.Lock()
+		defer outsider.mu.Unlock()
+		return len(outsider.peers) == 1
+	})
+	start := time.Now()
+	if got := outsider.Retrieve(secretHash, 5*time.Second); got != nil {
+		t.Fatal("payload handed to non-participant")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("refused retrieval took %v", elapsed)
+	}
+	if got := newTestNode("node4").Retrieve(secretHash, time.Second); got != nil {
+		t.Fatal("payload retrieved without peers")
+	}
+}
+
+func TestExchangeUnsolicited(t *testing.T) {
+	node2 := newTestNode("node2")
+	rw, rwNode2 := p2p.MsgPipe()
+	defer rw.Close()
+	go node2.handle("node1", rwNode2)
+
+	// Payloads the receiver doesn't participate in are dropped
+	env := &private.Envelope{Participants: []string{"node1", "node3"}, Ciphertexts: [][]byte{{1}, {3}}}
+	if err := p2p.Send(rw, PayloadMsg, env); err != nil {
+		t.Fatalf("failed to send payload: %v", err)
+	}
+	if err := p2p.Send(rw, GetPayloadMsg, env.Hash()); err != nil {
+		t.Fatalf("failed to request payload: %v", err)
+	}
+	if err := p2p.ExpectMsg(rw, NotFoundMsg, env.Hash()); err != nil {
+		t.Fatalf("reply mismatch: %v", err)
+	}
+	if node2.store.Get(env.Hash()) != nil {
+		t.Fatal("unsolicited payload stored")
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// Package private implements the payloads of private transactions. A payload
+// is encrypted with ECIES for the enrollment keys of the participants, only
+// its hash is included in the chain.
+package private
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// ErrNotParticipant is returned when opening an envelope the node is not
+	// a participant of.
+	ErrNotParticipant = errors.New("not a participant of the private payload")
+
+	// ErrNoParticipants is returned when sealing a payload for nobody.
+	ErrNoParticipants = errors.New("private payload without participants")
+)
+
+// Participant is a party of a private transaction, identified by the common
+// name of its enrollment certificate.
+type Participant struct {
+	Name string
+	Key  *ecdsa.PublicKey
+}
+
+// Envelope holds a payload encrypted separately for each participant.
+type Envelope struct {
+	Participants []string // certificate names of the participants
+	Ciphertexts  [][]byte // payload encrypted for the participant at the same index
+}
+
+// Seal encrypts payload for the participants.
+func Seal(payload []byte, participants []Participant) (*Envelope, error) {
+	if len(participants) == 0 {
+		return nil, ErrNoParticipants
+	}
+	env := new(Envelope)
+	for _, p := range participants {
+		ct, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(p.Key), payload, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not encrypt for %s: %v", p.Name, err)
+		}
+		env.Participants = append(env.Participants, p.Name)
+		env.Ciphertexts = append(env.Ciphertexts, ct)
+	}
+	return env, nil
+}
+
+// Open decrypts the payload as the participant with the given name.
+func (env *Envelope) Open(name string, key *ecdsa.PrivateKey) ([]byte, error) {
+	for i, p := range env.Participants {
+		if p == name && i < len(env.Ciphertexts) {
+			return ecies.ImportECDSA(key).Decrypt(rand.Reader, env.Ciphertexts[i], nil, nil)
+		}
+	}
+	return nil, ErrNotParticipant
+}
+
+// Hash returns the hash of the envelope, which is included in the chain.
+func (env *Envelope) Hash() common.Hash {
+	enc, _ := rlp.EncodeToBytes(env)
+	return crypto.Keccak256Hash(enc)
+}
+
+var (
+	payloadPrefix     = []byte("private-payload-")
+	certificatePrefix = []byte("private-cert-")
+)
+
+// Store keeps envelopes keyed by their hash.
+type Store struct {
+	db ethdb.Database
+}
+
+// NewStore returns a store keeping envelopes in db.
+func NewStore(db ethdb.Database) *Store {
+	return &Store{db: db}
+}
+
+// Put stores an envelope and returns its hash.
+func (s *Store) Put(env *Envelope) (common.Hash, error) {
+	enc, err := rlp.EncodeToBytes(env)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hash := crypto.Keccak256Hash(enc)
+	return hash, s.db.Put(append(payloadPrefix, hash[:]...), enc)
+}
+
+// Get returns the envelope with the given hash, nil if it is unknown.
+func (s *Store) Get(hash common.Hash) *Envelope {
+	enc, err := s.db.Get(append(payloadPrefix, hash[:]...))
+	if err != nil {
+		return nil
+	}
+	env := new(Envelope)
+	if err := rlp.DecodeBytes(enc, env); err != nil {
+		return nil
+	}
+	return env
+}
+
+// Directory keeps the enrollment certificates of the members seen by the
+// node, so that payloads can be sealed for members that are not connected.
+// Certificates must be verified before they are added.
+type Directory struct {
+	db ethdb.Database
+}
+
+// NewDirectory returns a directory keeping certificates in db.
+func NewDirectory(db ethdb.Database) *Directory {
+	return &Directory{db: db}
+}
+
+// Add stores cert under its common name, replacing any earlier one.
+func (d *Directory) Add(cert *x509.Certificate) error {
+	return d.db.Put(append(certificatePrefix, cert.Subject.CommonName...), cert.Raw)
+}
+
+// Lookup returns the participant with the given certificate name.
+func (d *Directory) Lookup(name string) (Participant, error) {
+	raw, err := d.db.Get(append(certificatePrefix, name...))
+	if err != nil {
+		return Participant{}, fmt.Errorf("unknown participant %q", name)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return Participant{}, err
+	}
+	key, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return Participant{}, fmt.Errorf("participant %q has no ECDSA key", name)
+	}
+	return Participant{Name: name, Key: key}, nil
+}
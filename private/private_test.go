@@ -0,0 +1,87 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package private
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func newCertificate(t *testing.T, name string) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(raw)
+	return key, cert
+}
+
+func TestEnvelope(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	dir, store := NewDirectory(db), NewStore(db)
+
+	key1, cert1 := newCertificate(t, "node1")
+	key2, cert2 := newCertificate(t, "node2")
+	key3, _ := newCertificate(t, "node3")
+	dir.Add(cert1)
+	dir.Add(cert2)
+	if _, err := dir.Lookup("node3"); err == nil {
+		t.Fatal("unknown participant found")
+	}
+	var participants []Participant
+	for _, name := range []string{"node1", "node2"} {
+		p, err := dir.Lookup(name)
+		if err != nil {
+			t.Fatalf("participant %s not found: %v", name, err)
+		}
+		participants = append(participants, p)
+	}
+	if _, err := Seal([]byte("payload"), nil); err != ErrNoParticipants {
+		t.Fatalf("sealing for nobody error mismatch: %v", err)
+	}
+	sealed, err := Seal([]byte("payload"), participants)
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	hash, err := store.Put(sealed)
+	if err != nil || hash != sealed.Hash() {
+		t.Fatalf("stored hash mismatch: have %x, want %x (%v)", hash, sealed.Hash(), err)
+	}
+	env := store.Get(hash)
+	if env == nil {
+		t.Fatal("stored envelope not found")
+	}
+	for name, key := range map[string]*ecdsa.PrivateKey{"node1": key1, "node2": key2} {
+		payload, err := env.Open(name, key)
+		if err != nil || !bytes.Equal(payload, []byte("payload")) {
+			t.Errorf("%s: payload mismatch: %q, %v", name, payload, err)
+		}
+	}
+	if _, err := env.Open("node3", key3); err != ErrNotParticipant {
+		t.Errorf("outsider error mismatch: %v", err)
+	}
+	if _, err := env.Open("node2", key1); err == nil {
+		t.Error("payload opened with the wrong key")
+	}
+}
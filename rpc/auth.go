@@ -0,0 +1,52 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package rpc
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// Authenticator identifies the callers of a server and decides which methods
+// they may call.
+type Authenticator interface {
+	// Authenticate returns the identity of the caller that sent r. A nil
+	// identity stands for an anonymous caller. An error rejects the request
+	// or connection before any method is called.
+	Authenticate(r *http.Request) (interface{}, error)
+
+	// Authorize returns an error if caller may not call the given method of
+	// the given service.
+	Authorize(caller interface{}, service, method string) error
+}
+
+// SetAuthenticator installs the authenticator used to identify HTTP and
+// websocket callers and to authorize every call made through the server.
+// Codecs served directly with ServeCodec are anonymous.
+func (s *Server) SetAuthenticator(auth Authenticator) {
+	s.auth = auth
+}
+
+type callerKey struct{}
+
+// CallerFromContext returns the caller identity the authenticator returned
+// for the request being served.
+func CallerFromContext(ctx context.Context) (interface{}, bool) {
+	caller := ctx.Value(callerKey{})
+	return caller, caller != nil
+}
+
+// authenticate returns the caller of r, or nil if the server has no
+// authenticator.
+func (s *Server) authenticate(r *http.Request) (interface{}, error) {
+	if s.auth == nil {
+		return nil, nil
+	}
+	return s.auth.Authenticate(r)
+}
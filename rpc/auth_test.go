@@ -0,0 +1,96 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+type AuthService struct{}
+
+func (s *AuthService) Whoami(ctx context.Context) string {
+	caller, _ := CallerFromContext(ctx)
+	name, _ := caller.(string)
+	return name
+}
+
+func (s *AuthService) Secret() string {
+	return "secret"
+}
+
+// roleAuthenticator takes the role of the caller from a header and lets
+// admins call everything, users only whoami.
+type roleAuthenticator struct{}
+
+func (roleAuthenticator) Authenticate(r *http.Request) (interface{}, error) {
+	switch role := r.Header.Get("X-Role"); role {
+	case "":
+		return nil, nil
+	case "admin", "user":
+		return role, nil
+	default:
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+}
+
+func (roleAuthenticator) Authorize(caller interface{}, service, method string) error {
+	if caller == "admin" || (caller == "user" && service == "auth" && method == "whoami") {
+		return nil
+	}
+	return fmt.Errorf("not permitted")
+}
+
+func TestServerAuthenticator(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("auth", new(AuthService)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuthenticator(roleAuthenticator{})
+	httpsrv := httptest.NewServer(newJSONHTTPHandler(server))
+	defer httpsrv.Close()
+
+	call := func(role, method string) (int, map[string]interface{}) {
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"auth_%s","params":[]}`, method)
+		req, _ := http.NewRequest("POST", httpsrv.URL, strings.NewReader(body))
+		if role != "" {
+			req.Header.Set("X-Role", role)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var reply map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&reply)
+		return resp.StatusCode, reply
+	}
+
+	if _, reply := call("user", "whoami"); reply["result"] != "user" {
+		t.Errorf("caller not passed to the callback: %v", reply)
+	}
+	if _, reply := call("admin", "secret"); reply["result"] != "secret" {
+		t.Errorf("admin refused: %v", reply)
+	}
+	for _, role := range []string{"user", ""} {
+		_, reply := call(role, "secret")
+		errobj, _ := reply["error"].(map[string]interface{})
+		if errobj == nil || errobj["code"] != float64(-32001) {
+			t.Errorf("role %q not refused: %v", role, reply)
+		}
+	}
+	if status, _ := call("intruder", "whoami"); status != http.StatusUnauthorized {
+		t.Errorf("unauthenticated caller status mismatch: have %d, want %d", status, http.StatusUnauthorized)
+	}
+}
@@ -95,3 +95,18 @@ func (e *shutdownError) Code() int {
 func (e *shutdownError) Error() string {
 	return "server is shutting down"
 }
+
+// issued when the caller may not call the requested method
+type accessDeniedError struct {
+	service string
+	method  string
+	reason  string
+}
+
+func (e *accessDeniedError) Code() int {
+	return -32001
+}
+
+func (e *accessDeniedError) Error() string {
+	return fmt.Sprintf("access to %s%s%s denied: %s", e.service, serviceMethodSeparator, e.method, e.reason)
+}
@@ -111,6 +111,12 @@ func newJSONHTTPHandler(srv *Server) http.HandlerFunc {
 			return
 		}
 
+		caller, err := srv.authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
 		w.Header().Set("content-type", "application/json")
 
 		// create a codec that reads direct from the request body until
@@ -118,7 +124,7 @@ func newJSONHTTPHandler(srv *Server) http.HandlerFunc {
 		// a single request.
 		codec := NewJSONCodec(&httpReadWriteNopCloser{r.Body, w})
 		defer codec.Close()
-		srv.ServeSingleRequest(codec, OptionMethodInvocation)
+		srv.serveRequest(codec, true, OptionMethodInvocation, caller)
 	}
 }
 
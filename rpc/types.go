@@ -58,6 +58,7 @@ type service struct {
 type serverRequest struct {
 	id            interface{}
 	svcname       string
+	method        string
 	rcvr          reflect.Value
 	callb         *callback
 	args          []reflect.Value
@@ -79,6 +80,8 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	auth Authenticator // nil = every caller may call every method
 }
 
 // rpcRequest represents a raw incoming RPC request
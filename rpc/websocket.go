@@ -93,8 +93,15 @@ func NewWSServer(allowedOrigins string, handler *Server) *http.Server {
 		Handler: websocket.Server{
 			Handshake: wsHandshakeValidator(strings.Split(allowedOrigins, ",")),
 			Handler: func(conn *websocket.Conn) {
-				handler.ServeCodec(NewJSONCodec(&wsReaderWriterCloser{conn}),
-					OptionMethodInvocation|OptionSubscriptions)
+				caller, err := handler.authenticate(conn.Request())
+				if err != nil {
+					glog.V(logger.Debug).Infof("WS-RPC caller rejected: %v\n", err)
+					conn.Close()
+					return
+				}
+				codec := NewJSONCodec(&wsReaderWriterCloser{conn})
+				defer codec.Close()
+				handler.serveRequest(codec, false, OptionMethodInvocation|OptionSubscriptions, caller)
 			},
 		},
 	}
@@ -0,0 +1,351 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// Package settings holds the DChain settings of nodes and of the CA server.
+//
+// The settings are loaded once from properties.yaml, overridden by BC_CONF_*
+// environment variables (e.g. BC_CONF_CONSENSUS_ALGORITHM) and then by
+// command line flags, and validated before any component uses them. They are
+// passed explicitly to the components, so several nodes with different
+// settings can run in one process.
+package settings
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding settings.
+const EnvPrefix = "BC_CONF"
+
+// DefaultPaths are the directories searched for properties.yaml.
+var DefaultPaths = []string{"./common/", "../../common/"}
+
+// Config holds the settings of properties.yaml.
+type Config struct {
+	File string // properties file the settings were read from, empty for defaults
+
+	CAServer  CAServer
+	Security  Security
+	PKI       PKI
+	Consensus Consensus
+}
+
+// CAServer holds the settings of the CA server and of its clients.
+type CAServer struct {
+	RootPath      string        // directory of the CA in the home directory
+	CADir         string        // subdirectory of RootPath holding the CA
+	DataDir       string        // directory of the CA, overrides RootPath and CADir
+	Port          string        // port the CA services listen on, e.g. ":50051"
+	Address       string        // host of the CA, used with Port if Addresses is empty
+	Addresses     []string      // host:port of every CA instance, tried in turn
+	Intermediate  bool          // run as the intermediate CA of a member organization
+	TCertValidity time.Duration // lifetime of transaction certificates
+	TLS           TLS
+}
+
+// TLS holds the settings securing connections to the CA.
+type TLS struct {
+	Enabled     bool   // serve and dial the CA over TLS
+	ServerName  string // name the TLS certificate of the CA is issued for
+	RootCert    string // pinned CA root certificate file
+	Fingerprint string // SHA-256 fingerprint of the CA root, to fetch and pin it
+	ClientAuth  bool   // require the enrollment certificate for renewal and admin services
+}
+
+// Security holds the cryptographic settings, all members must agree on them.
+type Security struct {
+	Level         int    // 256 or 384, the curve and signature hash size
+	HashAlgorithm string // SHA2 or SHA3
+}
+
+// PKI holds the subject of the CA certificates.
+type PKI struct {
+	Organization string
+	Country      string
+}
+
+// Consensus holds the consensus settings.
+type Consensus struct {
+	Algorithm        string // POW or PBFT
+	Mode             string // PBFT operational mode, only batch
+	N                int    // maximum number of PBFT replicas
+	F                int    // number of byzantine replicas tolerated
+	K                int    // checkpoint period
+	LogMultiplier    int    // the log size is K * LogMultiplier
+	BatchSize        int    // requests per pre-prepare
+	Byzantine        bool   // act as a byzantine replica, for debugging
+	ViewChangePeriod int    // checkpoint periods between automatic view changes, 0 disables
+
+	BatchTimeout            time.Duration // time to wait for a full batch
+	RequestTimeout          time.Duration // time a request may take until executed
+	ViewChangeTimeout       time.Duration // time a view change may take
+	ResendViewChangeTimeout time.Duration // time to wait for a view change quorum before resending
+	NullRequestTimeout      time.Duration // interval of null requests, 0 disables
+	BroadcastTimeout        time.Duration // time a broadcast may take
+}
+
+// defaults are the settings used for keys missing in properties.yaml.
+var defaults = map[string]interface{}{
+	"caserver.rootpath":                  "blockchain",
+	"caserver.cadir":                     "CA",
+	"caserver.datadir":                   "",
+	"caserver.port":                      ":50051",
+	"caserver.address":                   "",
+	"caserver.addresses":                 []string{},
+	"caserver.intermediate":              false,
+	"caserver.tcert.validity":            "24h",
+	"caserver.tls.enabled":               false,
+	"caserver.tls.servername":            "caserver",
+	"caserver.tls.rootcert":              "",
+	"caserver.tls.fingerprint":           "",
+	"caserver.tls.clientauth":            false,
+	"security.level":                     256,
+	"security.hashAlgorithm":             "SHA3",
+	"pki.ca.subject.organization":        "",
+	"pki.ca.subject.country":             "",
+	"consensus.algorithm":                "POW",
+	"consensus.mode":                     "batch",
+	"consensus.N":                        4,
+	"consensus.f":                        1,
+	"consensus.K":                        10,
+	"consensus.logmultiplier":            4,
+	"consensus.batchsize":                2,
+	"consensus.byzantine":                false,
+	"consensus.viewchangeperiod":         0,
+	"consensus.timeout.batch":            "1s",
+	"consensus.timeout.request":          "2s",
+	"consensus.timeout.viewchange":       "2s",
+	"consensus.timeout.resendviewchange": "2s",
+	"consensus.timeout.nullrequest":      "0s",
+	"consensus.timeout.broadcast":        "1s",
+}
+
+// Default returns the default settings.
+func Default() *Config {
+	conf, err := decode(newViper())
+	if err != nil {
+		panic(err)
+	}
+	return conf
+}
+
+// Load reads the settings from file, or from the properties.yaml found in
+// DefaultPaths if file is empty. Environment variables and then overrides,
+// keyed like the file (e.g. "consensus.N"), take precedence over the file.
+// The settings are validated.
+func Load(file string, overrides map[string]interface{}) (*Config, error) {
+	v := newViper()
+	v.SetEnvPrefix(EnvPrefix)
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.SetConfigType("yaml")
+	if file != "" {
+		v.SetConfigFile(file)
+	} else {
+		v.SetConfigName("properties")
+		for _, path := range DefaultPaths {
+			v.AddConfigPath(path)
+		}
+	}
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read properties: %v", err)
+	}
+	for key, value := range overrides {
+		v.Set(key, value)
+	}
+	conf, err := decode(v)
+	if err != nil {
+		return nil, err
+	}
+	conf.File = v.ConfigFileUsed()
+	if abs, err := filepath.Abs(conf.File); err == nil {
+		conf.File = abs
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func newViper() *viper.Viper {
+	v := viper.New()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+	return v
+}
+
+// decoder reads typed settings, keeping the first malformed one.
+type decoder struct {
+	v   *viper.Viper
+	err error
+}
+
+func (d *decoder) fail(key string, kind string) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%s: invalid %s %q", key, kind, fmt.Sprint(d.v.Get(key)))
+	}
+}
+
+func (d *decoder) string(key string) string {
+	return d.v.GetString(key)
+}
+
+func (d *decoder) strings(key string) []string {
+	var list []string
+	for _, s := range d.v.GetStringSlice(key) {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func (d *decoder) int(key string) int {
+	n, err := cast.ToIntE(d.v.Get(key))
+	if err != nil {
+		d.fail(key, "integer")
+	}
+	return n
+}
+
+func (d *decoder) bool(key string) bool {
+	b, err := cast.ToBoolE(d.v.Get(key))
+	if err != nil {
+		d.fail(key, "boolean")
+	}
+	return b
+}
+
+func (d *decoder) duration(key string) time.Duration {
+	t, err := cast.ToDurationE(d.v.Get(key))
+	if err != nil {
+		d.fail(key, "duration")
+	}
+	return t
+}
+
+func decode(v *viper.Viper) (*Config, error) {
+	d := &decoder{v: v}
+	conf := &Config{
+		CAServer: CAServer{
+			RootPath:      d.string("caserver.rootpath"),
+			CADir:         d.string("caserver.cadir"),
+			DataDir:       d.string("caserver.datadir"),
+			Port:          d.string("caserver.port"),
+			Address:       d.string("caserver.address"),
+			Addresses:     d.strings("caserver.addresses"),
+			Intermediate:  d.bool("caserver.intermediate"),
+			TCertValidity: d.duration("caserver.tcert.validity"),
+			TLS: TLS{
+				Enabled:     d.bool("caserver.tls.enabled"),
+				ServerName:  d.string("caserver.tls.servername"),
+				RootCert:    d.string("caserver.tls.rootcert"),
+				Fingerprint: d.string("caserver.tls.fingerprint"),
+				ClientAuth:  d.bool("caserver.tls.clientauth"),
+			},
+		},
+		Security: Security{
+			Level:         d.int("security.level"),
+			HashAlgorithm: d.string("security.hashAlgorithm"),
+		},
+		PKI: PKI{
+			Organization: d.string("pki.ca.subject.organization"),
+			Country:      d.string("pki.ca.subject.country"),
+		},
+		Consensus: Consensus{
+			Algorithm:               d.string("consensus.algorithm"),
+			Mode:                    d.string("consensus.mode"),
+			N:                       d.int("consensus.N"),
+			F:                       d.int("consensus.f"),
+			K:                       d.int("consensus.K"),
+			LogMultiplier:           d.int("consensus.logmultiplier"),
+			BatchSize:               d.int("consensus.batchsize"),
+			Byzantine:               d.bool("consensus.byzantine"),
+			ViewChangePeriod:        d.int("consensus.viewchangeperiod"),
+			BatchTimeout:            d.duration("consensus.timeout.batch"),
+			RequestTimeout:          d.duration("consensus.timeout.request"),
+			ViewChangeTimeout:       d.duration("consensus.timeout.viewchange"),
+			ResendViewChangeTimeout: d.duration("consensus.timeout.resendviewchange"),
+			NullRequestTimeout:      d.duration("consensus.timeout.nullrequest"),
+			BroadcastTimeout:        d.duration("consensus.timeout.broadcast"),
+		},
+	}
+	return conf, d.err
+}
+
+// Validate checks the settings, naming the offending key in the error.
+func (c *Config) Validate() error {
+	if c.CAServer.Port == "" {
+		return fmt.Errorf("caserver.port: must be set")
+	}
+	if c.CAServer.TCertValidity < 0 {
+		return fmt.Errorf("caserver.tcert.validity: must not be negative, have %v", c.CAServer.TCertValidity)
+	}
+	if c.Security.Level != 256 && c.Security.Level != 384 {
+		return fmt.Errorf("security.level: must be 256 or 384, have %d", c.Security.Level)
+	}
+	if c.Security.HashAlgorithm != "SHA2" && c.Security.HashAlgorithm != "SHA3" {
+		return fmt.Errorf("security.hashAlgorithm: must be SHA2 or SHA3, have %q", c.Security.HashAlgorithm)
+	}
+	switch c.Consensus.Algorithm {
+	case "POW":
+		return nil
+	case "PBFT":
+		return c.Consensus.validatePBFT()
+	default:
+		return fmt.Errorf("consensus.algorithm: must be POW or PBFT, have %q", c.Consensus.Algorithm)
+	}
+}
+
+func (c *Consensus) validatePBFT() error {
+	if !strings.EqualFold(c.Mode, "batch") {
+		return fmt.Errorf("consensus.mode: must be batch, have %q", c.Mode)
+	}
+	if c.F < 0 {
+		return fmt.Errorf("consensus.f: must not be negative, have %d", c.F)
+	}
+	if c.N < 3*c.F+1 {
+		return fmt.Errorf("consensus.N: need at least %d replicas to tolerate %d byzantine faults, have %d", 3*c.F+1, c.F, c.N)
+	}
+	if c.K <= 0 {
+		return fmt.Errorf("consensus.K: must be positive, have %d", c.K)
+	}
+	if c.LogMultiplier < 2 {
+		return fmt.Errorf("consensus.logmultiplier: must be at least 2, have %d", c.LogMultiplier)
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("consensus.batchsize: must be positive, have %d", c.BatchSize)
+	}
+	if c.ViewChangePeriod < 0 {
+		return fmt.Errorf("consensus.viewchangeperiod: must not be negative, have %d", c.ViewChangePeriod)
+	}
+	for _, t := range []struct {
+		key     string
+		timeout time.Duration
+	}{
+		{"consensus.timeout.batch", c.BatchTimeout},
+		{"consensus.timeout.request", c.RequestTimeout},
+		{"consensus.timeout.viewchange", c.ViewChangeTimeout},
+		{"consensus.timeout.resendviewchange", c.ResendViewChangeTimeout},
+		{"consensus.timeout.broadcast", c.BroadcastTimeout},
+	} {
+		if t.timeout <= 0 {
+			return fmt.Errorf("%s: must be positive, have %v", t.key, t.timeout)
+		}
+	}
+	if c.NullRequestTimeout < 0 {
+		return fmt.Errorf("consensus.timeout.nullrequest: must not be negative, have %v", c.NullRequestTimeout)
+	}
+	return nil
+}
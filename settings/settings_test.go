@@ -0,0 +1,100 @@
+// Copyright Dianrong.com Corp. 2016 All Rights Reserved.
+//
+// The DChain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+package settings
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeProperties(t *testing.T, contents string) (string, func()) {
+	dir, err := ioutil.TempDir("", "dchain-config")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	file := filepath.Join(dir, "properties.yaml")
+	if err := ioutil.WriteFile(file, []byte(contents), 0600); err != nil {
+		t.Fatalf("failed to write properties: %v", err)
+	}
+	return file, func() { os.RemoveAll(dir) }
+}
+
+func TestLoad(t *testing.T) {
+	file, cleanup := writeProperties(t, `
+caserver:
+    addresses: ["ca1:50051", " ", "ca2:50051"]
+consensus:
+    algorithm: "PBFT"
+    "N": 4
+    f: 1
+    timeout:
+        request: 3s
+`)
+	defer cleanup()
+
+	os.Setenv("BC_CONF_CONSENSUS_BATCHSIZE", "7")
+	defer os.Unsetenv("BC_CONF_CONSENSUS_BATCHSIZE")
+
+	conf, err := Load(file, map[string]interface{}{"consensus.N": 7, "consensus.f": 2})
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if conf.File != file {
+		t.Errorf("file mismatch: have %s, want %s", conf.File, file)
+	}
+	c := conf.Consensus
+	if c.Algorithm != "PBFT" || c.N != 7 || c.F != 2 || c.BatchSize != 7 {
+		t.Errorf("consensus mismatch: %+v", c)
+	}
+	if c.RequestTimeout != 3*time.Second || c.BatchTimeout != time.Second || c.K != 10 {
+		t.Errorf("file values or defaults not applied: %+v", c)
+	}
+	if addrs := conf.CAServer.Addresses; len(addrs) != 2 || addrs[1] != "ca2:50051" {
+		t.Errorf("CA addresses mismatch: %v", addrs)
+	}
+	if conf.Security.Level != 256 || conf.Security.HashAlgorithm != "SHA3" {
+		t.Errorf("security defaults mismatch: %+v", conf.Security)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		properties string
+		key        string
+	}{
+		{"consensus:\n    algorithm: RAFT\n", "consensus.algorithm"},
+		{"consensus:\n    algorithm: PBFT\n    \"N\": 3\n    f: 1\n", "consensus.N"},
+		{"consensus:\n    algorithm: PBFT\n    \"N\": four\n", "consensus.N"},
+		{"consensus:\n    algorithm: PBFT\n    logmultiplier: 1\n", "consensus.logmultiplier"},
+		{"consensus:\n    algorithm: PBFT\n    timeout:\n        request: soon\n", "consensus.timeout.request"},
+		{"consensus:\n    algorithm: PBFT\n    timeout:\n        broadcast: 0s\n", "consensus.timeout.broadcast"},
+		{"security:\n    level: 512\n", "security.level"},
+		{"caserver:\n    tls:\n        enabled: maybe\n", "caserver.tls.enabled"},
+	}
+	for i, tt := range tests {
+		file, cleanup := writeProperties(t, tt.properties)
+		_, err := Load(file, nil)
+		cleanup()
+		if err == nil || !strings.HasPrefix(err.Error(), tt.key+":") {
+			t.Errorf("test %d: error mismatch: have %v, want one for %s", i, err, tt.key)
+		}
+	}
+	if _, err := Load(filepath.Join(os.TempDir(), "missing.yaml"), nil); err == nil {
+		t.Error("missing properties file accepted")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("invalid defaults: %v", err)
+	}
+}